@@ -10,6 +10,49 @@ type GeneralConfig struct {
 	CallPrefix string `toml:"callprefix" json:"callprefix"`
 }
 
+// PermissionsConfig contains the initial roles of users and the roles needed to execute commands.
+// Users are identified by their ID or name. Further roles can be granted at runtime.
+type PermissionsConfig struct {
+	Admins []string `toml:"admins" bson:"admins"`
+	Mods   []string `toml:"mods" bson:"mods"`
+
+	// PlatformAdmins gives the admin role to the admins of the platform, e.g., Slack workspace admins.
+	PlatformAdmins bool `toml:"platformadmins" bson:"platformadmins"`
+
+	// Commands maps a command (without call prefix) to the role needed to execute it.
+	// The command is the name it is available under, i.e., the new name of a renamed command.
+	Commands map[string]string `toml:"commands" bson:"commands"`
+}
+
+// CooldownConfig holds the cooldowns of a command as durations, e.g., "30s". Empty means no cooldown.
+type CooldownConfig struct {
+	// User is the time a user has to wait before using the command again.
+	User string `toml:"user" bson:"user"`
+	// Channel is the time before the command can be used again in the same channel.
+	Channel string `toml:"channel" bson:"channel"`
+}
+
+// CooldownsConfig holds the cooldowns of the commands of a bot.
+type CooldownsConfig struct {
+	// ExemptMods disables the cooldowns for users with the mod role.
+	ExemptMods bool `toml:"exemptmods" bson:"exemptmods"`
+	// Reply tells users when a command is on cooldown instead of silently ignoring it.
+	Reply bool `toml:"reply" bson:"reply"`
+
+	// Default is used for all commands without their own entry in Commands.
+	Default CooldownConfig `toml:"default" bson:"default"`
+	// Commands maps a command (without call prefix), by the name it is available under, to its cooldowns.
+	Commands map[string]CooldownConfig `toml:"commands" bson:"commands"`
+}
+
+// QueueConfig holds the configuration of the event queues of the plugins of a bot.
+type QueueConfig struct {
+	// Size is the number of events each plugin queue can hold.
+	Size int `toml:"size" bson:"size"`
+	// Policy decides what happens if a queue is full: "dropoldest" (default), "dropnew" or "block".
+	Policy string `toml:"policy" bson:"policy"`
+}
+
 // StorageConfig holds the configuration for a storage
 type StorageConfig struct {
 	Type string `toml:"type" bson:"type"`
@@ -17,10 +60,20 @@ type StorageConfig struct {
 	Config map[string]interface{} `toml:"config" bson:"config"`
 }
 
+// PluginCommandsConfig holds the names under which the commands of a plugin are available.
+type PluginCommandsConfig struct {
+	// Rename maps a command of the plugin to the name under which it is available instead.
+	Rename map[string]string `toml:"rename" bson:"rename"`
+	// Aliases maps a command of the plugin to additional names under which it is available.
+	Aliases map[string][]string `toml:"aliases" bson:"aliases"`
+}
+
 // PluginConfig holds the configuration for one plugin
 type PluginConfig struct {
 	Type string `toml:"type" bson:"type"`
 
+	Commands PluginCommandsConfig `toml:"commands" bson:"commands"`
+
 	Config map[string]interface{} `toml:"config" bson:"config"`
 }
 
@@ -33,8 +86,11 @@ type BotConfig struct {
 	Type    string `toml:"type" bson:"type"`
 	Enabled bool   `toml:"enabled" bson:"enabled"`
 
-	GeneralConfig GeneralConfig `toml:"general" bson:"general"`
-	StorageConfig StorageConfig `toml:"storage" bson:"storage"`
+	GeneralConfig     GeneralConfig     `toml:"general" bson:"general"`
+	StorageConfig     StorageConfig     `toml:"storage" bson:"storage"`
+	PermissionsConfig PermissionsConfig `toml:"permissions" bson:"permissions"`
+	CooldownsConfig   CooldownsConfig   `toml:"cooldowns" bson:"cooldowns"`
+	QueueConfig       QueueConfig       `toml:"queue" bson:"queue"`
 
 	Config  map[string]interface{} `toml:"config" bson:"config"`
 	Plugins PluginConfigs          `toml:"plugins" bson:"plugins"`
@@ -66,10 +122,20 @@ func (c *BotConfig) AsDiscordConfig() (DiscordConfig, error) {
 		return DiscordConfig{}, fmt.Errorf("Cannot convert to Discord config, missing/unconvertible secret")
 	}
 
+	// the remaining options are optional
+	compress, _ := c.Config["compress"].(bool)
+	slashCommands, _ := c.Config["slash_commands"].(string)
+	if slashCommands != "" && slashCommands != "global" && slashCommands != "guild" {
+		return DiscordConfig{}, fmt.Errorf("Cannot convert to Discord config, slash_commands must be \"global\" or \"guild\"")
+	}
+
 	discordCfg := DiscordConfig{
-		ID:     id,
-		Token:  token,
-		Secret: secret,
+		ID:            id,
+		Token:         token,
+		Secret:        secret,
+		Shards:        intValue(c.Config["shards"]),
+		Compress:      compress,
+		SlashCommands: slashCommands,
 	}
 
 	return discordCfg, nil
@@ -90,23 +156,68 @@ func (c *BotConfig) AsMatrixConfig() (MatrixConfig, error) {
 		return MatrixConfig{}, fmt.Errorf("Cannot convert to Matrix config, missing/unconvertible server")
 	}
 
-	if username, ok = c.Config["username"].(string); !ok {
+	// Username and password are only needed when no token is given
+	token, _ := c.Config["token"].(string)
+
+	if username, ok = c.Config["username"].(string); !ok && len(token) == 0 {
 		return MatrixConfig{}, fmt.Errorf("Cannot convert to Matrix config, missing/unconvertible username")
 	}
 
-	if password, ok = c.Config["password"].(string); !ok {
+	if password, ok = c.Config["password"].(string); !ok && len(token) == 0 {
 		return MatrixConfig{}, fmt.Errorf("Cannot convert to Matrix config, missing/unconvertible password")
 	}
 
+	// the remaining options are optional
+	deviceID, _ := c.Config["device_id"].(string)
+	encryption, _ := c.Config["encryption"].(bool)
+	stateKey, _ := c.Config["state_key"].(string)
+	leaveEmptyRooms, _ := c.Config["leave_empty_rooms"].(bool)
+
 	mCfg := MatrixConfig{
-		Server:   server,
-		Username: username,
-		Password: password,
+		Server:          server,
+		Username:        username,
+		Password:        password,
+		Token:           token,
+		DeviceID:        deviceID,
+		Encryption:      encryption,
+		StateKey:        stateKey,
+		Rooms:           stringList(c.Config["rooms"]),
+		InviteAllowList: stringList(c.Config["invite_allowlist"]),
+		LeaveEmptyRooms: leaveEmptyRooms,
 	}
 
 	return mCfg, nil
 }
 
+// intValue returns the value of a number in the config or 0 if it is not a number.
+// Depending on the source of the config numbers are decoded into different types.
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// stringList returns the strings of a list in the config. Entries which are no strings are skipped.
+func stringList(value interface{}) []string {
+	var list []string
+	if entries, ok := value.([]interface{}); ok {
+		for _, entry := range entries {
+			if s, ok := entry.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
 // AsMattermostConfig converts the config to a MattermostConfig
 func (c *BotConfig) AsMattermostConfig() (MattermostConfig, error) {
 	if c.Type != "mattermost" {
@@ -157,9 +268,31 @@ func (c *BotConfig) AsSlackConfig() (SlackConfig, error) {
 		return SlackConfig{}, fmt.Errorf("Cannot convert to Slack config, missing/unconvertible token")
 	}
 
+	// the remaining options are optional, depending on the transport
+	transport, _ := c.Config["transport"].(string)
+	appToken, _ := c.Config["app_token"].(string)
+	signingSecret, _ := c.Config["signing_secret"].(string)
+
+	switch transport {
+	case "", "rtm":
+	case "socket":
+		if len(appToken) == 0 {
+			return SlackConfig{}, fmt.Errorf("Cannot convert to Slack config, Socket Mode requires an app_token")
+		}
+	case "events":
+		if len(signingSecret) == 0 {
+			return SlackConfig{}, fmt.Errorf("Cannot convert to Slack config, Events API requires a signing_secret")
+		}
+	default:
+		return SlackConfig{}, fmt.Errorf("Cannot convert to Slack config, unknown transport %s", transport)
+	}
+
 	slackCfg := SlackConfig{
-		Workspace: workspace,
-		Token:     token,
+		Workspace:     workspace,
+		Token:         token,
+		Transport:     transport,
+		AppToken:      appToken,
+		SigningSecret: signingSecret,
 	}
 
 	return slackCfg, nil
@@ -67,6 +67,33 @@ func TestBotConfig_AsDiscordConfig(t *testing.T) {
 	}
 	_, err = botConfig.AsDiscordConfig()
 	assert.Error(err)
+
+	botConfig = BotConfig{
+		Type: "discord",
+		Config: map[string]interface{}{
+			"id":             "some_id",
+			"token":          "username_goes_here",
+			"secret":         "sectet_goes_here",
+			"shards":         int64(4),
+			"compress":       true,
+			"slash_commands": "guild",
+		},
+	}
+	expectedDConfig = DiscordConfig{
+		ID:            "some_id",
+		Token:         "username_goes_here",
+		Secret:        "sectet_goes_here",
+		Shards:        4,
+		Compress:      true,
+		SlashCommands: "guild",
+	}
+	actualDConfig, err = botConfig.AsDiscordConfig()
+	assert.NoError(err)
+	assert.Equal(expectedDConfig, actualDConfig)
+
+	botConfig.Config["slash_commands"] = "everywhere"
+	_, err = botConfig.AsDiscordConfig()
+	assert.Error(err)
 }
 
 func TestBotConfig_AsMatrixConfig(t *testing.T) {
@@ -123,6 +150,44 @@ func TestBotConfig_AsMatrixConfig(t *testing.T) {
 	}
 	_, err = botConfig.AsMatrixConfig()
 	assert.Error(err)
+
+	botConfig = BotConfig{
+		Type: "matrix",
+		Config: map[string]interface{}{
+			"server":     "https://server.com",
+			"username":   "username_goes_here",
+			"password":   "password_goes_here",
+			"encryption": true,
+			"state_key":  "passphrase",
+		},
+	}
+	actualMConfig, err = botConfig.AsMatrixConfig()
+	assert.NoError(err)
+	assert.True(actualMConfig.Encryption)
+	assert.Equal("passphrase", actualMConfig.StateKey)
+
+	botConfig = BotConfig{
+		Type: "matrix",
+		Config: map[string]interface{}{
+			"server":            "https://server.com",
+			"token":             "token_goes_here",
+			"device_id":         "DEVICE",
+			"rooms":             []interface{}{"#room:server.com", "!id:server.com"},
+			"invite_allowlist":  []interface{}{"@user:server.com", "server.com"},
+			"leave_empty_rooms": true,
+		},
+	}
+	expectedMConfig = MatrixConfig{
+		Server:          "https://server.com",
+		Token:           "token_goes_here",
+		DeviceID:        "DEVICE",
+		Rooms:           []string{"#room:server.com", "!id:server.com"},
+		InviteAllowList: []string{"@user:server.com", "server.com"},
+		LeaveEmptyRooms: true,
+	}
+	actualMConfig, err = botConfig.AsMatrixConfig()
+	assert.NoError(err)
+	assert.Equal(expectedMConfig, actualMConfig)
 }
 
 func TestBotConfig_AsMattermostConfig(t *testing.T) {
@@ -233,6 +298,32 @@ func TestBotConfig_AsSlackConfig(t *testing.T) {
 	}
 	_, err = botConfig.AsSlackConfig()
 	assert.Error(err)
+
+	botConfig = BotConfig{
+		Type: "slack",
+		Config: map[string]interface{}{
+			"workspace": "something",
+			"token":     "token_goes_here",
+			"transport": "socket",
+			"app_token": "app_token_goes_here",
+		},
+	}
+	actualSlackConfig, err = botConfig.AsSlackConfig()
+	assert.NoError(err)
+	assert.Equal(SlackConfig{Workspace: "something", Token: "token_goes_here", Transport: "socket", AppToken: "app_token_goes_here"}, actualSlackConfig)
+
+	botConfig.Config["transport"] = "events"
+	_, err = botConfig.AsSlackConfig()
+	assert.Error(err)
+
+	botConfig.Config["signing_secret"] = "secret"
+	actualSlackConfig, err = botConfig.AsSlackConfig()
+	assert.NoError(err)
+	assert.Equal("secret", actualSlackConfig.SigningSecret)
+
+	botConfig.Config["transport"] = "carrier_pigeon"
+	_, err = botConfig.AsSlackConfig()
+	assert.Error(err)
 }
 
 func TestBotConfig_AsTwitchConfig(t *testing.T) {
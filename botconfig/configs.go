@@ -5,6 +5,14 @@ type DiscordConfig struct {
 	ID     string `toml:"id" json:"id"`
 	Token  string `toml:"token" json:"token"`
 	Secret string `toml:"secret" json:"secret"`
+
+	// Shards is the number of gateway connections. If it is 0 the number recommended by Discord is used.
+	Shards int `toml:"shards" json:"shards"`
+	// Compress enables the zlib-stream compression of the gateway connections.
+	Compress bool `toml:"compress" json:"compress"`
+	// SlashCommands registers the commands as "global" or "guild" application commands.
+	// If it is empty no application commands are registered.
+	SlashCommands string `toml:"slash_commands" json:"slash_commands"`
 }
 
 // MatrixConfig contains config related to the Matrix component
@@ -12,6 +20,24 @@ type MatrixConfig struct {
 	Server   string `toml:"server" json:"server"`
 	Username string `toml:"username" json:"username"`
 	Password string `toml:"password" json:"password"`
+	// Token is a pre-issued access token which is used instead of username and password.
+	Token string `toml:"token" json:"token"`
+	// DeviceID is the device to log in with. If empty the device of the last login or a new device is used.
+	DeviceID string `toml:"device_id" json:"device_id"`
+
+	// Encryption enables end-to-end encryption, so that the bot can take part in encrypted rooms.
+	Encryption bool `toml:"encryption" json:"encryption"`
+	// StateKey is a passphrase the private keys of the encryption state are encrypted with before
+	// they are stored. Without it they are stored unencrypted.
+	StateKey string `toml:"state_key" json:"state_key"`
+
+	// Rooms are joined on start, given by room ID or alias, e.g., #room:matrix.org.
+	Rooms []string `toml:"rooms" json:"rooms"`
+	// InviteAllowList contains the users, e.g., @user:matrix.org, and servers, e.g., matrix.org,
+	// whose invites are accepted. If it is empty all invites are accepted.
+	InviteAllowList []string `toml:"invite_allowlist" json:"invite_allowlist"`
+	// LeaveEmptyRooms lets the bot leave rooms in which it is the only member.
+	LeaveEmptyRooms bool `toml:"leave_empty_rooms" json:"leave_empty_rooms"`
 }
 
 // MattermostConfig contains config related to the Mattermost component
@@ -25,6 +51,14 @@ type MattermostConfig struct {
 type SlackConfig struct {
 	Workspace string `toml:"workspace" json:"workspace"`
 	Token     string `toml:"token" json:"token"`
+
+	// Transport is the way events are received: "rtm" (default), "socket" for Socket Mode
+	// or "events" for the HTTP Events API mounted on the control API.
+	Transport string `toml:"transport" json:"transport"`
+	// AppToken is the app-level token (xapp-...) needed for Socket Mode.
+	AppToken string `toml:"app_token" json:"app_token"`
+	// SigningSecret is used to verify the requests of the Events API.
+	SigningSecret string `toml:"signing_secret" json:"signing_secret"`
 }
 
 // TwitchConfig contains config related to the Twitch component
@@ -71,6 +71,10 @@ func TestTomlBotProvider_ParseTomlBotConfigFromFile(t *testing.T) {
 					},
 					"3": botconfig.PluginConfig{
 						Type: "roll",
+						Commands: botconfig.PluginCommandsConfig{
+							Rename:  map[string]string{"roll": "dice"},
+							Aliases: map[string][]string{"roll": {"r", "d"}},
+						},
 					},
 				},
 			},
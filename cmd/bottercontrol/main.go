@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/pool"
 )
 
@@ -41,6 +42,38 @@ func getBots(url string) {
 	}
 }
 
+func getBot(url string, botID string) {
+	data, code, err := realAPICall(url, "/bots/"+botID, "GET", "")
+	if err != nil {
+		fmt.Printf("Error getting bot with ID %s: %s", botID, err)
+		return
+	}
+
+	if code == 400 {
+		fmt.Printf("Error getting bot with ID %s: Bad Request %s", botID, data)
+		return
+	} else if code != 200 {
+		fmt.Printf("Error getting bot with ID %s: Unknown StatusCode: %d", botID, code)
+		return
+	}
+
+	info := platform.BotInfo{}
+	err = json.Unmarshal(data, &info)
+	if err != nil {
+		fmt.Printf("Error getting bot with ID %s: %s", botID, err)
+		return
+	}
+
+	fmt.Printf("Bot %s (%s) @ %s, healthy: %t\n", info.BotID, info.Platform, url, info.Healthy)
+	for _, p := range info.Plugins {
+		fmt.Printf("\t%s: active: %t, queue: %d/%d, dropped: %d", p.Plugin, p.Active, p.QueueDepth, p.QueueCapacity, p.QueueDropped)
+		if len(p.Error) > 0 {
+			fmt.Printf(", error: %s", p.Error)
+		}
+		fmt.Printf("\n")
+	}
+}
+
 type botAddRemoveRequest struct {
 	BotID string `json:"botId"`
 }
@@ -114,6 +147,7 @@ func main() {
 	if *listCommands {
 		fmt.Printf("Commands (case insensitive):\n")
 		fmt.Printf("GetBots: \nReturns all running bots, argument: NONE\n")
+		fmt.Printf("GetBot: \nReturns the state of a bot and its plugins, argument: botID\n")
 		fmt.Printf("StartBot: \nStarts a bot, argument: botID\n")
 		fmt.Printf("StopBot: \nStops a bot, argument: botID\n")
 		os.Exit(0)
@@ -138,6 +172,12 @@ func main() {
 	switch lowerCaseCommand {
 	case "getbots":
 		getBots(cleanURL)
+	case "getbot":
+		if len(*argument) == 0 {
+			fmt.Printf("Must specify a bot id as argument")
+			os.Exit(1)
+		}
+		getBot(cleanURL, *argument)
 	case "startbot":
 		if len(*argument) == 0 {
 			fmt.Printf("Must specify a bot id as argument")
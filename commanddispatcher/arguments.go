@@ -0,0 +1,90 @@
+package commanddispatcher
+
+import (
+	"time"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// Arguments holds the parsed arguments of a command according to its CommandSpec.
+type Arguments struct {
+	// Subcommand is the matched subcommand path, e.g., "remove" or "remove all". Empty if none matched.
+	Subcommand string
+	// Raw is the whitespace-trimmed content where the command is already stripped off.
+	Raw string
+
+	values map[string]interface{}
+}
+
+func (a Arguments) value(name string) interface{} {
+	if a.values == nil {
+		return nil
+	}
+	return a.values[name]
+}
+
+// Has returns true if the argument or flag with that name was provided.
+func (a Arguments) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String returns the argument as string. Text arguments are returned as they were entered.
+// For user and channel arguments the ID, or if not available the name, is returned.
+func (a Arguments) String(name string) string {
+	switch v := a.value(name).(type) {
+	case string:
+		return v
+	case model.User:
+		if len(v.ID) > 0 {
+			return v.ID
+		}
+		return v.Name
+	case model.Channel:
+		if len(v.ID) > 0 {
+			return v.ID
+		}
+		return v.Name
+	}
+	return ""
+}
+
+// Int returns the argument as int or 0 if it was not provided.
+func (a Arguments) Int(name string) int {
+	if v, ok := a.value(name).(int); ok {
+		return v
+	}
+	return 0
+}
+
+// Duration returns the argument as time.Duration or 0 if it was not provided.
+func (a Arguments) Duration(name string) time.Duration {
+	if v, ok := a.value(name).(time.Duration); ok {
+		return v
+	}
+	return 0
+}
+
+// User returns the mentioned user. Depending on the mention format only ID or Name is filled.
+func (a Arguments) User(name string) model.User {
+	if v, ok := a.value(name).(model.User); ok {
+		return v
+	}
+	return model.User{}
+}
+
+// Channel returns the mentioned channel. Depending on the mention format only ID or Name is filled.
+func (a Arguments) Channel(name string) model.Channel {
+	if v, ok := a.value(name).(model.Channel); ok {
+		return v
+	}
+	return model.Channel{}
+}
+
+// Bool returns true if the boolean flag was provided.
+func (a Arguments) Bool(name string) bool {
+	if v, ok := a.value(name).(bool); ok {
+		return v
+	}
+	return false
+}
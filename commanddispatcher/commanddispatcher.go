@@ -2,7 +2,12 @@ package commanddispatcher
 
 import (
 	"fmt"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/torlenor/redseligg/logging"
 	"github.com/torlenor/redseligg/model"
@@ -12,17 +17,85 @@ var log = logging.Get("CommandDispatcher")
 
 var defaultCallPrefix = "!"
 
+// defaultTimeout is the time a receiver gets to handle a command before the call is considered as timed out.
+const defaultTimeout = 30 * time.Second
+
+// maxTimeouts is the number of consecutive timeouts after which a receiver stays marked as failed.
+const maxTimeouts = 3
+
+// States of a call to a receiver
+const (
+	callRunning = iota
+	callReturned
+	callAbandoned
+)
+
 type receiver interface {
 	// OnCommand delivers the command, the whitespace-trimmed content where the command is already stripped off and the raw Post.
 	OnCommand(cmd string, content string, post model.Post)
 }
 
+type parsedReceiver interface {
+	receiver
+	// OnParsedCommand delivers the command, the arguments parsed according to the registered CommandSpec and the raw Post.
+	OnParsedCommand(cmd string, args Arguments, post model.Post)
+}
+
+type authorizer interface {
+	// IsMod returns true if the user has the mod role in the given channel.
+	IsMod(user model.User, channelID string) bool
+	// IsAllowed returns true if the author of the post is allowed to execute the command.
+	// The command is the name the command is available under, not the alias it was called with.
+	IsAllowed(cmd string, post model.Post) bool
+}
+
+type queues interface {
+	// Enqueue adds the function to the queue of the receiver. It returns false if it was dropped.
+	Enqueue(key interface{}, f func()) bool
+}
+
+type poster interface {
+	CreatePost(post model.Post) (model.PostResponse, error)
+}
+
+// registration is one receiver registered for a command name.
+type registration struct {
+	receiver receiver
+	// command is the command the receiver registered, it may differ from the name it is registered under.
+	command string
+	// name is the name the command is available under, also for its aliases.
+	name string
+	spec    *CommandSpec
+	help    CommandHelp
+	// alias is true if the name is an additional alias of the command.
+	alias   bool
+	aliases []string
+}
+
 // CommandDispatcher provides an architecture to let plugins (or other entities) register commands and get notified.
-// At first we will only support one receiver for a specific command.
+// Several receivers can be registered for the same command, but this is reported as a conflict.
+// It is safe for concurrent use. A receiver which panics is marked as failed and does not get any
+// further commands. A receiver which does not return within the timeout is marked as failed until
+// the call returns after all, unless that happened several times in a row.
 type CommandDispatcher struct {
 	callPrefix string
 
-	receivers map[string]receiver // [cmd]
+	mutex sync.RWMutex
+
+	receivers map[string][]registration // [cmd]
+	triggers  []triggerRegistration
+	conflicts []Conflict
+	failures  map[interface{}]error
+	timeouts  map[interface{}]int
+	mentions  []string
+
+	middlewares []Middleware
+
+	poster           poster
+	authorizer       authorizer
+	queues           queues
+	maxMessageLength int
+	timeout          time.Duration
 }
 
 // New CommandDispatcher
@@ -36,77 +109,571 @@ func New(callPrefix string) *CommandDispatcher {
 
 	log.Tracef("Created new CommandDispatcher with call prefix = '%s'", c.callPrefix)
 
-	c.receivers = make(map[string]receiver)
+	c.receivers = make(map[string][]registration)
+	c.failures = make(map[interface{}]error)
+	c.timeouts = make(map[interface{}]int)
+	c.timeout = defaultTimeout
 
 	return &c
 }
 
+// SetPoster sets the entity which is used to send replies, e.g., usage errors, back to the user.
+func (c *CommandDispatcher) SetPoster(p poster) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.poster = p
+}
+
+// SetAuthorizer sets the entity which decides if a user is allowed to execute a command.
+// Without authorizer every user is allowed to execute every command.
+func (c *CommandDispatcher) SetAuthorizer(a authorizer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.authorizer = a
+}
+
+// SetQueues sets the queues the commands are added to, so that they are handled asynchronously
+// by each receiver. Without queues the commands are handled synchronously.
+func (c *CommandDispatcher) SetQueues(q queues) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.queues = q
+}
+
+// SetMaxMessageLength sets the maximum length of a message on the platform.
+// Longer replies, e.g., the help, are split into several messages. A value <= 0 means no limit.
+func (c *CommandDispatcher) SetMaxMessageLength(maxLength int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxMessageLength = maxLength
+}
+
+// SetTimeout sets the time a receiver gets to handle a command. A value <= 0 means no timeout.
+func (c *CommandDispatcher) SetTimeout(timeout time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeout = timeout
+}
+
 // Register a new command receiver with the specified command (without call prefix).
-func (c *CommandDispatcher) Register(cmd string, r receiver) {
+// The help is used to generate the output of the help command.
+// If the receiver provides CommandNames, the command is registered under the configured names instead.
+// If another receiver is already registered for the command, both receive the command
+// and a *ConflictError is returned.
+func (c *CommandDispatcher) Register(cmd string, r receiver, help CommandHelp) error {
 	log.Tracef("Registering command %s", cmd)
-	if len(cmd) > 0 {
-		c.receivers[cmd] = r
-	} else {
+	if len(cmd) == 0 {
 		log.Warn("Tried to register an empty command")
+		return fmt.Errorf("Cannot register an empty command")
+	}
+
+	name, aliases := commandNamesOf(r).names(cmd)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.register(name, registration{receiver: r, command: cmd, name: name, help: help.renamed(cmd, name), aliases: aliases})
+	for _, alias := range aliases {
+		if aliasErr := c.register(alias, registration{receiver: r, command: cmd, name: name, help: help.renamed(cmd, alias), alias: true}); aliasErr != nil {
+			err = aliasErr
+		}
 	}
+
+	return err
 }
 
-// Unregister removes the specified command from the receivers list if it exists.
+// RegisterSpec registers a new command receiver for the command declared in the spec.
+// The content of the command is parsed according to the spec and the receiver gets the
+// parsed arguments via OnParsedCommand. If parsing fails, a usage error is sent back to the user.
+// Names and conflicts are handled as in Register.
+func (c *CommandDispatcher) RegisterSpec(spec CommandSpec, r parsedReceiver) error {
+	log.Tracef("Registering command %s with spec", spec.Command)
+	if len(spec.Command) == 0 {
+		log.Warn("Tried to register an empty command")
+		return fmt.Errorf("Cannot register an empty command")
+	}
+
+	name, aliases := commandNamesOf(r).names(spec.Command)
+
+	newReg := func(called string, alias bool) registration {
+		renamed := spec
+		renamed.Command = called
+		return registration{receiver: r, command: spec.Command, name: name, spec: &renamed, help: renamed.Help(), alias: alias}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	reg := newReg(name, false)
+	reg.aliases = aliases
+	err := c.register(name, reg)
+	for _, alias := range aliases {
+		if aliasErr := c.register(alias, newReg(alias, true)); aliasErr != nil {
+			err = aliasErr
+		}
+	}
+
+	return err
+}
+
+// register has to be called with the mutex locked.
+func (c *CommandDispatcher) register(name string, reg registration) error {
+	regs := c.receivers[name]
+	for i := range regs {
+		if regs[i].receiver == reg.receiver {
+			regs[i] = reg
+			return nil
+		}
+	}
+	c.receivers[name] = append(regs, reg)
+
+	if len(regs) == 0 {
+		return nil
+	}
+
+	conflict := Conflict{Command: name}
+	for _, r := range c.receivers[name] {
+		conflict.Receivers = append(conflict.Receivers, receiverName(r.receiver))
+	}
+	c.conflicts = append(c.conflicts, conflict)
+	log.Warnf("Command conflict: %s", conflict)
+
+	return &ConflictError{Conflict: conflict}
+}
+
+// removeIf removes all registrations for which the function returns true. It has to be called with the mutex locked.
+func (c *CommandDispatcher) removeIf(f func(name string, reg registration) bool) {
+	for name, regs := range c.receivers {
+		var kept []registration
+		for _, reg := range regs {
+			if !f(name, reg) {
+				kept = append(kept, reg)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.receivers, name)
+		} else {
+			c.receivers[name] = kept
+		}
+	}
+}
+
+// Unregister removes all receivers of the specified command including its aliases if it exists.
+// The command can be given by the name it was registered with or the name it is available under.
 func (c *CommandDispatcher) Unregister(cmd string) {
-	delete(c.receivers, cmd)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.removeIf(func(name string, reg registration) bool {
+		return name == cmd || reg.command == cmd
+	})
 }
 
-// OnPost feeds a post to the CommandDispatcher which will then do its magic.
-func (c *CommandDispatcher) OnPost(post model.Post) {
-	if len(post.Content) < 2 {
+// UnregisterReceiver removes the receiver from the specified command including its aliases
+// if it was registered for it.
+func (c *CommandDispatcher) UnregisterReceiver(cmd string, r receiver) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.removeIf(func(name string, reg registration) bool {
+		return reg.receiver == r && reg.command == cmd
+	})
+}
+
+// Conflicts returns all commands which were registered by more than one receiver.
+func (c *CommandDispatcher) Conflicts() []Conflict {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]Conflict{}, c.conflicts...)
+}
+
+// CommandInfo describes a command available in the CommandDispatcher, e.g., for platforms
+// which register the commands as native commands.
+type CommandInfo struct {
+	// Name is the name under which the command is available.
+	Name string
+	Help CommandHelp
+	// Spec is the declared syntax of the command or nil if it was registered without spec.
+	Spec *CommandSpec
+	// Alias is true if the name is an additional alias of another command.
+	Alias bool
+}
+
+// Commands returns all available commands sorted by name. The built-in help command is not included.
+func (c *CommandDispatcher) Commands() []CommandInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var cmds []CommandInfo
+	for name, regs := range c.receivers {
+		reg := regs[0]
+		cmds = append(cmds, CommandInfo{Name: name, Help: reg.help, Spec: reg.spec, Alias: reg.alias})
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+
+	return cmds
+}
+
+// CommandName returns the name the command called with the given name or alias is available under.
+// It returns false if there is no such command.
+func (c *CommandDispatcher) CommandName(called string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	regs := c.receivers[called]
+	if len(regs) == 0 {
+		return "", false
+	}
+	return regs[0].name, true
+}
+
+// CalledName returns the name or alias the command was called with in the post. Cmd is the name
+// the command is available under, as seen by the authorizer and the middlewares. It is returned
+// as it is if the post did not call that command, e.g., for triggers.
+func (c *CommandDispatcher) CalledName(post model.Post, cmd string) string {
+	if !strings.HasPrefix(post.Content, c.callPrefix) {
+		return cmd
+	}
+	called, _ := splitCommand(post.Content[len(c.callPrefix):])
+	if name, ok := c.CommandName(called); ok && name == cmd {
+		return called
+	}
+	return cmd
+}
+
+// Failure returns the reason why the receiver was marked as failed or nil if it did not fail.
+func (c *CommandDispatcher) Failure(r interface{}) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.failures[r]
+}
+
+// fail marks the receiver as failed after a panic. A failure caused by timeouts is replaced,
+// so that the receiver does not recover from it.
+func (c *CommandDispatcher) fail(r interface{}, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.failures[r]; !ok || c.timeouts[r] > 0 {
+		c.failures[r] = err
+	}
+	delete(c.timeouts, r)
+}
+
+// timedOut counts the timeout and marks the receiver as failed, so that it does not get any
+// further calls while the abandoned call is still running.
+func (c *CommandDispatcher) timedOut(r interface{}, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.failures[r]; ok {
+		return
+	}
+	c.timeouts[r]++
+	c.failures[r] = err
+	if c.timeouts[r] >= maxTimeouts {
+		log.Errorf("Receiver %s timed out %d times in a row, it stays marked as failed", receiverName(r), c.timeouts[r])
+	}
+}
+
+// succeeded resets the timeouts of the receiver if the call returned in time. If it returned after
+// a timeout, the failure caused by the timeout is lifted unless it timed out maxTimeouts times in a row.
+func (c *CommandDispatcher) succeeded(r interface{}, late bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	n, ok := c.timeouts[r]
+	if !ok {
 		return
 	}
-	splitted := strings.Split(post.Content, " ")
-	if !strings.HasPrefix(splitted[0], c.callPrefix) {
+	if !late {
+		delete(c.timeouts, r)
 		return
 	}
-	cmd := splitted[0][len(c.callPrefix):]
-	content := ""
+	if n < maxTimeouts {
+		delete(c.failures, r)
+		log.Infof("Receiver %s returned again, it is no longer marked as failed", receiverName(r))
+	}
+}
+
+// Reply sends the message as a reply to the post.
+func (c *CommandDispatcher) Reply(post model.Post, msg string) {
+	c.mutex.RLock()
+	poster := c.poster
+	c.mutex.RUnlock()
+
+	if poster == nil {
+		log.Debugf("No poster set, not sending reply '%s'", msg)
+		return
+	}
+	post.Content = msg
+	if _, err := poster.CreatePost(post); err != nil {
+		log.Errorf("Could not send reply: %s", err)
+	}
+}
+
+func (c *CommandDispatcher) replyHelp(post model.Post, content string) {
+	for _, page := range c.helpPages(content) {
+		c.Reply(post, page)
+	}
+}
+
+func (c *CommandDispatcher) dispatch(cmd string, content string, post model.Post, reg registration) {
+	pr, ok := reg.receiver.(parsedReceiver)
+	if reg.spec == nil || !ok {
+		reg.receiver.OnCommand(reg.command, content, post)
+		return
+	}
+
+	if _, ok := reg.spec.subcommand(helpCommand); !ok && content == helpCommand {
+		c.replyHelp(post, cmd)
+		return
+	}
+
+	args, err := reg.spec.Parse(content)
+	if err != nil {
+		if usageErr, ok := err.(*UsageError); ok {
+			c.Reply(post, usageErr.Message(c.callPrefix))
+		} else {
+			log.Errorf("Error parsing command %s: %s", cmd, err)
+		}
+		return
+	}
+	pr.OnParsedCommand(reg.command, args, post)
+}
+
+// call runs the receiver with panic recovery and the timeout. Nothing is run for a failed receiver.
+// Failures are logged and mark the receiver as failed. What describes what the receiver is handling.
+// A goroutine cannot be stopped, so on timeout it is abandoned and keeps running until the receiver
+// returns, if ever. Until then the receiver stays failed, so that its calls never overlap.
+func (c *CommandDispatcher) call(r interface{}, what string, timeout time.Duration, f func()) {
+	if err := c.Failure(r); err != nil {
+		log.Debugf("Not handling %s by failed receiver %s", what, receiverName(r))
+		return
+	}
+
+	// state is callRunning until either the call returns or the timeout hits first.
+	state := int32(callRunning)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if rec := recover(); rec != nil {
+				err := fmt.Errorf("Panic while handling %s: %v", what, rec)
+				log.Errorf("Receiver %s failed: %s\n%s", receiverName(r), err, debug.Stack())
+				c.fail(r, err)
+			}
+		}()
+		f()
+		c.succeeded(r, !atomic.CompareAndSwapInt32(&state, callRunning, callReturned))
+	}()
+
+	if timeout <= 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		if !atomic.CompareAndSwapInt32(&state, callRunning, callAbandoned) {
+			<-done
+			return
+		}
+		err := fmt.Errorf("Timeout after %s while handling %s", timeout, what)
+		log.Errorf("Receiver %s failed: %s", receiverName(r), err)
+		c.timedOut(r, err)
+	}
+}
+
+// Run runs a hook of the receiver, e.g., an event for a plugin, with the same panic recovery and
+// timeout as commands, so that a failing receiver is reported by Failure and gets no further calls.
+func (c *CommandDispatcher) Run(r interface{}, what string, f func()) {
+	c.mutex.RLock()
+	timeout := c.timeout
+	c.mutex.RUnlock()
+
+	c.call(r, what, timeout, f)
+}
+
+// dispatchAll returns a handler which delivers the command to all given receivers which did not fail.
+// If queues are given, the command is added to the queue of each receiver instead.
+// Name is the name the command was called with, it is used for the help of the command.
+func (c *CommandDispatcher) dispatchAll(name string, regs []registration, q queues, timeout time.Duration) HandlerFunc {
+	return func(cmd string, content string, post model.Post) {
+		for _, reg := range regs {
+			if err := c.Failure(reg.receiver); err != nil {
+				log.Debugf("Not dispatching command %s to failed receiver %s", name, receiverName(reg.receiver))
+				continue
+			}
+			reg := reg
+			c.deliver(reg.receiver, q, func() {
+				c.call(reg.receiver, "command "+reg.command, timeout, func() {
+					c.dispatch(name, content, post, reg)
+				})
+			})
+		}
+	}
+}
+
+// byName groups the registrations by the name their command is available under, keeping their order.
+func byName(regs []registration) ([]string, map[string][]registration) {
+	var names []string
+	groups := make(map[string][]registration)
+	for _, reg := range regs {
+		if _, ok := groups[reg.name]; !ok {
+			names = append(names, reg.name)
+		}
+		groups[reg.name] = append(groups[reg.name], reg)
+	}
+	return names, groups
+}
+
+// deliver runs the function directly or adds it to the queue of the receiver if queues are given.
+func (c *CommandDispatcher) deliver(r interface{}, q queues, f func()) {
+	if q == nil {
+		f()
+	} else if !q.Enqueue(r, f) {
+		log.Debugf("Dropped event for receiver %s", receiverName(r))
+	}
+}
+
+// triggerHandler returns a handler which delivers the match to the trigger receiver if it did not fail.
+func (c *CommandDispatcher) triggerHandler(reg triggerRegistration, match TriggerMatch, q queues, timeout time.Duration) HandlerFunc {
+	return func(name string, content string, post model.Post) {
+		if err := c.Failure(reg.receiver); err != nil {
+			log.Debugf("Not dispatching trigger %s to failed receiver %s", name, receiverName(reg.receiver))
+			return
+		}
+		match.Content = content
+		c.deliver(reg.receiver, q, func() {
+			c.call(reg.receiver, "trigger "+name, timeout, func() {
+				reg.receiver.OnTrigger(name, match, post)
+			})
+		})
+	}
+}
+
+func authorize(a authorizer, next HandlerFunc) HandlerFunc {
+	return func(cmd string, content string, post model.Post) {
+		if a != nil {
+			post.User.IsMod = post.User.IsMod || a.IsMod(post.User, post.ChannelID)
+			if !a.IsAllowed(cmd, post) {
+				log.Debugf("User %s is not allowed to execute command %s", post.User.Name, cmd)
+				return
+			}
+		}
+		next(cmd, content, post)
+	}
+}
+
+// splitCommand splits the content into the command and the whitespace-trimmed rest.
+func splitCommand(content string) (string, string) {
+	splitted := strings.Split(content, " ")
+	cmd := splitted[0]
+	rest := ""
 	if len(splitted) > 1 {
-		content = strings.Join(splitted[1:], " ")
-		content = strings.TrimSpace(content)
+		rest = strings.TrimSpace(strings.Join(splitted[1:], " "))
 	}
-	for c, r := range c.receivers {
-		if cmd == c {
-			r.OnCommand(cmd, content, post)
+	return cmd, rest
+}
+
+// OnPost feeds a post to the CommandDispatcher which will then do its magic.
+// Posts starting with the call prefix or with a mention of the bot are dispatched as commands,
+// all other posts are checked against the registered triggers.
+func (c *CommandDispatcher) OnPost(post model.Post) {
+	c.mutex.RLock()
+	mentions := c.mentions
+	hasTriggers := len(c.triggers) > 0
+	c.mutex.RUnlock()
+
+	stripped, mentioned, addressed := stripMentions(post.Content, mentions)
+
+	if strings.HasPrefix(post.Content, c.callPrefix) && len(post.Content) >= 2 {
+		cmd, content := splitCommand(post.Content[len(c.callPrefix):])
+		if c.onCommand(cmd, content, post) {
+			return
+		}
+	} else if addressed && len(stripped) > 0 {
+		command := strings.TrimPrefix(stripped, c.callPrefix)
+		cmd, content := splitCommand(command)
+		post.Content = c.callPrefix + command
+		if c.onCommand(cmd, content, post) {
+			return
 		}
+		post.Content = stripped
+	}
+
+	if hasTriggers {
+		if !mentioned {
+			stripped = post.Content
+		}
+		c.onTriggers(stripped, mentioned, post)
 	}
 }
 
-// IsHelp returns true and the help message if the post contains a help command.
-func (c *CommandDispatcher) IsHelp(post model.Post) (bool, string) {
-	if len(post.Content) < 2 {
-		return false, ""
+// onCommand dispatches the command and returns true if there was a receiver for it.
+func (c *CommandDispatcher) onCommand(cmd string, content string, post model.Post) bool {
+	if cmd == helpCommand {
+		c.replyHelp(post, content)
+		return true
 	}
-	splitted := strings.Split(post.Content, " ")
-	if !strings.HasPrefix(splitted[0], c.callPrefix) {
-		return false, ""
+
+	c.mutex.RLock()
+	regs := append([]registration{}, c.receivers[cmd]...)
+	middlewares := append([]Middleware{}, c.middlewares...)
+	a := c.authorizer
+	q := c.queues
+	timeout := c.timeout
+	c.mutex.RUnlock()
+
+	if len(regs) == 0 {
+		return false
 	}
-	cmd := splitted[0][len(c.callPrefix):]
-	if cmd == "help" {
-		return true, c.getHelpText()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Panic while handling command %s: %v\n%s", cmd, r, debug.Stack())
+		}
+	}()
+
+	// Permissions and middlewares see the name the command is available under, not the alias it was called with.
+	// Renamed instances of the same plugin are therefore distinct commands for them.
+	names, groups := byName(regs)
+	for _, name := range names {
+		authorize(a, chain(middlewares, c.dispatchAll(cmd, groups[name], q, timeout)))(name, content, post)
 	}
-	return false, ""
+
+	return true
 }
 
-// getHelpText returns a generic help message listing all available commands.
-func (c *CommandDispatcher) getHelpText() string {
-	helptext := fmt.Sprintf("The following commands are available: ")
-	first := true
-	for cmd := range c.receivers {
-		if !first {
-			helptext += ", "
+// onTriggers notifies the receivers of all triggers matching the content.
+func (c *CommandDispatcher) onTriggers(content string, mentioned bool, post model.Post) {
+	c.mutex.RLock()
+	triggers := append([]triggerRegistration{}, c.triggers...)
+	middlewares := append([]Middleware{}, c.middlewares...)
+	a := c.authorizer
+	q := c.queues
+	timeout := c.timeout
+	c.mutex.RUnlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Panic while handling triggers: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	for _, reg := range triggers {
+		match, ok := reg.trigger.match(content, mentioned)
+		if !ok {
+			continue
 		}
-		helptext += fmt.Sprintf("%s%s", c.callPrefix, cmd)
-		first = false
+		authorize(a, chain(middlewares, c.triggerHandler(reg, match, q, timeout)))(reg.trigger.Name, content, post)
 	}
-	helptext += "\nNote: Some of them are only available for mods."
-	return helptext
 }
 
 // GetCallPrefix returns the current call prefix.
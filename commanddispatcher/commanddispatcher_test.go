@@ -2,7 +2,10 @@ package commanddispatcher
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/torlenor/redseligg/model"
@@ -33,9 +36,9 @@ func TestCommandDispatcher(t *testing.T) {
 	expectedCommand := "someCommand"
 	receiver := &mockCommandReceiver{}
 	assert.Equal(0, len(dispatcher.receivers))
-	dispatcher.Register(expectedCommand, receiver)
+	assert.NoError(dispatcher.Register(expectedCommand, receiver, CommandHelp{}))
 	assert.Equal(1, len(dispatcher.receivers))
-	assert.Equal(receiver, dispatcher.receivers[expectedCommand])
+	assert.Equal(receiver, dispatcher.receivers[expectedCommand][0].receiver)
 
 	assert.Equal("", receiver.lastReceivedCmd)
 	assert.Equal(model.Post{}, receiver.lastReceivedPost)
@@ -70,8 +73,6 @@ func TestCommandDispatcher(t *testing.T) {
 	assert.Equal(expectedContent, receiver.lastReceivedContent)
 	assert.Equal(expectedPost, receiver.lastReceivedPost)
 
-	assert.Equal(fmt.Sprintf("The following commands are available: ~%s\nNote: Some of them are only available for mods.", expectedCommand), dispatcher.getHelpText())
-
 	dispatcher.Unregister(expectedCommand)
 	assert.Equal(0, len(dispatcher.receivers))
 }
@@ -79,7 +80,7 @@ func TestCommandDispatcher(t *testing.T) {
 func TestCommandDispatcher_GetCallPrefix(t *testing.T) {
 	type fields struct {
 		callPrefix string
-		receivers  map[string]receiver
+		receivers  map[string][]registration
 	}
 	tests := []struct {
 		name   string
@@ -110,80 +111,581 @@ func TestCommandDispatcher_GetCallPrefix(t *testing.T) {
 	}
 }
 
-func TestCommandDispatcher_IsHelp(t *testing.T) {
-	type fields struct {
-		callPrefix string
-		receivers  map[string]receiver
-	}
-	type args struct {
-		post model.Post
-	}
+func TestCommandDispatcher_Help(t *testing.T) {
+	dispatcher := New("!")
+	dispatcher.Register("echo", &mockCommandReceiver{}, CommandHelp{
+		Description: "Echoes the given text",
+		Usage:       []string{"echo <text>"},
+		Examples:    []string{"echo Hello World!"},
+	})
+	dispatcher.Register("version", &mockCommandReceiver{}, CommandHelp{})
+	dispatcher.RegisterSpec(testSpec, &mockParsedCommandReceiver{})
+
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   bool
-		want1  string
+		name    string
+		content string
+		want    []string
 	}{
 		{
-			name:   "Default call prefix: is help",
-			fields: fields{callPrefix: "!"},
-			args: args{
-				post: model.Post{Content: "!help"},
-			},
-			want:  true,
-			want1: "The following commands are available: \nNote: Some of them are only available for mods.",
+			name:    "List of commands",
+			content: "!help",
+			want: []string{"The following commands are available:\n" +
+				"`!echo` - Echoes the given text\n" +
+				"`!test` - Does test things\n" +
+				"`!version`\n" +
+				"Type `!help <command>` to get more information about a command. Note: Some of them are only available for mods."},
 		},
 		{
-			name:   "Default call prefix: is not help",
-			fields: fields{callPrefix: "!"},
-			args: args{
-				post: model.Post{Content: "!nothelp"},
-			},
-			want:  false,
-			want1: "",
+			name:    "Help for a command",
+			content: "!help echo",
+			want:    []string{"`!echo` - Echoes the given text\nUsage:\n`!echo <text>`\nExamples:\n`!echo Hello World!`"},
 		},
 		{
-			name:   "Default call prefix: also not help",
-			fields: fields{callPrefix: "!"},
-			args: args{
-				post: model.Post{Content: "!helpabcabc"},
-			},
-			want:  false,
-			want1: "",
+			name:    "Help for a command given with call prefix",
+			content: "!help !version",
+			want:    []string{"`!version`"},
 		},
 		{
-			name:   "Other call prefix: is help",
-			fields: fields{callPrefix: "abc"},
-			args: args{
-				post: model.Post{Content: "abchelp"},
-			},
-			want:  true,
-			want1: "The following commands are available: \nNote: Some of them are only available for mods.",
+			name:    "Help for a command with spec",
+			content: "!help test",
+			want: []string{"`!test` - Does test things\nUsage:\n" +
+				"`!test add <interval> [count] [message...] [--silent] [--user <user>]`\n" +
+				"`!test move <channel> [link]`\n" +
+				"Examples:\n`!test add 1m`"},
 		},
 		{
-			name:   "Other call prefix: is not help",
-			fields: fields{callPrefix: "abc"},
-			args: args{
-				post: model.Post{Content: "abcnothelp"},
-			},
-			want:  false,
-			want1: "",
+			name:    "Help subcommand of a command with spec",
+			content: "!test help",
+			want: []string{"`!test` - Does test things\nUsage:\n" +
+				"`!test add <interval> [count] [message...] [--silent] [--user <user>]`\n" +
+				"`!test move <channel> [link]`\n" +
+				"Examples:\n`!test add 1m`"},
+		},
+		{
+			name:    "Help for an unknown command",
+			content: "!help unknown",
+			want:    []string{"Unknown command 'unknown'. Type `!help` to list all available commands."},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &CommandDispatcher{
-				callPrefix: tt.fields.callPrefix,
-				receivers:  tt.fields.receivers,
-			}
-			got, got1 := c.IsHelp(tt.args.post)
-			if got != tt.want {
-				t.Errorf("CommandDispatcher.IsHelp() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("CommandDispatcher.IsHelp() got1 = %v, want %v", got1, tt.want1)
+			poster := &mockPoster{}
+			dispatcher.SetPoster(poster)
+			dispatcher.OnPost(model.Post{ChannelID: "some id", Content: tt.content})
+			var got []string
+			for _, p := range poster.posts {
+				got = append(got, p.Content)
 			}
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestCommandDispatcher_HelpPaginated(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	dispatcher.SetMaxMessageLength(64)
+	for _, cmd := range []string{"aaa", "bbb", "ccc"} {
+		dispatcher.Register(cmd, &mockCommandReceiver{}, CommandHelp{Description: "Some description"})
+	}
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	dispatcher.OnPost(model.Post{Content: "!help"})
+	assert.Equal(4, len(poster.posts))
+	for _, p := range poster.posts {
+		assert.True(len(p.Content) <= 64)
+	}
+	assert.Equal("The following commands are available:\n`!aaa` - Some description", poster.posts[0].Content)
+}
+
+type mockParsedCommandReceiver struct {
+	mockCommandReceiver
+
+	lastReceivedArgs Arguments
+}
+
+func (m *mockParsedCommandReceiver) OnParsedCommand(cmd string, args Arguments, post model.Post) {
+	m.lastReceivedCmd = cmd
+	m.lastReceivedArgs = args
+	m.lastReceivedPost = post
+}
+
+type mockPoster struct {
+	lastPost model.Post
+	posts    []model.Post
+}
+
+func (m *mockPoster) CreatePost(post model.Post) (model.PostResponse, error) {
+	m.lastPost = post
+	m.posts = append(m.posts, post)
+	return model.PostResponse{}, nil
+}
+
+func TestCommandDispatcher_RegisterSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	receiver := &mockParsedCommandReceiver{}
+	dispatcher.RegisterSpec(testSpec, receiver)
+	assert.Equal(1, len(dispatcher.receivers))
+
+	post := model.Post{ChannelID: "some id", Content: "!test add 1m 2 hello"}
+	dispatcher.OnPost(post)
+	assert.Equal("test", receiver.lastReceivedCmd)
+	assert.Equal("add", receiver.lastReceivedArgs.Subcommand)
+	assert.Equal(2, receiver.lastReceivedArgs.Int("count"))
+	assert.Equal("hello", receiver.lastReceivedArgs.String("message"))
+	assert.Equal(post, receiver.lastReceivedPost)
+	assert.Equal("", receiver.lastReceivedContent)
+	assert.Equal(model.Post{}, poster.lastPost)
+
+	receiver = &mockParsedCommandReceiver{}
+	dispatcher.RegisterSpec(testSpec, receiver)
+	post.Content = "!test add"
+	dispatcher.OnPost(post)
+	assert.Equal("", receiver.lastReceivedCmd)
+	expectedReply := post
+	expectedReply.Content = "Missing argument <interval>. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"
+	assert.Equal(expectedReply, poster.lastPost)
+
+	dispatcher.Unregister("test")
+	assert.Equal(0, len(dispatcher.receivers))
+}
+
+type mockAuthorizer struct {
+	mods    map[string]bool
+	allowed map[string]bool
+}
+
+func (m *mockAuthorizer) IsMod(user model.User, channelID string) bool {
+	return m.mods[user.Name]
+}
+
+func (m *mockAuthorizer) IsAllowed(cmd string, post model.Post) bool {
+	return m.allowed[post.User.Name]
+}
+
+func TestCommandDispatcher_Authorizer(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	receiver := &mockCommandReceiver{}
+	dispatcher.Register("test", receiver, CommandHelp{})
+	dispatcher.SetAuthorizer(&mockAuthorizer{
+		mods:    map[string]bool{"mod": true},
+		allowed: map[string]bool{"mod": true, "user": true},
+	})
+
+	post := model.Post{ChannelID: "some id", User: model.User{Name: "other"}, Content: "!test"}
+	dispatcher.OnPost(post)
+	assert.Equal("", receiver.lastReceivedCmd)
+
+	post.User.Name = "user"
+	dispatcher.OnPost(post)
+	assert.Equal("test", receiver.lastReceivedCmd)
+	assert.False(receiver.lastReceivedPost.User.IsMod)
+
+	post.User.Name = "mod"
+	dispatcher.OnPost(post)
+	assert.True(receiver.lastReceivedPost.User.IsMod)
+}
+
+// commandAuthorizer allows restricted commands only for mods.
+type commandAuthorizer struct {
+	restricted map[string]bool
+	commands   []string
+}
+
+func (a *commandAuthorizer) IsMod(user model.User, channelID string) bool {
+	return user.Name == "mod"
+}
+
+func (a *commandAuthorizer) IsAllowed(cmd string, post model.Post) bool {
+	a.commands = append(a.commands, cmd)
+	return !a.restricted[cmd] || post.User.IsMod
+}
+
+func TestCommandDispatcher_AuthorizerAliases(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	receiver := &mockNamedCommandReceiver{
+		names: CommandNames{
+			Rename:  map[string]string{"roll": "dice"},
+			Aliases: map[string][]string{"quote": {"q"}},
+		},
+	}
+	assert.NoError(dispatcher.Register("quote", receiver, CommandHelp{}))
+	assert.NoError(dispatcher.Register("roll", receiver, CommandHelp{}))
+	a := &commandAuthorizer{restricted: map[string]bool{"quote": true, "dice": true}}
+	dispatcher.SetAuthorizer(a)
+
+	var middlewareCommands []string
+	dispatcher.Use(func(next HandlerFunc) HandlerFunc {
+		return func(cmd string, content string, post model.Post) {
+			middlewareCommands = append(middlewareCommands, cmd)
+			next(cmd, content, post)
+		}
+	})
+
+	dispatcher.OnPost(model.Post{User: model.User{Name: "user"}, Content: "!q"})
+	dispatcher.OnPost(model.Post{User: model.User{Name: "user"}, Content: "!dice 6"})
+	assert.Equal("", receiver.lastReceivedCmd)
+	assert.Equal([]string{"quote", "dice"}, a.commands)
+	assert.Nil(middlewareCommands)
+
+	dispatcher.OnPost(model.Post{User: model.User{Name: "mod"}, Content: "!q"})
+	assert.Equal("quote", receiver.lastReceivedCmd)
+	dispatcher.OnPost(model.Post{User: model.User{Name: "mod"}, Content: "!dice 6"})
+	assert.Equal("roll", receiver.lastReceivedCmd)
+	assert.Equal([]string{"quote", "dice"}, middlewareCommands)
+
+	name, ok := dispatcher.CommandName("q")
+	assert.True(ok)
+	assert.Equal("quote", name)
+	name, ok = dispatcher.CommandName("dice")
+	assert.True(ok)
+	assert.Equal("dice", name)
+	_, ok = dispatcher.CommandName("roll")
+	assert.False(ok)
+
+	assert.Equal("q", dispatcher.CalledName(model.Post{Content: "!q something"}, "quote"))
+	assert.Equal("quote", dispatcher.CalledName(model.Post{Content: "!dice"}, "quote"))
+	assert.Equal("quote", dispatcher.CalledName(model.Post{Content: "q"}, "quote"))
+}
+
+func TestCommandDispatcher_AuthorizerRenamedInstances(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	quotes := &mockNamedCommandReceiver{}
+	cites := &mockNamedCommandReceiver{names: CommandNames{Rename: map[string]string{"quote": "cite"}}}
+	assert.NoError(dispatcher.Register("quote", quotes, CommandHelp{}))
+	assert.NoError(dispatcher.Register("quote", cites, CommandHelp{}))
+	dispatcher.SetAuthorizer(&commandAuthorizer{restricted: map[string]bool{"cite": true}})
+
+	dispatcher.OnPost(model.Post{User: model.User{Name: "user"}, Content: "!quote"})
+	dispatcher.OnPost(model.Post{User: model.User{Name: "user"}, Content: "!cite"})
+	assert.Equal("quote", quotes.lastReceivedCmd)
+	assert.Equal("", cites.lastReceivedCmd)
+}
+
+func TestCommandDispatcher_MultipleReceivers(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	receiver1 := &mockCommandReceiver{}
+	receiver2 := &mockCommandReceiver{}
+
+	assert.NoError(dispatcher.Register("test", receiver1, CommandHelp{Description: "First"}))
+	assert.NoError(dispatcher.Register("test", receiver1, CommandHelp{Description: "First again"}))
+	assert.Equal(0, len(dispatcher.Conflicts()))
+
+	err := dispatcher.Register("test", receiver2, CommandHelp{Description: "Second"})
+	conflictErr, ok := err.(*ConflictError)
+	assert.True(ok)
+	expectedConflict := Conflict{
+		Command:   "test",
+		Receivers: []string{"*commanddispatcher.mockCommandReceiver", "*commanddispatcher.mockCommandReceiver"},
+	}
+	assert.Equal(expectedConflict, conflictErr.Conflict)
+	assert.Equal([]Conflict{expectedConflict}, dispatcher.Conflicts())
+
+	dispatcher.OnPost(model.Post{Content: "!test content"})
+	assert.Equal("content", receiver1.lastReceivedContent)
+	assert.Equal("content", receiver2.lastReceivedContent)
+
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+	dispatcher.OnPost(model.Post{Content: "!help test"})
+	assert.Equal("`!test` - First again", poster.lastPost.Content)
+
+	dispatcher.UnregisterReceiver("test", receiver1)
+	dispatcher.OnPost(model.Post{Content: "!test other"})
+	assert.Equal("content", receiver1.lastReceivedContent)
+	assert.Equal("other", receiver2.lastReceivedContent)
+
+	dispatcher.UnregisterReceiver("test", receiver2)
+	assert.Equal(0, len(dispatcher.receivers))
+}
+
+func TestCommandDispatcher_Middleware(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	receiver := &mockCommandReceiver{}
+	dispatcher.Register("test", receiver, CommandHelp{})
+
+	var calls []string
+	dispatcher.Use(func(next HandlerFunc) HandlerFunc {
+		return func(cmd string, content string, post model.Post) {
+			calls = append(calls, "first "+cmd)
+			next(cmd, content+" first", post)
+		}
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(cmd string, content string, post model.Post) {
+			calls = append(calls, "second "+content)
+			if content == "stop first" {
+				return
+			}
+			next(cmd, content, post)
+		}
+	})
+
+	dispatcher.OnPost(model.Post{Content: "!test content"})
+	assert.Equal([]string{"first test", "second content first"}, calls)
+	assert.Equal("content first", receiver.lastReceivedContent)
+
+	dispatcher.OnPost(model.Post{Content: "!test stop"})
+	assert.Equal("content first", receiver.lastReceivedContent)
+
+	calls = nil
+	dispatcher.OnPost(model.Post{Content: "!unknown"})
+	assert.Equal(0, len(calls))
+}
+
+type mockNamedCommandReceiver struct {
+	mockParsedCommandReceiver
+
+	names CommandNames
+}
+
+func (m *mockNamedCommandReceiver) CommandNames() CommandNames {
+	return m.names
+}
+
+func TestCommandDispatcher_CommandNames(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	receiver := &mockNamedCommandReceiver{
+		names: CommandNames{
+			Rename:  map[string]string{"roll": "dice", "test": "check"},
+			Aliases: map[string][]string{"roll": {"r", "dice"}},
+		},
+	}
+	assert.NoError(dispatcher.Register("roll", receiver, CommandHelp{
+		Description: "Rolls a dice",
+		Usage:       []string{"roll [max]"},
+		Examples:    []string{"roll 6", "rolling"},
+	}))
+	assert.NoError(dispatcher.RegisterSpec(testSpec, receiver))
+
+	dispatcher.OnPost(model.Post{Content: "!roll 6"})
+	assert.Equal("", receiver.lastReceivedCmd)
+
+	dispatcher.OnPost(model.Post{Content: "!dice 6"})
+	assert.Equal("roll", receiver.lastReceivedCmd)
+	assert.Equal("6", receiver.lastReceivedContent)
+
+	dispatcher.OnPost(model.Post{Content: "!r 20"})
+	assert.Equal("roll", receiver.lastReceivedCmd)
+	assert.Equal("20", receiver.lastReceivedContent)
+
+	dispatcher.OnPost(model.Post{Content: "!check add 1m"})
+	assert.Equal("test", receiver.lastReceivedCmd)
+	assert.Equal("add", receiver.lastReceivedArgs.Subcommand)
+
+	dispatcher.OnPost(model.Post{Content: "!check add"})
+	assert.Equal("Missing argument <interval>. Usage: `!check add <interval> [count] [message...] [--silent] [--user <user>]`", poster.lastPost.Content)
+
+	poster.posts = nil
+	dispatcher.OnPost(model.Post{Content: "!help"})
+	assert.Equal([]string{"The following commands are available:\n" +
+		"`!check` - Does test things\n" +
+		"`!dice` - Rolls a dice\n" +
+		"Type `!help <command>` to get more information about a command. Note: Some of them are only available for mods."}, []string{poster.lastPost.Content})
+
+	dispatcher.OnPost(model.Post{Content: "!help dice"})
+	assert.Equal("`!dice` - Rolls a dice\nUsage:\n`!dice [max]`\nExamples:\n`!dice 6`\n`!rolling`\nAliases: `!r`", poster.lastPost.Content)
+
+	cmds := dispatcher.Commands()
+	assert.Equal([]string{"check", "dice", "r"}, []string{cmds[0].Name, cmds[1].Name, cmds[2].Name})
+	assert.Equal("Does test things", cmds[0].Help.Description)
+	assert.Equal("check", cmds[0].Spec.Command)
+	assert.False(cmds[0].Alias)
+	assert.Nil(cmds[1].Spec)
+	assert.Equal([]string{"dice [max]"}, cmds[1].Help.Usage)
+	assert.True(cmds[2].Alias)
+
+	dispatcher.UnregisterReceiver("roll", receiver)
+	_, ok := dispatcher.receivers["r"]
+	assert.False(ok)
+	assert.Equal(1, len(dispatcher.receivers))
+}
+
+type panickingCommandReceiver struct{}
+
+func (m *panickingCommandReceiver) OnCommand(cmd string, content string, post model.Post) {
+	panic("something went wrong")
+}
+
+type blockingCommandReceiver struct {
+	release chan struct{}
+	calls   int32
+}
+
+func (m *blockingCommandReceiver) OnCommand(cmd string, content string, post model.Post) {
+	atomic.AddInt32(&m.calls, 1)
+	<-m.release
+}
+
+func TestCommandDispatcher_FailingReceivers(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	dispatcher.SetTimeout(50 * time.Millisecond)
+
+	panicking := &panickingCommandReceiver{}
+	blocking := &blockingCommandReceiver{release: make(chan struct{})}
+	receiver := &mockCommandReceiver{}
+
+	dispatcher.Register("panic", panicking, CommandHelp{})
+	dispatcher.Register("block", blocking, CommandHelp{})
+	dispatcher.Register("panic", receiver, CommandHelp{})
+
+	dispatcher.OnPost(model.Post{Content: "!panic first"})
+	assert.EqualError(dispatcher.Failure(panicking), "Panic while handling command panic: something went wrong")
+	assert.Equal("first", receiver.lastReceivedContent)
+	assert.NoError(dispatcher.Failure(receiver))
+
+	dispatcher.OnPost(model.Post{Content: "!panic second"})
+	assert.Equal("second", receiver.lastReceivedContent)
+
+	for i := 1; i <= maxTimeouts; i++ {
+		blocking.release = make(chan struct{})
+		dispatcher.OnPost(model.Post{Content: "!block"})
+		assert.EqualError(dispatcher.Failure(blocking), "Timeout after 50ms while handling command block")
+
+		// The abandoned call is still running, so the receiver does not get the next one.
+		dispatcher.OnPost(model.Post{Content: "!block"})
+		assert.Equal(int32(i), atomic.LoadInt32(&blocking.calls))
+
+		close(blocking.release)
+		if i < maxTimeouts {
+			assert.Eventually(func() bool { return dispatcher.Failure(blocking) == nil }, time.Second, 10*time.Millisecond)
+		}
+	}
+	assert.Eventually(func() bool { return atomic.LoadInt32(&blocking.calls) == maxTimeouts }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Error(dispatcher.Failure(blocking))
+
+	dispatcher.OnPost(model.Post{Content: "!panic third"})
+	assert.Equal("third", receiver.lastReceivedContent)
+	assert.Error(dispatcher.Failure(panicking))
+}
+
+type countingCommandReceiver struct {
+	calls int32
+}
+
+func (m *countingCommandReceiver) OnCommand(cmd string, content string, post model.Post) {
+	atomic.AddInt32(&m.calls, 1)
+}
+
+func TestCommandDispatcher_Concurrent(t *testing.T) {
+	dispatcher := New("!")
+	receiver := &countingCommandReceiver{}
+	dispatcher.Register("test", receiver, CommandHelp{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			cmd := fmt.Sprintf("cmd%d", i)
+			dispatcher.Register(cmd, receiver, CommandHelp{})
+			dispatcher.UnregisterReceiver(cmd, receiver)
+		}(i)
+		go func() {
+			defer wg.Done()
+			dispatcher.OnPost(model.Post{Content: "!test"})
+			dispatcher.OnPost(model.Post{Content: "!help"})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), atomic.LoadInt32(&receiver.calls))
+}
+
+type mockQueues struct {
+	keys  []interface{}
+	funcs []func()
+}
+
+func (m *mockQueues) Enqueue(key interface{}, f func()) bool {
+	m.keys = append(m.keys, key)
+	m.funcs = append(m.funcs, f)
+	return true
+}
+
+func TestCommandDispatcher_Queues(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	queues := &mockQueues{}
+	dispatcher.SetQueues(queues)
+
+	receiver := &mockCommandReceiver{}
+	dispatcher.Register("test", receiver, CommandHelp{})
+
+	dispatcher.OnPost(model.Post{Content: "!test content"})
+	assert.Equal("", receiver.lastReceivedCmd)
+	assert.Equal([]interface{}{receiver}, queues.keys)
+
+	queues.funcs[0]()
+	assert.Equal("test", receiver.lastReceivedCmd)
+	assert.Equal("content", receiver.lastReceivedContent)
+}
+
+func TestCommandDispatcher_QueuesTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	dispatcher.SetTimeout(50 * time.Millisecond)
+	queues := &mockQueues{}
+	dispatcher.SetQueues(queues)
+
+	blocking := &blockingCommandReceiver{release: make(chan struct{})}
+	dispatcher.Register("block", blocking, CommandHelp{})
+
+	dispatcher.OnPost(model.Post{Content: "!block first"})
+	dispatcher.OnPost(model.Post{Content: "!block second"})
+	assert.Equal(2, len(queues.funcs))
+
+	// The second command was queued before the first one timed out, it must not run
+	// while the first one is still running.
+	queues.funcs[0]()
+	assert.Error(dispatcher.Failure(blocking))
+	queues.funcs[1]()
+	assert.Equal(int32(1), atomic.LoadInt32(&blocking.calls))
+
+	close(blocking.release)
+	assert.Eventually(func() bool { return dispatcher.Failure(blocking) == nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestCommandDispatcher_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	receiver := &mockCommandReceiver{}
+
+	calls := 0
+	dispatcher.Run(receiver, "event", func() { calls++ })
+	assert.Equal(1, calls)
+	assert.NoError(dispatcher.Failure(receiver))
+
+	dispatcher.Run(receiver, "event", func() { panic("something went wrong") })
+	assert.EqualError(dispatcher.Failure(receiver), "Panic while handling event: something went wrong")
+
+	dispatcher.Run(receiver, "event", func() { calls++ })
+	assert.Equal(1, calls)
+}
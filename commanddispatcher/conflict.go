@@ -0,0 +1,38 @@
+package commanddispatcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conflict describes a command which was registered by more than one receiver.
+type Conflict struct {
+	Command string
+	// Receivers lists the receivers in the order they registered the command.
+	Receivers []string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("Command '%s' is registered by %s", c.Command, strings.Join(c.Receivers, ", "))
+}
+
+// ConflictError is returned when a command is registered which already has a receiver.
+type ConflictError struct {
+	Conflict Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return e.Conflict.String()
+}
+
+type namedReceiver interface {
+	PluginType() string
+}
+
+// receiverName returns the plugin type for plugins and the type name for everything else.
+func receiverName(r interface{}) string {
+	if n, ok := r.(namedReceiver); ok {
+		return n.PluginType()
+	}
+	return fmt.Sprintf("%T", r)
+}
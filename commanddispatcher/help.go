@@ -0,0 +1,133 @@
+package commanddispatcher
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const helpCommand = "help"
+
+// CommandHelp describes a command for the auto-generated help.
+type CommandHelp struct {
+	// Description is a short one-line description of the command.
+	Description string
+	// Usage lists the invocation forms without call prefix, e.g., "echo <text>".
+	Usage []string
+	// Examples lists example invocations without call prefix, e.g., "echo Hello World".
+	Examples []string
+}
+
+func (h CommandHelp) summary(callPrefix string, cmd string) string {
+	if len(h.Description) > 0 {
+		return "`" + callPrefix + cmd + "` - " + h.Description
+	}
+	return "`" + callPrefix + cmd + "`"
+}
+
+func (h CommandHelp) lines(callPrefix string, cmd string) []string {
+	lines := []string{h.summary(callPrefix, cmd)}
+	if len(h.Usage) > 0 {
+		lines = append(lines, "Usage:")
+		for _, u := range h.Usage {
+			lines = append(lines, "`"+callPrefix+u+"`")
+		}
+	}
+	if len(h.Examples) > 0 {
+		lines = append(lines, "Examples:")
+		for _, e := range h.Examples {
+			lines = append(lines, "`"+callPrefix+e+"`")
+		}
+	}
+	return lines
+}
+
+// Text returns the detailed help for the command as it is shown by "help <command>".
+func (h CommandHelp) Text(callPrefix string, cmd string) string {
+	return strings.Join(h.lines(callPrefix, cmd), "\n")
+}
+
+// Help returns the CommandHelp for the spec. The usage is generated from the declared arguments.
+func (s CommandSpec) Help() CommandHelp {
+	return CommandHelp{
+		Description: s.Description,
+		Usage:       s.usage(s.Command),
+		Examples:    s.Examples,
+	}
+}
+
+// paginate joins the lines into as few messages as possible which are not longer than maxLength bytes.
+// Lines which are longer than maxLength on their own are split. A maxLength <= 0 means no limit.
+func paginate(lines []string, maxLength int) []string {
+	if maxLength <= 0 {
+		return []string{strings.Join(lines, "\n")}
+	}
+
+	var pages []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			pages = append(pages, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, line := range lines {
+		for len(line) > maxLength {
+			flush()
+			cut := maxLength
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			pages = append(pages, line[:cut])
+			line = line[cut:]
+		}
+		if current.Len() > 0 && current.Len()+1+len(line) > maxLength {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return pages
+}
+
+// helpPages returns the help text, split into pages according to the max message length.
+// Without argument all available commands are listed, otherwise the detailed help for
+// the given command is returned.
+func (c *CommandDispatcher) helpPages(content string) []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	content = strings.TrimPrefix(strings.TrimSpace(content), c.callPrefix)
+	if len(content) > 0 {
+		if _, ok := c.receivers[content]; !ok {
+			return []string{"Unknown command '" + content + "'. Type `" + c.callPrefix + helpCommand + "` to list all available commands."}
+		}
+		reg := c.receivers[content][0]
+		lines := reg.help.lines(c.callPrefix, content)
+		if len(reg.aliases) > 0 {
+			lines = append(lines, "Aliases: `"+c.callPrefix+strings.Join(reg.aliases, "`, `"+c.callPrefix)+"`")
+		}
+		return paginate(lines, c.maxMessageLength)
+	}
+
+	var cmds []string
+	for cmd, regs := range c.receivers {
+		if !regs[0].alias {
+			cmds = append(cmds, cmd)
+		}
+	}
+	sort.Strings(cmds)
+
+	lines := []string{"The following commands are available:"}
+	for _, cmd := range cmds {
+		lines = append(lines, c.receivers[cmd][0].help.summary(c.callPrefix, cmd))
+	}
+	lines = append(lines, "Type `"+c.callPrefix+helpCommand+" <command>` to get more information about a command. Note: Some of them are only available for mods.")
+
+	return paginate(lines, c.maxMessageLength)
+}
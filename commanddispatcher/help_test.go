@@ -0,0 +1,52 @@
+package commanddispatcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_paginate(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		maxLength int
+		want      []string
+	}{
+		{
+			name:      "No limit",
+			lines:     []string{"aaa", "bbb", "ccc"},
+			maxLength: 0,
+			want:      []string{"aaa\nbbb\nccc"},
+		},
+		{
+			name:      "Fits into one page",
+			lines:     []string{"aaa", "bbb", "ccc"},
+			maxLength: 11,
+			want:      []string{"aaa\nbbb\nccc"},
+		},
+		{
+			name:      "Split at lines",
+			lines:     []string{"aaa", "bbb", "ccc"},
+			maxLength: 10,
+			want:      []string{"aaa\nbbb", "ccc"},
+		},
+		{
+			name:      "Split long lines",
+			lines:     []string{"aaa", "bbbbbbbbbb", "ccc"},
+			maxLength: 4,
+			want:      []string{"aaa", "bbbb", "bbbb", "bb", "ccc"},
+		},
+		{
+			name:      "Do not split in the middle of a rune",
+			lines:     []string{"äää"},
+			maxLength: 3,
+			want:      []string{"ä", "ä", "ä"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, paginate(tt.lines, tt.maxLength))
+		})
+	}
+}
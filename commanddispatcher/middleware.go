@@ -0,0 +1,29 @@
+package commanddispatcher
+
+import (
+	"github.com/torlenor/redseligg/model"
+)
+
+// HandlerFunc handles a command with the content where the command is already stripped off and the raw Post.
+type HandlerFunc func(cmd string, content string, post model.Post)
+
+// Middleware wraps the handling of every dispatched command, e.g., for logging, rate limiting or metrics.
+// The command is the name the command is available under, also when it was called by an alias.
+// A Middleware can modify the command, content and post before calling next or stop the command by not calling next.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use adds middlewares to the chain. They are called in the order they were added,
+// after the permission checks and before the receivers get the command.
+func (c *CommandDispatcher) Use(middlewares ...Middleware) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// chain wraps the handler with the middlewares.
+func chain(middlewares []Middleware, h HandlerFunc) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
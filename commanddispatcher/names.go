@@ -0,0 +1,68 @@
+package commanddispatcher
+
+import (
+	"strings"
+)
+
+// CommandNames configures under which names the commands of a receiver are available.
+type CommandNames struct {
+	// Rename maps a command to the name under which it is available instead, e.g., "roll" -> "dice".
+	Rename map[string]string
+	// Aliases maps a command to additional names under which it is available.
+	Aliases map[string][]string
+}
+
+type namedCommandsReceiver interface {
+	CommandNames() CommandNames
+}
+
+func commandNamesOf(r receiver) CommandNames {
+	if n, ok := r.(namedCommandsReceiver); ok {
+		return n.CommandNames()
+	}
+	return CommandNames{}
+}
+
+// names returns the name the command is available under and its aliases.
+func (n CommandNames) names(cmd string) (string, []string) {
+	name := cmd
+	if renamed, ok := n.Rename[cmd]; ok && len(renamed) > 0 {
+		name = renamed
+	}
+
+	var aliases []string
+	for _, alias := range n.Aliases[cmd] {
+		if len(alias) > 0 && alias != name {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return name, aliases
+}
+
+func renameLine(line string, from string, to string) string {
+	if line == from {
+		return to
+	}
+	if strings.HasPrefix(line, from+" ") {
+		return to + line[len(from):]
+	}
+	return line
+}
+
+// renamed returns the help with all usage lines and examples starting with the command renamed.
+func (h CommandHelp) renamed(from string, to string) CommandHelp {
+	if from == to {
+		return h
+	}
+
+	renamed := CommandHelp{Description: h.Description}
+	for _, u := range h.Usage {
+		renamed.Usage = append(renamed.Usage, renameLine(u, from, to))
+	}
+	for _, e := range h.Examples {
+		renamed.Examples = append(renamed.Examples, renameLine(e, from, to))
+	}
+
+	return renamed
+}
@@ -0,0 +1,308 @@
+package commanddispatcher
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+var errUnterminatedQuote = errors.New("Unterminated quoted string")
+
+var (
+	userMentionRegexp    = regexp.MustCompile(`^<@!?([^<>\s]+)>$`)
+	channelMentionRegexp = regexp.MustCompile(`^<#([^<>|\s]+)(\|([^<>]*))?>$`)
+)
+
+type token struct {
+	value  string
+	start  int // byte offset of the token in the tokenized string
+	quoted bool
+}
+
+// lexer splits a text into tokens on demand, so that the remainder of the text
+// can be taken verbatim at any point.
+type lexer struct {
+	text string
+	pos  int
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.text) {
+		r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		l.pos += size
+	}
+}
+
+// done reports whether there are no more tokens left.
+func (l *lexer) done() bool {
+	l.skipSpace()
+	return l.pos >= len(l.text)
+}
+
+// peek returns the raw text of the remainder without consuming it.
+func (l *lexer) peek() string {
+	l.skipSpace()
+	return l.text[l.pos:]
+}
+
+// rest consumes and returns the remainder of the text as it is.
+func (l *lexer) rest() string {
+	rest := strings.TrimSpace(l.peek())
+	l.pos = len(l.text)
+	return rest
+}
+
+// next returns the next token. A token is delimited by whitespace. When it starts with a
+// double quote it extends to the closing double quote and inside of it a backslash escapes
+// the next character. Quotes inside of a word are kept as they are.
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+
+	tok := token{start: l.pos}
+	var current strings.Builder
+
+	if strings.HasPrefix(l.text[l.pos:], `"`) {
+		tok.quoted = true
+		l.pos++
+		escaped := false
+		closed := false
+		for l.pos < len(l.text) && !closed {
+			r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+			l.pos += size
+			switch {
+			case escaped:
+				current.WriteRune(r)
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				closed = true
+			default:
+				current.WriteRune(r)
+			}
+		}
+		if !closed {
+			return token{}, errUnterminatedQuote
+		}
+	}
+
+	for l.pos < len(l.text) {
+		r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		current.WriteRune(r)
+		l.pos += size
+	}
+
+	tok.value = current.String()
+	return tok, nil
+}
+
+// tokenize splits the whole text into tokens, see lexer.next.
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+
+	l := &lexer{text: text}
+	for !l.done() {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+// parseUserMention understands <@ID>, <@!ID> and @name. Everything else is treated as a user name.
+func parseUserMention(text string) (model.User, error) {
+	if matches := userMentionRegexp.FindStringSubmatch(text); matches != nil {
+		return model.User{ID: matches[1]}, nil
+	}
+	name := strings.TrimPrefix(text, "@")
+	if len(name) == 0 {
+		return model.User{}, errors.New("empty user")
+	}
+	return model.User{Name: name}, nil
+}
+
+// parseChannelMention understands <#ID>, <#ID|name> and #name. Everything else is treated as a channel name.
+func parseChannelMention(text string) (model.Channel, error) {
+	if matches := channelMentionRegexp.FindStringSubmatch(text); matches != nil {
+		return model.Channel{ID: matches[1], Name: matches[3]}, nil
+	}
+	name := strings.TrimPrefix(text, "#")
+	if len(name) == 0 {
+		return model.Channel{}, errors.New("empty channel")
+	}
+	return model.Channel{Name: name}, nil
+}
+
+func parseValue(t ArgType, text string) (interface{}, error) {
+	switch t {
+	case ArgInt:
+		v, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, errors.New("not a valid number")
+		}
+		return v, nil
+	case ArgDuration:
+		v, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, errors.New("not a valid duration")
+		}
+		return v, nil
+	case ArgURL:
+		if _, err := url.ParseRequestURI(text); err != nil {
+			return nil, errors.New("not a valid url")
+		}
+		return text, nil
+	case ArgUser:
+		return parseUserMention(text)
+	case ArgChannel:
+		return parseChannelMention(text)
+	default:
+		return text, nil
+	}
+}
+
+// Parse parses the content of a command (where the command itself is already stripped off)
+// according to the CommandSpec. If the content does not match, a *UsageError is returned.
+func (s CommandSpec) Parse(content string) (Arguments, error) {
+	content = strings.TrimSpace(content)
+
+	return s.parse(s.Command, "", content, &lexer{text: content})
+}
+
+func (s CommandSpec) parse(path string, subcommand string, content string, l *lexer) (Arguments, error) {
+	if len(s.Subcommands) > 0 && !l.done() {
+		pos := l.pos
+		tok, err := l.next()
+		if err != nil {
+			return Arguments{}, &UsageError{Command: path, Syntax: s.Syntax(), Reason: err.Error()}
+		}
+		if !tok.quoted {
+			if sub, ok := s.subcommand(tok.value); ok {
+				return sub.parse(path+" "+sub.Command, strings.TrimSpace(subcommand+" "+sub.Command), content, l)
+			}
+		}
+		l.pos = pos
+	}
+
+	if len(s.Subcommands) > 0 && len(s.Args) == 0 {
+		var names []string
+		for _, sub := range s.Subcommands {
+			names = append(names, sub.Command)
+		}
+		reason := "Missing subcommand"
+		if !l.done() {
+			tok, _ := l.next()
+			reason = fmt.Sprintf("Unknown subcommand '%s'", tok.value)
+		}
+		return Arguments{}, &UsageError{Command: path, Syntax: "<" + strings.Join(names, "|") + ">", Reason: reason}
+	}
+
+	usageError := func(format string, a ...interface{}) error {
+		return &UsageError{Command: path, Syntax: s.Syntax(), Reason: fmt.Sprintf(format, a...)}
+	}
+
+	args := Arguments{
+		Subcommand: subcommand,
+		Raw:        content,
+		values:     make(map[string]interface{}),
+	}
+
+	argIdx := 0
+	for !l.done() {
+		// A text argument takes the remainder verbatim, without unquoting it.
+		if argIdx < len(s.Args) && s.Args[argIdx].Type == ArgText && !strings.HasPrefix(l.peek(), "--") {
+			args.values[s.Args[argIdx].Name] = l.rest()
+			argIdx++
+			break
+		}
+
+		tok, err := l.next()
+		if err != nil {
+			return Arguments{}, usageError("%s", err)
+		}
+
+		if !tok.quoted && strings.HasPrefix(tok.value, "--") && len(tok.value) > 2 {
+			name := tok.value[2:]
+			value := ""
+			hasValue := false
+			if idx := strings.Index(name, "="); idx >= 0 {
+				value = name[idx+1:]
+				name = name[:idx]
+				hasValue = true
+			}
+
+			var flag *FlagSpec
+			for j := range s.Flags {
+				if s.Flags[j].Name == name {
+					flag = &s.Flags[j]
+				}
+			}
+			if flag == nil {
+				return Arguments{}, usageError("Unknown flag --%s", name)
+			}
+
+			if flag.Bool {
+				if hasValue {
+					return Arguments{}, usageError("Flag --%s does not take a value", name)
+				}
+				args.values[flag.Name] = true
+				continue
+			}
+
+			if !hasValue {
+				if l.done() {
+					return Arguments{}, usageError("Flag --%s requires a value", name)
+				}
+				next, err := l.next()
+				if err != nil {
+					return Arguments{}, usageError("%s", err)
+				}
+				value = next.value
+			}
+			v, err := parseValue(flag.Type, value)
+			if err != nil {
+				return Arguments{}, usageError("Flag --%s is %s", name, err)
+			}
+			args.values[flag.Name] = v
+			continue
+		}
+
+		if argIdx >= len(s.Args) {
+			return Arguments{}, usageError("Too many arguments")
+		}
+
+		spec := s.Args[argIdx]
+		argIdx++
+
+		v, err := parseValue(spec.Type, tok.value)
+		if err != nil {
+			return Arguments{}, usageError("Argument <%s> is %s", spec.Name, err)
+		}
+		args.values[spec.Name] = v
+	}
+
+	for _, spec := range s.Args {
+		if _, ok := args.values[spec.Name]; !ok && !spec.Optional {
+			return Arguments{}, usageError("Missing argument <%s>", spec.Name)
+		}
+	}
+
+	return args, nil
+}
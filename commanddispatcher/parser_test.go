@@ -0,0 +1,148 @@
+package commanddispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torlenor/redseligg/model"
+)
+
+var testSpec = CommandSpec{
+	Command:     "test",
+	Description: "Does test things",
+	Examples:    []string{"test add 1m"},
+	Subcommands: []CommandSpec{
+		{
+			Command: "add",
+			Args: []ArgSpec{
+				{Name: "interval", Type: ArgDuration},
+				{Name: "count", Type: ArgInt, Optional: true},
+				{Name: "message", Type: ArgText, Optional: true},
+			},
+			Flags: []FlagSpec{
+				{Name: "silent", Bool: true},
+				{Name: "user", Type: ArgUser},
+			},
+		},
+		{
+			Command: "move",
+			Args: []ArgSpec{
+				{Name: "channel", Type: ArgChannel},
+				{Name: "link", Type: ArgURL, Optional: true},
+			},
+		},
+	},
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Empty", text: "", want: nil},
+		{name: "Words", text: "a  b\tc", want: []string{"a", "b", "c"}},
+		{name: "Double quotes", text: `a "b c" d`, want: []string{"a", "b c", "d"}},
+		{name: "Single quotes", text: `'b c'`, want: []string{"'b", "c'"}},
+		{name: "Apostrophe inside word", text: `It's rock'n'roll`, want: []string{"It's", "rock'n'roll"}},
+		{name: "Escaped quote", text: `"b \"c\""`, want: []string{`b "c"`}},
+		{name: "Quote inside word", text: `ab"c d"`, want: []string{`ab"c`, `d"`}},
+		{name: "Unterminated quote", text: `a "b c`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tokenize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got []string
+			for _, tok := range tokens {
+				got = append(got, tok.value)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCommandSpec_Parse(t *testing.T) {
+	assert := assert.New(t)
+
+	args, err := testSpec.Parse("add 1m 3 some message text")
+	assert.NoError(err)
+	assert.Equal("add", args.Subcommand)
+	assert.Equal(time.Minute, args.Duration("interval"))
+	assert.Equal(3, args.Int("count"))
+	assert.Equal("some message text", args.String("message"))
+	assert.False(args.Bool("silent"))
+
+	args, err = testSpec.Parse(`add --silent 1m --user <@!1234> 3 "quoted message"`)
+	assert.NoError(err)
+	assert.Equal(time.Minute, args.Duration("interval"))
+	assert.True(args.Bool("silent"))
+	assert.Equal(model.User{ID: "1234"}, args.User("user"))
+	assert.Equal(`"quoted message"`, args.String("message"))
+
+	args, err = testSpec.Parse(`add 1m 3 It's "rock'n'roll`)
+	assert.NoError(err)
+	assert.Equal(`It's "rock'n'roll`, args.String("message"))
+
+	args, err = testSpec.Parse("add 1m --user=@someone")
+	assert.NoError(err)
+	assert.Equal(model.User{Name: "someone"}, args.User("user"))
+	assert.Equal("someone", args.String("user"))
+	assert.False(args.Has("count"))
+	assert.Equal(0, args.Int("count"))
+
+	args, err = testSpec.Parse("move <#C1234|general> https://example.com/feed.xml")
+	assert.NoError(err)
+	assert.Equal("move", args.Subcommand)
+	assert.Equal(model.Channel{ID: "C1234", Name: "general"}, args.Channel("channel"))
+	assert.Equal("https://example.com/feed.xml", args.String("link"))
+
+	args, err = testSpec.Parse("move #general")
+	assert.NoError(err)
+	assert.Equal(model.Channel{Name: "general"}, args.Channel("channel"))
+}
+
+func TestCommandSpec_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "Missing subcommand", content: "", want: "Missing subcommand. Usage: `!test <add|move>`"},
+		{name: "Unknown subcommand", content: "blub", want: "Unknown subcommand 'blub'. Usage: `!test <add|move>`"},
+		{name: "Missing argument", content: "add", want: "Missing argument <interval>. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Invalid duration", content: "add 1kk", want: "Argument <interval> is not a valid duration. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Invalid number", content: "add 1m k", want: "Argument <count> is not a valid number. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Unknown flag", content: "add 1m --loud", want: "Unknown flag --loud. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Bool flag with value", content: "add 1m --silent=yes", want: "Flag --silent does not take a value. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Flag without value", content: "add 1m --user", want: "Flag --user requires a value. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+		{name: "Too many arguments", content: "move #general https://example.com something", want: "Too many arguments. Usage: `!test move <channel> [link]`"},
+		{name: "Invalid url", content: "move #general example.com", want: "Argument <link> is not a valid url. Usage: `!test move <channel> [link]`"},
+		{name: "Unterminated quote", content: `add 1m "text`, want: "Unterminated quoted string. Usage: `!test add <interval> [count] [message...] [--silent] [--user <user>]`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSpec.Parse(tt.content)
+			usageErr, ok := err.(*UsageError)
+			if !ok {
+				t.Fatalf("Parse() error = %v, want *UsageError", err)
+			}
+			assert.Equal(t, tt.want, usageErr.Message("!"))
+		})
+	}
+}
+
+func TestCommandSpec_Usage(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := []string{
+		"!test add <interval> [count] [message...] [--silent] [--user <user>]",
+		"!test move <channel> [link]",
+	}
+	assert.Equal(expected, testSpec.Usage("!"))
+}
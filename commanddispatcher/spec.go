@@ -0,0 +1,142 @@
+package commanddispatcher
+
+import (
+	"strings"
+)
+
+// ArgType describes how a positional argument or a flag value is parsed.
+type ArgType int
+
+// All currently supported argument types
+const (
+	// ArgString is a single word or a quoted string.
+	ArgString ArgType = iota
+	// ArgInt is an integer number.
+	ArgInt
+	// ArgDuration is a duration in the format understood by time.ParseDuration, e.g., 1h30m.
+	ArgDuration
+	// ArgUser is a user mention, e.g., <@ID>, <@!ID> or @name.
+	ArgUser
+	// ArgChannel is a channel mention, e.g., <#ID>, <#ID|name> or #name.
+	ArgChannel
+	// ArgURL is an absolute URL, e.g., https://example.com/feed.xml.
+	ArgURL
+	// ArgText consumes the remaining content. It must be the last argument.
+	ArgText
+)
+
+var argTypeNames = [...]string{
+	"string",
+	"int",
+	"duration",
+	"user",
+	"channel",
+	"url",
+	"text",
+}
+
+func (t ArgType) String() string {
+	if int(t) < len(argTypeNames) {
+		return argTypeNames[t]
+	}
+	return "unknown"
+}
+
+// ArgSpec describes one positional argument of a command.
+type ArgSpec struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+}
+
+// FlagSpec describes a flag of a command which can be given as --name value, --name=value
+// or, for boolean flags, just as --name.
+type FlagSpec struct {
+	Name string
+	Type ArgType
+	Bool bool
+}
+
+// CommandSpec declares the syntax of a command (without call prefix).
+// If Subcommands are given, the first word of the content selects the subcommand.
+// When no subcommand matches, the content is parsed with the Args of the command itself.
+type CommandSpec struct {
+	Command string
+
+	// Description and Examples are shown in the auto-generated help of the command.
+	Description string
+	Examples    []string
+
+	Args  []ArgSpec
+	Flags []FlagSpec
+
+	Subcommands []CommandSpec
+}
+
+func (s CommandSpec) subcommand(name string) (CommandSpec, bool) {
+	for _, sub := range s.Subcommands {
+		if sub.Command == name {
+			return sub, true
+		}
+	}
+	return CommandSpec{}, false
+}
+
+// Syntax returns the argument syntax of the command, e.g., "<interval> <message...> [--silent]".
+func (s CommandSpec) Syntax() string {
+	var parts []string
+	for _, a := range s.Args {
+		name := a.Name
+		if a.Type == ArgText {
+			name += "..."
+		}
+		if a.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	for _, f := range s.Flags {
+		if f.Bool {
+			parts = append(parts, "[--"+f.Name+"]")
+		} else {
+			parts = append(parts, "[--"+f.Name+" <"+f.Type.String()+">]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Usage returns one usage line per invocation form of the command, including all subcommands.
+func (s CommandSpec) Usage(callPrefix string) []string {
+	return s.usage(callPrefix + s.Command)
+}
+
+func (s CommandSpec) usage(path string) []string {
+	var lines []string
+	if len(s.Subcommands) == 0 || len(s.Args) > 0 {
+		lines = append(lines, strings.TrimSpace(path+" "+s.Syntax()))
+	}
+	for _, sub := range s.Subcommands {
+		lines = append(lines, sub.usage(path+" "+sub.Command)...)
+	}
+	return lines
+}
+
+// UsageError is returned when the content of a command does not match its CommandSpec.
+type UsageError struct {
+	// Command is the full command path without call prefix, e.g., "tm add".
+	Command string
+	// Syntax is the expected argument syntax of that command.
+	Syntax string
+	// Reason describes what went wrong.
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	return e.Reason
+}
+
+// Message returns a user facing error message including the correct usage.
+func (e *UsageError) Message(callPrefix string) string {
+	return e.Reason + ". Usage: `" + strings.TrimSpace(callPrefix+e.Command+" "+e.Syntax) + "`"
+}
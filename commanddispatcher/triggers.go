@@ -0,0 +1,215 @@
+package commanddispatcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// TriggerMatch describes why a trigger matched a post.
+type TriggerMatch struct {
+	// Content is the content of the post with the mentions of the bot removed.
+	Content string
+	// Groups are the submatches of a regular expression trigger with Groups[0] being the whole match.
+	// For keyword triggers it contains the keyword as found in the post.
+	Groups []string
+	// Mentioned is true if the bot was mentioned in the post.
+	Mentioned bool
+}
+
+type triggerReceiver interface {
+	// OnTrigger delivers the name of the trigger, the match and the raw Post.
+	OnTrigger(name string, match TriggerMatch, post model.Post)
+}
+
+type triggerKind int
+
+const (
+	mentionTrigger triggerKind = iota
+	regexpTrigger
+	keywordTrigger
+)
+
+// Trigger describes on which posts, besides commands, a receiver gets notified.
+// Use MentionTrigger, RegexpTrigger or KeywordTrigger to create one.
+type Trigger struct {
+	// Name identifies the trigger, e.g., for unregistering it. Permissions and cooldowns
+	// are applied to the trigger as if it was a command with this name.
+	Name string
+
+	kind   triggerKind
+	regexp *regexp.Regexp
+}
+
+// MentionTrigger returns a trigger matching all posts which mention the bot and do not contain a command.
+func MentionTrigger(name string) Trigger {
+	return Trigger{Name: name, kind: mentionTrigger}
+}
+
+// RegexpTrigger returns a trigger matching all posts which match the regular expression.
+func RegexpTrigger(name string, expr string) (Trigger, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Trigger{}, fmt.Errorf("Invalid regular expression for trigger %s: %s", name, err)
+	}
+	return Trigger{Name: name, kind: regexpTrigger, regexp: re}, nil
+}
+
+// KeywordTrigger returns a trigger matching all posts which contain one of the keywords as a whole word.
+// The keywords are case-insensitive.
+func KeywordTrigger(name string, keywords ...string) (Trigger, error) {
+	var quoted []string
+	for _, k := range keywords {
+		if k = strings.TrimSpace(k); len(k) > 0 {
+			quoted = append(quoted, regexp.QuoteMeta(k))
+		}
+	}
+	if len(quoted) == 0 {
+		return Trigger{}, fmt.Errorf("Trigger %s has no keywords", name)
+	}
+
+	re, err := regexp.Compile(`(?i)(?:^|[^\pL\pN_])(` + strings.Join(quoted, "|") + `)(?:$|[^\pL\pN_])`)
+	if err != nil {
+		return Trigger{}, fmt.Errorf("Invalid keywords for trigger %s: %s", name, err)
+	}
+	return Trigger{Name: name, kind: keywordTrigger, regexp: re}, nil
+}
+
+// match returns the match of the trigger on the content, which has the mentions already removed.
+func (t Trigger) match(content string, mentioned bool) (TriggerMatch, bool) {
+	m := TriggerMatch{Content: content, Mentioned: mentioned}
+
+	switch t.kind {
+	case mentionTrigger:
+		return m, mentioned
+	case regexpTrigger:
+		m.Groups = t.regexp.FindStringSubmatch(content)
+	case keywordTrigger:
+		if groups := t.regexp.FindStringSubmatch(content); groups != nil {
+			m.Groups = groups[1:]
+		}
+	}
+
+	return m, m.Groups != nil
+}
+
+// triggerRegistration is one receiver registered for a trigger.
+type triggerRegistration struct {
+	receiver triggerReceiver
+	trigger  Trigger
+}
+
+// RegisterTrigger registers a receiver which gets notified via OnTrigger about all posts
+// matching the trigger. Posts which are dispatched as a command do not fire triggers.
+// Registering a trigger with the same name again for the receiver replaces it.
+func (c *CommandDispatcher) RegisterTrigger(t Trigger, r triggerReceiver) error {
+	log.Tracef("Registering trigger %s", t.Name)
+	if len(t.Name) == 0 {
+		log.Warn("Tried to register a trigger without name")
+		return fmt.Errorf("Cannot register a trigger without name")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.triggers {
+		if c.triggers[i].receiver == r && c.triggers[i].trigger.Name == t.Name {
+			c.triggers[i].trigger = t
+			return nil
+		}
+	}
+	c.triggers = append(c.triggers, triggerRegistration{receiver: r, trigger: t})
+
+	return nil
+}
+
+// UnregisterTrigger removes the trigger with the specified name of the receiver if it exists.
+func (c *CommandDispatcher) UnregisterTrigger(name string, r triggerReceiver) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var kept []triggerRegistration
+	for _, reg := range c.triggers {
+		if reg.receiver != r || reg.trigger.Name != name {
+			kept = append(kept, reg)
+		}
+	}
+	c.triggers = kept
+}
+
+// SetBotMentions sets the platform specific ways the bot can be mentioned in a post, e.g.,
+// "<@123>" on Discord or "@botname" on Twitch. A post starting with a mention of the bot
+// is handled like a command even without call prefix, e.g., "@bot roll 20".
+// The mentions are compared case-insensitively.
+func (c *CommandDispatcher) SetBotMentions(mentions ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.mentions = nil
+	for _, m := range mentions {
+		if len(m) > 0 {
+			c.mentions = append(c.mentions, m)
+		}
+	}
+}
+
+func isMentionBoundary(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', ':', ',', '.', '!', '?':
+		return true
+	}
+	return false
+}
+
+// mentionAt returns the length of the mention at position i of the content or 0 if there is none.
+func mentionAt(content string, i int, mentions []string) int {
+	for _, m := range mentions {
+		end := i + len(m)
+		if end > len(content) || !strings.EqualFold(content[i:end], m) {
+			continue
+		}
+		if end == len(content) || isMentionBoundary(content[end]) {
+			return len(m)
+		}
+	}
+	return 0
+}
+
+// stripMentions removes all mentions of the bot from the content. It returns the stripped content,
+// if the bot was mentioned anywhere and if the content started with a mention.
+func stripMentions(content string, mentions []string) (string, bool, bool) {
+	if len(mentions) == 0 {
+		return content, false, false
+	}
+
+	content = strings.TrimSpace(content)
+
+	var stripped strings.Builder
+	mentioned := false
+	leading := false
+	for i := 0; i < len(content); {
+		if i == 0 || isMentionBoundary(content[i-1]) {
+			if n := mentionAt(content, i, mentions); n > 0 {
+				if i == 0 {
+					leading = true
+				}
+				mentioned = true
+				i += n
+				// Remove the separator following directly on a mention, e.g., "@bot: roll"
+				if i < len(content) && (content[i] == ':' || content[i] == ',') {
+					i++
+				}
+				for i < len(content) && content[i] == ' ' {
+					i++
+				}
+				continue
+			}
+		}
+		stripped.WriteByte(content[i])
+		i++
+	}
+
+	return strings.TrimSpace(stripped.String()), mentioned, leading
+}
@@ -0,0 +1,149 @@
+package commanddispatcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+type mockTriggerReceiver struct {
+	names   []string
+	matches []TriggerMatch
+	posts   []model.Post
+}
+
+func (m *mockTriggerReceiver) OnTrigger(name string, match TriggerMatch, post model.Post) {
+	m.names = append(m.names, name)
+	m.matches = append(m.matches, match)
+	m.posts = append(m.posts, post)
+}
+
+func Test_stripMentions(t *testing.T) {
+	mentions := []string{"<@123>", "<@!123>", "@bot"}
+
+	tests := []struct {
+		content       string
+		wantContent   string
+		wantMentioned bool
+		wantLeading   bool
+	}{
+		{content: "roll 20", wantContent: "roll 20"},
+		{content: "<@123> roll 20", wantContent: "roll 20", wantMentioned: true, wantLeading: true},
+		{content: "<@!123> roll 20", wantContent: "roll 20", wantMentioned: true, wantLeading: true},
+		{content: "@BOT: roll 20", wantContent: "roll 20", wantMentioned: true, wantLeading: true},
+		{content: "  @bot, roll", wantContent: "roll", wantMentioned: true, wantLeading: true},
+		{content: "hello @bot how are you?", wantContent: "hello how are you?", wantMentioned: true},
+		{content: "thanks @bot!", wantContent: "thanks !", wantMentioned: true},
+		{content: "@botany is fun", wantContent: "@botany is fun"},
+		{content: "mail@bot", wantContent: "mail@bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			got, mentioned, leading := stripMentions(tt.content, mentions)
+			assert.Equal(t, tt.wantContent, got)
+			assert.Equal(t, tt.wantMentioned, mentioned)
+			assert.Equal(t, tt.wantLeading, leading)
+		})
+	}
+}
+
+func TestCommandDispatcher_MentionCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	dispatcher.SetBotMentions("<@123>", "<@!123>")
+
+	receiver := &mockCommandReceiver{}
+	dispatcher.Register("roll", receiver, CommandHelp{})
+
+	post := model.Post{ChannelID: "CHANNEL", Content: "<@123> roll 20"}
+	dispatcher.OnPost(post)
+	assert.Equal("roll", receiver.lastReceivedCmd)
+	assert.Equal("20", receiver.lastReceivedContent)
+	assert.Equal("!roll 20", receiver.lastReceivedPost.Content)
+
+	post.Content = "<@!123> !roll 6"
+	dispatcher.OnPost(post)
+	assert.Equal("6", receiver.lastReceivedContent)
+	assert.Equal("!roll 6", receiver.lastReceivedPost.Content)
+
+	receiver.lastReceivedCmd = ""
+	post.Content = "roll 20 <@123>"
+	dispatcher.OnPost(post)
+	assert.Equal("", receiver.lastReceivedCmd)
+}
+
+func TestTriggers(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := RegexpTrigger("invalid", "(")
+	assert.Error(err)
+	_, err = KeywordTrigger("empty", " ")
+	assert.Error(err)
+
+	dispatcher := New("!")
+	dispatcher.SetBotMentions("@bot")
+
+	assert.Error(dispatcher.RegisterTrigger(MentionTrigger(""), &mockTriggerReceiver{}))
+
+	mention := &mockTriggerReceiver{}
+	assert.NoError(dispatcher.RegisterTrigger(MentionTrigger("mention"), mention))
+
+	regexp := &mockTriggerReceiver{}
+	trigger, err := RegexpTrigger("dice", `(\d+)d(\d+)`)
+	assert.NoError(err)
+	assert.NoError(dispatcher.RegisterTrigger(trigger, regexp))
+
+	keyword := &mockTriggerReceiver{}
+	trigger, err = KeywordTrigger("greeting", "hello", "good morning")
+	assert.NoError(err)
+	assert.NoError(dispatcher.RegisterTrigger(trigger, keyword))
+
+	command := &mockCommandReceiver{}
+	dispatcher.Register("roll", command, CommandHelp{})
+
+	dispatcher.OnPost(model.Post{Content: "@bot how are you?"})
+	assert.Equal([]string{"mention"}, mention.names)
+	assert.Equal(TriggerMatch{Content: "how are you?", Mentioned: true}, mention.matches[0])
+	assert.Equal("how are you?", mention.posts[0].Content)
+
+	dispatcher.OnPost(model.Post{Content: "I want to roll 2d6"})
+	assert.Equal([]string{"dice"}, regexp.names)
+	assert.Equal([]string{"2d6", "2", "6"}, regexp.matches[0].Groups)
+
+	dispatcher.OnPost(model.Post{Content: "Good Morning, everyone"})
+	dispatcher.OnPost(model.Post{Content: "othello"})
+	assert.Equal([]string{"greeting"}, keyword.names)
+	assert.Equal([]string{"Good Morning"}, keyword.matches[0].Groups)
+
+	// Commands do not fire triggers
+	dispatcher.OnPost(model.Post{Content: "@bot roll 2d6 hello"})
+	dispatcher.OnPost(model.Post{Content: "!roll 2d6 hello"})
+	assert.Equal("2d6 hello", command.lastReceivedContent)
+	assert.Equal(1, len(mention.names))
+	assert.Equal(1, len(regexp.names))
+	assert.Equal(1, len(keyword.names))
+
+	dispatcher.UnregisterTrigger("greeting", keyword)
+	dispatcher.OnPost(model.Post{Content: "hello"})
+	assert.Equal(1, len(keyword.names))
+}
+
+func TestTriggers_Authorizer(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := New("!")
+	dispatcher.SetAuthorizer(&mockAuthorizer{allowed: map[string]bool{"user": true}})
+
+	receiver := &mockTriggerReceiver{}
+	trigger, _ := KeywordTrigger("greeting", "hello")
+	dispatcher.RegisterTrigger(trigger, receiver)
+
+	dispatcher.OnPost(model.Post{User: model.User{Name: "other"}, Content: "hello"})
+	assert.Equal(0, len(receiver.names))
+
+	dispatcher.OnPost(model.Post{User: model.User{Name: "user"}, Content: "hello"})
+	assert.Equal(1, len(receiver.names))
+}
@@ -0,0 +1,197 @@
+// Package cooldown provides per-user and per-channel cooldowns for the commands of a bot.
+package cooldown
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
+)
+
+var log = logging.Get("Cooldown")
+
+// pruneInterval is the interval in which expired cooldowns are removed.
+const pruneInterval = time.Minute
+
+type cooldown struct {
+	user    time.Duration
+	channel time.Duration
+}
+
+type key struct {
+	cmd string
+	id  string
+}
+
+type replier interface {
+	Reply(post model.Post, msg string)
+	GetCallPrefix() string
+	CalledName(post model.Post, cmd string) string
+}
+
+// Limiter keeps track of the last use of commands and drops commands which are on cooldown.
+type Limiter struct {
+	exemptMods bool
+	reply      bool
+
+	defaultCooldown cooldown
+	commands        map[string]cooldown
+
+	mutex     sync.Mutex
+	users     map[key]time.Time // [cmd, user] -> end of cooldown
+	channels  map[key]time.Time // [cmd, channel] -> end of cooldown
+	notified  map[key]time.Time // [cmd, user] -> end of cooldown the user was told about
+	lastPrune time.Time
+
+	replier replier
+
+	now func() time.Time
+}
+
+func parseCooldown(cfg botconfig.CooldownConfig) (cooldown, error) {
+	var c cooldown
+	var err error
+	if len(cfg.User) > 0 {
+		if c.user, err = time.ParseDuration(cfg.User); err != nil {
+			return cooldown{}, fmt.Errorf("Invalid user cooldown '%s': %s", cfg.User, err)
+		}
+	}
+	if len(cfg.Channel) > 0 {
+		if c.channel, err = time.ParseDuration(cfg.Channel); err != nil {
+			return cooldown{}, fmt.Errorf("Invalid channel cooldown '%s': %s", cfg.Channel, err)
+		}
+	}
+	return c, nil
+}
+
+// New creates a new cooldown Limiter from the config.
+func New(cfg botconfig.CooldownsConfig) (*Limiter, error) {
+	l := &Limiter{
+		exemptMods: cfg.ExemptMods,
+		reply:      cfg.Reply,
+		commands:   make(map[string]cooldown),
+		users:      make(map[key]time.Time),
+		channels:   make(map[key]time.Time),
+		notified:   make(map[key]time.Time),
+		now:        time.Now,
+	}
+
+	var err error
+	if l.defaultCooldown, err = parseCooldown(cfg.Default); err != nil {
+		return nil, fmt.Errorf("Error in default cooldown: %s", err)
+	}
+	for cmd, c := range cfg.Commands {
+		if l.commands[cmd], err = parseCooldown(c); err != nil {
+			return nil, fmt.Errorf("Error in cooldown for command %s: %s", cmd, err)
+		}
+	}
+
+	return l, nil
+}
+
+// Register adds the cooldowns as middleware to the dispatcher.
+func (l *Limiter) Register(d *commanddispatcher.CommandDispatcher) {
+	l.replier = d
+	d.Use(l.Middleware)
+}
+
+// Middleware drops commands which are on cooldown and starts the cooldowns for all other commands.
+func (l *Limiter) Middleware(next commanddispatcher.HandlerFunc) commanddispatcher.HandlerFunc {
+	return func(cmd string, content string, post model.Post) {
+		if l.exemptMods && post.User.IsMod {
+			next(cmd, content, post)
+			return
+		}
+
+		if remaining, notify := l.check(cmd, post); remaining > 0 {
+			log.Debugf("Command %s of user %s is on cooldown for another %s", cmd, post.User.Name, remaining)
+			if notify {
+				l.notify(cmd, post, remaining)
+			}
+			return
+		}
+
+		next(cmd, content, post)
+	}
+}
+
+func userKey(cmd string, user model.User) key {
+	if len(user.ID) > 0 {
+		return key{cmd: cmd, id: user.ID}
+	}
+	return key{cmd: cmd, id: user.Name}
+}
+
+// check returns the remaining cooldown of the command for the post and if the user should
+// be told about it. If there is no remaining cooldown, the cooldowns are started.
+func (l *Limiter) check(cmd string, post model.Post) (time.Duration, bool) {
+	c, ok := l.commands[cmd]
+	if !ok {
+		c = l.defaultCooldown
+	}
+	if c.user == 0 && c.channel == 0 {
+		return 0, false
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	uk := userKey(cmd, post.User)
+	ck := key{cmd: cmd, id: post.ChannelID}
+
+	end := l.users[uk]
+	if l.channels[ck].After(end) {
+		end = l.channels[ck]
+	}
+	if end.After(now) {
+		notify := l.reply && !l.notified[uk].Equal(end)
+		if notify {
+			l.notified[uk] = end
+		}
+		return end.Sub(now), notify
+	}
+
+	if c.user > 0 {
+		l.users[uk] = now.Add(c.user)
+	}
+	if c.channel > 0 {
+		l.channels[ck] = now.Add(c.channel)
+	}
+
+	return 0, false
+}
+
+// prune removes expired cooldowns. It has to be called with the mutex locked.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for _, m := range []map[key]time.Time{l.users, l.channels, l.notified} {
+		for k, end := range m {
+			if !end.After(now) {
+				delete(m, k)
+			}
+		}
+	}
+}
+
+// notify tells the user how long the command is on cooldown, using the name or alias the user typed.
+func (l *Limiter) notify(cmd string, post model.Post, remaining time.Duration) {
+	if l.replier == nil {
+		return
+	}
+	remaining = remaining.Round(time.Second)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	l.replier.Reply(post, fmt.Sprintf("Command %s%s is on cooldown, try again in %s.", l.replier.GetCallPrefix(), l.replier.CalledName(post, cmd), remaining))
+}
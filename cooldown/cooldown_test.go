@@ -0,0 +1,168 @@
+package cooldown
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+)
+
+type mockReceiver struct {
+	calls int
+}
+
+func (m *mockReceiver) OnCommand(cmd string, content string, post model.Post) {
+	m.calls++
+}
+
+type mockNamedReceiver struct {
+	mockReceiver
+	names commanddispatcher.CommandNames
+}
+
+func (m *mockNamedReceiver) CommandNames() commanddispatcher.CommandNames { return m.names }
+
+type mockPoster struct {
+	posts []model.Post
+}
+
+func (m *mockPoster) CreatePost(post model.Post) (model.PostResponse, error) {
+	m.posts = append(m.posts, post)
+	return model.PostResponse{}, nil
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func setup(t *testing.T, cfg botconfig.CooldownsConfig) (*commanddispatcher.CommandDispatcher, *mockReceiver, *mockPoster, *clock) {
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c := &clock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l.now = c.Now
+
+	d := commanddispatcher.New("!")
+	poster := &mockPoster{}
+	d.SetPoster(poster)
+	l.Register(d)
+
+	receiver := &mockReceiver{}
+	d.Register("roll", receiver, commanddispatcher.CommandHelp{})
+	d.Register("quote", receiver, commanddispatcher.CommandHelp{})
+
+	return d, receiver, poster, c
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(botconfig.CooldownsConfig{Default: botconfig.CooldownConfig{User: "abc"}})
+	assert.Error(t, err)
+
+	_, err = New(botconfig.CooldownsConfig{Commands: map[string]botconfig.CooldownConfig{"roll": {Channel: "1x"}}})
+	assert.Error(t, err)
+}
+
+func TestLimiter_UserCooldown(t *testing.T) {
+	assert := assert.New(t)
+
+	d, receiver, poster, c := setup(t, botconfig.CooldownsConfig{
+		Commands: map[string]botconfig.CooldownConfig{"roll": {User: "30s"}},
+	})
+
+	user1 := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "1", Name: "user1"}, Content: "!roll"}
+	user2 := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "2", Name: "user2"}, Content: "!roll"}
+
+	d.OnPost(user1)
+	d.OnPost(user1)
+	assert.Equal(1, receiver.calls)
+
+	d.OnPost(user2)
+	assert.Equal(2, receiver.calls)
+
+	user1.Content = "!quote"
+	d.OnPost(user1)
+	assert.Equal(3, receiver.calls)
+
+	user1.Content = "!roll"
+	c.now = c.now.Add(31 * time.Second)
+	d.OnPost(user1)
+	assert.Equal(4, receiver.calls)
+
+	assert.Equal(0, len(poster.posts))
+}
+
+func TestLimiter_Aliases(t *testing.T) {
+	assert := assert.New(t)
+
+	d, _, poster, _ := setup(t, botconfig.CooldownsConfig{
+		Reply:    true,
+		Commands: map[string]botconfig.CooldownConfig{"d": {User: "30s"}},
+	})
+	receiver := &mockNamedReceiver{names: commanddispatcher.CommandNames{
+		Rename:  map[string]string{"dice": "d"},
+		Aliases: map[string][]string{"dice": {"die", "w"}},
+	}}
+	d.Register("dice", receiver, commanddispatcher.CommandHelp{})
+
+	post := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "1", Name: "user1"}}
+	for _, content := range []string{"!d", "!die", "!w"} {
+		post.Content = content
+		d.OnPost(post)
+	}
+	assert.Equal(1, receiver.calls)
+	assert.Equal(1, len(poster.posts))
+	assert.Equal("Command !die is on cooldown, try again in 30s.", poster.posts[0].Content)
+}
+
+func TestLimiter_ChannelCooldownWithReply(t *testing.T) {
+	assert := assert.New(t)
+
+	d, receiver, poster, c := setup(t, botconfig.CooldownsConfig{
+		Reply:   true,
+		Default: botconfig.CooldownConfig{Channel: "1m"},
+	})
+
+	user1 := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "1", Name: "user1"}, Content: "!quote"}
+	user2 := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "2", Name: "user2"}, Content: "!quote"}
+	other := model.Post{ChannelID: "OTHER", User: model.User{ID: "2", Name: "user2"}, Content: "!quote"}
+
+	d.OnPost(user1)
+	assert.Equal(1, receiver.calls)
+
+	c.now = c.now.Add(20 * time.Second)
+	d.OnPost(user2)
+	d.OnPost(user2)
+	assert.Equal(1, receiver.calls)
+	assert.Equal(1, len(poster.posts))
+	assert.Equal("Command !quote is on cooldown, try again in 40s.", poster.posts[0].Content)
+
+	d.OnPost(other)
+	assert.Equal(2, receiver.calls)
+}
+
+func TestLimiter_ExemptMods(t *testing.T) {
+	assert := assert.New(t)
+
+	d, receiver, _, _ := setup(t, botconfig.CooldownsConfig{
+		ExemptMods: true,
+		Default:    botconfig.CooldownConfig{User: "1m", Channel: "1m"},
+	})
+
+	mod := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "1", Name: "mod", IsMod: true}, Content: "!roll"}
+	user := model.Post{ChannelID: "CHANNEL", User: model.User{ID: "2", Name: "user"}, Content: "!roll"}
+
+	d.OnPost(mod)
+	d.OnPost(mod)
+	assert.Equal(2, receiver.calls)
+
+	d.OnPost(user)
+	d.OnPost(user)
+	assert.Equal(3, receiver.calls)
+}
@@ -0,0 +1,162 @@
+// Package eventqueue provides bounded per-plugin event queues, so that a slow plugin does not block
+// the event processing of a bot.
+package eventqueue
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/logging"
+)
+
+var log = logging.Get("EventQueue")
+
+// DefaultSize is the number of events a queue can hold if nothing else is configured.
+const DefaultSize = 100
+
+// Policy decides what happens when an event is added to a full queue.
+type Policy int
+
+// All supported backpressure policies. PolicyDropOldest is the default, because a blocked queue
+// blocks the event processing of the whole bot.
+const (
+	// PolicyDropOldest drops the oldest event in the queue.
+	PolicyDropOldest Policy = iota
+	// PolicyDropNew drops the new event.
+	PolicyDropNew
+	// PolicyBlock waits until there is space in the queue.
+	PolicyBlock
+)
+
+var policyNames = map[string]Policy{
+	"block":      PolicyBlock,
+	"dropoldest": PolicyDropOldest,
+	"dropnew":    PolicyDropNew,
+}
+
+// ParsePolicy returns the Policy with the given name. An empty name is PolicyDropOldest.
+func ParsePolicy(name string) (Policy, error) {
+	if len(name) == 0 {
+		return PolicyDropOldest, nil
+	}
+	if p, ok := policyNames[name]; ok {
+		return p, nil
+	}
+	return PolicyDropOldest, fmt.Errorf("Unknown queue policy '%s'", name)
+}
+
+// Stats contains the current state of a queue.
+type Stats struct {
+	Depth    int
+	Capacity int
+	Dropped  uint64
+}
+
+type named interface {
+	PluginType() string
+}
+
+func keyName(key interface{}) string {
+	if n, ok := key.(named); ok {
+		return n.PluginType()
+	}
+	return fmt.Sprintf("%T", key)
+}
+
+// Run handles the event for the key synchronously with the same panic recovery as the queues.
+func Run(key interface{}, f func()) {
+	run(keyName(key), f)
+}
+
+// Queues holds one queue per key, usually per plugin. Queues are created on first use.
+// After Stop, Start allows to create them again, e.g., when the bot is restarted.
+type Queues struct {
+	size   int
+	policy Policy
+
+	mutex   sync.Mutex
+	queues  map[interface{}]*queue
+	stopped bool
+}
+
+// New creates new Queues with the given size and backpressure policy for each queue.
+func New(size int, policy Policy) *Queues {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Queues{
+		size:   size,
+		policy: policy,
+		queues: make(map[interface{}]*queue),
+	}
+}
+
+// NewFromConfig creates new Queues from the bot config.
+func NewFromConfig(cfg botconfig.QueueConfig) (*Queues, error) {
+	policy, err := ParsePolicy(cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg.Size, policy), nil
+}
+
+func (q *Queues) get(key interface{}) *queue {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.stopped {
+		return nil
+	}
+
+	if qu, ok := q.queues[key]; ok {
+		return qu
+	}
+
+	qu := newQueue(keyName(key), q.size, q.policy)
+	q.queues[key] = qu
+
+	return qu
+}
+
+// Enqueue adds the event to the queue of the key. It returns false if the event was dropped.
+func (q *Queues) Enqueue(key interface{}, f func()) bool {
+	qu := q.get(key)
+	if qu == nil {
+		return false
+	}
+	return qu.enqueue(f)
+}
+
+// Stats returns the current state of the queue of the key.
+func (q *Queues) Stats(key interface{}) Stats {
+	q.mutex.Lock()
+	qu, ok := q.queues[key]
+	q.mutex.Unlock()
+
+	if !ok {
+		return Stats{Capacity: q.size}
+	}
+	return qu.stats()
+}
+
+// Start allows to add events again after the queues were stopped.
+func (q *Queues) Start() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.stopped = false
+}
+
+// Stop handles all events which are already queued and stops all queues.
+// Events added afterwards are dropped until Start is called.
+func (q *Queues) Stop() {
+	q.mutex.Lock()
+	q.stopped = true
+	queues := q.queues
+	q.queues = make(map[interface{}]*queue)
+	q.mutex.Unlock()
+
+	for _, qu := range queues {
+		qu.stop()
+	}
+}
@@ -0,0 +1,198 @@
+package eventqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+)
+
+type recorder struct {
+	mutex  sync.Mutex
+	events []int
+}
+
+func (r *recorder) add(i int) func() {
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.events = append(r.events, i)
+	}
+}
+
+func (r *recorder) get() []int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]int{}, r.events...)
+}
+
+// blockQueue blocks the worker of the queue of the key until the returned channel is closed.
+func blockQueue(q *Queues, key interface{}) chan struct{} {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	q.Enqueue(key, func() {
+		close(started)
+		<-release
+	})
+	<-started
+	return release
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Policy
+		wantErr bool
+	}{
+		{name: "", want: PolicyDropOldest},
+		{name: "block", want: PolicyBlock},
+		{name: "dropoldest", want: PolicyDropOldest},
+		{name: "dropnew", want: PolicyDropNew},
+		{name: "something", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	q, err := NewFromConfig(botconfig.QueueConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSize, q.size)
+	assert.Equal(t, PolicyDropOldest, q.policy)
+
+	_, err = NewFromConfig(botconfig.QueueConfig{Policy: "something"})
+	assert.Error(t, err)
+}
+
+func TestQueues_DropNew(t *testing.T) {
+	assert := assert.New(t)
+
+	q := New(2, PolicyDropNew)
+	r := &recorder{}
+	key := "plugin"
+
+	release := blockQueue(q, key)
+	assert.True(q.Enqueue(key, r.add(1)))
+	assert.True(q.Enqueue(key, r.add(2)))
+	assert.False(q.Enqueue(key, r.add(3)))
+	assert.Equal(Stats{Depth: 2, Capacity: 2, Dropped: 1}, q.Stats(key))
+
+	close(release)
+	q.Stop()
+	assert.Equal([]int{1, 2}, r.get())
+	assert.False(q.Enqueue(key, r.add(4)))
+}
+
+func TestQueues_DropOldest(t *testing.T) {
+	assert := assert.New(t)
+
+	q := New(2, PolicyDropOldest)
+	r := &recorder{}
+	key := "plugin"
+
+	release := blockQueue(q, key)
+	assert.True(q.Enqueue(key, r.add(1)))
+	assert.True(q.Enqueue(key, r.add(2)))
+	assert.True(q.Enqueue(key, r.add(3)))
+	assert.Equal(Stats{Depth: 2, Capacity: 2, Dropped: 1}, q.Stats(key))
+
+	close(release)
+	q.Stop()
+	assert.Equal([]int{2, 3}, r.get())
+}
+
+func TestQueues_Block(t *testing.T) {
+	assert := assert.New(t)
+
+	q := New(1, PolicyBlock)
+	r := &recorder{}
+	key := "plugin"
+
+	release := blockQueue(q, key)
+	assert.True(q.Enqueue(key, r.add(1)))
+
+	enqueued := make(chan bool)
+	go func() {
+		enqueued <- q.Enqueue(key, r.add(2))
+	}()
+
+	select {
+	case <-enqueued:
+		t.Fatal("Enqueue did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	assert.True(<-enqueued)
+	q.Stop()
+	assert.Equal([]int{1, 2}, r.get())
+}
+
+func TestQueues_Isolation(t *testing.T) {
+	assert := assert.New(t)
+
+	q := New(1, PolicyBlock)
+	r := &recorder{}
+
+	release := blockQueue(q, "slow plugin")
+	defer close(release)
+
+	done := make(chan struct{})
+	q.Enqueue("other plugin", func() {
+		r.add(1)()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Event of other plugin was not handled")
+	}
+	assert.Equal([]int{1}, r.get())
+}
+
+func TestQueues_Panic(t *testing.T) {
+	q := New(1, PolicyBlock)
+	r := &recorder{}
+
+	q.Enqueue("plugin", func() { panic("something went wrong") })
+	q.Enqueue("plugin", r.add(1))
+	q.Stop()
+
+	assert.Equal(t, []int{1}, r.get())
+}
+
+func TestRun_Panic(t *testing.T) {
+	r := &recorder{}
+
+	Run("plugin", func() { panic("something went wrong") })
+	Run("plugin", r.add(1))
+
+	assert.Equal(t, []int{1}, r.get())
+}
+
+func TestQueues_Restart(t *testing.T) {
+	assert := assert.New(t)
+
+	q := New(1, PolicyBlock)
+	r := &recorder{}
+
+	assert.True(q.Enqueue("plugin", r.add(1)))
+	q.Stop()
+	assert.False(q.Enqueue("plugin", r.add(2)))
+
+	q.Start()
+	assert.True(q.Enqueue("plugin", r.add(3)))
+	q.Stop()
+
+	assert.Equal([]int{1, 3}, r.get())
+}
@@ -0,0 +1,133 @@
+package eventqueue
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// queue is a bounded queue of events which are handled one after another by its own worker.
+type queue struct {
+	name   string
+	policy Policy
+
+	events chan func()
+	quit   chan struct{}
+	done   chan struct{}
+
+	dropped uint64
+	blocked uint64
+}
+
+func newQueue(name string, size int, policy Policy) *queue {
+	q := &queue{
+		name:   name,
+		policy: policy,
+		events: make(chan func(), size),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go q.work()
+	return q
+}
+
+// run handles the event and recovers from a panic, so that one event cannot take down the worker
+// or, for synchronous delivery, the bot.
+func run(name string, f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Panic while handling event for %s: %v\n%s", name, r, debug.Stack())
+		}
+	}()
+	f()
+}
+
+func (q *queue) run(f func()) {
+	run(q.name, f)
+}
+
+func (q *queue) work() {
+	defer close(q.done)
+	for {
+		select {
+		case f := <-q.events:
+			q.run(f)
+		case <-q.quit:
+			for {
+				select {
+				case f := <-q.events:
+					q.run(f)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *queue) drop() {
+	if atomic.AddUint64(&q.dropped, 1)%100 == 1 {
+		log.Warnf("Event queue of %s is full, dropped %d events so far", q.name, atomic.LoadUint64(&q.dropped))
+	}
+}
+
+// enqueue adds the event according to the backpressure policy. It returns false if the event was dropped.
+func (q *queue) enqueue(f func()) bool {
+	select {
+	case <-q.quit:
+		return false
+	default:
+	}
+
+	switch q.policy {
+	case PolicyDropNew:
+		select {
+		case q.events <- f:
+			return true
+		default:
+			q.drop()
+			return false
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case q.events <- f:
+				return true
+			default:
+			}
+			select {
+			case <-q.events:
+				q.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case q.events <- f:
+			return true
+		default:
+		}
+		if atomic.AddUint64(&q.blocked, 1)%100 == 1 {
+			log.Warnf("Event queue of %s is full, blocking until there is space (%d times so far)", q.name, atomic.LoadUint64(&q.blocked))
+		}
+		select {
+		case q.events <- f:
+			return true
+		case <-q.quit:
+			return false
+		}
+	}
+}
+
+// stop handles all events which are already queued and stops the worker.
+func (q *queue) stop() {
+	close(q.quit)
+	<-q.done
+}
+
+func (q *queue) stats() Stats {
+	return Stats{
+		Depth:    len(q.events),
+		Capacity: cap(q.events),
+		Dropped:  atomic.LoadUint64(&q.dropped),
+	}
+}
@@ -5,8 +5,11 @@ import (
 
 	"github.com/torlenor/redseligg/botconfig"
 	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/cooldown"
+	"github.com/torlenor/redseligg/eventqueue"
 
 	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/permissions"
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/platform/discord"
 	"github.com/torlenor/redseligg/platform/matrix"
@@ -20,6 +23,10 @@ var (
 	logBotFactory = logging.Get("BotFactory")
 )
 
+type eventQueuesSetter interface {
+	SetEventQueues(q *eventqueue.Queues)
+}
+
 // BotFactory can be used to generate bots for specific platforms
 type BotFactory struct{}
 
@@ -38,6 +45,25 @@ func (b *BotFactory) CreateBot(p string, config botconfig.BotConfig) (platform.B
 	logBotFactory.Tracef("Creating CommandDispatcher for botID %s", p)
 	dispatcher := commanddispatcher.New(config.GeneralConfig.CallPrefix)
 
+	logBotFactory.Tracef("Creating permissions for botID %s", p)
+	permissionsConfig := config.PermissionsConfig
+	permissionsConfig.Mods = append(append([]string{}, permissionsConfig.Mods...), permissions.LegacyPluginMods(config.Plugins)...)
+	perms := permissions.New(config.BotID, permissionsConfig, storage)
+	perms.RegisterCommands(dispatcher)
+
+	logBotFactory.Tracef("Creating cooldowns for botID %s", p)
+	cooldowns, err := cooldown.New(config.CooldownsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating cooldowns for botID %s: %s", p, err)
+	}
+	cooldowns.Register(dispatcher)
+
+	logBotFactory.Tracef("Creating event queues for botID %s", p)
+	queues, err := eventqueue.NewFromConfig(config.QueueConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating event queues for botID %s: %s", p, err)
+	}
+
 	switch p {
 	case "slack":
 		slackCfg, err := config.AsSlackConfig()
@@ -75,7 +101,7 @@ func (b *BotFactory) CreateBot(p string, config botconfig.BotConfig) (platform.B
 			return nil, fmt.Errorf("Error creating Matrix bot: %s", err)
 		}
 
-		bot, err = matrix.CreateMatrixBot(matrixCfg, storage, dispatcher)
+		bot, err = matrix.CreateMatrixBot(config.BotID, matrixCfg, storage, dispatcher)
 		if err != nil {
 			return nil, fmt.Errorf("Error creating Matrix bot: %s", err)
 		}
@@ -92,5 +118,10 @@ func (b *BotFactory) CreateBot(p string, config botconfig.BotConfig) (platform.B
 	default:
 		return nil, fmt.Errorf("Unknown platform %s", p)
 	}
+
+	if q, ok := bot.(eventQueuesSetter); ok {
+		q.SetEventQueues(queues)
+	}
+
 	return bot, nil
 }
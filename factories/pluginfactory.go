@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin/archiveplugin"
@@ -83,6 +84,10 @@ func (b *PluginFactory) CreatePlugin(botID, pluginID string, pluginConfig botcon
 	}
 
 	p.SetBotPluginID(botID, pluginID)
+	p.SetCommandNames(commanddispatcher.CommandNames{
+		Rename:  pluginConfig.Commands.Rename,
+		Aliases: pluginConfig.Commands.Aliases,
+	})
 
 	return p, nil
 }
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Membership is a user joining or leaving a channel.
+type Membership struct {
+	ChannelID string
+
+	Type string // "joined" or "left"
+	User User   // The User who joined or left the channel
+}
+
+// Types of subscriptions
+const (
+	SubscriptionNew         = "new"          // A user subscribed for the first time
+	SubscriptionRenewed     = "renewed"      // A user renewed their subscription
+	SubscriptionGift        = "gift"         // A user gifted a subscription to another user
+	SubscriptionMysteryGift = "mystery_gift" // A user gifted subscriptions to random users of the channel
+)
+
+// Subscription is a user subscribing to a channel or gifting subscriptions.
+type Subscription struct {
+	ChannelID string
+
+	Type      string // One of the types of subscriptions
+	User      User   // The User who subscribed or gifted the subscription
+	Anonymous bool   // Anonymous is true for gifts of anonymous users
+	Recipient User   // [optional] The User who received a gifted subscription
+	Plan      string // The subscription plan, e.g., "Prime", "1000", "2000" or "3000" on Twitch
+	Months    int    // The cumulative number of months the user or recipient subscribed
+	Streak    int    // [optional] The number of consecutive months the user subscribed, if shared by the user
+	Count     int    // The number of gifted subscriptions for mystery gifts
+	Message   string // [optional] The message the user shared together with the subscription
+}
+
+// Raid is a streamer sending their viewers to a channel.
+type Raid struct {
+	ChannelID string
+
+	User    User // The User who raided the channel
+	Viewers int  // The number of viewers who joined with the raid
+}
+
+// Cheer is a user sending bits to a channel together with a message.
+type Cheer struct {
+	ChannelID string
+
+	User    User
+	Bits    int
+	Message string
+}
+
+// ChannelState contains the chat settings of a channel.
+type ChannelState struct {
+	ChannelID string
+
+	EmoteOnly             bool          // Only emotes are allowed in the chat
+	SubsOnly              bool          // Only subscribers can write in the chat
+	FollowersOnly         bool          // Only followers can write in the chat
+	FollowersOnlyDuration time.Duration // How long users have to follow before they can write in followers-only mode
+	SlowMode              time.Duration // The time users have to wait between messages, zero if disabled
+	UniqueChat            bool          // Messages must be unique (R9K mode)
+}
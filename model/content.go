@@ -0,0 +1,140 @@
+package model
+
+// NodeType is the type of a node of rich content.
+type NodeType string
+
+// All node types of rich content
+const (
+	NodeText        NodeType = "text"
+	NodeBold        NodeType = "bold"
+	NodeItalic      NodeType = "italic"
+	NodeCode        NodeType = "code"
+	NodeCodeBlock   NodeType = "codeblock"
+	NodeLink        NodeType = "link"
+	NodeList        NodeType = "list"
+	NodeOrderedList NodeType = "orderedlist"
+	NodeParagraph   NodeType = "paragraph"
+	NodeLineBreak   NodeType = "linebreak"
+
+	// nodeVerbatim contains the plain Content of a post which is not escaped.
+	nodeVerbatim NodeType = "verbatim"
+)
+
+// Node is one element of platform-neutral rich content. The platforms render it in their native
+// formatting or fall back to plain text if they do not support formatting.
+type Node struct {
+	Type NodeType
+
+	Text     string // Text is the text of text, code and code block nodes
+	URL      string // URL is the target of link nodes
+	Children []Node // Children are the content of bold, italic, link and paragraph nodes or the items of lists
+}
+
+// Text returns a node with unformatted text.
+func Text(text string) Node { return Node{Type: NodeText, Text: text} }
+
+// Bold returns a node which formats its children bold.
+func Bold(children ...Node) Node { return Node{Type: NodeBold, Children: children} }
+
+// Italic returns a node which formats its children italic.
+func Italic(children ...Node) Node { return Node{Type: NodeItalic, Children: children} }
+
+// Code returns a node with inline code.
+func Code(code string) Node { return Node{Type: NodeCode, Text: code} }
+
+// CodeBlock returns a node with a block of code.
+func CodeBlock(code string) Node { return Node{Type: NodeCodeBlock, Text: code} }
+
+// Link returns a node linking its children to the URL. Without children the URL is shown.
+func Link(url string, children ...Node) Node {
+	return Node{Type: NodeLink, URL: url, Children: children}
+}
+
+// List returns a node with a bulleted list. Every item is one entry.
+func List(items ...Node) Node { return Node{Type: NodeList, Children: items} }
+
+// OrderedList returns a node with a numbered list. Every item is one entry.
+func OrderedList(items ...Node) Node { return Node{Type: NodeOrderedList, Children: items} }
+
+// Paragraph returns a node which puts its children into their own paragraph.
+func Paragraph(children ...Node) Node { return Node{Type: NodeParagraph, Children: children} }
+
+// LineBreak returns a node which starts a new line.
+func LineBreak() Node { return Node{Type: NodeLineBreak} }
+
+// Attachment is a file attached to a post.
+type Attachment struct {
+	Name        string // Name is the file name shown to the users
+	URL         string // URL from where the file can be retrieved
+	ContentType string // [optional] ContentType is the MIME type of the file, e.g., image/png
+}
+
+// Nodes returns the attachment as rich content for platforms which cannot attach files.
+func (a Attachment) Nodes() []Node {
+	if len(a.Name) == 0 {
+		return []Node{Link(a.URL)}
+	}
+	return []Node{Link(a.URL, Text(a.Name))}
+}
+
+// EmbedField is a name/value pair shown in an Embed.
+type EmbedField struct {
+	Name  string
+	Value string
+	// Inline indicates that the field may be shown next to other inline fields.
+	Inline bool
+}
+
+// Button is shown below a post. Clicking it executes the command as the user who clicked
+// or, if an URL is given, opens the URL.
+type Button struct {
+	Label   string
+	Command string // [optional] Command is the command to execute without call prefix, e.g., "roll 6"
+	URL     string // [optional] URL is opened instead of executing a command
+}
+
+// Embed is a box with structured content shown together with a post, e.g., a Discord embed
+// or a Slack attachment.
+type Embed struct {
+	Title       string
+	Description string
+	URL         string // [optional] URL the title links to
+	Color       int    // [optional] Color of the embed as RGB value, e.g., 0xff0000 for red
+
+	Fields []EmbedField
+
+	ImageURL     string // [optional]
+	ThumbnailURL string // [optional]
+	Footer       string // [optional]
+}
+
+// Nodes returns the embed as rich content for platforms which do not support embeds.
+func (e Embed) Nodes() []Node {
+	var nodes []Node
+
+	if len(e.Title) > 0 {
+		title := Bold(Text(e.Title))
+		if len(e.URL) > 0 {
+			title = Link(e.URL, title)
+		}
+		nodes = append(nodes, Paragraph(title))
+	}
+	if len(e.Description) > 0 {
+		nodes = append(nodes, Paragraph(Text(e.Description)))
+	}
+	if len(e.Fields) > 0 {
+		var items []Node
+		for _, f := range e.Fields {
+			items = append(items, Paragraph(Bold(Text(f.Name+":")), Text(" "+f.Value)))
+		}
+		nodes = append(nodes, List(items...))
+	}
+	if len(e.ImageURL) > 0 {
+		nodes = append(nodes, Paragraph(Link(e.ImageURL)))
+	}
+	if len(e.Footer) > 0 {
+		nodes = append(nodes, Paragraph(Italic(Text(e.Footer))))
+	}
+
+	return nodes
+}
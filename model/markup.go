@@ -0,0 +1,181 @@
+package model
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Markup describes how rich content is rendered into the markup language of a platform.
+type Markup struct {
+	Escape func(text string) string // Escape is applied to all text nodes
+	// Verbatim is applied to the plain Content of posts, which may already contain markup of the platform.
+	Verbatim func(text string) string
+
+	Bold   [2]string // Bold is the opening and closing markup of bold text
+	Italic [2]string // Italic is the opening and closing markup of italic text
+
+	Code      func(code string) string
+	CodeBlock func(code string) string
+
+	// Link renders a link. The text is empty if the link node has no children.
+	Link func(url string, text string) string
+
+	List        [2]string // List is the opening and closing markup of a bulleted list
+	OrderedList [2]string // OrderedList is the opening and closing markup of a numbered list
+	// ListItem renders an item of a list, index starts with 1.
+	ListItem      func(index int, ordered bool, item string) string
+	ItemSeparator string
+
+	Paragraph      [2]string // Paragraph is the opening and closing markup of a paragraph
+	LineBreak      string
+	BlockSeparator string // BlockSeparator separates paragraphs, lists and code blocks from other content
+}
+
+// PlainText renders rich content without any formatting.
+var PlainText = Markup{
+	Escape:    func(text string) string { return text },
+	Verbatim:  func(text string) string { return text },
+	Code:      func(code string) string { return code },
+	CodeBlock: func(code string) string { return code },
+	Link: func(url string, text string) string {
+		if len(text) == 0 || text == url {
+			return url
+		}
+		return text + " (" + url + ")"
+	},
+	ListItem:       textListItem,
+	ItemSeparator:  "\n",
+	LineBreak:      "\n",
+	BlockSeparator: "\n",
+}
+
+// Markdown renders rich content as Markdown as understood by, e.g., Discord and Mattermost.
+var Markdown = Markup{
+	Escape:    escapeMarkdown,
+	Verbatim:  func(text string) string { return text },
+	Bold:      [2]string{"**", "**"},
+	Italic:    [2]string{"*", "*"},
+	Code:      func(code string) string { return "`" + code + "`" },
+	CodeBlock: func(code string) string { return "```\n" + code + "\n```" },
+	Link: func(url string, text string) string {
+		if len(text) == 0 {
+			return url
+		}
+		return "[" + text + "](" + url + ")"
+	},
+	ListItem:       textListItem,
+	ItemSeparator:  "\n",
+	LineBreak:      "\n",
+	BlockSeparator: "\n",
+}
+
+// HTML renders rich content as HTML, e.g., for the formatted body of Matrix messages.
+var HTML = Markup{
+	Escape:    html.EscapeString,
+	Verbatim:  func(text string) string { return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") },
+	Bold:      [2]string{"<strong>", "</strong>"},
+	Italic:    [2]string{"<em>", "</em>"},
+	Code:      func(code string) string { return "<code>" + html.EscapeString(code) + "</code>" },
+	CodeBlock: func(code string) string { return "<pre><code>" + html.EscapeString(code) + "</code></pre>" },
+	Link: func(url string, text string) string {
+		if len(text) == 0 {
+			text = html.EscapeString(url)
+		}
+		return `<a href="` + html.EscapeString(url) + `">` + text + "</a>"
+	},
+	List:        [2]string{"<ul>", "</ul>"},
+	OrderedList: [2]string{"<ol>", "</ol>"},
+	ListItem: func(index int, ordered bool, item string) string {
+		return "<li>" + item + "</li>"
+	},
+	Paragraph: [2]string{"<p>", "</p>"},
+	LineBreak: "<br>",
+}
+
+func textListItem(index int, ordered bool, item string) string {
+	if ordered {
+		return strconv.Itoa(index) + ". " + item
+	}
+	return "- " + item
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func isBlock(n Node) bool {
+	switch n.Type {
+	case NodeParagraph, NodeList, NodeOrderedList, NodeCodeBlock:
+		return true
+	}
+	return false
+}
+
+// Render renders the rich content in the markup.
+func (m Markup) Render(nodes []Node) string {
+	var sb strings.Builder
+	m.render(&sb, nodes)
+	return strings.TrimSpace(sb.String())
+}
+
+func (m Markup) render(sb *strings.Builder, nodes []Node) {
+	lastWasBlock := false
+	for _, n := range nodes {
+		block := isBlock(n)
+		if (block || lastWasBlock) && sb.Len() > 0 && len(m.BlockSeparator) > 0 && !strings.HasSuffix(sb.String(), m.BlockSeparator) {
+			sb.WriteString(m.BlockSeparator)
+		}
+		m.renderNode(sb, n)
+		lastWasBlock = block
+	}
+}
+
+func (m Markup) renderNode(sb *strings.Builder, n Node) {
+	switch n.Type {
+	case NodeText:
+		sb.WriteString(m.Escape(n.Text))
+	case nodeVerbatim:
+		sb.WriteString(m.Verbatim(n.Text))
+	case NodeBold:
+		sb.WriteString(m.Bold[0])
+		m.render(sb, n.Children)
+		sb.WriteString(m.Bold[1])
+	case NodeItalic:
+		sb.WriteString(m.Italic[0])
+		m.render(sb, n.Children)
+		sb.WriteString(m.Italic[1])
+	case NodeCode:
+		sb.WriteString(m.Code(n.Text))
+	case NodeCodeBlock:
+		sb.WriteString(m.CodeBlock(n.Text))
+	case NodeLink:
+		sb.WriteString(m.Link(n.URL, m.Render(n.Children)))
+	case NodeList, NodeOrderedList:
+		ordered := n.Type == NodeOrderedList
+		tags := m.List
+		if ordered {
+			tags = m.OrderedList
+		}
+		sb.WriteString(tags[0])
+		for i, item := range n.Children {
+			if i > 0 {
+				sb.WriteString(m.ItemSeparator)
+			}
+			content := []Node{item}
+			if item.Type == NodeParagraph {
+				content = item.Children
+			}
+			sb.WriteString(m.ListItem(i+1, ordered, m.Render(content)))
+		}
+		sb.WriteString(tags[1])
+	case NodeParagraph:
+		sb.WriteString(m.Paragraph[0])
+		m.render(sb, n.Children)
+		sb.WriteString(m.Paragraph[1])
+	case NodeLineBreak:
+		sb.WriteString(m.LineBreak)
+	}
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkup_Render(t *testing.T) {
+	nodes := []Node{
+		Text("Hello "),
+		Bold(Text("*World*")),
+		Text(", "),
+		Italic(Link("https://example.com", Text("link"))),
+		Paragraph(Text("Run "), Code("<cmd>")),
+		List(Text("one"), Paragraph(Text("two"))),
+		OrderedList(Text("first")),
+		CodeBlock("a < b"),
+	}
+
+	tests := []struct {
+		name   string
+		markup Markup
+		want   string
+	}{
+		{
+			name:   "PlainText",
+			markup: PlainText,
+			want:   "Hello *World*, link (https://example.com)\nRun <cmd>\n- one\n- two\n1. first\na < b",
+		},
+		{
+			name:   "Markdown",
+			markup: Markdown,
+			want:   "Hello **\\*World\\***, *[link](https://example.com)*\nRun `<cmd>`\n- one\n- two\n1. first\n```\na < b\n```",
+		},
+		{
+			name:   "HTML",
+			markup: HTML,
+			want:   `Hello <strong>*World*</strong>, <em><a href="https://example.com">link</a></em><p>Run <code>&lt;cmd&gt;</code></p><ul><li>one</li><li>two</li></ul><ol><li>first</li></ol><pre><code>a &lt; b</code></pre>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.markup.Render(nodes))
+		})
+	}
+}
+
+func TestPost_Render(t *testing.T) {
+	assert := assert.New(t)
+
+	post := Post{Content: "Some *text*"}
+	assert.False(post.IsRich())
+	assert.Equal("Some *text*", post.Render(Markdown, true, true))
+
+	post.Embeds = []Embed{{
+		Title:       "Title",
+		URL:         "https://example.com",
+		Description: "Description",
+		Fields:      []EmbedField{{Name: "Name", Value: "Value"}},
+		Footer:      "Footer",
+	}}
+	post.Attachments = []Attachment{{Name: "file.png", URL: "https://example.com/file.png"}}
+	assert.True(post.IsRich())
+	assert.Equal("Some *text*", post.Render(Markdown, false, false))
+	assert.Equal("<p>Some *text*</p><p><a href=\"https://example.com/file.png\">file.png</a></p>", post.Render(HTML, false, true))
+	assert.Equal("Some *text*\nTitle (https://example.com)\nDescription\n- Name: Value\nFooter\nfile.png (https://example.com/file.png)", post.Render(PlainText, true, true))
+
+	post.Rich = []Node{Bold(Text("Rich"))}
+	assert.Equal("**Rich**\n[file.png](https://example.com/file.png)", post.Render(Markdown, false, true))
+}
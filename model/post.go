@@ -10,11 +10,52 @@ type Post struct {
 
 	User User // User is the sender/receiver
 
-	Content string
+	Content string // Content is the plain text of the post, it is used when no Rich content is given
+
+	Rich        []Node       // [optional] Rich is formatted content which is used instead of Content
+	Attachments []Attachment // [optional] Attachments are files attached to the post
+	Embeds      []Embed      // [optional] Embeds are shown together with the post
+	Buttons     []Button     // [optional] Buttons are shown below the post, if the platform supports it
+
+	ReplyTo  string // [optional] ReplyTo is the ID of the message in the same channel the post replies to
+	ThreadID string // [optional] ThreadID is the ID of the thread the post belongs to
 
 	IsPrivate bool // IsPrivate indicates it is a whisper or similar (depending on the Bot)
 }
 
+// IsRich returns true if the post contains more than plain text content.
+func (p Post) IsRich() bool {
+	return len(p.Rich) > 0 || len(p.Attachments) > 0 || len(p.Embeds) > 0
+}
+
+// Render returns the content of the post in the markup. Without rich content the plain Content
+// is returned unchanged. Embeds and attachments are appended to the content if requested,
+// for platforms which cannot show them natively.
+func (p Post) Render(m Markup, withEmbeds bool, withAttachments bool) string {
+	var nodes []Node
+	if len(p.Rich) > 0 {
+		nodes = append(nodes, p.Rich...)
+	} else if len(p.Content) > 0 {
+		nodes = append(nodes, Paragraph(Node{Type: nodeVerbatim, Text: p.Content}))
+	}
+	contentNodes := len(nodes)
+	if withEmbeds {
+		for _, e := range p.Embeds {
+			nodes = append(nodes, e.Nodes()...)
+		}
+	}
+	if withAttachments {
+		for _, a := range p.Attachments {
+			nodes = append(nodes, Paragraph(a.Nodes()...))
+		}
+	}
+
+	if len(p.Rich) == 0 && len(nodes) == contentNodes {
+		return p.Content
+	}
+	return m.Render(nodes)
+}
+
 // MessageIdentifier is a unique identifier for a message on a platform
 type MessageIdentifier struct {
 	ID      string
@@ -20,6 +20,9 @@ type User struct {
 
 	// IsMod is a Redseligg property indicating certain rights of that user inside of Redseligg and its plugins
 	IsMod bool `json:"is_mod"`
+
+	// Extras are platform specific properties of the user, e.g., the badges on Twitch
+	Extras map[string]string `json:"extras,omitempty"`
 }
 
 // IsValid indicates if a User object is valid
@@ -0,0 +1,199 @@
+package permissions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+)
+
+const roleCommand = "role"
+
+type replier interface {
+	Reply(post model.Post, msg string)
+}
+
+type commandResolver interface {
+	// CommandName returns the name the command called with the given name or alias is available under.
+	CommandName(called string) (string, bool)
+}
+
+var commandSpec = commanddispatcher.CommandSpec{
+	Command:     roleCommand,
+	Description: "Manage the roles of users and the roles needed to execute commands (admins only)",
+	Examples:    []string{"role grant @someone mod", "role restrict quote mod --here"},
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command: "grant",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "user", Type: commanddispatcher.ArgUser},
+				{Name: "role", Type: commanddispatcher.ArgString},
+			},
+			Flags: []commanddispatcher.FlagSpec{{Name: "here", Bool: true}},
+		},
+		{
+			Command: "revoke",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "user", Type: commanddispatcher.ArgUser},
+				{Name: "role", Type: commanddispatcher.ArgString},
+			},
+			Flags: []commanddispatcher.FlagSpec{{Name: "here", Bool: true}},
+		},
+		{
+			Command: "list",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "user", Type: commanddispatcher.ArgUser, Optional: true},
+			},
+		},
+		{
+			Command: "restrict",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "command", Type: commanddispatcher.ArgString},
+				{Name: "role", Type: commanddispatcher.ArgString},
+			},
+			Flags: []commanddispatcher.FlagSpec{{Name: "here", Bool: true}},
+		},
+		{
+			Command: "unrestrict",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "command", Type: commanddispatcher.ArgString},
+			},
+			Flags: []commanddispatcher.FlagSpec{{Name: "here", Bool: true}},
+		},
+	},
+}
+
+// RegisterCommands registers the admin commands to manage the permissions at the
+// dispatcher and makes the Manager the authorizer of the dispatcher.
+func (m *Manager) RegisterCommands(d *commanddispatcher.CommandDispatcher) {
+	m.replier = d
+	m.commands = d
+	d.SetAuthorizer(m)
+	d.RegisterSpec(commandSpec, m)
+}
+
+func (m *Manager) reply(post model.Post, msg string) {
+	if m.replier == nil {
+		log.Debugf("No replier set, not sending reply '%s'", msg)
+		return
+	}
+	m.replier.Reply(post, msg)
+}
+
+// commandName returns the name the command is available under, so that a rule for an alias
+// applies to the command and renamed instances of a plugin can be restricted separately.
+func (m *Manager) commandName(cmd string) (string, bool) {
+	if m.commands == nil {
+		return cmd, true
+	}
+	return m.commands.CommandName(cmd)
+}
+
+func userString(user model.User) string {
+	if len(user.Name) > 0 {
+		return user.Name
+	}
+	return user.ID
+}
+
+func channelString(channelID string) string {
+	if len(channelID) > 0 {
+		return "in channel " + channelID
+	}
+	return "in all channels"
+}
+
+// OnCommand is only needed to satisfy the dispatcher, all commands are handled by OnParsedCommand.
+func (m *Manager) OnCommand(cmd string, content string, post model.Post) {}
+
+// OnParsedCommand handles the admin commands to manage the permissions.
+func (m *Manager) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
+	channelID := ""
+	if args.Bool("here") {
+		channelID = post.ChannelID
+	}
+
+	switch args.Subcommand {
+	case "grant":
+		user := args.User("user")
+		if err := m.Grant(user, args.String("role"), channelID); err != nil {
+			log.Errorf("Error storing permissions: %s", err)
+			m.reply(post, "Could not store the permissions, the role is only granted until the bot restarts.")
+			return
+		}
+		m.reply(post, fmt.Sprintf("Granted role %s to %s %s.", args.String("role"), userString(user), channelString(channelID)))
+	case "revoke":
+		user := args.User("user")
+		found, err := m.Revoke(user, args.String("role"), channelID)
+		if err != nil {
+			log.Errorf("Error storing permissions: %s", err)
+		}
+		if !found {
+			m.reply(post, fmt.Sprintf("%s was not granted role %s %s.", userString(user), args.String("role"), channelString(channelID)))
+			return
+		}
+		m.reply(post, fmt.Sprintf("Revoked role %s from %s %s.", args.String("role"), userString(user), channelString(channelID)))
+	case "list":
+		m.reply(post, m.list(args))
+	case "restrict":
+		command, ok := m.commandName(args.String("command"))
+		if !ok {
+			m.reply(post, fmt.Sprintf("Unknown command %s.", args.String("command")))
+			return
+		}
+		if err := m.Restrict(command, args.String("role"), channelID); err != nil {
+			log.Errorf("Error storing permissions: %s", err)
+			m.reply(post, "Could not store the permissions, the restriction is only active until the bot restarts.")
+			return
+		}
+		m.reply(post, fmt.Sprintf("Command %s now needs role %s %s.", command, args.String("role"), channelString(channelID)))
+	case "unrestrict":
+		// Rules of commands which are no longer available can still be removed
+		command, ok := m.commandName(args.String("command"))
+		if !ok {
+			command = args.String("command")
+		}
+		found, err := m.Unrestrict(command, channelID)
+		if err != nil {
+			log.Errorf("Error storing permissions: %s", err)
+		}
+		if !found {
+			m.reply(post, fmt.Sprintf("There is no restriction for command %s %s.", command, channelString(channelID)))
+			return
+		}
+		m.reply(post, fmt.Sprintf("Removed the restriction for command %s %s.", command, channelString(channelID)))
+	}
+}
+
+func (m *Manager) list(args commanddispatcher.Arguments) string {
+	var lines []string
+
+	for _, g := range m.Grants() {
+		if args.Has("user") && !grantMatchesUser(g, args.User("user")) {
+			continue
+		}
+		name := g.UserName
+		if len(name) == 0 {
+			name = g.UserID
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s %s", name, g.Role, channelString(g.ChannelID)))
+	}
+	sort.Strings(lines)
+
+	if !args.Has("user") {
+		var rules []string
+		for _, r := range m.CommandRules() {
+			rules = append(rules, fmt.Sprintf("%s: needs %s %s", r.Command, r.Role, channelString(r.ChannelID)))
+		}
+		sort.Strings(rules)
+		lines = append(lines, rules...)
+	}
+
+	if len(lines) == 0 {
+		return "No roles granted and no commands restricted."
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,293 @@
+// Package permissions provides role-based permissions for the commands of a bot.
+package permissions
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/storagemodels"
+)
+
+var log = logging.Get("Permissions")
+
+// Built-in roles
+const (
+	// RoleAdmin has every role and is allowed to manage the permissions.
+	RoleAdmin = "admin"
+	// RoleMod is the role needed for mod-only plugin commands.
+	RoleMod = "mod"
+)
+
+type reader interface {
+	GetPermissions(botID string) (storagemodels.Permissions, error)
+}
+
+type writer interface {
+	StorePermissions(botID string, data storagemodels.Permissions) error
+}
+
+// Manager holds the roles granted to users and the roles needed to execute commands.
+// The permissions set in the bot config are always active, permissions changed at runtime
+// are persisted in the storage of the bot.
+type Manager struct {
+	botID   string
+	cfg     botconfig.PermissionsConfig
+	storage storage.Storage
+
+	mutex sync.RWMutex
+	data  storagemodels.Permissions
+
+	replier  replier
+	commands commandResolver
+}
+
+// New creates a new permissions Manager and loads the stored permissions of the bot.
+func New(botID string, cfg botconfig.PermissionsConfig, s storage.Storage) *Manager {
+	m := &Manager{
+		botID:   botID,
+		cfg:     cfg,
+		storage: s,
+	}
+
+	if r, ok := s.(reader); ok {
+		data, err := r.GetPermissions(botID)
+		if err != nil && err != storage.ErrNotFound {
+			log.Errorf("Error loading permissions for bot %s: %s", botID, err)
+		} else if err == nil {
+			m.data = data
+		}
+	} else {
+		log.Warnf("Storage does not support permissions, changes to the permissions of bot %s will not be persisted", botID)
+	}
+
+	return m
+}
+
+func (m *Manager) store() error {
+	w, ok := m.storage.(writer)
+	if !ok {
+		return nil
+	}
+	return w.StorePermissions(m.botID, m.data)
+}
+
+func matchesUser(user model.User, ids []string) bool {
+	for _, id := range ids {
+		if (len(user.ID) > 0 && id == user.ID) || (len(user.Name) > 0 && id == user.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func grantMatchesUser(g storagemodels.PermissionsGrant, user model.User) bool {
+	return (len(g.UserID) > 0 && g.UserID == user.ID) || (len(g.UserName) > 0 && g.UserName == user.Name)
+}
+
+// HasRole returns true if the user has the role in the given channel.
+// Owners of the platform, users with the admin role and, if enabled in the config,
+// admins of the platform have every role.
+func (m *Manager) HasRole(user model.User, role string, channelID string) bool {
+	if user.IsOwner || (m.cfg.PlatformAdmins && user.IsAdmin) || matchesUser(user, m.cfg.Admins) {
+		return true
+	}
+	if role == RoleMod && matchesUser(user, m.cfg.Mods) {
+		return true
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, g := range m.data.Grants {
+		if !grantMatchesUser(g, user) {
+			continue
+		}
+		if len(g.ChannelID) > 0 && g.ChannelID != channelID {
+			continue
+		}
+		if g.Role == role || g.Role == RoleAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsMod returns true if the user has the mod role in the given channel.
+func (m *Manager) IsMod(user model.User, channelID string) bool {
+	return m.HasRole(user, RoleMod, channelID)
+}
+
+// RequiredRole returns the role needed to execute the command in the given channel. The command is
+// the name the command is available under, i.e., the new name of a renamed command.
+// A rule for the channel takes precedence over a rule for all channels and the rules
+// set at runtime take precedence over the bot config. An empty role means everyone is allowed.
+func (m *Manager) RequiredRole(cmd string, channelID string) string {
+	if cmd == roleCommand {
+		return RoleAdmin
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	global := ""
+	hasGlobal := false
+	for _, r := range m.data.Commands {
+		if r.Command != cmd {
+			continue
+		}
+		if len(r.ChannelID) > 0 && r.ChannelID == channelID {
+			return r.Role
+		}
+		if len(r.ChannelID) == 0 {
+			global = r.Role
+			hasGlobal = true
+		}
+	}
+	if hasGlobal {
+		return global
+	}
+
+	return m.cfg.Commands[cmd]
+}
+
+// IsAllowed returns true if the author of the post is allowed to execute the command.
+// Users the platform marks as mods of the channel of the post, e.g., the broadcaster and
+// the moderators on Twitch, have the mod role in that channel.
+func (m *Manager) IsAllowed(cmd string, post model.Post) bool {
+	role := m.RequiredRole(cmd, post.ChannelID)
+	if len(role) == 0 {
+		return true
+	}
+	if role == RoleMod && post.User.IsMod {
+		return true
+	}
+	return m.HasRole(post.User, role, post.ChannelID)
+}
+
+// Grant grants the role to the user. An empty channelID grants the role in all channels.
+func (m *Manager) Grant(user model.User, role string, channelID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, g := range m.data.Grants {
+		if g.UserID == user.ID && g.UserName == user.Name && g.Role == role && g.ChannelID == channelID {
+			return nil
+		}
+	}
+	m.data.Grants = append(m.data.Grants, storagemodels.PermissionsGrant{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Role:      role,
+		ChannelID: channelID,
+	})
+
+	return m.store()
+}
+
+// Revoke revokes the role granted to the user. It returns false if the user was not granted the role.
+func (m *Manager) Revoke(user model.User, role string, channelID string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	found := false
+	grants := []storagemodels.PermissionsGrant{}
+	for _, g := range m.data.Grants {
+		if grantMatchesUser(g, user) && g.Role == role && g.ChannelID == channelID {
+			found = true
+			continue
+		}
+		grants = append(grants, g)
+	}
+	if !found {
+		return false, nil
+	}
+	m.data.Grants = grants
+
+	return true, m.store()
+}
+
+// Restrict restricts the command to users with the role. An empty channelID restricts the command in all channels.
+func (m *Manager) Restrict(cmd string, role string, channelID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, r := range m.data.Commands {
+		if r.Command == cmd && r.ChannelID == channelID {
+			m.data.Commands[i].Role = role
+			return m.store()
+		}
+	}
+	m.data.Commands = append(m.data.Commands, storagemodels.PermissionsCommandRule{
+		Command:   cmd,
+		Role:      role,
+		ChannelID: channelID,
+	})
+
+	return m.store()
+}
+
+// Unrestrict removes the rule for the command set at runtime. It returns false if there was no such rule.
+func (m *Manager) Unrestrict(cmd string, channelID string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, r := range m.data.Commands {
+		if r.Command == cmd && r.ChannelID == channelID {
+			m.data.Commands = append(m.data.Commands[:i], m.data.Commands[i+1:]...)
+			return true, m.store()
+		}
+	}
+
+	return false, nil
+}
+
+// Grants returns all roles granted at runtime.
+func (m *Manager) Grants() []storagemodels.PermissionsGrant {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]storagemodels.PermissionsGrant{}, m.data.Grants...)
+}
+
+// CommandRules returns all command rules set at runtime.
+func (m *Manager) CommandRules() []storagemodels.PermissionsCommandRule {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]storagemodels.PermissionsCommandRule{}, m.data.Commands...)
+}
+
+// LegacyPluginMods returns the users of the deprecated plugin option mods. Before roles existed,
+// plugins with onlymods = true only allowed these users for the commands of that plugin. Now they
+// get the mod role for the whole bot, which widens their permissions: they can use the mod-only
+// commands of every plugin in every channel. A warning is logged for every plugin with mods.
+func LegacyPluginMods(plugins botconfig.PluginConfigs) []string {
+	var mods []string
+	for id, p := range plugins {
+		var pluginMods []string
+		switch entries := p.Config["mods"].(type) {
+		case []interface{}:
+			for _, entry := range entries {
+				if mod, ok := entry.(string); ok {
+					pluginMods = append(pluginMods, mod)
+				}
+			}
+		case []string:
+			pluginMods = entries
+		}
+		if len(pluginMods) == 0 {
+			continue
+		}
+
+		log.Warnf("The mods option of plugin %s is deprecated, use mods in the permissions of the bot instead. %s get the mod role for the whole bot", id, strings.Join(pluginMods, ", "))
+		mods = append(mods, pluginMods...)
+	}
+	sort.Strings(mods)
+	return mods
+}
@@ -0,0 +1,253 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/storage/memorystorage"
+	"github.com/torlenor/redseligg/storagemodels"
+)
+
+type mockReceiver struct {
+	lastReceivedPost model.Post
+	calls            int
+}
+
+func (m *mockReceiver) OnCommand(cmd string, content string, post model.Post) {
+	m.lastReceivedPost = post
+	m.calls++
+}
+
+type mockPoster struct {
+	lastPost model.Post
+}
+
+func (m *mockPoster) CreatePost(post model.Post) (model.PostResponse, error) {
+	m.lastPost = post
+	return model.PostResponse{}, nil
+}
+
+func TestManager_HasRole(t *testing.T) {
+	m := New("BOT", botconfig.PermissionsConfig{
+		Admins: []string{"admin"},
+		Mods:   []string{"MOD_ID"},
+	}, nil)
+	m.Grant(model.User{Name: "channelmod"}, RoleMod, "CHANNEL")
+	m.Grant(model.User{ID: "VIP_ID"}, "vip", "")
+
+	tests := []struct {
+		name      string
+		user      model.User
+		role      string
+		channelID string
+		want      bool
+	}{
+		{name: "Nobody", user: model.User{Name: "user"}, role: RoleMod, want: false},
+		{name: "Config admin", user: model.User{Name: "admin"}, role: RoleMod, want: true},
+		{name: "Platform owner", user: model.User{Name: "user", IsOwner: true}, role: "vip", want: true},
+		{name: "Platform admin", user: model.User{Name: "user", IsAdmin: true}, role: RoleMod, want: false},
+		{name: "Config mod by ID", user: model.User{ID: "MOD_ID", Name: "mod"}, role: RoleMod, want: true},
+		{name: "Config mod is no admin", user: model.User{ID: "MOD_ID"}, role: RoleAdmin, want: false},
+		{name: "Channel grant in channel", user: model.User{Name: "channelmod"}, role: RoleMod, channelID: "CHANNEL", want: true},
+		{name: "Channel grant in other channel", user: model.User{Name: "channelmod"}, role: RoleMod, channelID: "OTHER", want: false},
+		{name: "Custom role", user: model.User{ID: "VIP_ID"}, role: "vip", channelID: "OTHER", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, m.HasRole(tt.user, tt.role, tt.channelID))
+		})
+	}
+}
+
+func TestManager_PlatformAdmins(t *testing.T) {
+	m := New("BOT", botconfig.PermissionsConfig{PlatformAdmins: true}, nil)
+	assert.True(t, m.HasRole(model.User{Name: "user", IsAdmin: true}, RoleAdmin, ""))
+	assert.False(t, m.HasRole(model.User{Name: "user"}, RoleAdmin, ""))
+}
+
+func TestLegacyPluginMods(t *testing.T) {
+	plugins := botconfig.PluginConfigs{
+		"vote":     {Type: "vote", Config: map[string]interface{}{"mods": []interface{}{"user1", "U1234"}, "onlymods": true}},
+		"giveaway": {Type: "giveaway", Config: map[string]interface{}{"mods": []string{"user2"}}},
+		"roll":     {Type: "roll"},
+	}
+	assert.Equal(t, []string{"U1234", "user1", "user2"}, LegacyPluginMods(plugins))
+
+	m := New("BOT", botconfig.PermissionsConfig{Mods: LegacyPluginMods(plugins)}, nil)
+	assert.True(t, m.IsMod(model.User{ID: "U1234"}, "CHANNEL"))
+}
+
+func TestManager_IsAllowed(t *testing.T) {
+	assert := assert.New(t)
+
+	m := New("BOT", botconfig.PermissionsConfig{
+		Mods:     []string{"mod"},
+		Commands: map[string]string{"quote": RoleMod},
+	}, nil)
+
+	user := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "user"}}
+	mod := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "mod"}}
+
+	assert.True(m.IsAllowed("echo", user))
+	assert.False(m.IsAllowed("quote", user))
+	assert.True(m.IsAllowed("quote", mod))
+	assert.False(m.IsAllowed(roleCommand, mod))
+
+	platformMod := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "broadcaster", IsMod: true}}
+	assert.True(m.IsAllowed("quote", platformMod))
+	assert.False(m.IsAllowed(roleCommand, platformMod))
+
+	assert.NoError(m.Restrict("quote", "", "CHANNEL"))
+	assert.True(m.IsAllowed("quote", user))
+	user.ChannelID = "OTHER"
+	assert.False(m.IsAllowed("quote", user))
+
+	assert.NoError(m.Restrict("echo", RoleMod, ""))
+	assert.False(m.IsAllowed("echo", user))
+
+	found, err := m.Unrestrict("echo", "")
+	assert.NoError(err)
+	assert.True(found)
+	assert.True(m.IsAllowed("echo", user))
+}
+
+func TestManager_Persistence(t *testing.T) {
+	assert := assert.New(t)
+
+	s := memorystorage.New()
+
+	m := New("BOT", botconfig.PermissionsConfig{}, s)
+	assert.NoError(m.Grant(model.User{Name: "user"}, RoleMod, ""))
+	assert.NoError(m.Restrict("quote", RoleMod, ""))
+
+	expected := storagemodels.Permissions{
+		Grants:   []storagemodels.PermissionsGrant{{UserName: "user", Role: RoleMod}},
+		Commands: []storagemodels.PermissionsCommandRule{{Command: "quote", Role: RoleMod}},
+	}
+	actual, err := s.GetPermissions("BOT")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	m = New("BOT", botconfig.PermissionsConfig{}, s)
+	assert.True(m.IsMod(model.User{Name: "user"}, "CHANNEL"))
+
+	found, err := m.Revoke(model.User{Name: "user"}, RoleMod, "")
+	assert.NoError(err)
+	assert.True(found)
+	assert.False(m.IsMod(model.User{Name: "user"}, "CHANNEL"))
+
+	found, err = m.Revoke(model.User{Name: "user"}, RoleMod, "")
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func TestManager_Commands(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := commanddispatcher.New("!")
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	m := New("BOT", botconfig.PermissionsConfig{Admins: []string{"admin"}}, nil)
+	m.RegisterCommands(dispatcher)
+
+	receiver := &mockReceiver{}
+	dispatcher.Register("quote", receiver, commanddispatcher.CommandHelp{})
+
+	admin := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "admin"}}
+	user := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "user"}}
+
+	user.Content = "!role grant @user mod"
+	dispatcher.OnPost(user)
+	assert.Equal(model.Post{}, poster.lastPost)
+
+	admin.Content = "!role restrict quote mod"
+	dispatcher.OnPost(admin)
+	assert.Equal("Command quote now needs role mod in all channels.", poster.lastPost.Content)
+
+	user.Content = "!quote"
+	dispatcher.OnPost(user)
+	assert.Equal(0, receiver.calls)
+
+	admin.Content = "!role grant @user mod --here"
+	dispatcher.OnPost(admin)
+	assert.Equal("Granted role mod to user in channel CHANNEL.", poster.lastPost.Content)
+
+	dispatcher.OnPost(user)
+	assert.Equal(1, receiver.calls)
+	assert.True(receiver.lastReceivedPost.User.IsMod)
+
+	admin.Content = "!role list"
+	dispatcher.OnPost(admin)
+	assert.Equal("user: mod in channel CHANNEL\nquote: needs mod in all channels", poster.lastPost.Content)
+
+	admin.Content = "!role revoke @user mod"
+	dispatcher.OnPost(admin)
+	assert.Equal("user was not granted role mod in all channels.", poster.lastPost.Content)
+
+	admin.Content = "!role revoke @user mod --here"
+	dispatcher.OnPost(admin)
+	assert.Equal("Revoked role mod from user in channel CHANNEL.", poster.lastPost.Content)
+
+	dispatcher.OnPost(user)
+	assert.Equal(1, receiver.calls)
+}
+
+type mockNamedReceiver struct {
+	mockReceiver
+	names commanddispatcher.CommandNames
+}
+
+func (m *mockNamedReceiver) CommandNames() commanddispatcher.CommandNames {
+	return m.names
+}
+
+func TestManager_RestrictRenamedCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := commanddispatcher.New("!")
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	m := New("BOT", botconfig.PermissionsConfig{Admins: []string{"admin"}}, nil)
+	m.RegisterCommands(dispatcher)
+
+	quotes := &mockNamedReceiver{}
+	cites := &mockNamedReceiver{names: commanddispatcher.CommandNames{
+		Rename:  map[string]string{"quote": "cite"},
+		Aliases: map[string][]string{"quote": {"c"}},
+	}}
+	dispatcher.Register("quote", quotes, commanddispatcher.CommandHelp{})
+	dispatcher.Register("quote", cites, commanddispatcher.CommandHelp{})
+
+	admin := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "admin"}}
+	user := model.Post{ChannelID: "CHANNEL", User: model.User{Name: "user"}}
+
+	admin.Content = "!role restrict c mod"
+	dispatcher.OnPost(admin)
+	assert.Equal("Command cite now needs role mod in all channels.", poster.lastPost.Content)
+
+	admin.Content = "!role restrict unknown mod"
+	dispatcher.OnPost(admin)
+	assert.Equal("Unknown command unknown.", poster.lastPost.Content)
+	assert.Equal([]storagemodels.PermissionsCommandRule{{Command: "cite", Role: RoleMod}}, m.CommandRules())
+
+	for _, content := range []string{"!quote", "!cite", "!c"} {
+		user.Content = content
+		dispatcher.OnPost(user)
+	}
+	assert.Equal(1, quotes.calls)
+	assert.Equal(0, cites.calls)
+
+	admin.Content = "!role unrestrict c"
+	dispatcher.OnPost(admin)
+	assert.Equal("Removed the restriction for command cite in all channels.", poster.lastPost.Content)
+
+	user.Content = "!cite"
+	dispatcher.OnPost(user)
+	assert.Equal(1, cites.calls)
+}
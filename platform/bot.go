@@ -2,18 +2,39 @@ package platform
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/eventqueue"
+	"github.com/torlenor/redseligg/logging"
 	"github.com/torlenor/redseligg/plugin"
 	"github.com/torlenor/redseligg/storage"
 )
 
+var log = logging.Get("Platform")
+
 // All currently supported features a platform can support
 const (
 	FeatureMessagePost    string = "FEATURE_MESSAGE_POST"
 	FeatureMessageUpdate  string = "FEATURE_MESSAGE_UPDATE"
 	FeatureMessageDelete  string = "FEATURE_MESSAGE_DELETE"
 	FeatureReactionNotify string = "FEATURE_REACTION_NOTIFY"
+
+	// Features for rich posts. Platforms without them fall back to plain text.
+	FeatureMessageFormatting  string = "FEATURE_MESSAGE_FORMATTING"
+	FeatureMessageAttachments string = "FEATURE_MESSAGE_ATTACHMENTS"
+	FeatureMessageEmbeds      string = "FEATURE_MESSAGE_EMBEDS"
+	FeatureMessageReplies     string = "FEATURE_MESSAGE_REPLIES"
+	FeatureMessageThreads     string = "FEATURE_MESSAGE_THREADS"
+
+	// FeatureMessageButtons shows the Buttons of a post. Platforms without it ignore them.
+	FeatureMessageButtons string = "FEATURE_MESSAGE_BUTTONS"
+
+	// Features for the moderation functions of the plugin API.
+	FeatureModerationTimeout   string = "FEATURE_MODERATION_TIMEOUT"
+	FeatureModerationBan       string = "FEATURE_MODERATION_BAN"
+	FeatureModerationClearChat string = "FEATURE_MODERATION_CLEAR_CHAT"
+	FeatureModerationSlowMode  string = "FEATURE_MODERATION_SLOW_MODE"
 )
 
 // Bot type interface which every Bot has to implement
@@ -26,12 +47,20 @@ type Bot interface {
 	GetInfo() BotInfo
 }
 
+// EventReceiver is implemented by bots which receive the events of their platform via HTTP,
+// e.g., the Slack Events API. The control API forwards the requests to /bots/{botId}/events.
+type EventReceiver interface {
+	HandleEventRequest(w http.ResponseWriter, r *http.Request)
+}
+
 // BotPlugin is needed to connect a Plugin to a Bot
 type BotPlugin interface {
 	plugin.Hooks
 
 	SetBotPluginID(botID string, pluginID string)
 
+	SetCommandNames(names commanddispatcher.CommandNames)
+
 	SetAPI(api plugin.API) error
 }
 
@@ -39,6 +68,11 @@ type BotPlugin interface {
 type PluginInfo struct {
 	Plugin string `json:"plugin"`
 	Active bool   `json:"active"`
+	Error  string `json:"error,omitempty"`
+
+	QueueDepth    int    `json:"queueDepth"`
+	QueueCapacity int    `json:"queueCapacity"`
+	QueueDropped  uint64 `json:"queueDropped"`
 }
 
 // BotInfo contains info about one bot
@@ -56,6 +90,58 @@ type BotImpl struct {
 
 	Dispatcher *commanddispatcher.CommandDispatcher
 	Storage    storage.Storage
+
+	// Queues are the event queues of the plugins. If nil, events are delivered synchronously.
+	Queues *eventqueue.Queues
+}
+
+// SetEventQueues sets the event queues of the plugins and lets the dispatcher use them for commands.
+func (b *BotImpl) SetEventQueues(q *eventqueue.Queues) {
+	b.Queues = q
+	if q == nil {
+		b.Dispatcher.SetQueues(nil)
+	} else {
+		b.Dispatcher.SetQueues(q)
+	}
+}
+
+// Enqueue adds the event for the plugin to its queue or handles it directly if there are no queues.
+// In both cases the event is handled like a command by the Dispatcher: a panic or a timeout marks
+// the plugin as failed and a failed plugin does not get the event.
+func (b *BotImpl) Enqueue(p plugin.Hooks, f func()) {
+	hook := func() { b.Dispatcher.Run(p, "event", f) }
+	if b.Queues == nil {
+		eventqueue.Run(p, hook)
+		return
+	}
+	b.Queues.Enqueue(p, hook)
+}
+
+// StartEventQueues allows to add events to the event queues again after they were stopped.
+// Call it when the bot starts, so that a restarted bot still delivers events.
+func (b *BotImpl) StartEventQueues() {
+	if b.Queues != nil {
+		b.Queues.Start()
+	}
+}
+
+// StopEventQueues handles all events which are already queued and stops the event queues.
+func (b *BotImpl) StopEventQueues() {
+	if b.Queues != nil {
+		b.Queues.Stop()
+	}
+}
+
+// RunPlugins calls OnRun of all plugins. Afterwards all plugins have registered their commands,
+// so every command registered by more than one plugin is reported in the log.
+func (b *BotImpl) RunPlugins(plugins []plugin.Hooks) {
+	for _, p := range plugins {
+		p.OnRun()
+	}
+
+	for _, conflict := range b.Dispatcher.Conflicts() {
+		log.Warnf("%s, all of them receive the command", conflict)
+	}
 }
 
 // HasFeature returns true if the bot serving the API implements the feature.
@@ -67,17 +153,52 @@ func (b *BotImpl) HasFeature(feature string) bool {
 func (b *BotImpl) GetStorage() storage.Storage { return b.Storage }
 
 // RegisterCommand registers a custom slash or ! command, depending on what the bot supports.
-func (b *BotImpl) RegisterCommand(p plugin.Hooks, command string) error {
-	b.Dispatcher.Register(command, p)
+func (b *BotImpl) RegisterCommand(p plugin.Hooks, command string, help commanddispatcher.CommandHelp) error {
+	return b.Dispatcher.Register(command, p, help)
+}
+
+// RegisterCommandSpec registers a command with a declarative description of its arguments.
+func (b *BotImpl) RegisterCommandSpec(p plugin.Hooks, spec commanddispatcher.CommandSpec) error {
+	return b.Dispatcher.RegisterSpec(spec, p)
+}
+
+// UnRegisterCommand unregisters a command previously registered by the plugin via RegisterCommand.
+func (b *BotImpl) UnRegisterCommand(p plugin.Hooks, command string) error {
+	b.Dispatcher.UnregisterReceiver(command, p)
 	return nil
 }
 
-// UnRegisterCommand unregisters a command previously registered via RegisterCommand.
-func (b *BotImpl) UnRegisterCommand(command string) error {
-	b.Dispatcher.Unregister(command)
+// RegisterTrigger registers a trigger on mentions of the bot, regular expressions or keywords.
+func (b *BotImpl) RegisterTrigger(p plugin.Hooks, trigger commanddispatcher.Trigger) error {
+	return b.Dispatcher.RegisterTrigger(trigger, p)
+}
+
+// UnRegisterTrigger unregisters a trigger previously registered by the plugin via RegisterTrigger.
+func (b *BotImpl) UnRegisterTrigger(p plugin.Hooks, name string) error {
+	b.Dispatcher.UnregisterTrigger(name, p)
 	return nil
 }
 
+// PluginInfos returns the info about the plugins. Plugins which failed while handling a command or an event are not active.
+func (b *BotImpl) PluginInfos(plugins []plugin.Hooks) []PluginInfo {
+	infos := []PluginInfo{}
+	for _, p := range plugins {
+		info := PluginInfo{Plugin: p.PluginType(), Active: true}
+		if err := b.Dispatcher.Failure(p); err != nil {
+			info.Active = false
+			info.Error = err.Error()
+		}
+		if b.Queues != nil {
+			stats := b.Queues.Stats(p)
+			info.QueueDepth = stats.Depth
+			info.QueueCapacity = stats.Capacity
+			info.QueueDropped = stats.Dropped
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 // GetCallPrefix returns the current command call prefix.
 func (b *BotImpl) GetCallPrefix() string {
 	return b.Dispatcher.GetCallPrefix()
@@ -0,0 +1,89 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/eventqueue"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/plugin"
+)
+
+type panickingPlugin struct {
+	plugin.RedseliggPlugin
+	posts int
+}
+
+func (p *panickingPlugin) OnPost(post model.Post) {
+	p.posts++
+	if post.Content == "panic" {
+		panic("something went wrong")
+	}
+}
+
+func TestBotImpl_EnqueuePanic(t *testing.T) {
+	tests := []struct {
+		name   string
+		queues *eventqueue.Queues
+	}{
+		{name: "Synchronous"},
+		{name: "Queued", queues: eventqueue.New(10, eventqueue.PolicyDropOldest)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			b := BotImpl{Dispatcher: commanddispatcher.New("!")}
+			b.SetEventQueues(tt.queues)
+
+			p := &panickingPlugin{RedseliggPlugin: plugin.RedseliggPlugin{Type: "panicking"}}
+			for _, content := range []string{"hi", "panic", "hi"} {
+				post := model.Post{Content: content}
+				b.Enqueue(p, func() { p.OnPost(post) })
+			}
+			b.StopEventQueues()
+
+			assert.Equal(2, p.posts)
+			info := b.PluginInfos([]plugin.Hooks{p})[0]
+			assert.False(info.Active)
+			assert.Equal("Panic while handling event: something went wrong", info.Error)
+		})
+	}
+}
+
+type quotesPlugin struct {
+	plugin.RedseliggPlugin
+	dispatcher *commanddispatcher.CommandDispatcher
+	runs       int
+}
+
+func (p *quotesPlugin) OnRun() {
+	p.runs++
+	p.dispatcher.Register("quote", p, commanddispatcher.CommandHelp{})
+}
+
+func TestBotImpl_RunPlugins(t *testing.T) {
+	assert := assert.New(t)
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	b := BotImpl{Dispatcher: commanddispatcher.New("!")}
+	first := &quotesPlugin{RedseliggPlugin: plugin.RedseliggPlugin{Type: "quotes"}, dispatcher: b.Dispatcher}
+	second := &quotesPlugin{RedseliggPlugin: plugin.RedseliggPlugin{Type: "customquotes"}, dispatcher: b.Dispatcher}
+
+	hook.Reset()
+	b.RunPlugins([]plugin.Hooks{first, second})
+
+	assert.Equal(1, first.runs)
+	assert.Equal(1, second.runs)
+	entry := hook.LastEntry()
+	if assert.NotNil(entry) {
+		assert.Equal(logrus.WarnLevel, entry.Level)
+		assert.Equal("Command 'quote' is registered by quotes, customquotes, all of them receive the command", entry.Message)
+	}
+}
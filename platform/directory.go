@@ -0,0 +1,158 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// Directory caches the users and channels known to a bot. Names are compared case-insensitively.
+// It is safe for concurrent use, so that the platform can update it from its events while
+// plugins look up users and channels.
+type Directory struct {
+	mutex sync.RWMutex
+
+	users     map[string]model.User // [ID]
+	userNames map[string]string     // [lower case name] -> ID
+
+	channels     map[string]model.Channel // [ID]
+	channelNames map[string]string        // [lower case name] -> ID
+}
+
+// NewDirectory creates an empty Directory.
+func NewDirectory() *Directory {
+	return &Directory{
+		users:        make(map[string]model.User),
+		userNames:    make(map[string]string),
+		channels:     make(map[string]model.Channel),
+		channelNames: make(map[string]string),
+	}
+}
+
+// AddUser adds the user or replaces the known user with the same ID.
+func (d *Directory) AddUser(user model.User) {
+	if len(user.ID) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if old, ok := d.users[user.ID]; ok {
+		delete(d.userNames, strings.ToLower(old.Name))
+	}
+	d.users[user.ID] = user
+	if len(user.Name) > 0 {
+		d.userNames[strings.ToLower(user.Name)] = user.ID
+	}
+}
+
+// RemoveUser removes the user with the ID.
+func (d *Directory) RemoveUser(userID string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if old, ok := d.users[userID]; ok {
+		delete(d.userNames, strings.ToLower(old.Name))
+		delete(d.users, userID)
+	}
+}
+
+// User returns the user with the ID.
+func (d *Directory) User(userID string) (model.User, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if user, ok := d.users[userID]; ok {
+		return user, nil
+	}
+	return model.User{}, fmt.Errorf("User with ID %s not known", userID)
+}
+
+// UserByName returns the user with the name.
+func (d *Directory) UserByName(name string) (model.User, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if id, ok := d.userNames[strings.ToLower(name)]; ok {
+		return d.users[id], nil
+	}
+	return model.User{}, fmt.Errorf("User with Name %s not known", name)
+}
+
+// Users returns all known users.
+func (d *Directory) Users() []model.User {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	users := []model.User{}
+	for _, user := range d.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// AddChannel adds the channel or replaces the known channel with the same ID.
+func (d *Directory) AddChannel(channel model.Channel) {
+	if len(channel.ID) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if old, ok := d.channels[channel.ID]; ok {
+		delete(d.channelNames, strings.ToLower(old.Name))
+	}
+	d.channels[channel.ID] = channel
+	if len(channel.Name) > 0 {
+		d.channelNames[strings.ToLower(channel.Name)] = channel.ID
+	}
+}
+
+// RemoveChannel removes the channel with the ID.
+func (d *Directory) RemoveChannel(channelID string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if old, ok := d.channels[channelID]; ok {
+		delete(d.channelNames, strings.ToLower(old.Name))
+		delete(d.channels, channelID)
+	}
+}
+
+// Channel returns the channel with the ID.
+func (d *Directory) Channel(channelID string) (model.Channel, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if channel, ok := d.channels[channelID]; ok {
+		return channel, nil
+	}
+	return model.Channel{}, fmt.Errorf("Channel with ID %s not known", channelID)
+}
+
+// ChannelByName returns the channel with the name.
+func (d *Directory) ChannelByName(name string) (model.Channel, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if id, ok := d.channelNames[strings.ToLower(name)]; ok {
+		return d.channels[id], nil
+	}
+	return model.Channel{}, fmt.Errorf("Channel with Name %s not known", name)
+}
+
+// Channels returns all known channels.
+func (d *Directory) Channels() []model.Channel {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	channels := []model.Channel{}
+	for _, channel := range d.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+func TestDirectory_Users(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDirectory()
+
+	_, err := d.User("1")
+	assert.Error(err)
+
+	d.AddUser(model.User{Name: "no id"})
+	assert.Equal(0, len(d.Users()))
+
+	d.AddUser(model.User{ID: "1", Name: "User#1234"})
+	user, err := d.UserByName("user#1234")
+	assert.NoError(err)
+	assert.Equal("1", user.ID)
+
+	d.AddUser(model.User{ID: "1", Name: "Renamed#1234", Nickname: "nick"})
+	_, err = d.UserByName("User#1234")
+	assert.Error(err)
+	user, err = d.User("1")
+	assert.NoError(err)
+	assert.Equal(model.User{ID: "1", Name: "Renamed#1234", Nickname: "nick"}, user)
+	assert.Equal(1, len(d.Users()))
+
+	d.RemoveUser("1")
+	_, err = d.User("1")
+	assert.Error(err)
+	_, err = d.UserByName("Renamed#1234")
+	assert.Error(err)
+}
+
+func TestDirectory_Channels(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDirectory()
+
+	d.AddChannel(model.Channel{ID: "C1", Name: "General"})
+	d.AddChannel(model.Channel{ID: "C2", Name: "random"})
+	assert.Equal(2, len(d.Channels()))
+
+	channel, err := d.ChannelByName("general")
+	assert.NoError(err)
+	assert.Equal("C1", channel.ID)
+
+	d.AddChannel(model.Channel{ID: "C1", Name: "welcome", Topic: "Hello"})
+	_, err = d.ChannelByName("general")
+	assert.Error(err)
+	channel, err = d.Channel("C1")
+	assert.NoError(err)
+	assert.Equal("Hello", channel.Topic)
+
+	d.RemoveChannel("C2")
+	_, err = d.Channel("C2")
+	assert.Error(err)
+	assert.Equal(1, len(d.Channels()))
+}
@@ -2,7 +2,6 @@ package discord
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -10,25 +9,31 @@ import (
 	"github.com/torlenor/redseligg/botconfig"
 	"github.com/torlenor/redseligg/commanddispatcher"
 
-	"github.com/gorilla/websocket"
-
 	"github.com/torlenor/redseligg/logging"
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin"
 	"github.com/torlenor/redseligg/storage"
-	"github.com/torlenor/redseligg/utils"
 	"github.com/torlenor/redseligg/webclient"
+	"github.com/torlenor/redseligg/ws"
 )
 
 var (
 	log = logging.Get("DiscordBot")
 )
 
+// maxMessageLength is the maximum length of a Discord message.
+const maxMessageLength = 2000
+
 // Used for injection in unit tests
 var newHeartbeatSender = func(ws webSocketClient) *discordHeartBeatSender {
 	return &discordHeartBeatSender{ws: ws}
 }
 
+// Used for injection in unit tests, the WebSocket clients of all shards but the first are created with it
+var newWebSocketClient = func() webSocketClient {
+	return ws.NewClient()
+}
+
 type webSocketClient interface {
 	Dial(wsURL string) error
 	Close() error
@@ -47,35 +52,47 @@ type api interface {
 type Bot struct {
 	platform.BotImpl
 
-	api api
+	api         api
+	rateLimiter *rateLimiter
 
 	gatewayURL string
-	ws         webSocketClient
+	compress   bool
+	shards     []*shard
 
-	knownChannels     map[string]channelCreate
-	token             string
-	ownSnowflakeID    string
-	currentSeqNumber  int
-	heartBeatStopChan chan bool
-	seqNumberChan     chan int
+	// Discord allows only one identify every 5 seconds
+	identifyMutex sync.Mutex
+	lastIdentify  time.Time
 
-	plugins []plugin.Hooks
+	// eventMutex serializes the handling of the events received by the shards
+	eventMutex sync.Mutex
 
-	wg sync.WaitGroup
+	knownChannels      map[string]channelCreate
+	knownChannelsMutex sync.RWMutex
+	token              string
+	ownSnowflakeID     string
 
-	watchdog *utils.Watchdog
+	plugins []plugin.Hooks
 
 	guilds        map[string]guildCreate // map[ID]
 	guildNameToID map[string]string
 
-	sessionID string
+	directory *platform.Directory
+
+	// applicationID is the ID of the application the bot belongs to, slashCommands is "global",
+	// "guild" or empty if no application commands are registered
+	applicationID       string
+	slashCommands       string
+	interactionsMutex   sync.Mutex
+	pendingInteractions map[string][]*pendingInteraction // [channelID]
 }
 
 // CreateDiscordBotWithAPI creates a new instance of a DiscordBot with the
-// provided api
+// provided api. The WebSocket client ws is used for the first shard.
 func CreateDiscordBotWithAPI(api api, storage storage.Storage, commandDispatcher *commanddispatcher.CommandDispatcher, cfg botconfig.DiscordConfig, ws webSocketClient) (*Bot, error) {
 	log.Info("DiscordBot is CREATING itself")
 
+	rateLimiter := newRateLimiter(api)
+
 	b := Bot{
 		BotImpl: platform.BotImpl{
 			ProvidedFeatures: map[string]bool{
@@ -83,32 +100,66 @@ func CreateDiscordBotWithAPI(api api, storage storage.Storage, commandDispatcher
 				platform.FeatureMessageUpdate:  true,
 				platform.FeatureMessageDelete:  true,
 				platform.FeatureReactionNotify: true,
+
+				platform.FeatureMessageFormatting: true,
+				platform.FeatureMessageEmbeds:     true,
+				platform.FeatureMessageReplies:    true,
+				platform.FeatureMessageButtons:    true,
+
+				platform.FeatureModerationBan:      true,
+				platform.FeatureModerationSlowMode: true,
 			},
 			Dispatcher: commandDispatcher,
 			Storage:    storage,
 		},
 
-		api: api,
+		api:         rateLimiter,
+		rateLimiter: rateLimiter,
 
-		token: cfg.Token,
-		ws:    ws,
+		token:    cfg.Token,
+		compress: cfg.Compress,
 
-		watchdog: &utils.Watchdog{},
+		applicationID:       cfg.ID,
+		slashCommands:       cfg.SlashCommands,
+		pendingInteractions: make(map[string][]*pendingInteraction),
 	}
 
-	url, err := b.getGateway()
+	gateway, err := b.getGateway()
 	if err != nil {
 		return nil, fmt.Errorf("Error connecting to Discord servers: %s", err)
 	}
-	b.gatewayURL = url
+	b.gatewayURL = gateway.URL
 
-	b.knownChannels = make(map[string]channelCreate)
+	shardCount := cfg.Shards
+	if shardCount <= 0 {
+		shardCount = gateway.Shards
+	}
+	if shardCount <= 0 {
+		shardCount = 1
+	}
+	if shardCount > 1 && gateway.SessionStartLimit.Remaining > 0 && gateway.SessionStartLimit.Remaining < shardCount {
+		log.Warnf("Only %d of %d shards can be started before the session start limit is reached", gateway.SessionStartLimit.Remaining, shardCount)
+	}
+
+	b.shards = make([]*shard, shardCount)
+	for i := range b.shards {
+		client := ws
+		if i > 0 {
+			client = newWebSocketClient()
+		}
+		b.shards[i] = newShard(&b, i, shardCount, client)
+	}
 
-	b.seqNumberChan = make(chan int)
+	b.knownChannels = make(map[string]channelCreate)
 
 	b.guilds = make(map[string]guildCreate)
 	b.guildNameToID = make(map[string]string)
 
+	b.directory = platform.NewDirectory()
+
+	b.Dispatcher.SetPoster(&b)
+	b.Dispatcher.SetMaxMessageLength(maxMessageLength)
+
 	return &b, nil
 }
 
@@ -119,182 +170,99 @@ func CreateDiscordBot(cfg botconfig.DiscordConfig, storage storage.Storage, comm
 	return CreateDiscordBotWithAPI(api, storage, commandDispatcher, cfg, ws)
 }
 
-func (b *Bot) startHeartbeatSender(heartbeatInterval time.Duration) {
-	b.heartBeatStopChan = make(chan bool)
-
-	go func() {
-		b.wg.Add(1)
-		heartBeat(heartbeatInterval, newHeartbeatSender(b.ws), b.heartBeatStopChan, b.seqNumberChan, b.onFail)
-		defer b.wg.Done()
-	}()
-	b.watchdog.SetFailCallback(b.onFail).Start(2 * heartbeatInterval)
-}
-
-func (b *Bot) openGatewayConnection() error {
-	b.ws.Dial(b.gatewayURL)
+// waitForIdentify blocks until a shard is allowed to identify. It returns false if done is closed before.
+func (b *Bot) waitForIdentify(done chan struct{}) bool {
+	b.identifyMutex.Lock()
+	defer b.identifyMutex.Unlock()
 
-	// WS: 1 - 10 HELLO
-	_, message, err := b.ws.ReadMessage()
-	if err != nil {
-		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: %s", err)
-	}
-
-	var data event
-	if err := json.Unmarshal(message, &data); err != nil {
-		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not unmarshal event: %s", err)
-	}
-
-	if data.Op != 10 { // If not Hello from Discord Gateway
-		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Did not receive a HELLO, but OP Code %d", data.Op)
-	}
-
-	// Perform IDENT/RESUME
-	if b.sessionID == "" {
-		err = sendIdent(b.token, b.ws)
-	} else {
-		err = sendResume(b.token, b.sessionID, b.currentSeqNumber, b.ws)
-	}
-	if err != nil {
-		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not send IDENT/RESUME: %s", err)
-	}
-
-	var helloEvent hello
-	if err := json.Unmarshal(data.RawData, &helloEvent); err != nil {
-		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not unmarshal HELLO event: %s", err)
-	}
-
-	// Start sending Heartbeats
-	b.startHeartbeatSender(time.Duration(helloEvent.HeartbeatInterval) * time.Millisecond)
-
-	return nil
-}
-
-func (b *Bot) run() {
-	var e error
-	defer func() {
-		if e != nil {
-			log.Error(e)
-			go b.onFail()
+	if wait := identifyInterval - time.Since(b.lastIdentify); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-done:
+			return false
 		}
-	}()
-
-	e = b.openGatewayConnection()
-	if e != nil {
-		return
 	}
+	b.lastIdentify = time.Now()
 
-	// Go into event handling
-	for {
-		_, message, err := b.ws.ReadMessage()
-		if err != nil {
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				log.Tracef("Connection to Discord Gateway closed normally: %s", err)
-				break
-			} else if websocket.IsCloseError(err, websocket.CloseGoingAway) {
-				log.Debugf("Received GoingAway from Discord Gateway, attempting a reconnect")
-				b.stopHeartBeatWatchdog()
-				b.ws.Close()
-				err := b.openGatewayConnection()
-				if err != nil {
-					e = fmt.Errorf("Could not reconnect to Discord Gateway: %s", err.Error())
-					break
-				}
-				continue
-			} else {
-				e = fmt.Errorf("Unhandled error in Discord Gateway communication logic: %s", err)
-				break
-			}
-		}
-
-		var data event
-		if err := json.Unmarshal(message, &data); err != nil {
-			log.Warnf("Could not unmarshal event from Discord Gateway: %s", err)
-			continue
-		}
+	return true
+}
 
-		if data.Op == 7 { // Reconnect: You must reconnect with a new session immediately.
-			log.Debugf("Received request to reconnect")
-			b.stopHeartBeatWatchdog()
-			b.ws.Close()
-			e = b.openGatewayConnection()
-		} else if data.Op == 9 { // Invalid Session: The session has been invalidated. You should reconnect and identify/resume accordingly.
-			log.Warn("Invalid Session received")
-			var invalidSessionData invalidSession
-			json.Unmarshal(message, &invalidSessionData)
-			if !invalidSessionData.D {
-				// It does not want us to resume, so we are resetting our sessionID
-				b.sessionID = ""
-			}
-			b.stopHeartBeatWatchdog()
-			b.ws.Close()
-			e = b.openGatewayConnection()
-		} else if data.Op == 11 { // Heartbeat ACK
-			b.watchdog.Feed()
-		} else if data.Op == 0 { // Regular events to dispatch to event handlers
-			switch data.Type {
-			// Events managing communication with Discord API
-			case "READY":
-				b.handleReady(data.RawData)
-			case "RESUMED":
-				// Not needed for now
-			case "RECONNECT":
-				b.ws.Close()
-				e = b.openGatewayConnection()
-			// Real events
-			case "MESSAGE_CREATE":
-				b.handleMessageCreate(data.RawData)
-			case "GUILD_CREATE":
-				b.handleGuildCreate(data.RawData)
-			case "PRESENCE_UPDATE":
-				b.handlePresenceUpdate(data.RawData)
-			case "PRESENCE_REPLACE":
-				b.handlePresenceReplace(data.RawData)
-			case "TYPING_START":
-				b.handleTypingStart(data.RawData)
-			case "CHANNEL_CREATE":
-				b.handleChannelCreate(data.RawData)
-			case "MESSAGE_REACTION_ADD":
-				b.handleMessageReactionAdd(data.RawData)
-			case "MESSAGE_REACTION_REMOVE":
-				b.handleMessageReactionRemove(data.RawData)
-			case "MESSAGE_DELETE":
-				b.handleMessageDelete(data.RawData)
-			case "MESSAGE_UPDATE":
-				b.handleMessageUpdate(data.RawData)
-			case "CHANNEL_PINS_UPDATE":
-				b.handleChannelPinsUpdate(data.RawData)
-			case "GUILD_MEMBER_UPDATE":
-				b.handleGuildMemberUpdate(data.RawData)
-			case "PRESENCES_REPLACE":
-				b.handlePresencesReplace(data.RawData)
-			default:
-				log.Warnln("Unhandled message:", string(message))
-			}
-			b.currentSeqNumber = int(data.Seq)
-			b.seqNumberChan <- b.currentSeqNumber
-		} else {
-			log.Warnf("Unknown Op Code %d received, data: %v", data.Op, data)
-		}
+// handleEvent dispatches a regular event received by one of the shards to the event handlers.
+func (b *Bot) handleEvent(data event) {
+	b.eventMutex.Lock()
+	defer b.eventMutex.Unlock()
+
+	switch data.Type {
+	case "MESSAGE_CREATE":
+		b.handleMessageCreate(data.RawData)
+	case "GUILD_CREATE":
+		b.handleGuildCreate(data.RawData)
+	case "PRESENCE_UPDATE":
+		b.handlePresenceUpdate(data.RawData)
+	case "PRESENCE_REPLACE":
+		b.handlePresenceReplace(data.RawData)
+	case "TYPING_START":
+		b.handleTypingStart(data.RawData)
+	case "CHANNEL_CREATE":
+		b.handleChannelCreate(data.RawData)
+	case "MESSAGE_REACTION_ADD":
+		b.handleMessageReactionAdd(data.RawData)
+	case "MESSAGE_REACTION_REMOVE":
+		b.handleMessageReactionRemove(data.RawData)
+	case "MESSAGE_DELETE":
+		b.handleMessageDelete(data.RawData)
+	case "MESSAGE_UPDATE":
+		b.handleMessageUpdate(data.RawData)
+	case "CHANNEL_PINS_UPDATE":
+		b.handleChannelPinsUpdate(data.RawData)
+	case "GUILD_MEMBER_UPDATE":
+		b.handleGuildMemberUpdate(data.RawData)
+	case "GUILD_MEMBER_ADD":
+		b.handleGuildMemberAdd(data.RawData)
+	case "GUILD_MEMBER_REMOVE":
+		b.handleGuildMemberRemove(data.RawData)
+	case "CHANNEL_UPDATE":
+		b.handleChannelUpdate(data.RawData)
+	case "CHANNEL_DELETE":
+		b.handleChannelDelete(data.RawData)
+	case "PRESENCES_REPLACE":
+		b.handlePresencesReplace(data.RawData)
+	case "INTERACTION_CREATE":
+		b.handleInteractionCreate(data.RawData)
+	default:
+		log.Warnln("Unhandled event:", data.Type, string(data.RawData))
 	}
 }
 
 // Start the Discord Bot
 func (b *Bot) start() error {
-	log.Infof("DiscordBot is STARTING (have %d plugin(s))", len(b.plugins))
+	log.Infof("DiscordBot is STARTING (have %d plugin(s), %d shard(s))", len(b.plugins), len(b.shards))
+	b.StartEventQueues()
 
-	err := b.ws.Dial(b.gatewayURL)
-	if err != nil {
-		return fmt.Errorf("Could not dial Discord WebSocket, Discord Bot not operational: %s", err.Error())
+	for _, shard := range b.shards {
+		shard.start()
 	}
 
-	go func() {
-		b.wg.Add(1)
-		b.run()
-		defer b.wg.Done()
-	}()
+	b.RunPlugins(b.plugins)
 
-	for _, plugin := range b.plugins {
-		plugin.OnRun()
+	// The plugins registered their commands in OnRun, guilds received afterwards get them on GUILD_CREATE
+	switch b.slashCommands {
+	case "global":
+		if err := b.registerCommands(""); err != nil {
+			log.Errorf("Could not register application commands: %s", err)
+		}
+	case "guild":
+		b.eventMutex.Lock()
+		var guildIDs []string
+		for id := range b.guilds {
+			guildIDs = append(guildIDs, id)
+		}
+		b.eventMutex.Unlock()
+		for _, id := range guildIDs {
+			if err := b.registerCommands(id); err != nil {
+				log.Errorf("Could not register application commands for Guild %s: %s", id, err)
+			}
+		}
 	}
 	log.Info("DiscordBot is RUNNING")
 
@@ -310,6 +278,8 @@ func (b *Bot) Run(ctx context.Context) error {
 
 	<-ctx.Done()
 
+	b.StopEventQueues()
+
 	for _, plugin := range b.plugins {
 		plugin.OnStop()
 	}
@@ -319,29 +289,23 @@ func (b *Bot) Run(ctx context.Context) error {
 	return nil
 }
 
-func (b *Bot) stopHeartBeatWatchdog() {
-	b.watchdog.Stop()
-	b.heartBeatStopChan <- true
-}
-
 // Stop the Discord Bot
 func (b *Bot) stop() {
 	log.Infoln("DiscordBot is SHUTING DOWN")
 
-	b.stopHeartBeatWatchdog()
-
-	err := b.sendCloseToWebsocket()
-	if err != nil {
-		log.Errorln("Error when writing close message to ws:", err)
+	for _, shard := range b.shards {
+		shard.stop()
 	}
 
-	b.wg.Wait()
-
-	b.ws.Close()
-
+	log.Infof("DiscordBot REST API Stats:\n%s", b.RateLimitStats().toString())
 	log.Infoln("DiscordBot is SHUT DOWN")
 }
 
+// RateLimitStats returns statistics about the requests to the Discord REST API and their rate limits.
+func (b *Bot) RateLimitStats() RateLimitStats {
+	return b.rateLimiter.Stats()
+}
+
 // AddPlugin takes as argument a plugin and
 // adds it to the bot providing it with the API
 func (b *Bot) AddPlugin(plugin platform.BotPlugin) {
@@ -359,63 +323,6 @@ func (b *Bot) GetInfo() platform.BotInfo {
 		BotID:    "",
 		Platform: "Discord",
 		Healthy:  true,
-		Plugins:  []platform.PluginInfo{},
-	}
-}
-
-func (b *Bot) sendCloseToWebsocket() error {
-	if b.ws != nil {
-		return b.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-	}
-	return nil
-}
-
-func (b *Bot) sendCloseRestartToWebsocket() error {
-	if b.ws != nil {
-		return b.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, ""))
-	}
-	return nil
-}
-
-func (b *Bot) onFail() {
-	log.Warn("Encountered an error, trying to restart the bot")
-
-	log.Debug("Stopping heartbeat watchdog")
-	b.stopHeartBeatWatchdog()
-
-	log.Debug("Sending close to websocket")
-	err := b.sendCloseToWebsocket()
-	if err != nil {
-		log.Errorf("Error when writing close message to ws: %s, still trying to recover", err)
-	}
-
-	log.Debug("Waiting for waitgroup to be done")
-	b.wg.Wait()
-
-	log.Debug("Closing websocket")
-	b.ws.Close()
-
-	log.Debug("Get new gateway address")
-	url, err := b.getGateway()
-	if err != nil {
-		log.Errorf("Error connecting to Discord servers: %s", err)
-		return
+		Plugins:  b.PluginInfos(b.plugins),
 	}
-	b.gatewayURL = url
-
-	log.Debugf("Dialing gateway at %s", b.gatewayURL)
-	err = b.ws.Dial(b.gatewayURL)
-	if err != nil {
-		log.Errorln("Could not dial Discord WebSocket, Discord Bot not operational:", err)
-		return
-	}
-
-	log.Debug("Launching run goroutine")
-	go func() {
-		b.wg.Add(1)
-		b.run()
-		defer b.wg.Done()
-	}()
-
-	log.Info("Recovery attempt finished")
 }
@@ -18,7 +18,7 @@ func Test_CreateDiscordBot(t *testing.T) {
 	dispatcher := commanddispatcher.CommandDispatcher{}
 	api := webclient.NewMock()
 
-	expectedAPICallPath := "/gateway"
+	expectedAPICallPath := "/gateway/bot"
 	expectedAPICallMethod := "GET"
 	expectedAPICallBody := ""
 	expectedWebSocketGatewayURL := "ws://something"
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// Discord channel types which are not part of a guild
+const (
+	channelTypeDM      = 1
+	channelTypeGroupDM = 3
+)
+
+type discordUser struct {
+	Username      string `json:"username"`
+	ID            string `json:"id"`
+	Discriminator string `json:"discriminator"`
+	Bot           bool   `json:"bot"`
+}
+
+type guildMember struct {
+	User    discordUser `json:"user"`
+	Nick    string      `json:"nick,omitempty"`
+	GuildID string      `json:"guild_id"`
+}
+
+type channelDelete struct {
+	ID      string `json:"id"`
+	GuildID string `json:"guild_id"`
+}
+
+func convertUser(u discordUser, nick string) model.User {
+	return model.User{
+		ID:       u.ID,
+		Name:     combineUsernameAndDiscriminator(u.Username, u.Discriminator),
+		Nickname: nick,
+		IsBot:    u.Bot,
+	}
+}
+
+func convertChannel(c channel) model.Channel {
+	return model.Channel{ID: c.ID, Name: c.Name, Topic: c.Topic}
+}
+
+func convertChannelCreate(c channelCreate) model.Channel {
+	return model.Channel{
+		ID:        c.ID,
+		Name:      c.Name,
+		Topic:     c.Topic,
+		IsPrivate: c.Type == channelTypeDM || c.Type == channelTypeGroupDM,
+	}
+}
+
+func (b *Bot) addGuildToDirectory(guild guildCreate) {
+	for _, member := range guild.Members {
+		b.directory.AddUser(convertUser(discordUser{
+			Username:      member.User.Username,
+			ID:            member.User.ID,
+			Discriminator: member.User.Discriminator,
+		}, member.Nick))
+	}
+	for _, c := range guild.Channels {
+		b.directory.AddChannel(convertChannel(c))
+	}
+}
+
+func (b *Bot) handleGuildMemberAdd(data json.RawMessage) {
+	var member guildMember
+	err := json.Unmarshal(data, &member)
+	if err != nil {
+		log.Errorln("UNHANDLED ERROR: GUILD_MEMBER_ADD", err)
+		return
+	}
+
+	log.Tracef("Received: GUILD_MEMBER_ADD for UserID = %s", member.User.ID)
+	b.directory.AddUser(convertUser(member.User, member.Nick))
+}
+
+func (b *Bot) handleGuildMemberRemove(data json.RawMessage) {
+	var member guildMember
+	err := json.Unmarshal(data, &member)
+	if err != nil {
+		log.Errorln("UNHANDLED ERROR: GUILD_MEMBER_REMOVE", err)
+		return
+	}
+
+	log.Tracef("Received: GUILD_MEMBER_REMOVE for UserID = %s", member.User.ID)
+	b.directory.RemoveUser(member.User.ID)
+}
+
+func (b *Bot) handleChannelUpdate(data json.RawMessage) {
+	var updatedChannel channelCreate
+	err := json.Unmarshal(data, &updatedChannel)
+	if err != nil {
+		log.Errorln("UNHANDLED ERROR: CHANNEL_UPDATE", err)
+		return
+	}
+
+	log.Tracef("Received: CHANNEL_UPDATE with ID = %s", updatedChannel.ID)
+	b.addKnownChannel(updatedChannel)
+}
+
+func (b *Bot) handleChannelDelete(data json.RawMessage) {
+	var deletedChannel channelDelete
+	err := json.Unmarshal(data, &deletedChannel)
+	if err != nil {
+		log.Errorln("UNHANDLED ERROR: CHANNEL_DELETE", err)
+		return
+	}
+
+	log.Tracef("Received: CHANNEL_DELETE with ID = %s", deletedChannel.ID)
+	b.knownChannelsMutex.Lock()
+	delete(b.knownChannels, deletedChannel.ID)
+	b.knownChannelsMutex.Unlock()
+	b.directory.RemoveChannel(deletedChannel.ID)
+}
+
+// fetchUser gets the user from the Discord API and adds it to the directory.
+func (b *Bot) fetchUser(userID string) (model.User, error) {
+	response, err := b.api.Call("/users/"+userID, "GET", "")
+	if err != nil {
+		return model.User{}, errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode != 200 {
+		return model.User{}, fmt.Errorf("User with ID %s not found: %s", userID, response.Body)
+	}
+
+	var u discordUser
+	if err := json.Unmarshal(response.Body, &u); err != nil {
+		return model.User{}, errors.Wrap(err, "json unmarshal failed")
+	}
+
+	user := convertUser(u, "")
+	b.directory.AddUser(user)
+	return user, nil
+}
+
+// fetchChannel gets the channel from the Discord API and adds it to the directory.
+func (b *Bot) fetchChannel(channelID string) (model.Channel, error) {
+	response, err := b.api.Call("/channels/"+channelID, "GET", "")
+	if err != nil {
+		return model.Channel{}, errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode != 200 {
+		return model.Channel{}, fmt.Errorf("Channel with ID %s not found: %s", channelID, response.Body)
+	}
+
+	var c channelCreate
+	if err := json.Unmarshal(response.Body, &c); err != nil {
+		return model.Channel{}, errors.Wrap(err, "json unmarshal failed")
+	}
+
+	channel := convertChannelCreate(c)
+	b.directory.AddChannel(channel)
+	return channel, nil
+}
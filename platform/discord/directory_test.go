@@ -0,0 +1,56 @@
+package discord
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/platform"
+	"github.com/torlenor/redseligg/webclient"
+)
+
+func TestBot_Directory(t *testing.T) {
+	assert := assert.New(t)
+
+	api := webclient.NewMock()
+	b := &Bot{
+		api:           api,
+		knownChannels: make(map[string]channelCreate),
+		directory:     platform.NewDirectory(),
+	}
+
+	b.handleGuildMemberAdd(json.RawMessage(`{"user":{"username":"user","id":"1","discriminator":"1234"},"nick":"nick","guild_id":"G"}`))
+	user, err := b.GetUserByUsername("user#1234")
+	assert.NoError(err)
+	assert.Equal(model.User{ID: "1", Name: "user#1234", Nickname: "nick"}, user)
+
+	b.handleGuildMemberRemove(json.RawMessage(`{"user":{"username":"user","id":"1","discriminator":"1234"},"guild_id":"G"}`))
+	_, err = b.GetUserByUsername("user#1234")
+	assert.Error(err)
+
+	api.ReturnOnCall = webclient.APIResponse{StatusCode: 200, Body: []byte(`{"username":"user","id":"1","discriminator":"1234"}`)}
+	user, err = b.GetUser("1")
+	assert.NoError(err)
+	assert.Equal("/users/1", api.LastCallPath)
+	assert.Equal("user#1234", user.Name)
+
+	b.handleChannelCreate(json.RawMessage(`{"id":"C1","type":0,"name":"general","topic":"Hello","guild_id":"G"}`))
+	channel, err := b.GetChannelByName("general")
+	assert.NoError(err)
+	assert.Equal(model.Channel{ID: "C1", Name: "general", Topic: "Hello"}, channel)
+
+	b.handleChannelUpdate(json.RawMessage(`{"id":"C1","type":0,"name":"welcome","guild_id":"G"}`))
+	channel, err = b.GetChannel("C1")
+	assert.NoError(err)
+	assert.Equal("welcome", channel.Name)
+
+	b.handleChannelDelete(json.RawMessage(`{"id":"C1","guild_id":"G"}`))
+	_, err = b.GetChannelByName("welcome")
+	assert.Error(err)
+
+	api.ReturnOnCall = webclient.APIResponse{StatusCode: 404, Body: []byte(`{"message":"Unknown Channel"}`)}
+	_, err = b.GetChannel("C1")
+	assert.Error(err)
+}
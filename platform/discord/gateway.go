@@ -3,26 +3,49 @@ package discord
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 )
 
+// gatewayVersion is the version of the gateway API used by the bot.
+const gatewayVersion = "6"
+
 type gatewayResponse struct {
 	URL string `json:"url"`
+	// Shards is the number of shards recommended by Discord.
+	Shards            int `json:"shards"`
+	SessionStartLimit struct {
+		Total      int `json:"total"`
+		Remaining  int `json:"remaining"`
+		ResetAfter int `json:"reset_after"`
+	} `json:"session_start_limit"`
 }
 
-func (b *Bot) getGateway() (string, error) {
+func (b *Bot) getGateway() (gatewayResponse, error) {
 	log.Traceln("DiscordBot: Requesting the Discord gateway address")
-	response, err := b.api.Call("/gateway", "GET", "")
+	response, err := b.api.Call("/gateway/bot", "GET", "")
 	if err != nil {
-		return "", fmt.Errorf("Could not get the Discord gateway: %s", err.Error())
+		return gatewayResponse{}, fmt.Errorf("Could not get the Discord gateway: %s", err.Error())
 	}
 
-	var dat map[string]interface{}
-
-	if err := json.Unmarshal(response.Body, &dat); err != nil {
-		return "", fmt.Errorf("Could not parse the response to our Discord gateway request: %s", err.Error())
+	var gateway gatewayResponse
+	if err := json.Unmarshal(response.Body, &gateway); err != nil {
+		return gatewayResponse{}, fmt.Errorf("Could not parse the response to our Discord gateway request: %s", err.Error())
+	}
+	if len(gateway.URL) == 0 {
+		return gatewayResponse{}, fmt.Errorf("Could not parse the response to our Discord gateway request: No URL received")
 	}
 
-	url := dat["url"].(string)
-	log.Tracef("Received Discord gateway address: %s", url)
-	return url, nil
+	log.Tracef("Received Discord gateway address: %s, recommended shards: %d", gateway.URL, gateway.Shards)
+	return gateway, nil
+}
+
+// gatewayConnectURL returns the URL to connect to the gateway, optionally with zlib-stream compression.
+func gatewayConnectURL(gatewayURL string, compress bool) string {
+	query := url.Values{}
+	query.Set("v", gatewayVersion)
+	query.Set("encoding", "json")
+	if compress {
+		query.Set("compress", "zlib-stream")
+	}
+	return gatewayURL + "?" + query.Encode()
 }
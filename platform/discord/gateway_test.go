@@ -2,6 +2,7 @@ package discord
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/torlenor/redseligg/webclient"
@@ -18,7 +19,7 @@ func TestBot_getGateway(t *testing.T) {
 	tests := []struct {
 		name    string
 		fields  fields
-		want    string
+		want    gatewayResponse
 		wantErr bool
 	}{
 		{name: "Successful API call with valid gateway URL",
@@ -26,17 +27,28 @@ func TestBot_getGateway(t *testing.T) {
 				api: webclient.NewMock(),
 				apiResponse: webclient.APIResponse{
 					StatusCode: 200,
-					Body:       []byte(`{"url": "` + wsGatewayURL + `"}`),
+					Body:       []byte(`{"url": "` + wsGatewayURL + `", "shards": 3}`),
 				},
 			},
-			want: wsGatewayURL,
+			want: gatewayResponse{URL: wsGatewayURL, Shards: 3},
 		},
 		{name: "Failed API call",
 			fields: fields{
 				api:              webclient.NewMock(),
 				apiResponseError: fmt.Errorf("Some error"),
 			},
-			want:    "",
+			want:    gatewayResponse{},
+			wantErr: true,
+		},
+		{name: "Successful API call without URL",
+			fields: fields{
+				api: webclient.NewMock(),
+				apiResponse: webclient.APIResponse{
+					StatusCode: 200,
+					Body:       []byte(`{"shards": 1}`),
+				},
+			},
+			want:    gatewayResponse{},
 			wantErr: true,
 		},
 		{name: "Successful API call with invalid json response",
@@ -47,7 +59,7 @@ func TestBot_getGateway(t *testing.T) {
 					Body:       []byte(`{{{"url": "` + wsGatewayURL + `"}`),
 				},
 			},
-			want:    "",
+			want:    gatewayResponse{},
 			wantErr: true,
 		},
 	}
@@ -64,9 +76,18 @@ func TestBot_getGateway(t *testing.T) {
 				t.Errorf("Bot.getGateway() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Bot.getGateway() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_gatewayConnectURL(t *testing.T) {
+	if got := gatewayConnectURL("wss://gateway.discord.gg", false); got != "wss://gateway.discord.gg?encoding=json&v=6" {
+		t.Errorf("gatewayConnectURL() = %v", got)
+	}
+	if got := gatewayConnectURL("wss://gateway.discord.gg", true); got != "wss://gateway.discord.gg?compress=zlib-stream&encoding=json&v=6" {
+		t.Errorf("gatewayConnectURL() with compression = %v", got)
+	}
+}
@@ -222,7 +222,10 @@ func (r ready) toString() string {
 }
 
 type channelCreate struct {
-	Type       int `json:"type"`
+	Type       int    `json:"type"`
+	Name       string `json:"name,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+	GuildID    string `json:"guild_id,omitempty"`
 	Recipients []struct {
 		Username      string `json:"username"`
 		ID            string `json:"id"`
@@ -26,6 +26,8 @@ func (messageType messageType) String() string {
 }
 
 func (b *Bot) getMessageType(mc messageCreate) messageType {
+	b.knownChannelsMutex.RLock()
+	defer b.knownChannelsMutex.RUnlock()
 	if val, ok := b.knownChannels[mc.ChannelID]; ok {
 		if len(val.Recipients) == 1 {
 			return WHISPER
@@ -40,18 +42,21 @@ func (b *Bot) dispatchMessage(msg messageCreate) {
 	if b.getMessageType(msg) == WHISPER {
 		receiveMessage.IsPrivate = true
 	}
+	if _, err := b.directory.User(msg.Author.ID); err != nil {
+		b.directory.AddUser(convertUser(discordUser{
+			Username:      msg.Author.Username,
+			ID:            msg.Author.ID,
+			Discriminator: msg.Author.Discriminator,
+			Bot:           msg.Author.Bot,
+		}, ""))
+	}
 
 	for _, plugin := range b.plugins {
-		plugin.OnPost(receiveMessage)
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnPost(receiveMessage) })
 	}
 
-	if ok, text := b.Dispatcher.IsHelp(receiveMessage); ok {
-		postMessage := receiveMessage
-		postMessage.Content = text
-		b.CreatePost(postMessage)
-	} else {
-		b.Dispatcher.OnPost(receiveMessage)
-	}
+	b.Dispatcher.OnPost(receiveMessage)
 }
 
 func (b *Bot) handleMessageCreate(data json.RawMessage) {
@@ -66,17 +71,24 @@ func (b *Bot) handleMessageCreate(data json.RawMessage) {
 	b.dispatchMessage(newMessageCreate)
 }
 
-func (b *Bot) handleReady(data json.RawMessage) {
+// handleReady handles the READY event of a shard and returns the ID of the new session.
+func (b *Bot) handleReady(data json.RawMessage) string {
 	var newReady ready
 	err := json.Unmarshal(data, &newReady)
 	if err != nil {
 		log.Errorln("UNHANDLED ERROR: READY", err)
-		return
+		return ""
 	}
+
+	b.eventMutex.Lock()
+	defer b.eventMutex.Unlock()
+
 	b.ownSnowflakeID = newReady.User.ID
-	b.sessionID = newReady.SessionID
+	b.Dispatcher.SetBotMentions("<@"+newReady.User.ID+">", "<@!"+newReady.User.ID+">")
 
 	log.Tracef("Received: READY for Bot User = %s, UserID = %s, SnowflakeID = %s", newReady.User.Username, newReady.User.ID, b.ownSnowflakeID)
+
+	return newReady.SessionID
 }
 
 func (b *Bot) handleGuildCreate(data json.RawMessage) {
@@ -89,6 +101,13 @@ func (b *Bot) handleGuildCreate(data json.RawMessage) {
 
 	b.guilds[newGuildCreate.ID] = newGuildCreate
 	b.guildNameToID[newGuildCreate.Name] = newGuildCreate.ID
+	b.addGuildToDirectory(newGuildCreate)
+
+	if b.slashCommands == "guild" {
+		if err := b.registerCommands(newGuildCreate.ID); err != nil {
+			log.Errorf("Could not register application commands for Guild %s: %s", newGuildCreate.Name, err)
+		}
+	}
 
 	log.Traceln("GUILD_CREATE: Added new Guild:", newGuildCreate.Name)
 }
@@ -120,7 +139,10 @@ func (b *Bot) handleTypingStart(data json.RawMessage) {
 }
 
 func (b *Bot) addKnownChannel(channel channelCreate) {
+	b.knownChannelsMutex.Lock()
 	b.knownChannels[channel.ID] = channel
+	b.knownChannelsMutex.Unlock()
+	b.directory.AddChannel(convertChannelCreate(channel))
 }
 
 func (b *Bot) handleChannelCreate(data json.RawMessage) {
@@ -160,7 +182,8 @@ func (b *Bot) handleMessageReactionAdd(data json.RawMessage) {
 	}
 
 	for _, plugin := range b.plugins {
-		plugin.OnReactionAdded(reaction)
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnReactionAdded(reaction) })
 	}
 
 	log.Traceln("Received: MESSAGE_REACTION_ADD", newMessageReactionAdd)
@@ -190,7 +213,8 @@ func (b *Bot) handleMessageReactionRemove(data json.RawMessage) {
 	}
 
 	for _, plugin := range b.plugins {
-		plugin.OnReactionRemoved(reaction)
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnReactionRemoved(reaction) })
 	}
 
 	log.Traceln("Received: MESSAGE_REACTION_REMOVE", newMessageReactionRemove)
@@ -238,6 +262,14 @@ func (b *Bot) handleGuildMemberUpdate(data json.RawMessage) {
 	}
 
 	log.Traceln("Received: GUILD_MEMBER_UPDATE", newGuildMemberUpdate)
+
+	nick, _ := newGuildMemberUpdate.Nick.(string)
+	b.directory.AddUser(convertUser(discordUser{
+		Username:      newGuildMemberUpdate.User.Username,
+		ID:            newGuildMemberUpdate.User.ID,
+		Discriminator: newGuildMemberUpdate.User.Discriminator,
+		Bot:           newGuildMemberUpdate.User.Bot,
+	}, nick))
 }
 
 func (b *Bot) handlePresencesReplace(data json.RawMessage) {
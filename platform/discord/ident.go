@@ -7,7 +7,7 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-func sendIdent(token string, ws webSocketClient) error {
+func sendIdent(token string, shardID int, shardCount int, ws webSocketClient) error {
 	ident := []byte(`{"op": 2,
 			"d": {
 				"token": "` + token + `",
@@ -17,7 +17,8 @@ func sendIdent(token string, ws webSocketClient) error {
 					"$device": "redseligg"
 				  },
 				"compress": false,
-				"large_threshold": 250
+				"large_threshold": 250,
+				"shard": [` + strconv.Itoa(shardID) + `, ` + strconv.Itoa(shardCount) + `]
 			}
 }`)
 
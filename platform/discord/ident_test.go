@@ -23,11 +23,12 @@ func Test_SendIdent(t *testing.T) {
 					"$device": "redseligg"
 				  },
 				"compress": false,
-				"large_threshold": 250
+				"large_threshold": 250,
+				"shard": [1, 2]
 			}
 }`)
 
-	err := sendIdent(testToken, ws)
+	err := sendIdent(testToken, 1, 2, ws)
 	if err != nil {
 		t.Fatalf("Sending Ident failed")
 	}
@@ -55,13 +56,14 @@ func Test_SendIdentFail(t *testing.T) {
 					"$device": "redseligg"
 				  },
 				"compress": false,
-				"large_threshold": 250
+				"large_threshold": 250,
+				"shard": [1, 2]
 			}
 }`)
 
 	ws.ReturnError = fmt.Errorf("Some error")
 
-	err := sendIdent(testToken, ws)
+	err := sendIdent(testToken, 1, 2, ws)
 	if err == nil {
 		t.Fatalf("Sending Ident did not fail")
 	}
@@ -0,0 +1,58 @@
+package discord
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// zlibSuffix ends every complete message of a zlib-stream compressed connection.
+var zlibSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+// windowSize is the size of the deflate window.
+const windowSize = 32 * 1024
+
+// inflator decompresses the messages of a zlib-stream compressed gateway connection.
+// All messages of a connection share one zlib context, i.e., each message is flushed
+// with a sync flush and may refer to the output of the previous messages.
+type inflator struct {
+	buf     []byte // buf holds the parts of an incomplete message
+	window  []byte // window holds the end of the decompressed output
+	started bool
+}
+
+// inflate adds the received data and returns the decompressed message, if it is complete.
+func (i *inflator) inflate(data []byte) ([]byte, bool, error) {
+	i.buf = append(i.buf, data...)
+	if !bytes.HasSuffix(i.buf, zlibSuffix) {
+		return nil, false, nil
+	}
+
+	compressed := i.buf
+	i.buf = nil
+
+	if !i.started {
+		// Only the first message contains the zlib header
+		if len(compressed) < 2 || compressed[0]&0x0f != 8 || (uint16(compressed[0])<<8|uint16(compressed[1]))%31 != 0 {
+			return nil, false, fmt.Errorf("Could not decompress message: Invalid zlib header")
+		}
+		compressed = compressed[2:]
+		i.started = true
+	}
+
+	// A sync flush ends on a byte boundary, so the next message can be decompressed by
+	// a new decompressor which knows the previous output.
+	out, err := ioutil.ReadAll(flate.NewReaderDict(bytes.NewReader(compressed), i.window))
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, false, fmt.Errorf("Could not decompress message: %s", err)
+	}
+
+	i.window = append(i.window, out...)
+	if len(i.window) > windowSize {
+		i.window = append([]byte(nil), i.window[len(i.window)-windowSize:]...)
+	}
+
+	return out, true, nil
+}
@@ -0,0 +1,50 @@
+package discord
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+func TestInflator(t *testing.T) {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+
+	messages := []string{
+		`{"op":10,"d":{"heartbeat_interval":41250}}`,
+		`{"op":0,"t":"MESSAGE_CREATE","s":1,"d":{"content":"` + strings.Repeat("spam ", 100) + `"}}`,
+		`{"op":0,"t":"MESSAGE_CREATE","s":2,"d":{"content":"` + strings.Repeat("spam ", 100) + `"}}`,
+		`{"op":11}`,
+	}
+
+	i := &inflator{}
+	for n, message := range messages {
+		compressed.Reset()
+		w.Write([]byte(message))
+		w.Flush()
+		data := compressed.Bytes()
+
+		// Messages can be received in several parts
+		out, complete, err := i.inflate(data[:len(data)/2])
+		if err != nil || complete || out != nil {
+			t.Fatalf("Incomplete message %d returned: %s, %v, %v", n, out, complete, err)
+		}
+
+		out, complete, err = i.inflate(data[len(data)/2:])
+		if err != nil {
+			t.Fatalf("Could not inflate message %d: %s", n, err)
+		}
+		if !complete {
+			t.Fatalf("Message %d not complete", n)
+		}
+		if string(out) != message {
+			t.Fatalf("Wrong message %d, got %s, want %s", n, out, message)
+		}
+	}
+
+	i = &inflator{}
+	if _, _, err := i.inflate(append([]byte("not zlib"), zlibSuffix...)); err == nil {
+		t.Fatalf("Invalid data did not fail")
+	}
+}
@@ -0,0 +1,501 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+)
+
+// interactionsAPIVersion is the API version used for application commands and interactions,
+// they are not available in the default version of the API.
+const interactionsAPIVersion = "/v8"
+
+// Application command option types
+const (
+	optionSubcommand      = 1
+	optionSubcommandGroup = 2
+	optionString          = 3
+	optionInteger         = 4
+	optionBoolean         = 5
+	optionUser            = 6
+	optionChannel         = 7
+)
+
+// Interaction types and responses
+const (
+	interactionApplicationCommand = 2
+	interactionMessageComponent   = 3
+
+	responseDeferredChannelMessage = 5
+
+	messageFlagEphemeral = 64
+)
+
+// Limits of application commands
+const (
+	maxApplicationCommands = 100
+	maxCommandOptions      = 25
+	maxDescriptionLength   = 100
+)
+
+var commandNameRegexp = regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+// interactionReplyTimeout is the time a command gets to reply to an interaction.
+// Afterwards the "thinking" response is removed.
+var interactionReplyTimeout = 1 * time.Minute
+
+type applicationCommandOption struct {
+	Type        int                        `json:"type"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Required    bool                       `json:"required,omitempty"`
+	Options     []applicationCommandOption `json:"options,omitempty"`
+}
+
+type applicationCommand struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Options     []applicationCommandOption `json:"options,omitempty"`
+}
+
+type interactionDataOption struct {
+	Name    string                  `json:"name"`
+	Type    int                     `json:"type"`
+	Value   interface{}             `json:"value"`
+	Options []interactionDataOption `json:"options"`
+}
+
+type interactionCreate struct {
+	ID        string `json:"id"`
+	Type      int    `json:"type"`
+	Token     string `json:"token"`
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User discordUser `json:"user"`
+		Nick string      `json:"nick"`
+	} `json:"member"`
+	User *discordUser `json:"user"` // User is only set for interactions in DMs
+	Data struct {
+		Name     string                  `json:"name"`
+		Options  []interactionDataOption `json:"options"`
+		CustomID string                  `json:"custom_id"`
+	} `json:"data"`
+}
+
+// pendingInteraction is an interaction which is waiting for the reply of the command.
+type pendingInteraction struct {
+	token string
+	timer *time.Timer
+}
+
+// description returns a valid description for an application command or option.
+func description(text string, fallback string) string {
+	if len(text) == 0 {
+		text = fallback
+	}
+	return truncate(text, maxDescriptionLength)
+}
+
+func optionType(t commanddispatcher.ArgType) int {
+	switch t {
+	case commanddispatcher.ArgInt:
+		return optionInteger
+	case commanddispatcher.ArgUser:
+		return optionUser
+	case commanddispatcher.ArgChannel:
+		return optionChannel
+	default:
+		return optionString
+	}
+}
+
+// argumentOptions converts the arguments and flags of the spec. Required options have to come first.
+func argumentOptions(spec commanddispatcher.CommandSpec) []applicationCommandOption {
+	var options []applicationCommandOption
+	for _, arg := range spec.Args {
+		options = append(options, applicationCommandOption{
+			Type:        optionType(arg.Type),
+			Name:        arg.Name,
+			Description: description("", arg.Type.String()),
+			Required:    !arg.Optional,
+		})
+	}
+	for _, flag := range spec.Flags {
+		option := applicationCommandOption{Type: optionType(flag.Type), Name: flag.Name, Description: description("", flag.Type.String())}
+		if flag.Bool {
+			option.Type = optionBoolean
+			option.Description = "flag"
+		}
+		options = append(options, option)
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Required && !options[j].Required })
+	return options
+}
+
+// subcommandOptions converts the subcommands of the spec. Discord allows only one level of subcommand groups.
+func subcommandOptions(spec commanddispatcher.CommandSpec, groups bool) []applicationCommandOption {
+	var options []applicationCommandOption
+	for _, sub := range spec.Subcommands {
+		option := applicationCommandOption{Name: sub.Command, Description: description(sub.Description, sub.Command)}
+		if groups && len(sub.Subcommands) > 0 {
+			option.Type = optionSubcommandGroup
+			option.Options = subcommandOptions(sub, false)
+		} else {
+			option.Type = optionSubcommand
+			option.Options = argumentOptions(sub)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+func validOptions(options []applicationCommandOption) bool {
+	if len(options) > maxCommandOptions {
+		return false
+	}
+	for _, option := range options {
+		if !commandNameRegexp.MatchString(option.Name) || !validOptions(option.Options) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertCommand converts a command of the dispatcher into an application command. Commands
+// without spec get one optional text option with all arguments.
+func convertCommand(cmd commanddispatcher.CommandInfo) applicationCommand {
+	converted := applicationCommand{Name: cmd.Name, Description: description(cmd.Help.Description, cmd.Name)}
+	switch {
+	case cmd.Spec == nil:
+		usage := "arguments"
+		if len(cmd.Help.Usage) > 0 {
+			usage = cmd.Help.Usage[0]
+		}
+		converted.Options = []applicationCommandOption{{Type: optionString, Name: "arguments", Description: description(usage, "arguments")}}
+	case len(cmd.Spec.Subcommands) > 0:
+		converted.Options = subcommandOptions(*cmd.Spec, true)
+	default:
+		converted.Options = argumentOptions(*cmd.Spec)
+	}
+	return converted
+}
+
+// applicationCommands returns the commands of the dispatcher as application commands.
+// Commands which cannot be represented as application command are skipped.
+func (b *Bot) applicationCommands() []applicationCommand {
+	commands := []applicationCommand{{
+		Name:        "help",
+		Description: "Lists the available commands or shows the help of a command",
+		Options:     []applicationCommandOption{{Type: optionString, Name: "command", Description: "command"}},
+	}}
+
+	for _, cmd := range b.Dispatcher.Commands() {
+		if cmd.Alias {
+			continue
+		}
+		converted := convertCommand(cmd)
+		if !commandNameRegexp.MatchString(converted.Name) || !validOptions(converted.Options) {
+			log.Warnf("Command %s cannot be registered as application command, skipping it", cmd.Name)
+			continue
+		}
+		if len(commands) == maxApplicationCommands {
+			log.Warnf("Discord supports only %d application commands, skipping the remaining ones", maxApplicationCommands)
+			break
+		}
+		commands = append(commands, converted)
+	}
+
+	return commands
+}
+
+// registerCommands replaces the application commands of the bot with the commands of the dispatcher.
+// Without guildID the commands are registered globally, otherwise only for the guild.
+func (b *Bot) registerCommands(guildID string) error {
+	path := "/applications/" + b.applicationID + "/commands"
+	if len(guildID) > 0 {
+		path = "/applications/" + b.applicationID + "/guilds/" + guildID + "/commands"
+	}
+
+	body, err := json.Marshal(b.applicationCommands())
+	if err != nil {
+		return errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.api.Call(interactionsAPIVersion+path, "PUT", string(body))
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("Registering application commands failed: %s", response.Body)
+	}
+
+	log.Debugf("Registered application commands at %s", path)
+	return nil
+}
+
+// quoteArgument quotes the value if needed, so that the command parser reads it as one argument.
+func quoteArgument(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, " \t\n\"'\\") && !strings.HasPrefix(value, "--") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func optionValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func findOption(options []interactionDataOption, name string) (interactionDataOption, bool) {
+	for _, option := range options {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return interactionDataOption{}, false
+}
+
+// argumentsContent converts the options of an interaction into the content of a command
+// according to the spec. Flags come first, because text arguments consume the rest of the content.
+func argumentsContent(spec commanddispatcher.CommandSpec, options []interactionDataOption) []string {
+	var parts []string
+	for _, flag := range spec.Flags {
+		option, ok := findOption(options, flag.Name)
+		if !ok {
+			continue
+		}
+		if flag.Bool {
+			if v, _ := option.Value.(bool); v {
+				parts = append(parts, "--"+flag.Name)
+			}
+			continue
+		}
+		parts = append(parts, "--"+flag.Name, argumentValue(flag.Type, option.Value))
+	}
+
+	for _, arg := range spec.Args {
+		option, ok := findOption(options, arg.Name)
+		if !ok {
+			continue
+		}
+		if arg.Type == commanddispatcher.ArgText {
+			parts = append(parts, optionValue(option.Value))
+		} else {
+			parts = append(parts, argumentValue(arg.Type, option.Value))
+		}
+	}
+	return parts
+}
+
+func argumentValue(t commanddispatcher.ArgType, value interface{}) string {
+	switch t {
+	case commanddispatcher.ArgUser:
+		return "<@" + optionValue(value) + ">"
+	case commanddispatcher.ArgChannel:
+		return "<#" + optionValue(value) + ">"
+	default:
+		return quoteArgument(optionValue(value))
+	}
+}
+
+// interactionContent converts an application command interaction into the command as it would be typed (without call prefix).
+func (b *Bot) interactionContent(data interactionCreate) string {
+	parts := []string{data.Data.Name}
+	options := data.Data.Options
+
+	var spec *commanddispatcher.CommandSpec
+	for _, cmd := range b.Dispatcher.Commands() {
+		if cmd.Name == data.Data.Name {
+			spec = cmd.Spec
+			break
+		}
+	}
+
+	for len(options) == 1 && (options[0].Type == optionSubcommand || options[0].Type == optionSubcommandGroup) {
+		parts = append(parts, options[0].Name)
+		if spec != nil {
+			var sub *commanddispatcher.CommandSpec
+			for i := range spec.Subcommands {
+				if spec.Subcommands[i].Command == options[0].Name {
+					sub = &spec.Subcommands[i]
+				}
+			}
+			spec = sub
+		}
+		options = options[0].Options
+	}
+
+	if spec == nil {
+		// Without spec all values are arguments of the command
+		for _, option := range options {
+			parts = append(parts, optionValue(option.Value))
+		}
+	} else {
+		parts = append(parts, argumentsContent(*spec, options)...)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (b *Bot) respondToInteraction(interaction interactionCreate) error {
+	response, err := b.api.Call(interactionsAPIVersion+"/interactions/"+interaction.ID+"/"+interaction.Token+"/callback", "POST", fmt.Sprintf(`{"type":%d}`, responseDeferredChannelMessage))
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("Responding to interaction failed: %s", response.Body)
+	}
+	return nil
+}
+
+// handleInteractionCreate dispatches application commands and button clicks like typed commands.
+// The interaction is acknowledged right away and the first reply of the command to the channel
+// is sent as response to the interaction.
+func (b *Bot) handleInteractionCreate(data json.RawMessage) {
+	var interaction interactionCreate
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		log.Errorln("UNHANDLED ERROR: INTERACTION_CREATE", err)
+		return
+	}
+
+	var content string
+	switch interaction.Type {
+	case interactionApplicationCommand:
+		content = b.interactionContent(interaction)
+	case interactionMessageComponent:
+		content = interaction.Data.CustomID
+	default:
+		log.Debugf("Ignoring interaction of type %d", interaction.Type)
+		return
+	}
+
+	post := model.Post{ServerID: interaction.GuildID, ChannelID: interaction.ChannelID, Content: b.Dispatcher.GetCallPrefix() + content}
+	if interaction.Member != nil {
+		post.User = convertUser(interaction.Member.User, interaction.Member.Nick)
+	} else if interaction.User != nil {
+		post.User = convertUser(*interaction.User, "")
+		post.IsPrivate = true
+	}
+
+	log.Tracef("Received: INTERACTION_CREATE from User = %s, Command = %s, ChannelID = %s", post.User.Name, content, post.ChannelID)
+
+	if err := b.respondToInteraction(interaction); err != nil {
+		log.Errorf("Could not respond to interaction: %s", err)
+		return
+	}
+	b.addPendingInteraction(interaction.ChannelID, interaction.Token)
+
+	b.Dispatcher.OnPost(post)
+}
+
+func (b *Bot) addPendingInteraction(channelID string, token string) {
+	b.interactionsMutex.Lock()
+	defer b.interactionsMutex.Unlock()
+
+	pending := &pendingInteraction{token: token}
+	pending.timer = time.AfterFunc(interactionReplyTimeout, func() {
+		if b.removePendingInteraction(channelID, pending) {
+			log.Debugf("No reply to interaction in channel %s, removing the response", channelID)
+			b.deleteInteractionResponse(token)
+		}
+	})
+	b.pendingInteractions[channelID] = append(b.pendingInteractions[channelID], pending)
+}
+
+// removePendingInteraction returns false if the interaction was not pending anymore.
+func (b *Bot) removePendingInteraction(channelID string, pending *pendingInteraction) bool {
+	b.interactionsMutex.Lock()
+	defer b.interactionsMutex.Unlock()
+
+	interactions := b.pendingInteractions[channelID]
+	for i := range interactions {
+		if interactions[i] == pending {
+			b.pendingInteractions[channelID] = append(interactions[:i:i], interactions[i+1:]...)
+			if len(b.pendingInteractions[channelID]) == 0 {
+				delete(b.pendingInteractions, channelID)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// takePendingInteraction returns the oldest interaction of the channel which waits for a reply.
+func (b *Bot) takePendingInteraction(channelID string) (*pendingInteraction, bool) {
+	b.interactionsMutex.Lock()
+	interactions := b.pendingInteractions[channelID]
+	b.interactionsMutex.Unlock()
+
+	for _, pending := range interactions {
+		if b.removePendingInteraction(channelID, pending) {
+			pending.timer.Stop()
+			return pending, true
+		}
+	}
+	return nil, false
+}
+
+func (b *Bot) webhookCall(path string, method string, msg *messageRequest) (messageObject, error) {
+	var body []byte
+	if msg != nil {
+		var err error
+		if body, err = json.Marshal(msg); err != nil {
+			return messageObject{}, errors.Wrap(err, "json marshal failed")
+		}
+	}
+
+	response, err := b.api.Call(interactionsAPIVersion+"/webhooks/"+b.applicationID+"/"+path, method, string(body))
+	if err != nil {
+		return messageObject{}, errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode >= 300 {
+		return messageObject{}, fmt.Errorf("Webhook call failed: %s", response.Body)
+	}
+	if msg == nil {
+		return messageObject{}, nil
+	}
+	return getMessageObject(response.Body)
+}
+
+func (b *Bot) deleteInteractionResponse(token string) {
+	if _, err := b.webhookCall(token+"/messages/@original", "DELETE", nil); err != nil {
+		log.Warnf("Could not delete interaction response: %s", err)
+	}
+}
+
+// replyToInteraction sends the message as response to the interaction. Private messages are
+// sent as ephemeral follow-up message, which only the user who used the command can see.
+func (b *Bot) replyToInteraction(pending *pendingInteraction, msg messageRequest, private bool) (messageObject, error) {
+	// Replies to interactions are not shown as replies to a message
+	msg.MessageReference = nil
+
+	if !private {
+		return b.webhookCall(pending.token+"/messages/@original", "PATCH", &msg)
+	}
+
+	msg.Flags = messageFlagEphemeral
+	mo, err := b.webhookCall(pending.token, "POST", &msg)
+	if err != nil {
+		return messageObject{}, err
+	}
+	b.deleteInteractionResponse(pending.token)
+	return mo, nil
+}
@@ -0,0 +1,223 @@
+package discord
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/webclient"
+	"github.com/torlenor/redseligg/ws"
+)
+
+// recordingAPI records all calls as "METHOD path body".
+type recordingAPI struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (a *recordingAPI) Call(path string, method string, body string) (webclient.APIResponse, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.calls = append(a.calls, method+" "+path+" "+body)
+	if path == "/gateway/bot" {
+		return webclient.APIResponse{StatusCode: 200, Body: []byte(`{"url": "wss://gateway"}`)}, nil
+	}
+	if method == "GET" && strings.HasPrefix(path, "/channels/") {
+		return webclient.APIResponse{StatusCode: 200, Body: []byte(`{"id":"CHANNEL","guild_id":"GUILD","name":"channel"}`)}, nil
+	}
+	return webclient.APIResponse{StatusCode: 200, Body: []byte(`{"id":"MESSAGE","channel_id":"CHANNEL"}`)}, nil
+}
+
+func (a *recordingAPI) takeCalls() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	calls := a.calls
+	a.calls = nil
+	return calls
+}
+
+type mockInteractionReceiver struct {
+	bot *Bot
+
+	lastCmd     string
+	lastContent string
+	lastArgs    commanddispatcher.Arguments
+	lastPost    model.Post
+	reply       model.Post
+}
+
+func (m *mockInteractionReceiver) OnCommand(cmd string, content string, post model.Post) {
+	m.lastCmd = cmd
+	m.lastContent = content
+	m.lastPost = post
+	if m.reply.Content != "" {
+		m.reply.ChannelID = post.ChannelID
+		m.bot.CreatePost(m.reply)
+	}
+}
+
+func (m *mockInteractionReceiver) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
+	m.lastArgs = args
+	m.OnCommand(cmd, args.Raw, post)
+}
+
+var testInteractionSpec = commanddispatcher.CommandSpec{
+	Command:     "timer",
+	Description: "Manages timers",
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command:     "add",
+			Description: "Adds a timer",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "interval", Type: commanddispatcher.ArgDuration},
+				{Name: "count", Type: commanddispatcher.ArgInt, Optional: true},
+				{Name: "message", Type: commanddispatcher.ArgText},
+			},
+			Flags: []commanddispatcher.FlagSpec{
+				{Name: "silent", Bool: true},
+				{Name: "user", Type: commanddispatcher.ArgUser},
+			},
+		},
+		{
+			Command: "admin",
+			Subcommands: []commanddispatcher.CommandSpec{
+				{Command: "clear"},
+			},
+		},
+	},
+}
+
+func createInteractionTestBot(t *testing.T, api *recordingAPI) *Bot {
+	bot, err := CreateDiscordBotWithAPI(api, &storage.MockStorage{}, commanddispatcher.New("!"), botconfig.DiscordConfig{ID: "APP", SlashCommands: "guild"}, &ws.MockClient{})
+	if err != nil {
+		t.Fatalf("Could not create bot: %s", err)
+	}
+	api.takeCalls()
+	return bot
+}
+
+func TestBot_applicationCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &recordingAPI{}
+	bot := createInteractionTestBot(t, api)
+
+	receiver := &mockInteractionReceiver{bot: bot}
+	bot.Dispatcher.RegisterSpec(testInteractionSpec, receiver)
+	bot.Dispatcher.Register("roll", receiver, commanddispatcher.CommandHelp{Description: "Rolls a dice", Usage: []string{"roll [max]"}})
+	bot.Dispatcher.Register("Invalid", receiver, commanddispatcher.CommandHelp{})
+
+	bot.handleGuildCreate(json.RawMessage(`{"id":"GUILD","name":"guild"}`))
+	calls := api.takeCalls()
+	assert.Equal(1, len(calls))
+	assert.Equal("PUT /v8/applications/APP/guilds/GUILD/commands "+
+		`[{"name":"help","description":"Lists the available commands or shows the help of a command","options":[{"type":3,"name":"command","description":"command"}]},`+
+		`{"name":"roll","description":"Rolls a dice","options":[{"type":3,"name":"arguments","description":"roll [max]"}]},`+
+		`{"name":"timer","description":"Manages timers","options":[`+
+		`{"type":1,"name":"add","description":"Adds a timer","options":[`+
+		`{"type":3,"name":"interval","description":"duration","required":true},`+
+		`{"type":3,"name":"message","description":"text","required":true},`+
+		`{"type":4,"name":"count","description":"int"},`+
+		`{"type":5,"name":"silent","description":"flag"},`+
+		`{"type":6,"name":"user","description":"user"}]},`+
+		`{"type":2,"name":"admin","description":"admin","options":[{"type":1,"name":"clear","description":"clear"}]}]}]`, calls[0])
+}
+
+func TestBot_handleInteractionCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &recordingAPI{}
+	bot := createInteractionTestBot(t, api)
+
+	receiver := &mockInteractionReceiver{bot: bot, reply: model.Post{Content: "Done"}}
+	bot.Dispatcher.RegisterSpec(testInteractionSpec, receiver)
+	bot.Dispatcher.Register("roll", receiver, commanddispatcher.CommandHelp{})
+
+	bot.handleEvent(event{Type: "INTERACTION_CREATE", RawData: json.RawMessage(`{"id":"ID","type":2,"token":"TOKEN","guild_id":"GUILD","channel_id":"CHANNEL",
+		"member":{"user":{"id":"USER","username":"user","discriminator":"1234"},"nick":"nick"},
+		"data":{"name":"timer","options":[{"name":"add","type":1,"options":[
+			{"name":"interval","type":3,"value":"1h"},
+			{"name":"message","type":3,"value":"Hello \"World\""},
+			{"name":"count","type":4,"value":3},
+			{"name":"silent","type":5,"value":true},
+			{"name":"user","type":6,"value":"OTHER"}]}]}}`)})
+
+	assert.Equal("timer", receiver.lastCmd)
+	assert.Equal("add", receiver.lastArgs.Subcommand)
+	assert.Equal(time.Hour, receiver.lastArgs.Duration("interval"))
+	assert.Equal(3, receiver.lastArgs.Int("count"))
+	assert.Equal(`Hello "World"`, receiver.lastArgs.String("message"))
+	assert.True(receiver.lastArgs.Bool("silent"))
+	assert.Equal("OTHER", receiver.lastArgs.User("user").ID)
+	assert.Equal(model.User{ID: "USER", Name: "user#1234", Nickname: "nick"}, receiver.lastPost.User)
+	assert.Equal("GUILD", receiver.lastPost.ServerID)
+
+	// The reply edits the deferred response
+	assert.Equal([]string{
+		`POST /v8/interactions/ID/TOKEN/callback {"type":5}`,
+		`PATCH /v8/webhooks/APP/TOKEN/messages/@original {"content":"Done"}`,
+	}, api.takeCalls())
+
+	// Further posts are sent to the channel
+	bot.CreatePost(model.Post{ChannelID: "CHANNEL", Content: "Another"})
+	assert.Equal([]string{`POST /channels/CHANNEL/messages {"content":"Another"}`}, api.takeCalls())
+
+	// Private replies are ephemeral
+	receiver.reply = model.Post{Content: "Secret", IsPrivate: true}
+	bot.handleEvent(event{Type: "INTERACTION_CREATE", RawData: json.RawMessage(`{"id":"ID","type":3,"token":"TOKEN","channel_id":"CHANNEL",
+		"member":{"user":{"id":"USER","username":"user","discriminator":"1234"}},
+		"data":{"custom_id":"roll 6"}}`)})
+	assert.Equal("roll", receiver.lastCmd)
+	assert.Equal("6", receiver.lastContent)
+	assert.Equal([]string{
+		`POST /v8/interactions/ID/TOKEN/callback {"type":5}`,
+		`POST /v8/webhooks/APP/TOKEN {"content":"Secret","flags":64}`,
+		`DELETE /v8/webhooks/APP/TOKEN/messages/@original `,
+	}, api.takeCalls())
+
+	// Without reply the deferred response is removed
+	oldTimeout := interactionReplyTimeout
+	interactionReplyTimeout = time.Millisecond
+	defer func() { interactionReplyTimeout = oldTimeout }()
+
+	receiver.reply = model.Post{}
+	bot.handleEvent(event{Type: "INTERACTION_CREATE", RawData: json.RawMessage(`{"id":"ID","type":2,"token":"TOKEN","channel_id":"DM",
+		"user":{"id":"USER","username":"user","discriminator":"1234"},
+		"data":{"name":"roll","options":[{"name":"arguments","type":3,"value":"20"}]}}`)})
+	assert.Equal("20", receiver.lastContent)
+	assert.True(receiver.lastPost.IsPrivate)
+
+	assert.Eventually(func() bool {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		return len(api.calls) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(`DELETE /v8/webhooks/APP/TOKEN/messages/@original `, api.takeCalls()[1])
+}
+
+func Test_quoteArgument(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "word", want: "word"},
+		{value: "", want: `""`},
+		{value: "two words", want: `"two words"`},
+		{value: `say "hi"`, want: `"say \"hi\""`},
+		{value: `back\slash`, want: `"back\\slash"`},
+		{value: "--flag", want: `"--flag"`},
+	}
+	for _, tt := range tests {
+		if got := quoteArgument(tt.value); got != tt.want {
+			t.Errorf("quoteArgument(%s) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
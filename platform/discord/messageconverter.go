@@ -1,9 +1,144 @@
 package discord
 
-import "strings"
+import (
+	"github.com/torlenor/redseligg/model"
+)
 
-func convertMessageFromRedseligg(text string) string {
-	n := strings.ReplaceAll(text, "\n", "\\n")
-	n = strings.ReplaceAll(n, `"`, `\"`)
-	return n
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type embedImage struct {
+	URL string `json:"url"`
+}
+
+type embedFooter struct {
+	Text string `json:"text"`
+}
+
+type embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Fields      []embedField `json:"fields,omitempty"`
+	Image       *embedImage  `json:"image,omitempty"`
+	Thumbnail   *embedImage  `json:"thumbnail,omitempty"`
+	Footer      *embedFooter `json:"footer,omitempty"`
+}
+
+type messageReference struct {
+	MessageID string `json:"message_id"`
+}
+
+// Component types and button styles
+const (
+	componentActionRow = 1
+	componentButton    = 2
+
+	buttonStylePrimary = 1
+	buttonStyleLink    = 5
+)
+
+// Discord allows up to 5 action rows with 5 buttons each.
+const (
+	maxActionRows        = 5
+	maxButtonsPerRow     = 5
+	maxCustomIDLength    = 100
+	maxButtonLabelLength = 80
+)
+
+// component is an action row or a button of a message.
+type component struct {
+	Type       int         `json:"type"`
+	Style      int         `json:"style,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	CustomID   string      `json:"custom_id,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	Components []component `json:"components,omitempty"`
+}
+
+// messageRequest is the body for creating and editing messages.
+type messageRequest struct {
+	Content          string            `json:"content"`
+	Embed            *embed            `json:"embed,omitempty"`
+	MessageReference *messageReference `json:"message_reference,omitempty"`
+	Components       []component       `json:"components,omitempty"`
+	Flags            int               `json:"flags,omitempty"`
+}
+
+func convertEmbedFromRedseligg(e model.Embed) *embed {
+	converted := &embed{
+		Title:       e.Title,
+		Description: e.Description,
+		URL:         e.URL,
+		Color:       e.Color,
+	}
+	for _, f := range e.Fields {
+		converted.Fields = append(converted.Fields, embedField{Name: f.Name, Value: f.Value, Inline: f.Inline})
+	}
+	if len(e.ImageURL) > 0 {
+		converted.Image = &embedImage{URL: e.ImageURL}
+	}
+	if len(e.ThumbnailURL) > 0 {
+		converted.Thumbnail = &embedImage{URL: e.ThumbnailURL}
+	}
+	if len(e.Footer) > 0 {
+		converted.Footer = &embedFooter{Text: e.Footer}
+	}
+	return converted
+}
+
+// convertMessageFromRedseligg converts a post into a Discord message. Discord supports only
+// one embed per message, all further embeds and attachments are added as Markdown to the content.
+func convertMessageFromRedseligg(post model.Post) messageRequest {
+	var msg messageRequest
+
+	additional := post
+	if len(post.Embeds) > 0 {
+		msg.Embed = convertEmbedFromRedseligg(post.Embeds[0])
+		additional.Embeds = post.Embeds[1:]
+	}
+	msg.Content = additional.Render(model.Markdown, true, true)
+
+	if len(post.ReplyTo) > 0 {
+		msg.MessageReference = &messageReference{MessageID: post.ReplyTo}
+	}
+
+	msg.Components = convertButtonsFromRedseligg(post.Buttons)
+
+	return msg
+}
+
+// convertButtonsFromRedseligg converts the buttons into action rows. The custom ID of a command
+// button is the command, so that clicking it can be dispatched like a typed command.
+func convertButtonsFromRedseligg(buttons []model.Button) []component {
+	var rows []component
+	for i, b := range buttons {
+		if i >= maxActionRows*maxButtonsPerRow {
+			log.Warnf("Discord supports only %d buttons per message, ignoring the remaining %d", maxActionRows*maxButtonsPerRow, len(buttons)-i)
+			break
+		}
+
+		button := component{Type: componentButton, Label: truncate(b.Label, maxButtonLabelLength)}
+		if len(b.URL) > 0 {
+			button.Style = buttonStyleLink
+			button.URL = b.URL
+		} else {
+			if len(b.Command) > maxCustomIDLength {
+				log.Warnf("Command of button '%s' is too long, ignoring the button", b.Label)
+				continue
+			}
+			button.Style = buttonStylePrimary
+			button.CustomID = b.Command
+		}
+
+		if len(rows) == 0 || len(rows[len(rows)-1].Components) == maxButtonsPerRow {
+			rows = append(rows, component{Type: componentActionRow})
+		}
+		rows[len(rows)-1].Components = append(rows[len(rows)-1].Components, button)
+	}
+	return rows
 }
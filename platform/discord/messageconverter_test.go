@@ -1,29 +1,57 @@
 package discord
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/model"
+)
 
 func Test_convertMessageFromRedseligg(t *testing.T) {
-	type args struct {
-		text string
-	}
 	tests := []struct {
 		name string
-		args args
+		post model.Post
 		want string
 	}{
 		{
 			name: "Convert a message with newlines",
-			args: args{
-				text: "Some\nText",
+			post: model.Post{Content: "Some\n\"Text\""},
+			want: `{"content":"Some\n\"Text\""}`,
+		},
+		{
+			name: "Convert a rich message with an embed and a reply",
+			post: model.Post{
+				Rich:    []model.Node{model.Text("Hello "), model.Bold(model.Text("World"))},
+				Embeds:  []model.Embed{{Title: "Title", Color: 0xff0000, Fields: []model.EmbedField{{Name: "a", Value: "b", Inline: true}}}},
+				ReplyTo: "123",
+			},
+			want: `{"content":"Hello **World**","embed":{"title":"Title","color":16711680,"fields":[{"name":"a","value":"b","inline":true}]},"message_reference":{"message_id":"123"}}`,
+		},
+		{
+			name: "Convert a message with attachment and two embeds",
+			post: model.Post{
+				Content:     "Text",
+				Embeds:      []model.Embed{{Title: "First"}, {Title: "Second"}},
+				Attachments: []model.Attachment{{Name: "file.png", URL: "https://example.com/file.png"}},
+			},
+			want: `{"content":"Text\n**Second**\n[file.png](https://example.com/file.png)","embed":{"title":"First"}}`,
+		},
+		{
+			name: "Convert a message with buttons",
+			post: model.Post{
+				Content: "Roll?",
+				Buttons: []model.Button{{Label: "Roll", Command: "roll 6"}, {Label: "Docs", URL: "https://example.com"}},
 			},
-			want: "Some\\nText",
+			want: `{"content":"Roll?","components":[{"type":1,"components":[{"type":2,"style":1,"label":"Roll","custom_id":"roll 6"},{"type":2,"style":5,"label":"Docs","url":"https://example.com"}]}]}`,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := convertMessageFromRedseligg(tt.args.text); got != tt.want {
-				t.Errorf("convertMessageFromRedseligg() = %v, want %v", got, tt.want)
-			}
+			got, err := json.Marshal(convertMessageFromRedseligg(tt.post))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
 		})
 	}
 }
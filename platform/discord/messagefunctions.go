@@ -2,7 +2,7 @@ package discord
 
 import (
 	"encoding/json"
-	"strconv"
+	"fmt"
 	"strings"
 	"time"
 
@@ -10,12 +10,6 @@ import (
 	"github.com/torlenor/redseligg/model"
 )
 
-type rateLimitResponse struct {
-	Global     bool   `json:"global"`
-	Message    string `json:"message"`
-	RetryAfter int    `json:"retry_after"`
-}
-
 type messageObject struct {
 	Nonce           interface{}   `json:"nonce"`
 	Attachments     []interface{} `json:"attachments"`
@@ -40,13 +34,13 @@ type messageObject struct {
 	Type         int           `json:"type"`
 }
 
-func (b *Bot) sendWhisper(snowflakeID string, content string) (messageObject, error) {
+func (b *Bot) sendWhisper(snowflakeID string, msg messageRequest) (messageObject, error) {
 	response, err := b.api.Call("/users/@me/channels", "POST", `{"recipient_id": "`+snowflakeID+`"}`)
 	if err != nil {
 		return messageObject{}, errors.Wrap(err, "apiCall failed")
 	}
-	if checkRateLimit(response.Body) > 0 {
-		return messageObject{}, errors.New("sending failed (create channel)")
+	if response.StatusCode >= 300 {
+		return messageObject{}, fmt.Errorf("sending failed (create channel): %s", response.Body)
 	}
 
 	var channelResponseData map[string]interface{}
@@ -61,10 +55,10 @@ func (b *Bot) sendWhisper(snowflakeID string, content string) (messageObject, er
 		return messageObject{}, errors.Wrap(err, "no valid channel id found")
 	}
 
-	return b.messageRunner(channelID, content)
+	return b.messageRunner(channelID, msg)
 }
 
-func (b *Bot) sendMessage(receiver string, content string) (messageObject, error) {
+func (b *Bot) sendMessage(receiver string, msg messageRequest) (messageObject, error) {
 	var channelID string
 
 	splitString := strings.Split(receiver, "#")
@@ -93,11 +87,11 @@ func (b *Bot) sendMessage(receiver string, content string) (messageObject, error
 		}
 	}
 
-	return b.messageRunner(channelID, content)
+	return b.messageRunner(channelID, msg)
 }
 
-func (b *Bot) updateMessage(messageIdent model.MessageIdentifier, content string) (messageObject, error) {
-	mo, err := b.updateRunner(messageIdent.Channel, messageIdent.ID, content)
+func (b *Bot) updateMessage(messageIdent model.MessageIdentifier, msg messageRequest) (messageObject, error) {
+	mo, err := b.updateRunner(messageIdent.Channel, messageIdent.ID, msg)
 	return mo, err
 }
 
@@ -105,75 +99,45 @@ func (b *Bot) deleteMessage(messageIdent model.MessageIdentifier) error {
 	return b.deleteRunner(messageIdent.Channel, messageIdent.ID)
 }
 
-func (b *Bot) messageRunner(channelID string, content string) (messageObject, error) {
-	for tries := 0; tries < 4; tries++ {
-		if tries > 3 {
-			return messageObject{}, errors.New("Message sending still failing after 3 tries, giving up")
-		}
-
-		response, err := b.api.Call("/channels/"+channelID+"/messages", "POST", `{"content": "`+convertMessageFromRedseligg(content)+`"}`)
-		if err != nil {
-			return messageObject{}, errors.Wrap(err, "apiCall failed")
-		}
-		var retryAfter int
-		if retryAfter = checkRateLimit(response.Body); retryAfter > 0 {
-			log.Warn("Sending failed because we are rate limited. Trying to resend after: " + strconv.Itoa(retryAfter))
-			time.Sleep(time.Duration(retryAfter) * time.Millisecond)
-			continue
-		}
-		log.Tracef("Sent: MESSAGE to ChannelID = %s, Content = %s", channelID, content)
-		return getMessageObject(response.Body)
+func (b *Bot) messageRunner(channelID string, msg messageRequest) (messageObject, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return messageObject{}, errors.Wrap(err, "json marshal failed")
 	}
 
-	return messageObject{}, nil
+	response, err := b.api.Call("/channels/"+channelID+"/messages", "POST", string(body))
+	if err != nil {
+		return messageObject{}, errors.Wrap(err, "apiCall failed")
+	}
+	log.Tracef("Sent: MESSAGE to ChannelID = %s, Content = %s", channelID, msg.Content)
+	return getMessageObject(response.Body)
 }
 
-func (b *Bot) updateRunner(channelID, messageID, content string) (messageObject, error) {
-	for tries := 0; tries < 4; tries++ {
-		if tries > 3 {
-			return messageObject{}, errors.New("Message update still failing after 3 tries, giving up")
-		}
-
-		response, err := b.api.Call("/channels/"+channelID+"/messages/"+messageID, "PATCH", `{"content": "`+convertMessageFromRedseligg(content)+`"}`)
-		if err != nil {
-			return messageObject{}, errors.Wrap(err, "apiCall failed")
-		}
-		var retryAfter int
-		if retryAfter = checkRateLimit(response.Body); retryAfter > 0 {
-			log.Warn("Sending failed because we are rate limited. Trying to resend after: " + strconv.Itoa(retryAfter))
-			time.Sleep(time.Duration(retryAfter) * time.Millisecond)
-			continue
-		}
-		log.Debugf("DiscordBot: Update MESSAGE in ChannelID = %s, MessageID = %s, Content = %s", channelID, messageID, content)
-		return getMessageObject(response.Body)
+func (b *Bot) updateRunner(channelID, messageID string, msg messageRequest) (messageObject, error) {
+	// Replies cannot be changed when editing a message
+	msg.MessageReference = nil
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return messageObject{}, errors.Wrap(err, "json marshal failed")
 	}
 
-	return messageObject{}, nil
+	response, err := b.api.Call("/channels/"+channelID+"/messages/"+messageID, "PATCH", string(body))
+	if err != nil {
+		return messageObject{}, errors.Wrap(err, "apiCall failed")
+	}
+	log.Debugf("DiscordBot: Update MESSAGE in ChannelID = %s, MessageID = %s, Content = %s", channelID, messageID, msg.Content)
+	return getMessageObject(response.Body)
 }
 
 func (b *Bot) deleteRunner(channelID string, messageID string) error {
-	for tries := 0; tries < 4; tries++ {
-		if tries > 3 {
-			return errors.New("Message delete still failing after 3 tries, giving up")
-		}
-
-		response, err := b.api.Call("/channels/"+channelID+"/messages/"+messageID, "DELETE", "")
-		if err != nil {
-			return errors.Wrap(err, "apiCall failed")
-		}
-		var retryAfter int
-		if retryAfter = checkRateLimit(response.Body); retryAfter > 0 {
-			log.Warn("Deleting failed because we are rate limited. Trying to resend after: " + strconv.Itoa(retryAfter))
-			time.Sleep(time.Duration(retryAfter) * time.Millisecond)
-			continue
-		}
-		log.Debugf("DiscordBot: Deleted MESSAGE from ChannelID = %s, MessageID = %s", channelID, messageID)
-		if response.StatusCode != 204 {
-			return errors.Wrap(err, "error deleting message")
-		}
-		return nil
+	response, err := b.api.Call("/channels/"+channelID+"/messages/"+messageID, "DELETE", "")
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
 	}
-
+	if response.StatusCode != 204 {
+		return fmt.Errorf("Error deleting message: %s", response.Body)
+	}
+	log.Debugf("DiscordBot: Deleted MESSAGE from ChannelID = %s, MessageID = %s", channelID, messageID)
 	return nil
 }
 
@@ -182,12 +146,3 @@ func getMessageObject(response []byte) (messageObject, error) {
 	err := json.Unmarshal(response, &messageObject)
 	return messageObject, err
 }
-
-func checkRateLimit(response []byte) int {
-	var rateLimited rateLimitResponse
-	err := json.Unmarshal(response, &rateLimited)
-	if err != nil {
-		return 0
-	}
-	return rateLimited.RetryAfter
-}
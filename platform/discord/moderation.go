@@ -0,0 +1,96 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxSlowModeInterval is the maximum rate limit per user Discord allows for a channel.
+const maxSlowModeInterval = 6 * time.Hour
+
+type banRequest struct {
+	DeleteMessageDays int    `json:"delete_message_days"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+type modifyChannelRequest struct {
+	RateLimitPerUser int `json:"rate_limit_per_user"`
+}
+
+// guildOfChannel returns the ID of the guild the channel belongs to.
+func (b *Bot) guildOfChannel(channelID string) (string, error) {
+	response, err := b.api.Call("/channels/"+channelID, "GET", "")
+	if err != nil {
+		return "", errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("Channel with ID %s not found: %s", channelID, response.Body)
+	}
+
+	var c channelCreate
+	if err := json.Unmarshal(response.Body, &c); err != nil {
+		return "", errors.Wrap(err, "json unmarshal failed")
+	}
+	if len(c.GuildID) == 0 {
+		return "", fmt.Errorf("Channel with ID %s does not belong to a guild", channelID)
+	}
+	return c.GuildID, nil
+}
+
+// TimeoutUser is not supported on Discord.
+func (b *Bot) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// BanUser bans the user from the guild the channel belongs to.
+func (b *Bot) BanUser(channelID string, userID string, reason string) error {
+	guildID, err := b.guildOfChannel(channelID)
+	if err != nil {
+		return fmt.Errorf("Could not ban user: %s", err)
+	}
+
+	body, err := json.Marshal(banRequest{Reason: reason})
+	if err != nil {
+		return errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.api.Call("/guilds/"+guildID+"/bans/"+userID, "PUT", string(body))
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("Could not ban user: %s", response.Body)
+	}
+	log.Debugf("DiscordBot: Banned UserID = %s from GuildID = %s", userID, guildID)
+	return nil
+}
+
+// ClearChat is not supported on Discord.
+func (b *Bot) ClearChat(channelID string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// SetSlowMode sets the rate limit per user of the channel.
+func (b *Bot) SetSlowMode(channelID string, interval time.Duration) error {
+	if interval < 0 || interval > maxSlowModeInterval {
+		return fmt.Errorf("Slow mode interval must be between 0 and %s", maxSlowModeInterval)
+	}
+
+	body, err := json.Marshal(modifyChannelRequest{RateLimitPerUser: int(interval / time.Second)})
+	if err != nil {
+		return errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.api.Call("/channels/"+channelID, "PATCH", string(body))
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("Could not set slow mode: %s", response.Body)
+	}
+	log.Debugf("DiscordBot: Set slow mode of ChannelID = %s to %s", channelID, interval)
+	return nil
+}
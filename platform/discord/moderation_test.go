@@ -0,0 +1,29 @@
+package discord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBot_Moderation(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &recordingAPI{}
+	bot := createInteractionTestBot(t, api)
+
+	assert.NoError(bot.BanUser("CHANNEL", "USER", "spam"))
+	assert.Equal([]string{
+		"GET /channels/CHANNEL ",
+		`PUT /guilds/GUILD/bans/USER {"delete_message_days":0,"reason":"spam"}`,
+	}, api.takeCalls())
+
+	assert.NoError(bot.SetSlowMode("CHANNEL", 30*time.Second))
+	assert.Equal([]string{`PATCH /channels/CHANNEL {"rate_limit_per_user":30}`}, api.takeCalls())
+	assert.Error(bot.SetSlowMode("CHANNEL", 7*time.Hour))
+
+	assert.Error(bot.TimeoutUser("CHANNEL", "USER", time.Minute, ""))
+	assert.Error(bot.ClearChat("CHANNEL"))
+	assert.Equal(0, len(api.takeCalls()))
+}
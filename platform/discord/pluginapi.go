@@ -9,30 +9,46 @@ import (
 
 var version string
 
-// GetUsers a list of users based on search options.
-func (b *Bot) GetUsers() ([]model.User, error) { return nil, nil }
+// GetUsers returns all users known from the guilds the bot is a member of.
+func (b *Bot) GetUsers() ([]model.User, error) { return b.directory.Users(), nil }
 
-// GetUser gets a user.
-func (b *Bot) GetUser(userID string) (model.User, error) { return model.User{}, nil }
+// GetUser gets a user. Users not seen on the gateway are requested from the Discord API.
+func (b *Bot) GetUser(userID string) (model.User, error) {
+	if user, err := b.directory.User(userID); err == nil {
+		return user, nil
+	}
+	return b.fetchUser(userID)
+}
 
-// GetUserByUsername gets a user by their username.
-func (b *Bot) GetUserByUsername(name string) (model.User, error) { return model.User{}, nil }
+// GetUserByUsername gets a user by their username, e.g., "name#1234".
+func (b *Bot) GetUserByUsername(name string) (model.User, error) {
+	return b.directory.UserByName(name)
+}
 
-// GetChannel gets a channel.
-func (b *Bot) GetChannel(channelID string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannel gets a channel. Channels not seen on the gateway are requested from the Discord API.
+func (b *Bot) GetChannel(channelID string) (model.Channel, error) {
+	if channel, err := b.directory.Channel(channelID); err == nil {
+		return channel, nil
+	}
+	return b.fetchChannel(channelID)
+}
 
-// GetChannelByName gets a channel by its name, given a team id.
-func (b *Bot) GetChannelByName(name string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannelByName gets a channel by its name.
+func (b *Bot) GetChannelByName(name string) (model.Channel, error) {
+	return b.directory.ChannelByName(name)
+}
 
 // CreatePost creates a post.
 func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
 	var mo messageObject
 	var err error
 
-	if post.IsPrivate {
-		mo, err = b.sendWhisper(post.User.ID, post.Content)
+	if pending, ok := b.takePendingInteraction(post.ChannelID); ok {
+		mo, err = b.replyToInteraction(pending, convertMessageFromRedseligg(post), post.IsPrivate)
+	} else if post.IsPrivate {
+		mo, err = b.sendWhisper(post.User.ID, convertMessageFromRedseligg(post))
 	} else {
-		mo, err = b.sendMessage(post.ChannelID, post.Content)
+		mo, err = b.sendMessage(post.ChannelID, convertMessageFromRedseligg(post))
 	}
 	if err != nil {
 		return model.PostResponse{}, fmt.Errorf("Error sending: %s", err)
@@ -45,7 +61,7 @@ func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
 
 // UpdatePost updates a post.
 func (b *Bot) UpdatePost(messageID model.MessageIdentifier, newPost model.Post) (model.PostResponse, error) {
-	mo, err := b.updateMessage(messageID, newPost.Content)
+	mo, err := b.updateMessage(messageID, convertMessageFromRedseligg(newPost))
 	if err != nil {
 		return model.PostResponse{}, fmt.Errorf("Error updating message: %s", err)
 	}
@@ -0,0 +1,274 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torlenor/redseligg/webclient"
+)
+
+// maxRateLimitRetries is the number of times a request is retried when it was rate limited nevertheless.
+const maxRateLimitRetries = 3
+
+var snowflakeRegexp = regexp.MustCompile(`^[0-9]{15,21}$`)
+
+type rateLimitResponse struct {
+	Global     bool    `json:"global"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"` // RetryAfter is given in milliseconds
+}
+
+// RateLimitStats contains statistics about the requests to the Discord REST API.
+type RateLimitStats struct {
+	Requests          uint64 // Requests is the number of requests sent to Discord
+	Delayed           uint64 // Delayed is the number of requests which waited for the reset of their bucket
+	RateLimited       uint64 // RateLimited is the number of requests Discord answered with 429 Too Many Requests
+	GlobalRateLimited uint64 // GlobalRateLimited is the number of requests which hit the global rate limit
+	Queued            int    // Queued is the number of requests currently waiting for their bucket
+	Buckets           int    // Buckets is the number of known rate limit buckets
+}
+
+func (s RateLimitStats) toString() string {
+	return fmt.Sprintf("Requests: %d\nDelayed: %d\nRate Limited: %d\nGlobal Rate Limited: %d\nQueued: %d\nBuckets: %d",
+		s.Requests, s.Delayed, s.RateLimited, s.GlobalRateLimited, s.Queued, s.Buckets)
+}
+
+// bucket holds the state of one rate limit bucket. Requests of the same bucket are queued
+// on its mutex, so that only one of them is in flight at a time.
+type bucket struct {
+	mutex     sync.Mutex
+	remaining int
+	reset     time.Time
+}
+
+// rateLimiter wraps the Discord REST API and keeps track of the rate limits given in the
+// X-RateLimit-* headers. Requests wait until their bucket or the global rate limit is reset
+// instead of being sent and rejected.
+type rateLimiter struct {
+	api api
+
+	mutex       sync.Mutex
+	routes      map[string]string  // [route]bucketID
+	buckets     map[string]*bucket // [bucketID]
+	globalReset time.Time
+	stats       RateLimitStats
+}
+
+func newRateLimiter(api api) *rateLimiter {
+	return &rateLimiter{
+		api:     api,
+		routes:  make(map[string]string),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// routeKey returns the route of the request. The IDs in the path are replaced, except for the
+// major parameters (channel, guild and webhook), because Discord has separate limits for them.
+func routeKey(method string, path string) string {
+	if idx := strings.Index(path, "?"); idx >= 0 {
+		path = path[:idx]
+	}
+
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		switch {
+		case parts[i-1] == "channels" || parts[i-1] == "guilds" || parts[i-1] == "webhooks":
+		case i > 1 && parts[i-2] == "webhooks":
+			// webhook token
+		case i > 1 && parts[i-2] == "interactions":
+			parts[i] = ":token"
+		case snowflakeRegexp.MatchString(parts[i]):
+			parts[i] = ":id"
+		}
+	}
+
+	return method + " " + strings.Join(parts, "/")
+}
+
+// majorParameters returns the major parameters of the route, e.g., "channels/123".
+func majorParameters(route string) string {
+	parts := strings.Split(route, "/")
+	var major []string
+	for i := 1; i < len(parts); i++ {
+		if parts[i-1] == "channels" || parts[i-1] == "guilds" || parts[i-1] == "webhooks" {
+			major = append(major, parts[i-1]+"/"+parts[i])
+		}
+	}
+	return strings.Join(major, "/")
+}
+
+// bucket returns the bucket of the route, new routes get their own bucket until Discord tells otherwise.
+func (r *rateLimiter) bucket(route string) *bucket {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id, ok := r.routes[route]
+	if !ok {
+		id = route
+		r.routes[route] = id
+	}
+	b, ok := r.buckets[id]
+	if !ok {
+		b = &bucket{remaining: 1}
+		r.buckets[id] = b
+	}
+	return b
+}
+
+// assignBucket moves the route to the bucket with the given hash. Routes with the same hash
+// and the same major parameters share their limits.
+func (r *rateLimiter) assignBucket(route string, hash string, b *bucket) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := hash + " " + majorParameters(route)
+	if r.routes[route] == id {
+		return
+	}
+	if r.routes[route] == route {
+		// The bucket of the route was not known before
+		delete(r.buckets, route)
+	}
+	r.routes[route] = id
+	if _, ok := r.buckets[id]; !ok {
+		r.buckets[id] = b
+	}
+}
+
+// waitForGlobal blocks until the global rate limit is reset.
+func (r *rateLimiter) waitForGlobal() {
+	r.mutex.Lock()
+	wait := time.Until(r.globalReset)
+	r.mutex.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func parseSeconds(value string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// retryAfter returns the time to wait after the request was rate limited and if the global limit was hit.
+func retryAfter(response webclient.APIResponse) (time.Duration, bool) {
+	global := response.Header.Get("X-RateLimit-Global") == "true"
+
+	if wait, ok := parseSeconds(response.Header.Get("Retry-After")); ok {
+		return wait, global
+	}
+
+	var rateLimited rateLimitResponse
+	if err := json.Unmarshal(response.Body, &rateLimited); err != nil {
+		return time.Second, global
+	}
+	return time.Duration(rateLimited.RetryAfter * float64(time.Millisecond)), global || rateLimited.Global
+}
+
+// update updates the bucket with the rate limit headers of the response.
+func (r *rateLimiter) update(route string, b *bucket, response webclient.APIResponse) {
+	if hash := response.Header.Get("X-RateLimit-Bucket"); len(hash) > 0 {
+		r.assignBucket(route, hash, b)
+	}
+	if remaining, err := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining")); err == nil {
+		b.remaining = remaining
+	}
+	if resetAfter, ok := parseSeconds(response.Header.Get("X-RateLimit-Reset-After")); ok {
+		b.reset = time.Now().Add(resetAfter)
+	}
+
+	if response.StatusCode != 429 {
+		return
+	}
+
+	wait, global := retryAfter(response)
+	r.mutex.Lock()
+	r.stats.RateLimited++
+	if global {
+		r.stats.GlobalRateLimited++
+		r.globalReset = time.Now().Add(wait)
+	}
+	r.mutex.Unlock()
+
+	if !global {
+		b.remaining = 0
+		b.reset = time.Now().Add(wait)
+	}
+	log.Warnf("Rate limited on %s (global: %t), retrying after %s", route, global, wait)
+}
+
+// do sends one request as soon as the bucket and the global rate limit allow it.
+func (r *rateLimiter) do(route string, path string, method string, body string) (webclient.APIResponse, error) {
+	b := r.bucket(route)
+
+	r.mutex.Lock()
+	r.stats.Queued++
+	r.mutex.Unlock()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	r.mutex.Lock()
+	r.stats.Queued--
+	r.mutex.Unlock()
+
+	if wait := time.Until(b.reset); b.remaining <= 0 && wait > 0 {
+		r.mutex.Lock()
+		r.stats.Delayed++
+		r.mutex.Unlock()
+		log.Debugf("Bucket of %s exhausted, waiting %s", route, wait)
+		time.Sleep(wait)
+	}
+	r.waitForGlobal()
+
+	r.mutex.Lock()
+	r.stats.Requests++
+	r.mutex.Unlock()
+
+	response, err := r.api.Call(path, method, body)
+	if err != nil {
+		return response, err
+	}
+
+	r.update(route, b, response)
+	if b.remaining <= 0 && time.Now().After(b.reset) {
+		// The bucket is reset or unknown, e.g., for routes without rate limit headers
+		b.remaining = 1
+	}
+
+	return response, nil
+}
+
+// Call sends the request to the Discord REST API. Requests which are rate limited nevertheless are retried.
+func (r *rateLimiter) Call(path string, method string, body string) (webclient.APIResponse, error) {
+	route := routeKey(method, path)
+
+	for tries := 0; ; tries++ {
+		response, err := r.do(route, path, method, body)
+		if err != nil || response.StatusCode != 429 {
+			return response, err
+		}
+		if tries >= maxRateLimitRetries {
+			return response, fmt.Errorf("Still rate limited after %d retries", maxRateLimitRetries)
+		}
+	}
+}
+
+// Stats returns the current statistics of the rate limiter.
+func (r *rateLimiter) Stats() RateLimitStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := r.stats
+	stats.Buckets = len(r.buckets)
+	return stats
+}
@@ -0,0 +1,126 @@
+package discord
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/webclient"
+)
+
+// scriptedAPI returns the given responses in order and an empty response afterwards.
+type scriptedAPI struct {
+	mutex     sync.Mutex
+	responses []webclient.APIResponse
+	calls     []time.Time
+}
+
+func (a *scriptedAPI) Call(path string, method string, body string) (webclient.APIResponse, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.calls = append(a.calls, time.Now())
+	if len(a.responses) == 0 {
+		return webclient.APIResponse{StatusCode: 200}, nil
+	}
+	response := a.responses[0]
+	a.responses = a.responses[1:]
+	return response, nil
+}
+
+func rateLimitResponseWith(statusCode int, headers map[string]string) webclient.APIResponse {
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	return webclient.APIResponse{StatusCode: statusCode, Header: header}
+}
+
+func Test_routeKey(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: "POST", path: "/channels/123456789012345678/messages", want: "POST /channels/123456789012345678/messages"},
+		{method: "DELETE", path: "/channels/123456789012345678/messages/223456789012345678", want: "DELETE /channels/123456789012345678/messages/:id"},
+		{method: "GET", path: "/users/123456789012345678", want: "GET /users/:id"},
+		{method: "GET", path: "/gateway/bot?v=6", want: "GET /gateway/bot"},
+		{method: "PATCH", path: "/v8/webhooks/123456789012345678/TOKEN/messages/@original", want: "PATCH /v8/webhooks/123456789012345678/TOKEN/messages/@original"},
+		{method: "POST", path: "/v8/interactions/123456789012345678/TOKEN/callback", want: "POST /v8/interactions/:id/:token/callback"},
+	}
+	for _, tt := range tests {
+		if got := routeKey(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeKey(%s, %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &scriptedAPI{responses: []webclient.APIResponse{
+		rateLimitResponseWith(200, map[string]string{"X-RateLimit-Bucket": "abc", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset-After": "0.1"}),
+	}}
+	limiter := newRateLimiter(api)
+
+	// The exhausted bucket delays the next request until it is reset
+	_, err := limiter.Call("/channels/1/messages", "POST", "")
+	assert.NoError(err)
+	_, err = limiter.Call("/channels/1/messages", "POST", "")
+	assert.NoError(err)
+	assert.True(api.calls[1].Sub(api.calls[0]) >= 90*time.Millisecond)
+
+	// Other channels have their own limits
+	api.calls = nil
+	api.responses = []webclient.APIResponse{
+		rateLimitResponseWith(200, map[string]string{"X-RateLimit-Bucket": "abc", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset-After": "1"}),
+	}
+	_, err = limiter.Call("/channels/2/messages", "POST", "")
+	assert.NoError(err)
+	_, err = limiter.Call("/channels/1/messages", "POST", "")
+	assert.NoError(err)
+	assert.True(api.calls[1].Sub(api.calls[0]) < 500*time.Millisecond)
+
+	stats := limiter.Stats()
+	assert.Equal(RateLimitStats{Requests: 4, Delayed: 1, Buckets: 2}, stats)
+
+	// Rate limited requests are retried after the given time
+	api.calls = nil
+	api.responses = []webclient.APIResponse{
+		rateLimitResponseWith(429, map[string]string{"Retry-After": "0.05"}),
+	}
+	response, err := limiter.Call("/users/1", "GET", "")
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal(2, len(api.calls))
+	assert.True(api.calls[1].Sub(api.calls[0]) >= 40*time.Millisecond)
+
+	// The global rate limit delays all requests
+	api.calls = nil
+	api.responses = []webclient.APIResponse{
+		{StatusCode: 429, Header: http.Header{"X-Ratelimit-Global": []string{"true"}}, Body: []byte(`{"global":true,"message":"You are being rate limited.","retry_after":50}`)},
+	}
+	_, err = limiter.Call("/channels/3/messages", "POST", "")
+	assert.NoError(err)
+	_, err = limiter.Call("/users/2", "GET", "")
+	assert.NoError(err)
+	assert.Equal(3, len(api.calls))
+	assert.True(api.calls[1].Sub(api.calls[0]) >= 40*time.Millisecond)
+
+	stats = limiter.Stats()
+	assert.Equal(uint64(2), stats.RateLimited)
+	assert.Equal(uint64(1), stats.GlobalRateLimited)
+	assert.Equal(0, stats.Queued)
+
+	// Requests which are still rate limited fail eventually
+	api.calls = nil
+	for i := 0; i <= maxRateLimitRetries; i++ {
+		api.responses = append(api.responses, rateLimitResponseWith(429, map[string]string{"Retry-After": "0"}))
+	}
+	_, err = limiter.Call("/users/3", "GET", "")
+	assert.Error(err)
+	assert.Equal(maxRateLimitRetries+1, len(api.calls))
+}
@@ -0,0 +1,365 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/torlenor/redseligg/utils"
+)
+
+// Gateway opcodes
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// Gateway close codes which need special handling
+const (
+	closeAuthenticationFailed = 4004
+	closeInvalidSeq           = 4007
+	closeSessionTimedOut      = 4009
+	closeInvalidShard         = 4010
+	closeShardingRequired     = 4011
+	closeInvalidAPIVersion    = 4012
+	closeInvalidIntents       = 4013
+	closeDisallowedIntents    = 4014
+)
+
+var (
+	// identifyInterval is the time between two identifies of the shards of a bot.
+	identifyInterval = 5 * time.Second
+	// invalidSessionWait returns the time to wait before identifying again after the session was invalidated.
+	invalidSessionWait = func() time.Duration { return time.Duration(1000+rand.Intn(4000)) * time.Millisecond }
+
+	// The time to wait before reconnecting after a failed connection doubles with every failure.
+	minReconnectWait = 1 * time.Second
+	maxReconnectWait = 2 * time.Minute
+)
+
+// errReconnect is returned when the gateway asks for an immediate reconnect.
+var errReconnect = errors.New("Reconnect requested by Discord Gateway")
+
+// fatalError is returned when the gateway closed the connection and reconnecting would not help.
+type fatalError struct {
+	err error
+}
+
+func (e fatalError) Error() string { return e.err.Error() }
+
+// The shard struct holds one connection to the Discord Gateway. Each shard receives the events
+// of its part of the guilds. If the connection is lost, the session is resumed if possible.
+type shard struct {
+	bot *Bot
+
+	id    int
+	count int
+
+	ws       webSocketClient
+	inflator *inflator // inflator is nil if compression is disabled
+
+	sessionID        string
+	currentSeqNumber int
+
+	heartBeatStopChan chan bool
+	seqNumberChan     chan int
+	watchdog          *utils.Watchdog // watchdog is replaced for each connection
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newShard(bot *Bot, id int, count int, ws webSocketClient) *shard {
+	return &shard{
+		bot:   bot,
+		id:    id,
+		count: count,
+		ws:    ws,
+
+		seqNumberChan: make(chan int),
+
+		done: make(chan struct{}),
+	}
+}
+
+// start connects the shard with a new session. A stopped shard can be started again.
+func (s *shard) start() {
+	s.done = make(chan struct{})
+	s.resetSession()
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+// stop closes the connection. The session is not resumed afterwards.
+func (s *shard) stop() {
+	if s.stopped() {
+		return
+	}
+	close(s.done)
+
+	if err := s.sendClose(websocket.CloseNormalClosure); err != nil {
+		log.Debugf("Shard %d: Error when writing close message to ws: %s", s.id, err)
+	}
+	s.ws.Close()
+
+	s.wg.Wait()
+}
+
+func (s *shard) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// run keeps the shard connected until it is stopped or a fatal error occurs.
+func (s *shard) run() {
+	defer s.wg.Done()
+
+	wait := minReconnectWait
+	for {
+		err := s.connect()
+		if err == nil {
+			wait = minReconnectWait
+			err = s.handleEvents()
+			s.disconnect()
+		} else {
+			s.ws.Close()
+		}
+
+		if s.stopped() {
+			return
+		}
+
+		if _, ok := err.(fatalError); ok {
+			log.Errorf("Shard %d: %s, not reconnecting", s.id, err)
+			return
+		}
+
+		if err == errReconnect {
+			log.Debugf("Shard %d: %s", s.id, err)
+			continue
+		}
+
+		log.Warnf("Shard %d: %s, reconnecting in %s", s.id, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-s.done:
+			return
+		}
+		wait *= 2
+		if wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+	}
+}
+
+// connect dials the gateway, identifies or resumes the session and starts the heartbeat.
+func (s *shard) connect() error {
+	if err := s.ws.Dial(gatewayConnectURL(s.bot.gatewayURL, s.bot.compress)); err != nil {
+		return fmt.Errorf("Could not dial Discord Gateway: %s", err)
+	}
+	if s.bot.compress {
+		s.inflator = &inflator{}
+	} else {
+		s.inflator = nil
+	}
+
+	// WS: 1 - 10 HELLO
+	message, err := s.readMessage()
+	if err != nil {
+		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: %s", err)
+	}
+
+	var data event
+	if err := json.Unmarshal(message, &data); err != nil {
+		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not unmarshal event: %s", err)
+	}
+
+	if data.Op != opHello {
+		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Did not receive a HELLO, but OP Code %d", data.Op)
+	}
+
+	var helloEvent hello
+	if err := json.Unmarshal(data.RawData, &helloEvent); err != nil {
+		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not unmarshal HELLO event: %s", err)
+	}
+
+	// Perform IDENT/RESUME
+	if s.sessionID == "" {
+		if !s.bot.waitForIdentify(s.done) {
+			return fmt.Errorf("Shard stopped before identifying")
+		}
+		err = sendIdent(s.bot.token, s.id, s.count, s.ws)
+	} else {
+		err = sendResume(s.bot.token, s.sessionID, s.currentSeqNumber, s.ws)
+	}
+	if err != nil {
+		return fmt.Errorf("Error occurred during initial communication with Discord Gateway: Could not send IDENT/RESUME: %s", err)
+	}
+
+	s.startHeartbeatSender(time.Duration(helloEvent.HeartbeatInterval) * time.Millisecond)
+
+	return nil
+}
+
+// disconnect stops the heartbeat and closes the connection. The session is kept, so that it can be resumed.
+func (s *shard) disconnect() {
+	s.watchdog.Stop()
+	close(s.heartBeatStopChan)
+
+	if !s.stopped() {
+		code := websocket.CloseServiceRestart
+		if s.sessionID == "" {
+			code = websocket.CloseNormalClosure
+		}
+		if err := s.sendClose(code); err != nil {
+			log.Tracef("Shard %d: Error when writing close message to ws: %s", s.id, err)
+		}
+	}
+	s.ws.Close()
+}
+
+// reconnect closes the connection when it failed, e.g., heartbeats are not acknowledged.
+// The session is resumed with a new connection.
+func (s *shard) reconnect() {
+	log.Warnf("Shard %d: Connection to Discord Gateway failed, reconnecting", s.id)
+	s.sendClose(websocket.CloseServiceRestart)
+	s.ws.Close()
+}
+
+func (s *shard) sendClose(code int) error {
+	return s.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
+}
+
+func (s *shard) startHeartbeatSender(heartbeatInterval time.Duration) {
+	s.heartBeatStopChan = make(chan bool)
+
+	stop := s.heartBeatStopChan
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		heartBeat(heartbeatInterval, newHeartbeatSender(s.ws), stop, s.seqNumberChan, s.reconnect)
+	}()
+	s.watchdog = &utils.Watchdog{}
+	s.watchdog.SetFailCallback(s.reconnect).Start(2 * heartbeatInterval)
+}
+
+// readMessage reads the next complete message from the gateway.
+func (s *shard) readMessage() ([]byte, error) {
+	for {
+		_, message, err := s.ws.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if s.inflator == nil {
+			return message, nil
+		}
+
+		data, complete, err := s.inflator.inflate(message)
+		if err != nil {
+			return nil, err
+		}
+		if complete {
+			return data, nil
+		}
+	}
+}
+
+// handleEvents handles the events received from the gateway until the connection ends.
+func (s *shard) handleEvents() error {
+	for {
+		message, err := s.readMessage()
+		if err != nil {
+			if s.stopped() {
+				return nil
+			}
+			return s.connectionError(err)
+		}
+
+		var data event
+		if err := json.Unmarshal(message, &data); err != nil {
+			log.Warnf("Shard %d: Could not unmarshal event from Discord Gateway: %s", s.id, err)
+			continue
+		}
+
+		switch data.Op {
+		case opDispatch:
+			s.currentSeqNumber = int(data.Seq)
+			s.seqNumberChan <- s.currentSeqNumber
+			s.handleDispatch(data)
+		case opHeartbeat: // The gateway requests a heartbeat immediately
+			if err := newHeartbeatSender(s.ws).sendHeartBeat(s.currentSeqNumber); err != nil {
+				return fmt.Errorf("Could not send requested heartbeat: %s", err)
+			}
+		case opReconnect: // Reconnect and resume
+			return errReconnect
+		case opInvalidSession:
+			var resumable bool
+			json.Unmarshal(data.RawData, &resumable)
+			if !resumable {
+				log.Warnf("Shard %d: Invalid Session received, identifying again", s.id)
+				s.resetSession()
+				select {
+				case <-time.After(invalidSessionWait()):
+				case <-s.done:
+					return nil
+				}
+			} else {
+				log.Warnf("Shard %d: Invalid Session received, resuming", s.id)
+			}
+			return errReconnect
+		case opHeartbeatACK:
+			s.watchdog.Feed()
+		default:
+			log.Warnf("Shard %d: Unknown Op Code %d received, data: %v", s.id, data.Op, data)
+		}
+	}
+}
+
+// connectionError returns the error for a lost connection. If the session cannot be resumed it is reset.
+func (s *shard) connectionError(err error) error {
+	closeErr, ok := err.(*websocket.CloseError)
+	if !ok {
+		return fmt.Errorf("Connection to Discord Gateway lost: %s", err)
+	}
+
+	switch closeErr.Code {
+	case closeAuthenticationFailed, closeInvalidShard, closeShardingRequired,
+		closeInvalidAPIVersion, closeInvalidIntents, closeDisallowedIntents:
+		return fatalError{fmt.Errorf("Discord Gateway closed the connection: %s", closeErr)}
+	case closeInvalidSeq, closeSessionTimedOut:
+		s.resetSession()
+	}
+
+	return fmt.Errorf("Discord Gateway closed the connection: %s", closeErr)
+}
+
+func (s *shard) resetSession() {
+	s.sessionID = ""
+	s.currentSeqNumber = 0
+}
+
+func (s *shard) handleDispatch(data event) {
+	switch data.Type {
+	case "READY":
+		s.sessionID = s.bot.handleReady(data.RawData)
+		log.Infof("Shard %d/%d: Session started", s.id, s.count)
+	case "RESUMED":
+		log.Infof("Shard %d/%d: Session resumed", s.id, s.count)
+	default:
+		s.bot.handleEvent(data)
+	}
+}
@@ -0,0 +1,261 @@
+package discord
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/webclient"
+)
+
+type gatewayFrame struct {
+	messageType int
+	data        []byte
+	err         error
+}
+
+// scriptedWebSocket is a WebSocket client which receives the frames given to receive and
+// reports the sent messages on sent.
+type scriptedWebSocket struct {
+	mutex  sync.Mutex
+	closed chan struct{}
+
+	receive chan gatewayFrame
+	sent    chan string
+	dials   chan string
+}
+
+func newScriptedWebSocket() *scriptedWebSocket {
+	return &scriptedWebSocket{
+		receive: make(chan gatewayFrame, 10),
+		sent:    make(chan string, 100),
+		dials:   make(chan string, 10),
+	}
+}
+
+func (c *scriptedWebSocket) Dial(wsURL string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = make(chan struct{})
+	c.dials <- wsURL
+	return nil
+}
+
+func (c *scriptedWebSocket) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed != nil {
+		close(c.closed)
+		c.closed = nil
+	}
+	return nil
+}
+
+func (c *scriptedWebSocket) ReadMessage() (int, []byte, error) {
+	c.mutex.Lock()
+	closed := c.closed
+	c.mutex.Unlock()
+	if closed == nil {
+		return 0, nil, fmt.Errorf("not connected")
+	}
+
+	select {
+	case frame := <-c.receive:
+		return frame.messageType, frame.data, frame.err
+	case <-closed:
+		return 0, nil, fmt.Errorf("use of closed network connection")
+	}
+}
+
+func (c *scriptedWebSocket) SendMessage(messageType int, data []byte) error {
+	if messageType == websocket.TextMessage {
+		c.sent <- string(data)
+	}
+	return nil
+}
+
+func (c *scriptedWebSocket) SendJSONMessage(v interface{}) error { return nil }
+
+func (c *scriptedWebSocket) receiveText(data string) {
+	c.receive <- gatewayFrame{messageType: websocket.TextMessage, data: []byte(data)}
+}
+
+func (c *scriptedWebSocket) expectSent(t *testing.T, contains string) {
+	select {
+	case message := <-c.sent:
+		if !strings.Contains(message, contains) {
+			t.Fatalf("Expected message containing %s, got %s", contains, message)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected message containing %s, got nothing", contains)
+	}
+}
+
+func (c *scriptedWebSocket) expectDial(t *testing.T) string {
+	select {
+	case url := <-c.dials:
+		return url
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a dial to the gateway")
+	}
+	return ""
+}
+
+const testHello = `{"op":10,"d":{"heartbeat_interval":45000}}`
+
+func createTestBot(t *testing.T, cfg botconfig.DiscordConfig, ws webSocketClient) *Bot {
+	identifyInterval = 0
+	invalidSessionWait = func() time.Duration { return 0 }
+	minReconnectWait = time.Millisecond
+
+	api := webclient.NewMock()
+	api.ReturnOnCall = webclient.APIResponse{Body: []byte(`{"url": "wss://gateway", "shards": 1}`)}
+	bot, err := CreateDiscordBotWithAPI(api, &storage.MockStorage{}, commanddispatcher.New(""), cfg, ws)
+	if err != nil {
+		t.Fatalf("Could not create bot: %s", err)
+	}
+	return bot
+}
+
+func TestShardResume(t *testing.T) {
+	ws := newScriptedWebSocket()
+	bot := createTestBot(t, botconfig.DiscordConfig{Token: "TOKEN"}, ws)
+
+	bot.start()
+	defer bot.stop()
+
+	if url := ws.expectDial(t); url != "wss://gateway?encoding=json&v=6" {
+		t.Fatalf("Wrong gateway URL %s", url)
+	}
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"shard": [0, 1]`)
+
+	ws.receiveText(`{"op":0,"s":1,"t":"READY","d":{"session_id":"SESSION","user":{"id":"123"}}}`)
+	ws.receiveText(`{"op":0,"s":2,"t":"TYPING_START","d":{}}`)
+
+	// Heartbeat requested by the gateway
+	ws.receiveText(`{"op":1}`)
+	ws.expectSent(t, `{"op":1,"d":2}`)
+
+	// Reconnect requested by the gateway resumes the session
+	ws.receiveText(`{"op":7}`)
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"session_id": "SESSION"`)
+
+	// Lost connections are resumed, too
+	ws.receive <- gatewayFrame{err: &websocket.CloseError{Code: 4000, Text: "Unknown error"}}
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"seq": 2`)
+
+	// Resumable invalid session
+	ws.receiveText(`{"op":9,"d":true}`)
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"session_id": "SESSION"`)
+
+	// Invalid session which cannot be resumed
+	ws.receiveText(`{"op":9,"d":false}`)
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"op": 2`)
+
+	if bot.shards[0].sessionID != "" {
+		t.Fatalf("Session not reset")
+	}
+}
+
+func TestShardRestart(t *testing.T) {
+	ws := newScriptedWebSocket()
+	bot := createTestBot(t, botconfig.DiscordConfig{Token: "TOKEN"}, ws)
+
+	bot.start()
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"op": 2`)
+	ws.receiveText(`{"op":0,"s":1,"t":"READY","d":{"session_id":"SESSION","user":{"id":"123"}}}`)
+	bot.stop()
+
+	// A restarted bot connects again with a new session
+	bot.start()
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"op": 2`)
+	bot.stop()
+
+	// Stopping a stopped bot does nothing
+	bot.stop()
+}
+
+func TestShardFatalClose(t *testing.T) {
+	ws := newScriptedWebSocket()
+	bot := createTestBot(t, botconfig.DiscordConfig{Token: "TOKEN"}, ws)
+
+	bot.start()
+
+	ws.expectDial(t)
+	ws.receiveText(testHello)
+	ws.expectSent(t, `"op": 2`)
+
+	ws.receive <- gatewayFrame{err: &websocket.CloseError{Code: closeAuthenticationFailed, Text: "Authentication failed."}}
+
+	done := make(chan struct{})
+	go func() {
+		bot.shards[0].wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Shard still running after fatal close code")
+	}
+	select {
+	case <-ws.dials:
+		t.Fatalf("Shard reconnected after fatal close code")
+	default:
+	}
+
+	bot.stop()
+}
+
+func TestShardsCompressed(t *testing.T) {
+	ws := newScriptedWebSocket()
+	secondWS := newScriptedWebSocket()
+	oldNewWebSocketClient := newWebSocketClient
+	newWebSocketClient = func() webSocketClient { return secondWS }
+	defer func() { newWebSocketClient = oldNewWebSocketClient }()
+
+	bot := createTestBot(t, botconfig.DiscordConfig{Token: "TOKEN", Shards: 2, Compress: true}, ws)
+	if len(bot.shards) != 2 {
+		t.Fatalf("Wrong number of shards %d", len(bot.shards))
+	}
+
+	bot.start()
+	defer bot.stop()
+
+	for i, client := range []*scriptedWebSocket{ws, secondWS} {
+		if url := client.expectDial(t); url != "wss://gateway?compress=zlib-stream&encoding=json&v=6" {
+			t.Fatalf("Wrong gateway URL %s", url)
+		}
+
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		w.Write([]byte(testHello))
+		w.Flush()
+		data := compressed.Bytes()
+		client.receive <- gatewayFrame{messageType: websocket.BinaryMessage, data: data[:5]}
+		client.receive <- gatewayFrame{messageType: websocket.BinaryMessage, data: data[5:]}
+
+		client.expectSent(t, fmt.Sprintf(`"shard": [%d, 2]`, i))
+	}
+}
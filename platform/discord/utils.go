@@ -3,3 +3,12 @@ package discord
 func combineUsernameAndDiscriminator(username, discriminator string) string {
 	return username + "#" + discriminator
 }
+
+// truncate shortens the text to at most maxLength characters.
+func truncate(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength])
+}
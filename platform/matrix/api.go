@@ -19,7 +19,9 @@ type api interface {
 
 	updateAuthToken(token string)
 
-	login(username string, password string) error
+	// login logs in the user with the device, or a new device if deviceID is empty. It returns
+	// the full user ID, e.g., @bot:matrix.org, and the device ID.
+	login(username string, password string, deviceID string) (loginResponse, error)
 }
 
 type matrixAPI struct {
@@ -43,6 +45,7 @@ func (api *matrixAPI) call(path string, method string, body string, auth bool) (
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
 	return ioutil.ReadAll(response.Body)
 }
@@ -58,22 +61,45 @@ type loginResponse struct {
 	DeviceID    string `json:"device_id"`
 }
 
-func (api *matrixAPI) login(username string, password string) error {
-	// get login server
-	response, err := api.call("/client/r0/login", "POST", `{"type":"m.login.password", "user":"`+username+`", "password":"`+password+`"}`, false)
+type whoamiResponse struct {
+	UserID   string `json:"user_id"`
+	DeviceID string `json:"device_id"`
+}
+
+type loginRequest struct {
+	Type                     string `json:"type"`
+	User                     string `json:"user"`
+	Password                 string `json:"password"`
+	DeviceID                 string `json:"device_id,omitempty"`
+	InitialDeviceDisplayName string `json:"initial_device_display_name,omitempty"`
+}
+
+func (api *matrixAPI) login(username string, password string, deviceID string) (loginResponse, error) {
+	body, err := json.Marshal(loginRequest{
+		Type:                     "m.login.password",
+		User:                     username,
+		Password:                 password,
+		DeviceID:                 deviceID,
+		InitialDeviceDisplayName: "Redseligg",
+	})
+	if err != nil {
+		return loginResponse{}, errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := api.call("/client/r0/login", "POST", string(body), false)
 	if err != nil {
-		return errors.Wrap(err, "apiCall failed")
+		return loginResponse{}, errors.Wrap(err, "apiCall failed")
 	}
 
 	var channelResponseData loginResponse
 	if err := json.Unmarshal(response, &channelResponseData); err != nil {
-		return errors.Wrap(err, "json unmarshal failed")
+		return loginResponse{}, errors.Wrap(err, "json unmarshal failed")
 	}
 
 	if len(channelResponseData.AccessToken) > 0 {
 		api.authToken = channelResponseData.AccessToken
-		return nil
+		return channelResponseData, nil
 	}
 
-	return errors.New("could not login")
+	return loginResponse{}, errors.New("could not login")
 }
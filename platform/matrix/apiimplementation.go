@@ -2,10 +2,24 @@ package matrix
 
 import (
 	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
 )
 
+const defaultSyncTimeout = 30 * time.Second
+
+// syncFilter limits the events returned by sync to the ones used by the bot.
+const syncFilter = `{"room":{"timeline":{"limit":50}},"presence":{"not_types":["*"]},"account_data":{"not_types":["*"]}}`
+
+type filterResponse struct {
+	FilterID string `json:"filter_id"`
+}
+
 func checkEventsOK(response []byte) bool {
 	// TODO (#30): Check if Matrix API response contains any errors
 	return true
@@ -13,22 +27,71 @@ func checkEventsOK(response []byte) bool {
 
 func (b *Bot) handleJoinRooms(rooms []room) {
 	for _, room := range rooms {
+		channel, err := b.directory.Channel(room.RoomID)
+		if err != nil {
+			channel.ID = room.RoomID
+		}
+
 		for _, event := range room.State.Events {
-			if event.Type == "m.room.name" {
+			switch event.Type {
+			case "m.room.name":
 				b.addKnownRoom(room.RoomID, event.Content.Name)
+				channel.Name = event.Content.Name
+			case "m.room.topic":
+				channel.Topic = event.Content.Topic
+			case "m.room.encryption":
+				b.setRoomEncrypted(room.RoomID)
+			case "m.room.member":
+				if event.Content.Membership == "join" {
+					b.directory.AddUser(model.User{ID: event.StateKey, Name: event.StateKey, Nickname: event.Content.Displayname})
+				}
 			}
 		}
+		b.directory.AddChannel(channel)
 
 		for _, event := range room.Timeline.Events {
-			if event.Type == "m.room.message" {
+			switch event.Type {
+			case "m.room.message":
+				b.addKnownUser(event.Sender)
 				log.Debugf("Received room message from User: %s, Content: %s, MsgType: %s", event.Sender,
 					event.Content.Body, event.Content.Msgtype)
+			case "m.room.encryption":
+				b.setRoomEncrypted(room.RoomID)
 			}
 		}
 
 	}
 }
 
+// handleRoomMessages reports the messages in the timelines of the rooms to the plugins.
+// The messages sent by the bot itself and edits are not reported.
+func (b *Bot) handleRoomMessages(rooms []room) {
+	for _, room := range rooms {
+		for _, event := range room.Timeline.Events {
+			if event.Type != "m.room.message" || event.Sender == b.userID || event.Content.RelatesTo.RelType == "m.replace" {
+				continue
+			}
+
+			post := model.Post{
+				ChannelID: room.RoomID,
+				Channel:   b.knownRoomIDs[room.RoomID],
+				User:      model.User{ID: event.Sender, Name: event.Sender},
+				Content:   event.Content.Body,
+			}
+
+			for _, plugin := range b.plugins {
+				plugin := plugin
+				b.Enqueue(plugin, func() { plugin.OnPost(post) })
+			}
+
+			// The dispatcher knows the bot by its user ID only
+			dispatched := post
+			dispatched.Content = b.normalizeMentions(event.Content)
+			b.Dispatcher.OnPost(dispatched)
+		}
+	}
+}
+
 func (b *Bot) handleLeaveRooms(rooms []room) {
 	for _, room := range rooms {
 		response, err := b.api.call("/client/r0/rooms/"+room.RoomID+"/forget", "POST", `{}`, true)
@@ -37,11 +100,19 @@ func (b *Bot) handleLeaveRooms(rooms []room) {
 			return
 		}
 		b.removeKnownRoomFromID(room.RoomID)
+		b.directory.RemoveChannel(room.RoomID)
 	}
 }
 
+// handleInviteRooms accepts the invites of the users in the invite allow list and rejects the others.
 func (b *Bot) handleInviteRooms(rooms []room) {
 	for _, room := range rooms {
+		if inviter := b.inviter(room); !b.isInviteAllowed(inviter) {
+			log.Infof("Rejecting invite into room %s from %s", room.RoomID, inviter)
+			b.leaveRoom(room.RoomID)
+			continue
+		}
+
 		_, err := b.api.call("/client/r0/rooms/"+room.RoomID+"/join", "POST", `{}`, true)
 		if err != nil {
 			log.Errorln("join room failed:", err)
@@ -49,21 +120,35 @@ func (b *Bot) handleInviteRooms(rooms []room) {
 	}
 }
 
-func (b *Bot) callSync() error {
-	var response []byte
-	var err error
-	if len(b.nextBatch) == 0 {
-		response, err = b.api.call("/client/r0/sync?filter={\"room\":{\"timeline\":{\"limit\":1}}}", "GET", `{}`, true)
-		if err != nil {
-			log.Errorln("UNHANDELED ERROR: ", err)
-			return err
+// createFilter uploads the filter used for syncing and returns its ID. If the upload fails the
+// filter itself is returned and sent with every sync.
+func (b *Bot) createFilter() string {
+	response, err := b.api.call("/client/r0/user/"+url.PathEscape(b.userID)+"/filter", "POST", syncFilter, true)
+	if err == nil {
+		var r filterResponse
+		if err = json.Unmarshal(response, &r); err == nil && len(r.FilterID) > 0 {
+			return r.FilterID
 		}
+	}
+
+	log.Debugf("Could not upload sync filter, sending it with every sync: %v", err)
+	return syncFilter
+}
+
+func (b *Bot) callSync() error {
+	initialSync := len(b.nextBatch) == 0
+
+	path := "/client/r0/sync?filter=" + url.QueryEscape(b.filter)
+	if initialSync {
+		path += "&timeout=0"
 	} else {
-		response, err = b.api.call("/client/r0/sync?since="+b.nextBatch, "GET", `{}`, true)
-		if err != nil {
-			log.Errorln("UNHANDELED ERROR: ", err)
-			return err
-		}
+		path += "&since=" + url.QueryEscape(b.nextBatch) + "&timeout=" + strconv.FormatInt(int64(b.syncTimeout/time.Millisecond), 10)
+	}
+
+	response, err := b.api.call(path, "GET", `{}`, true)
+	if err != nil {
+		log.Errorln("UNHANDELED ERROR: ", err)
+		return err
 	}
 
 	if !checkEventsOK(response) {
@@ -82,11 +167,24 @@ func (b *Bot) callSync() error {
 		return err
 	}
 
-	b.nextBatch = sr.NextBatch
+	if b.crypto != nil {
+		b.handleEncryption(sr)
+	}
 
 	b.handleJoinRooms(sr.Rooms.Join)
+	if !initialSync {
+		// The initial sync contains old events which shall not be reported again
+		b.handleRoomMessages(sr.Rooms.Join)
+		b.handleReactions(sr.Rooms.Join)
+	}
+	b.handleEmptyRooms(sr.Rooms.Join, initialSync)
 	b.handleLeaveRooms(sr.Rooms.Leave)
 	b.handleInviteRooms(sr.Rooms.Invite)
 
+	if len(sr.NextBatch) > 0 {
+		b.nextBatch = sr.NextBatch
+	}
+	b.storeState()
+
 	return err
 }
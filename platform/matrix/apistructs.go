@@ -8,26 +8,53 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+// roomEvent is an event in the timeline of a room.
+type roomEvent struct {
+	OriginServerTs int64  `json:"origin_server_ts"`
+	Sender         string `json:"sender"`
+	EventID        string `json:"event_id" mapstructure:"event_id"`
+	StateKey       string `json:"state_key,omitempty" mapstructure:"state_key"`
+	Redacts        string `json:"redacts,omitempty"` // Redacts is the ID of the event removed by a m.room.redaction
+	Unsigned       struct {
+		Age int `json:"age"`
+	} `json:"unsigned"`
+	Content roomEventContent `json:"content"`
+	Type    string           `json:"type"`
+}
+
+type roomEventContent struct {
+	Body          string `json:"body"`
+	Msgtype       string `json:"msgtype"`
+	FormattedBody string `json:"formatted_body" mapstructure:"formatted_body"`
+	// Mentions contains the users mentioned in the message
+	Mentions struct {
+		UserIDs []string `json:"user_ids" mapstructure:"user_ids"`
+	} `json:"m.mentions" mapstructure:"m.mentions"`
+	RelatesTo struct {
+		RelType string `json:"rel_type" mapstructure:"rel_type"`
+		EventID string `json:"event_id" mapstructure:"event_id"`
+		Key     string `json:"key"`
+	} `json:"m.relates_to" mapstructure:"m.relates_to"`
+
+	// Membership is the content of m.room.member events
+	Membership string `json:"membership,omitempty"`
+
+	// The fields of m.room.encrypted and m.room.encryption events
+	Algorithm  string      `json:"algorithm"`
+	SenderKey  string      `json:"sender_key" mapstructure:"sender_key"`
+	SessionID  string      `json:"session_id" mapstructure:"session_id"`
+	DeviceID   string      `json:"device_id" mapstructure:"device_id"`
+	Ciphertext interface{} `json:"ciphertext"`
+}
+
 type room struct {
 	RoomID              string
 	UnreadNotifications struct {
 	} `json:"unread_notifications"`
 	Timeline struct {
-		Limited   bool   `json:"limited"`
-		PrevBatch string `json:"prev_batch"`
-		Events    []struct {
-			OriginServerTs int64  `json:"origin_server_ts"`
-			Sender         string `json:"sender"`
-			EventID        string `json:"event_id"`
-			Unsigned       struct {
-				Age int `json:"age"`
-			} `json:"unsigned"`
-			Content struct {
-				Body    string `json:"body"`
-				Msgtype string `json:"msgtype"`
-			} `json:"content"`
-			Type string `json:"type"`
-		} `json:"events"`
+		Limited   bool        `json:"limited"`
+		PrevBatch string      `json:"prev_batch"`
+		Events    []roomEvent `json:"events"`
 	} `json:"timeline"`
 	State struct {
 		Events []struct {
@@ -37,10 +64,14 @@ type room struct {
 			Unsigned       struct {
 				Age int64 `json:"age"`
 			} `json:"unsigned"`
-			StateKey string `json:"state_key"`
+			StateKey string `json:"state_key" mapstructure:"state_key"`
 			Content  struct {
-				JoinRule string `json:"join_rule"`
-				Name     string `json:"name"`
+				JoinRule    string `json:"join_rule"`
+				Algorithm   string `json:"algorithm"`
+				Name        string `json:"name"`
+				Topic       string `json:"topic"`
+				Membership  string `json:"membership"`
+				Displayname string `json:"displayname"`
 			} `json:"content"`
 			Type       string `json:"type"`
 			Membership string `json:"membership,omitempty"`
@@ -55,7 +86,7 @@ type room struct {
 			} `json:"content"`
 			Type           string `json:"type"`
 			Sender         string `json:"sender"`
-			StateKey       string `json:"state_key"`
+			StateKey       string `json:"state_key" mapstructure:"state_key"`
 			OriginServerTs int64  `json:"origin_server_ts,omitempty"`
 			EventID        string `json:"event_id,omitempty"`
 			Unsigned       struct {
@@ -68,12 +99,19 @@ type room struct {
 			} `json:"unsigned,omitempty"`
 			Membership string `json:"membership,omitempty"`
 		} `json:"events"`
-	} `json:"invite_state"`
+	} `json:"invite_state" mapstructure:"invite_state"`
 	AccountData struct {
 		Events []interface{} `json:"events"`
 	} `json:"account_data"`
 }
 
+// toDeviceEvent is an event sent directly to the device of the bot, e.g., to share room keys.
+type toDeviceEvent struct {
+	Type    string                 `json:"type"`
+	Sender  string                 `json:"sender"`
+	Content map[string]interface{} `json:"content"`
+}
+
 type syncResponse struct {
 	NextBatch string `json:"next_batch"`
 	Rooms     struct {
@@ -81,6 +119,16 @@ type syncResponse struct {
 		Join   []room
 		Leave  []room
 	}
+
+	ToDevice struct {
+		Events []toDeviceEvent `json:"events"`
+	} `json:"to_device" mapstructure:"to_device"`
+	// DeviceLists contains the users whose devices changed since the last sync.
+	DeviceLists struct {
+		Changed []string `json:"changed"`
+		Left    []string `json:"left"`
+	} `json:"device_lists" mapstructure:"device_lists"`
+	OneTimeKeysCount map[string]int `json:"device_one_time_keys_count" mapstructure:"device_one_time_keys_count"`
 }
 
 func (sr syncResponse) toString() string {
@@ -110,6 +158,14 @@ func syncResponseFromMap(content map[string]interface{}) (syncResponse, error) {
 		sr.NextBatch = val
 	}
 
+	for _, key := range []string{"to_device", "device_lists", "device_one_time_keys_count"} {
+		if val, ok := content[key]; ok {
+			if err := mapstructure.Decode(map[string]interface{}{key: val}, &sr); err != nil {
+				log.Println("Error decoding", key, err)
+			}
+		}
+	}
+
 	if roomGroups, ok := content["rooms"].(map[string]interface{}); ok {
 		if rooms, ok := roomGroups["leave"].(map[string]interface{}); ok {
 			for roomID, value := range rooms {
@@ -1,6 +1,8 @@
 package matrix
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/torlenor/redseligg/botconfig"
@@ -8,6 +10,7 @@ import (
 	"github.com/torlenor/redseligg/storage"
 
 	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin"
 )
@@ -21,9 +24,17 @@ type Bot struct {
 	platform.BotImpl
 	api api
 
+	botID       string
+	userID      string
+	deviceID    string
+	displayName string // displayName is the display name of the bot, used in mentions
+
 	pollingDone chan bool
+	wg          sync.WaitGroup
 
-	pollingInterval time.Duration
+	pollingInterval time.Duration // pollingInterval is the time to wait after a failed sync
+	syncTimeout     time.Duration
+	filter          string // filter is the ID of the filter used for syncing or the filter itself
 
 	plugins []plugin.Hooks
 
@@ -32,40 +43,140 @@ type Bot struct {
 	knownRoomIDs map[string]string // mapping of RoomID to Room
 
 	nextBatch string // contains the next batch to fetch in sync
+
+	rooms           []string // rooms are joined on start
+	inviteAllowList []string
+	leaveEmptyRooms bool
+	aliases         *roomAliases
+
+	crypto *encryption // crypto is nil if end-to-end encryption is disabled
+	// stateKey encrypts the stored encryption state, it is stored unencrypted if empty
+	stateKey    string
+	stateSealer *stateSealer
+	// storedEncryption is the stored encryption state, it is kept while encryption is disabled
+	storedEncryption string
+
+	directory *platform.Directory
+
+	knownReactions     map[string]model.Reaction // [EventID], needed to know which reaction got redacted
+	knownReactionOrder []string
 }
 
 // The createMatrixBotWithAPI creates a new instance of a MatrixBot using the api interface api
-func createMatrixBotWithAPI(api api, username string, password string, commandDispatcher *commanddispatcher.CommandDispatcher, storage storage.Storage) (*Bot, error) {
+func createMatrixBotWithAPI(api api, botID string, cfg botconfig.MatrixConfig, commandDispatcher *commanddispatcher.CommandDispatcher, storage storage.Storage) (*Bot, error) {
 	log.Printf("MatrixBot is CREATING itself")
 	b := Bot{
 		BotImpl: platform.BotImpl{
 			ProvidedFeatures: map[string]bool{
-				platform.FeatureMessagePost: true,
+				platform.FeatureMessagePost:   true,
+				platform.FeatureMessageUpdate: true,
+				platform.FeatureMessageDelete: true,
+
+				platform.FeatureMessageFormatting: true,
+				platform.FeatureMessageReplies:    true,
+
+				platform.FeatureReactionNotify: true,
 			},
 			Dispatcher: commandDispatcher,
 			Storage:    storage,
 		},
-		api: api,
+		api:   api,
+		botID: botID,
+
+		rooms:           cfg.Rooms,
+		inviteAllowList: cfg.InviteAllowList,
+		leaveEmptyRooms: cfg.LeaveEmptyRooms,
+		aliases:         newRoomAliases(),
+		stateKey:        cfg.StateKey,
 	}
 
-	err := b.api.login(username, password)
-	if err != nil {
+	state := b.loadState()
+
+	if err := b.login(cfg, state.DeviceID); err != nil {
 		return nil, err
 	}
+	b.Dispatcher.SetBotMentions(b.userID)
+
+	if len(state.DeviceID) > 0 && state.DeviceID != b.deviceID {
+		log.Warnf("Logged in with new device %s instead of %s, the encryption state is discarded", b.deviceID, state.DeviceID)
+		state.Encryption = ""
+	}
+	b.nextBatch = state.NextBatch
+	b.storedEncryption = state.Encryption
 
 	b.pollingDone = make(chan bool)
 	b.pollingInterval = 1000 * time.Millisecond
+	b.syncTimeout = defaultSyncTimeout
 
 	b.knownRooms = make(map[string]string)
 	b.knownRoomIDs = make(map[string]string)
 
+	b.directory = platform.NewDirectory()
+	b.knownReactions = make(map[string]model.Reaction)
+
+	if cfg.Encryption {
+		if len(b.deviceID) == 0 {
+			return nil, fmt.Errorf("Could not initialize end-to-end encryption: No device ID known, please configure device_id")
+		}
+		if len(b.stateKey) == 0 {
+			log.Warnln("No state_key configured, the private keys of the encryption state are stored unencrypted")
+		}
+		if err := b.initEncryption(state.Encryption); err != nil {
+			return nil, fmt.Errorf("Could not initialize end-to-end encryption: %s", err)
+		}
+	}
+
+	b.filter = b.createFilter()
+	b.storeState()
+
+	b.Dispatcher.SetPoster(&b)
+
 	return &b, nil
 }
 
-// CreateMatrixBot creates a new instance of a DiscordBot
-func CreateMatrixBot(cfg botconfig.MatrixConfig, storage storage.Storage, commandDispatcher *commanddispatcher.CommandDispatcher) (*Bot, error) {
+// login logs in with the access token or, if no token is configured, with username and password.
+// The configured device is preferred over the device of the last login.
+func (b *Bot) login(cfg botconfig.MatrixConfig, lastDeviceID string) error {
+	deviceID := cfg.DeviceID
+	if len(deviceID) == 0 {
+		deviceID = lastDeviceID
+	}
+
+	if len(cfg.Token) > 0 {
+		b.api.updateAuthToken(cfg.Token)
+
+		var whoami whoamiResponse
+		if err := b.getJSON("/client/r0/account/whoami", &whoami); err != nil {
+			return fmt.Errorf("Could not log in with access token: %s", err)
+		}
+		if len(whoami.UserID) == 0 {
+			return fmt.Errorf("Could not log in with access token: No user ID returned")
+		}
+		b.userID = whoami.UserID
+
+		// Older servers do not return the device of the access token
+		b.deviceID = whoami.DeviceID
+		if len(b.deviceID) == 0 {
+			b.deviceID = deviceID
+		} else if len(cfg.DeviceID) > 0 && cfg.DeviceID != b.deviceID {
+			log.Warnf("The access token belongs to device %s instead of the configured device %s", b.deviceID, cfg.DeviceID)
+		}
+		return nil
+	}
+
+	login, err := b.api.login(cfg.Username, cfg.Password, deviceID)
+	if err != nil {
+		return err
+	}
+	b.userID = login.UserID
+	b.deviceID = login.DeviceID
+	return nil
+}
+
+// CreateMatrixBot creates a new instance of a MatrixBot
+func CreateMatrixBot(botID string, cfg botconfig.MatrixConfig, storage storage.Storage, commandDispatcher *commanddispatcher.CommandDispatcher) (*Bot, error) {
 	api := &matrixAPI{server: cfg.Server}
-	return createMatrixBotWithAPI(api, cfg.Username, cfg.Password, commandDispatcher, storage)
+	return createMatrixBotWithAPI(api, botID, cfg, commandDispatcher, storage)
 }
 
 // AddPlugin takes as argument a plugin and
@@ -103,6 +214,6 @@ func (b *Bot) GetInfo() platform.BotInfo {
 		BotID:    "",
 		Platform: "Matrix",
 		Healthy:  true,
-		Plugins:  []platform.PluginInfo{},
+		Plugins:  b.PluginInfos(b.plugins),
 	}
 }
@@ -1,18 +1,25 @@
 package matrix
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/torlenor/redseligg/botconfig"
 	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/storage/memorystorage"
 )
 
 type mockAPI struct {
 	letLoginFail   bool
 	letAPICallFail bool
 	apiResponse    string
+	apiResponses   map[string]string // apiResponses are the responses for specific paths, instead of apiResponse
 
 	server    string
 	authToken string
@@ -23,6 +30,7 @@ type mockAPI struct {
 	lastAPICallMethod string
 	lastAPICallBody   string
 	lastAPICallAuth   bool
+	apiCallPaths      []string
 }
 
 func (api *mockAPI) call(path string, method string, body string, auth bool) (r []byte, e error) {
@@ -30,9 +38,13 @@ func (api *mockAPI) call(path string, method string, body string, auth bool) (r
 	api.lastAPICallMethod = method
 	api.lastAPICallBody = body
 	api.lastAPICallAuth = auth
+	api.apiCallPaths = append(api.apiCallPaths, method+" "+path)
 	if api.letAPICallFail == true {
 		return []byte(""), errors.New("Fake API call fail")
 	}
+	if response, ok := api.apiResponses[path]; ok {
+		return []byte(response), nil
+	}
 	return []byte(api.apiResponse), nil
 }
 
@@ -40,12 +52,15 @@ func (api *mockAPI) updateAuthToken(token string) {
 	api.authToken = token
 }
 
-func (api *mockAPI) login(username string, password string) error {
+func (api *mockAPI) login(username string, password string, deviceID string) (loginResponse, error) {
 	api.loginCalled = true
 	if api.letLoginFail == true {
-		return errors.New("Fake Login Fail")
+		return loginResponse{}, errors.New("Fake Login Fail")
+	}
+	if len(deviceID) == 0 {
+		deviceID = "TEST_DEVICE"
 	}
-	return nil
+	return loginResponse{UserID: "@" + username + ":" + api.server, DeviceID: deviceID}, nil
 }
 
 func (api *mockAPI) reset() {
@@ -57,8 +72,11 @@ func (api *mockAPI) reset() {
 	api.lastAPICallMethod = ""
 	api.lastAPICallBody = ""
 	api.lastAPICallAuth = false
+	api.apiCallPaths = nil
 }
 
+var testConfig = botconfig.MatrixConfig{Username: "TEST_USER", Password: "TEST_PASS"}
+
 func TestCreateMatrixBot(t *testing.T) {
 	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
 	api.reset()
@@ -67,7 +85,7 @@ func TestCreateMatrixBot(t *testing.T) {
 	storage := &storage.MockStorage{}
 
 	// Login with user and password
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create MatrixBot from username and password")
@@ -81,7 +99,7 @@ func TestCreateMatrixBot(t *testing.T) {
 	api.reset()
 	api.letLoginFail = true
 
-	bot, err = createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err = createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 
 	if bot != nil || err == nil {
 		t.Fatalf("Created Matrix bot even though login failed")
@@ -99,14 +117,14 @@ func TestMatrixBotPolling(t *testing.T) {
 	dispatcher := commanddispatcher.New("")
 	storage := &storage.MockStorage{}
 
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create Matrix Bot")
 	}
 
 	// Polling without valid response from API has to fail
 	err = bot.handlePolling()
-	if api.lastAPICallPath != `/client/r0/sync?filter={"room":{"timeline":{"limit":1}}}` {
+	if api.lastAPICallPath != "/client/r0/sync?filter="+url.QueryEscape(syncFilter)+"&timeout=0" {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
 	if api.lastAPICallMethod != `GET` {
@@ -125,7 +143,7 @@ func TestMatrixBotPolling(t *testing.T) {
 	// Polling with valid JSON response from API should not fail
 	api.apiResponse = `{}`
 	err = bot.handlePolling()
-	if api.lastAPICallPath != `/client/r0/sync?filter={"room":{"timeline":{"limit":1}}}` {
+	if api.lastAPICallPath != "/client/r0/sync?filter="+url.QueryEscape(syncFilter)+"&timeout=0" {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
 	if api.lastAPICallMethod != `GET` {
@@ -150,7 +168,7 @@ func TestMatrixBotStartingAndStopping(t *testing.T) {
 	dispatcher := commanddispatcher.New("")
 	storage := &storage.MockStorage{}
 
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create Matrix Bot")
 	}
@@ -168,7 +186,7 @@ func TestMatrixBotAddRemoveRoom(t *testing.T) {
 	dispatcher := commanddispatcher.New("")
 	storage := &storage.MockStorage{}
 
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create Matrix Bot")
 	}
@@ -190,3 +208,91 @@ func TestMatrixBotAddRemoveRoom(t *testing.T) {
 		t.Fatalf("Room list not empty after removing the last room")
 	}
 }
+
+func TestMatrixBotDirectory(t *testing.T) {
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), &storage.MockStorage{})
+	if bot == nil || err != nil {
+		t.Fatalf("Could not create Matrix Bot")
+	}
+
+	api.apiResponse = `{"next_batch":"s1","rooms":{"join":{"!room:server":{"state":{"events":[
+		{"type":"m.room.name","state_key":"","content":{"name":"General"}},
+		{"type":"m.room.topic","state_key":"","content":{"topic":"Everything"}},
+		{"type":"m.room.member","state_key":"@user:server","content":{"membership":"join","displayname":"User"}}
+	]}}}}}`
+	if err := bot.handlePolling(); err != nil {
+		t.Fatalf("Sync failed: %s", err)
+	}
+
+	channel, err := bot.GetChannelByName("general")
+	if err != nil || channel != (model.Channel{ID: "!room:server", Name: "General", Topic: "Everything"}) {
+		t.Fatalf("Room not in directory: %v, %s", channel, err)
+	}
+	user, err := bot.GetUser("@user:server")
+	if err != nil || user.Nickname != "User" {
+		t.Fatalf("User not in directory: %v, %s", user, err)
+	}
+
+	api.apiResponse = `{"displayname":"Other"}`
+	user, err = bot.GetUserByUsername("@other:server")
+	if err != nil || user.Nickname != "Other" || api.lastAPICallPath != "/client/r0/profile/@other:server" {
+		t.Fatalf("User not requested from API: %v, %s, %s", user, err, api.lastAPICallPath)
+	}
+
+	api.apiResponse = `{"errcode":"M_NOT_FOUND","error":"Profile not found"}`
+	if _, err := bot.GetUser("@unknown:server"); err == nil {
+		t.Fatalf("Unknown user should return an error")
+	}
+}
+
+func TestMatrixBotRoomMessages(t *testing.T) {
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	st := memorystorage.New()
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), st)
+	if bot == nil || err != nil {
+		t.Fatalf("Could not create Matrix Bot")
+	}
+	p := &mockPostPlugin{}
+	bot.plugins = append(bot.plugins, p)
+
+	messages := `{"next_batch":"%s","rooms":{"join":{"!room:server":{"timeline":{"events":[
+		{"type":"m.room.message","sender":"@user:server","event_id":"$1","content":{"msgtype":"m.text","body":"hello"}},
+		{"type":"m.room.message","sender":"@TEST_USER:TEST_SERVER","event_id":"$2","content":{"msgtype":"m.text","body":"own message"}}
+	]}}}}}`
+
+	// Messages of the initial sync are old and not reported
+	api.apiResponse = fmt.Sprintf(messages, "s1")
+	if err := bot.handlePolling(); err != nil {
+		t.Fatalf("Sync failed: %s", err)
+	}
+	if len(p.posts) != 0 {
+		t.Fatalf("Messages of the initial sync reported: %v", p.posts)
+	}
+
+	api.apiResponse = fmt.Sprintf(messages, "s2")
+	if err := bot.handlePolling(); err != nil {
+		t.Fatalf("Sync failed: %s", err)
+	}
+	if !strings.Contains(api.lastAPICallPath, "&since=s1&timeout=30000") {
+		t.Fatalf("Sync did not continue from the last batch: %s", api.lastAPICallPath)
+	}
+	if len(p.posts) != 1 || p.posts[0].Content != "hello" || p.posts[0].User.ID != "@user:server" || p.posts[0].ChannelID != "!room:server" {
+		t.Fatalf("Wrong messages reported: %v", p.posts)
+	}
+
+	state, err := st.GetMatrixState("TEST_BOT")
+	if err != nil || state.NextBatch != "s2" || state.DeviceID != "TEST_DEVICE" {
+		t.Fatalf("Wrong state stored: %v, %s", state, err)
+	}
+
+	// A restarted bot continues with the stored device and batch
+	restarted, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), st)
+	if err != nil || restarted.nextBatch != "s2" || restarted.deviceID != "TEST_DEVICE" {
+		t.Fatalf("Restarted bot did not restore the state: %s", err)
+	}
+}
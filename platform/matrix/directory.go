@@ -0,0 +1,80 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// errorResponse is returned by the Matrix API when a request fails.
+type errorResponse struct {
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+type profileResponse struct {
+	Displayname string `json:"displayname"`
+}
+
+type roomNameResponse struct {
+	Name string `json:"name"`
+}
+
+type roomTopicResponse struct {
+	Topic string `json:"topic"`
+}
+
+// getJSON calls the Matrix API and unmarshals the response into v.
+func (b *Bot) getJSON(path string, v interface{}) error {
+	response, err := b.api.call(path, "GET", "", true)
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+
+	var apiErr errorResponse
+	if err := json.Unmarshal(response, &apiErr); err == nil && len(apiErr.ErrCode) > 0 {
+		return fmt.Errorf("%s: %s", apiErr.ErrCode, apiErr.Error)
+	}
+
+	return json.Unmarshal(response, v)
+}
+
+// addKnownUser adds the user with the user ID to the directory if it is not known, yet.
+func (b *Bot) addKnownUser(userID string) {
+	if _, err := b.directory.User(userID); err != nil {
+		b.directory.AddUser(model.User{ID: userID, Name: userID})
+	}
+}
+
+// fetchUser gets the profile of the user and adds it to the directory.
+func (b *Bot) fetchUser(userID string) (model.User, error) {
+	var profile profileResponse
+	if err := b.getJSON("/client/r0/profile/"+url.PathEscape(userID), &profile); err != nil {
+		return model.User{}, fmt.Errorf("Error getting profile of user %s: %s", userID, err)
+	}
+
+	user := model.User{ID: userID, Name: userID, Nickname: profile.Displayname}
+	b.directory.AddUser(user)
+	return user, nil
+}
+
+// fetchRoom gets the name and topic of the room and adds it to the directory.
+func (b *Bot) fetchRoom(roomID string) (model.Channel, error) {
+	var name roomNameResponse
+	if err := b.getJSON("/client/r0/rooms/"+url.PathEscape(roomID)+"/state/m.room.name", &name); err != nil {
+		return model.Channel{}, fmt.Errorf("Error getting name of room %s: %s", roomID, err)
+	}
+
+	// Rooms without topic are valid, therefore errors are ignored
+	var topic roomTopicResponse
+	b.getJSON("/client/r0/rooms/"+url.PathEscape(roomID)+"/state/m.room.topic", &topic)
+
+	channel := model.Channel{ID: roomID, Name: name.Name, Topic: topic.Topic}
+	b.addKnownRoom(roomID, name.Name)
+	b.directory.AddChannel(channel)
+	return channel, nil
+}
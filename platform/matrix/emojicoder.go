@@ -0,0 +1,71 @@
+package matrix
+
+import "fmt"
+
+func getRedseliggEmojiFromMatrixEmoji(matrixEmoji string) (string, error) {
+	switch matrixEmoji {
+
+	case "0️⃣":
+		return "zero", nil
+	case "1️⃣":
+		return "one", nil
+	case "2️⃣":
+		return "two", nil
+	case "3️⃣":
+		return "three", nil
+	case "4️⃣":
+		return "four", nil
+	case "5️⃣":
+		return "five", nil
+	case "6️⃣":
+		return "six", nil
+	case "7️⃣":
+		return "seven", nil
+	case "8️⃣":
+		return "eight", nil
+	case "9️⃣":
+		return "nine", nil
+	case "🔟":
+		return "keycap_ten", nil
+	case "👍", "👍️":
+		return "+1", nil
+	case "👎", "👎️":
+		return "-1", nil
+	default:
+		return matrixEmoji, fmt.Errorf("Emoji not known")
+	}
+}
+
+func getMatrixEmojiFromRedseliggEmoji(redseliggEmoji string) (string, error) {
+	switch redseliggEmoji {
+
+	case "zero":
+		return "0️⃣", nil
+	case "one":
+		return "1️⃣", nil
+	case "two":
+		return "2️⃣", nil
+	case "three":
+		return "3️⃣", nil
+	case "four":
+		return "4️⃣", nil
+	case "five":
+		return "5️⃣", nil
+	case "six":
+		return "6️⃣", nil
+	case "seven":
+		return "7️⃣", nil
+	case "eight":
+		return "8️⃣", nil
+	case "nine":
+		return "9️⃣", nil
+	case "keycap_ten":
+		return "🔟", nil
+	case "+1", "thumbsup":
+		return "👍", nil
+	case "-1", "thumbsdown":
+		return "👎", nil
+	default:
+		return "", fmt.Errorf("Emoji not known")
+	}
+}
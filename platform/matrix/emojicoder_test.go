@@ -0,0 +1,52 @@
+package matrix
+
+import "testing"
+
+func Test_getRedseliggEmojiFromMatrixEmoji(t *testing.T) {
+	tests := []struct {
+		name        string
+		matrixEmoji string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "Request valid Emoji",
+			matrixEmoji: "7️⃣",
+			want:        "seven",
+		},
+		{
+			name:        "Request invalid Emoji",
+			matrixEmoji: "Ä",
+			want:        "Ä",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRedseliggEmojiFromMatrixEmoji(tt.matrixEmoji)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getRedseliggEmojiFromMatrixEmoji() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getRedseliggEmojiFromMatrixEmoji() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getMatrixEmojiFromRedseliggEmoji(t *testing.T) {
+	for _, name := range []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "keycap_ten", "+1", "-1"} {
+		emoji, err := getMatrixEmojiFromRedseliggEmoji(name)
+		if err != nil {
+			t.Errorf("getMatrixEmojiFromRedseliggEmoji(%s) returned error: %s", name, err)
+		}
+		if got, _ := getRedseliggEmojiFromMatrixEmoji(emoji); got != name {
+			t.Errorf("Round trip of %s returned %s", name, got)
+		}
+	}
+
+	if _, err := getMatrixEmojiFromRedseliggEmoji("unknown"); err == nil {
+		t.Errorf("getMatrixEmojiFromRedseliggEmoji() should return an error for unknown emojis")
+	}
+}
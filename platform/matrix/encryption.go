@@ -0,0 +1,605 @@
+package matrix
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torlenor/redseligg/platform/matrix/olm"
+)
+
+const (
+	algorithmOlm    = "m.olm.v1.curve25519-aes-sha2"
+	algorithmMegolm = "m.megolm.v1.aes-sha2"
+
+	// A Megolm session is replaced after it was used for rotationMessages messages or for rotationPeriod
+	rotationMessages = 100
+	rotationPeriod   = 7 * 24 * time.Hour
+)
+
+// encryption is the end-to-end encryption state of the device of the bot.
+type encryption struct {
+	mutex sync.Mutex
+
+	account               *olm.Account
+	sessions              map[string][]*olm.Session           // [sender curve25519 key], newest session first
+	inboundGroupSessions  map[string]*olm.InboundGroupSession // [room ID|sender key|session ID]
+	outboundGroupSessions map[string]*outboundGroupSession    // [room ID]
+
+	// The following is not persisted, it is fetched again when needed
+	rooms   map[string]bool                  // [room ID] -> true if the room is encrypted
+	devices map[string]map[string]deviceKeys // [user ID][device ID]
+}
+
+// outboundGroupSession is the Megolm session used to send messages to a room.
+type outboundGroupSession struct {
+	Session    *olm.OutboundGroupSession  `json:"session"`
+	Created    time.Time                  `json:"created"`
+	SharedWith map[string]map[string]bool `json:"shared_with"` // [user ID][device ID]
+}
+
+func (s *outboundGroupSession) expired() bool {
+	return s.Session.MessageIndex() >= rotationMessages || time.Since(s.Created) > rotationPeriod
+}
+
+type encryptionJSON struct {
+	Account               *olm.Account                        `json:"account"`
+	Sessions              map[string][]*olm.Session           `json:"sessions"`
+	InboundGroupSessions  map[string]*olm.InboundGroupSession `json:"inbound_group_sessions"`
+	OutboundGroupSessions map[string]*outboundGroupSession    `json:"outbound_group_sessions"`
+}
+
+func newEncryption() *encryption {
+	return &encryption{
+		sessions:              make(map[string][]*olm.Session),
+		inboundGroupSessions:  make(map[string]*olm.InboundGroupSession),
+		outboundGroupSessions: make(map[string]*outboundGroupSession),
+		rooms:                 make(map[string]bool),
+		devices:               make(map[string]map[string]deviceKeys),
+	}
+}
+
+// MarshalJSON serializes the encryption state including all private keys in plain text.
+// storeState encrypts the result if a state key is configured.
+func (e *encryption) MarshalJSON() ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return json.Marshal(encryptionJSON{
+		Account:               e.account,
+		Sessions:              e.sessions,
+		InboundGroupSessions:  e.inboundGroupSessions,
+		OutboundGroupSessions: e.outboundGroupSessions,
+	})
+}
+
+// UnmarshalJSON restores the encryption state serialized with MarshalJSON.
+func (e *encryption) UnmarshalJSON(data []byte) error {
+	var ej encryptionJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return err
+	}
+	if ej.Account == nil {
+		return fmt.Errorf("No account in encryption state")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.account = ej.Account
+	if ej.Sessions != nil {
+		e.sessions = ej.Sessions
+	}
+	if ej.InboundGroupSessions != nil {
+		e.inboundGroupSessions = ej.InboundGroupSessions
+	}
+	if ej.OutboundGroupSessions != nil {
+		e.outboundGroupSessions = ej.OutboundGroupSessions
+	}
+	return nil
+}
+
+func (e *encryption) addSession(senderKey string, session *olm.Session) {
+	e.sessions[senderKey] = append([]*olm.Session{session}, e.sessions[senderKey]...)
+}
+
+func groupSessionKey(roomID string, senderKey string, sessionID string) string {
+	return roomID + "|" + senderKey + "|" + sessionID
+}
+
+// olmCiphertext is a message of an Olm session.
+type olmCiphertext struct {
+	Type int    `json:"type"`
+	Body string `json:"body"`
+}
+
+// olmEncryptedContent is the content of a m.room.encrypted event encrypted with Olm.
+type olmEncryptedContent struct {
+	Algorithm  string                   `json:"algorithm"`
+	SenderKey  string                   `json:"sender_key"`
+	Ciphertext map[string]olmCiphertext `json:"ciphertext"` // [recipient curve25519 key]
+}
+
+// olmPayload is the plaintext of an Olm message.
+type olmPayload struct {
+	Type          string            `json:"type"`
+	Content       json.RawMessage   `json:"content"`
+	Sender        string            `json:"sender"`
+	SenderDevice  string            `json:"sender_device,omitempty"`
+	Recipient     string            `json:"recipient"`
+	RecipientKeys map[string]string `json:"recipient_keys"`
+	Keys          map[string]string `json:"keys"`
+}
+
+// megolmEncryptedContent is the content of a m.room.encrypted event encrypted with Megolm.
+type megolmEncryptedContent struct {
+	Algorithm  string `json:"algorithm"`
+	SenderKey  string `json:"sender_key"`
+	Ciphertext string `json:"ciphertext"`
+	SessionID  string `json:"session_id"`
+	DeviceID   string `json:"device_id"`
+}
+
+// megolmPayload is the plaintext of a Megolm message.
+type megolmPayload struct {
+	Type    string          `json:"type"`
+	Content json.RawMessage `json:"content"`
+	RoomID  string          `json:"room_id"`
+}
+
+// roomKeyContent is the content of a m.room_key event which shares a Megolm session.
+type roomKeyContent struct {
+	Algorithm  string `json:"algorithm"`
+	RoomID     string `json:"room_id"`
+	SessionID  string `json:"session_id"`
+	SessionKey string `json:"session_key"`
+}
+
+type joinedMembersResponse struct {
+	Joined map[string]interface{} `json:"joined"`
+}
+
+type roomEncryptionContent struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// initEncryption restores the encryption state or creates a new account and uploads its keys.
+func (b *Bot) initEncryption(state string) error {
+	b.crypto = newEncryption()
+
+	if len(state) > 0 {
+		data, sealer, err := openState(state, b.stateKey)
+		if err != nil {
+			return err
+		}
+		b.stateSealer = sealer
+		if err := json.Unmarshal([]byte(data), b.crypto); err != nil {
+			return fmt.Errorf("Could not restore encryption state: %s", err)
+		}
+		return nil
+	}
+
+	account, err := olm.NewAccount()
+	if err != nil {
+		return err
+	}
+	b.crypto.account = account
+
+	b.crypto.mutex.Lock()
+	defer b.crypto.mutex.Unlock()
+	return b.uploadKeys(true, 0)
+}
+
+// setRoomEncrypted marks the room as encrypted, all messages sent to it are encrypted from now on.
+func (b *Bot) setRoomEncrypted(roomID string) {
+	if b.crypto == nil {
+		return
+	}
+
+	b.crypto.mutex.Lock()
+	defer b.crypto.mutex.Unlock()
+	b.crypto.rooms[roomID] = true
+}
+
+// isRoomEncrypted returns true if the room is encrypted. Rooms not seen during sync are looked up.
+func (b *Bot) isRoomEncrypted(roomID string) (bool, error) {
+	b.crypto.mutex.Lock()
+	defer b.crypto.mutex.Unlock()
+
+	if encrypted, ok := b.crypto.rooms[roomID]; ok {
+		return encrypted, nil
+	}
+
+	var content roomEncryptionContent
+	err := b.getJSON("/client/r0/rooms/"+url.PathEscape(roomID)+"/state/m.room.encryption", &content)
+	if err != nil && !strings.HasPrefix(err.Error(), "M_NOT_FOUND") {
+		return false, fmt.Errorf("Could not determine if room %s is encrypted: %s", roomID, err)
+	}
+
+	encrypted := err == nil && len(content.Algorithm) > 0
+	b.crypto.rooms[roomID] = encrypted
+	return encrypted, nil
+}
+
+// handleEncryption handles the keys and device changes received with a sync and decrypts the
+// encrypted events in the timelines of the joined rooms.
+func (b *Bot) handleEncryption(sr syncResponse) {
+	b.crypto.mutex.Lock()
+
+	for _, user := range append(sr.DeviceLists.Changed, sr.DeviceLists.Left...) {
+		delete(b.crypto.devices, user)
+	}
+
+	for _, event := range sr.ToDevice.Events {
+		if event.Type != "m.room.encrypted" {
+			continue
+		}
+		if err := b.handleEncryptedToDeviceEvent(event); err != nil {
+			log.Warnf("Could not decrypt to-device event from %s: %s", event.Sender, err)
+		}
+	}
+
+	if sr.OneTimeKeysCount != nil {
+		if count := sr.OneTimeKeysCount[keyAlgorithm]; count < olm.MaxOneTimeKeys/4 {
+			if err := b.uploadKeys(false, count); err != nil {
+				log.Errorln(err)
+			}
+		}
+	}
+
+	b.crypto.mutex.Unlock()
+
+	for i := range sr.Rooms.Join {
+		for j := range sr.Rooms.Join[i].Timeline.Events {
+			event := &sr.Rooms.Join[i].Timeline.Events[j]
+			if event.Type != "m.room.encrypted" {
+				continue
+			}
+			if err := b.decryptRoomEvent(sr.Rooms.Join[i].RoomID, event); err != nil {
+				log.Warnf("Could not decrypt event %s in room %s: %s", event.EventID, sr.Rooms.Join[i].RoomID, err)
+			}
+		}
+	}
+}
+
+// handleEncryptedToDeviceEvent decrypts an Olm encrypted event and adds the room keys it contains.
+// The caller has to hold the lock of the encryption state.
+func (b *Bot) handleEncryptedToDeviceEvent(event toDeviceEvent) error {
+	var content olmEncryptedContent
+	if err := decodeContent(event.Content, &content); err != nil {
+		return err
+	}
+	if content.Algorithm != algorithmOlm {
+		return fmt.Errorf("Unsupported algorithm %s", content.Algorithm)
+	}
+
+	ciphertext, ok := content.Ciphertext[b.crypto.account.IdentityKey()]
+	if !ok {
+		return fmt.Errorf("Event is not encrypted for this device")
+	}
+	body, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(ciphertext.Body, "="))
+	if err != nil {
+		return fmt.Errorf("Invalid ciphertext: %s", err)
+	}
+
+	plaintext, err := b.decryptOlm(content.SenderKey, ciphertext.Type, body)
+	if err != nil {
+		return err
+	}
+
+	var payload olmPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return fmt.Errorf("Invalid payload: %s", err)
+	}
+	if payload.Sender != event.Sender || payload.Recipient != b.userID || payload.RecipientKeys["ed25519"] != b.crypto.account.SigningKey() {
+		return fmt.Errorf("Payload does not match sender or recipient")
+	}
+
+	if payload.Type == "m.room_key" {
+		var roomKey roomKeyContent
+		if err := json.Unmarshal(payload.Content, &roomKey); err != nil {
+			return fmt.Errorf("Invalid room key: %s", err)
+		}
+		return b.addRoomKey(content.SenderKey, roomKey)
+	}
+
+	log.Debugf("Ignoring encrypted to-device event of type %s from %s", payload.Type, event.Sender)
+	return nil
+}
+
+// decryptOlm decrypts the Olm message with an existing session or creates a new session for a
+// pre-key message. The caller has to hold the lock of the encryption state.
+func (b *Bot) decryptOlm(senderKey string, messageType int, body []byte) ([]byte, error) {
+	for _, session := range b.crypto.sessions[senderKey] {
+		if messageType == olm.MessageTypePreKey && !session.MatchesInboundSession(body) {
+			continue
+		}
+		plaintext, err := session.Decrypt(messageType, body)
+		if err == nil {
+			return plaintext, nil
+		}
+		if messageType == olm.MessageTypePreKey {
+			return nil, err
+		}
+	}
+
+	if messageType != olm.MessageTypePreKey {
+		return nil, fmt.Errorf("No Olm session can decrypt the message")
+	}
+
+	session, err := b.crypto.account.NewInboundSession(senderKey, body)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := session.Decrypt(messageType, body)
+	if err != nil {
+		return nil, err
+	}
+	b.crypto.account.RemoveOneTimeKeys(session)
+	b.crypto.addSession(senderKey, session)
+
+	return plaintext, nil
+}
+
+// addRoomKey adds a Megolm session shared by another device. The caller has to hold the lock of the encryption state.
+func (b *Bot) addRoomKey(senderKey string, roomKey roomKeyContent) error {
+	if roomKey.Algorithm != algorithmMegolm {
+		return fmt.Errorf("Unsupported room key algorithm %s", roomKey.Algorithm)
+	}
+
+	session, err := olm.NewInboundGroupSession(roomKey.SessionKey)
+	if err != nil {
+		return err
+	}
+	if session.ID() != roomKey.SessionID {
+		return fmt.Errorf("Room key does not match session ID %s", roomKey.SessionID)
+	}
+
+	key := groupSessionKey(roomKey.RoomID, senderKey, roomKey.SessionID)
+	if existing, ok := b.crypto.inboundGroupSessions[key]; ok && existing.FirstKnownIndex() <= session.FirstKnownIndex() {
+		return nil
+	}
+	b.crypto.inboundGroupSessions[key] = session
+
+	log.Debugf("Received room key for session %s in room %s", roomKey.SessionID, roomKey.RoomID)
+	return nil
+}
+
+// decryptRoomEvent replaces the type and content of the Megolm encrypted event by the decrypted ones.
+func (b *Bot) decryptRoomEvent(roomID string, event *roomEvent) error {
+	if event.Content.Algorithm != algorithmMegolm {
+		return fmt.Errorf("Unsupported algorithm %s", event.Content.Algorithm)
+	}
+	ciphertext, ok := event.Content.Ciphertext.(string)
+	if !ok {
+		return fmt.Errorf("Invalid ciphertext")
+	}
+
+	b.crypto.mutex.Lock()
+	session, ok := b.crypto.inboundGroupSessions[groupSessionKey(roomID, event.Content.SenderKey, event.Content.SessionID)]
+	if !ok {
+		b.crypto.mutex.Unlock()
+		return fmt.Errorf("Unknown session %s", event.Content.SessionID)
+	}
+	plaintext, _, err := session.Decrypt(strings.TrimRight(ciphertext, "="))
+	b.crypto.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var payload megolmPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return fmt.Errorf("Invalid payload: %s", err)
+	}
+	if payload.RoomID != roomID {
+		return fmt.Errorf("Event was encrypted for room %s", payload.RoomID)
+	}
+
+	var content roomEventContent
+	if err := json.Unmarshal(payload.Content, &content); err != nil {
+		return fmt.Errorf("Invalid content: %s", err)
+	}
+	event.Type = payload.Type
+	event.Content = content
+
+	return nil
+}
+
+// encryptRoomEvent encrypts the event for the joined members of the room. The Megolm session of the
+// room is shared with all devices which do not know it, yet.
+func (b *Bot) encryptRoomEvent(roomID string, eventType string, content json.RawMessage) (megolmEncryptedContent, error) {
+	b.crypto.mutex.Lock()
+	defer b.crypto.mutex.Unlock()
+
+	var members joinedMembersResponse
+	if err := b.getJSON("/client/r0/rooms/"+url.PathEscape(roomID)+"/joined_members", &members); err != nil {
+		return megolmEncryptedContent{}, fmt.Errorf("Error getting members of room %s: %s", roomID, err)
+	}
+
+	session, ok := b.crypto.outboundGroupSessions[roomID]
+	if ok && session.expired() {
+		ok = false
+	}
+	if ok {
+		// Users which left must not be able to decrypt new messages
+		for user := range session.SharedWith {
+			if _, joined := members.Joined[user]; !joined {
+				ok = false
+				break
+			}
+		}
+	}
+	if !ok {
+		var err error
+		session, err = b.newOutboundGroupSession(roomID)
+		if err != nil {
+			return megolmEncryptedContent{}, err
+		}
+	}
+
+	var users []string
+	for user := range members.Joined {
+		users = append(users, user)
+	}
+	if err := b.shareGroupSession(roomID, session, users); err != nil {
+		return megolmEncryptedContent{}, err
+	}
+
+	plaintext, err := json.Marshal(megolmPayload{Type: eventType, Content: content, RoomID: roomID})
+	if err != nil {
+		return megolmEncryptedContent{}, err
+	}
+
+	return megolmEncryptedContent{
+		Algorithm:  algorithmMegolm,
+		SenderKey:  b.crypto.account.IdentityKey(),
+		Ciphertext: session.Session.Encrypt(plaintext),
+		SessionID:  session.Session.ID(),
+		DeviceID:   b.deviceID,
+	}, nil
+}
+
+// newOutboundGroupSession creates a new Megolm session for the room. The caller has to hold the lock of the encryption state.
+func (b *Bot) newOutboundGroupSession(roomID string) (*outboundGroupSession, error) {
+	s, err := olm.NewOutboundGroupSession()
+	if err != nil {
+		return nil, err
+	}
+	session := &outboundGroupSession{Session: s, Created: time.Now(), SharedWith: make(map[string]map[string]bool)}
+	b.crypto.outboundGroupSessions[roomID] = session
+
+	// Our own messages shall be readable, too
+	inbound, err := olm.NewInboundGroupSession(s.SessionKey())
+	if err != nil {
+		return nil, err
+	}
+	b.crypto.inboundGroupSessions[groupSessionKey(roomID, b.crypto.account.IdentityKey(), s.ID())] = inbound
+
+	log.Debugf("Created new Megolm session %s for room %s", s.ID(), roomID)
+	return session, nil
+}
+
+// shareGroupSession sends the key of the Megolm session to all devices of the users which do not
+// have it, yet. The caller has to hold the lock of the encryption state.
+func (b *Bot) shareGroupSession(roomID string, session *outboundGroupSession, users []string) error {
+	devices, err := b.getDevices(users)
+	if err != nil {
+		return err
+	}
+
+	var targets []deviceKeys
+	for user, userDevices := range devices {
+		for deviceID, device := range userDevices {
+			if (user == b.userID && deviceID == b.deviceID) || session.SharedWith[user][deviceID] {
+				continue
+			}
+			targets = append(targets, device)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if err := b.createOlmSessions(targets); err != nil {
+		return err
+	}
+
+	roomKey, err := json.Marshal(roomKeyContent{
+		Algorithm:  algorithmMegolm,
+		RoomID:     roomID,
+		SessionID:  session.Session.ID(),
+		SessionKey: session.Session.SessionKey(),
+	})
+	if err != nil {
+		return err
+	}
+
+	messages := make(map[string]map[string]olmEncryptedContent)
+	var shared []deviceKeys
+	for _, device := range targets {
+		sessions := b.crypto.sessions[device.curve25519()]
+		if len(sessions) == 0 {
+			log.Warnf("No Olm session with device %s of %s, it cannot decrypt messages in room %s", device.DeviceID, device.UserID, roomID)
+			continue
+		}
+		content, err := b.encryptOlm(sessions[0], device, "m.room_key", roomKey)
+		if err != nil {
+			log.Warnf("Could not encrypt room key for device %s of %s: %s", device.DeviceID, device.UserID, err)
+			continue
+		}
+		if _, ok := messages[device.UserID]; !ok {
+			messages[device.UserID] = make(map[string]olmEncryptedContent)
+		}
+		messages[device.UserID][device.DeviceID] = content
+		shared = append(shared, device)
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"messages": messages})
+	if err != nil {
+		return err
+	}
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	response, err := b.api.call("/client/r0/sendToDevice/m.room.encrypted/"+txnID, "PUT", string(body), true)
+	if err != nil {
+		return fmt.Errorf("Error sending room key: %s", err)
+	}
+	var apiErr errorResponse
+	if err := json.Unmarshal(response, &apiErr); err == nil && len(apiErr.ErrCode) > 0 {
+		return fmt.Errorf("Error sending room key: %s: %s", apiErr.ErrCode, apiErr.Error)
+	}
+
+	for _, device := range shared {
+		if _, ok := session.SharedWith[device.UserID]; !ok {
+			session.SharedWith[device.UserID] = make(map[string]bool)
+		}
+		session.SharedWith[device.UserID][device.DeviceID] = true
+	}
+
+	return nil
+}
+
+// encryptOlm encrypts the event for the device. The caller has to hold the lock of the encryption state.
+func (b *Bot) encryptOlm(session *olm.Session, device deviceKeys, eventType string, content json.RawMessage) (olmEncryptedContent, error) {
+	plaintext, err := json.Marshal(olmPayload{
+		Type:          eventType,
+		Content:       content,
+		Sender:        b.userID,
+		SenderDevice:  b.deviceID,
+		Recipient:     device.UserID,
+		RecipientKeys: map[string]string{"ed25519": device.ed25519()},
+		Keys:          map[string]string{"ed25519": b.crypto.account.SigningKey()},
+	})
+	if err != nil {
+		return olmEncryptedContent{}, err
+	}
+
+	messageType, body, err := session.Encrypt(plaintext)
+	if err != nil {
+		return olmEncryptedContent{}, err
+	}
+
+	return olmEncryptedContent{
+		Algorithm: algorithmOlm,
+		SenderKey: b.crypto.account.IdentityKey(),
+		Ciphertext: map[string]olmCiphertext{
+			device.curve25519(): {Type: messageType, Body: base64.RawStdEncoding.EncodeToString(body)},
+		},
+	}, nil
+}
+
+// decodeContent converts the generic content of an event into v.
+func decodeContent(content map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,357 @@
+package matrix
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/platform/matrix/olm"
+	"github.com/torlenor/redseligg/plugin"
+	"github.com/torlenor/redseligg/storage/memorystorage"
+)
+
+const (
+	testRoomID      = "!room:server"
+	testBotUserID   = "@bot:server"
+	testAliceUserID = "@alice:server"
+	testAliceDevice = "ALICEDEVICE"
+)
+
+type mockPostPlugin struct {
+	plugin.RedseliggPlugin
+
+	posts []model.Post
+}
+
+func (p *mockPostPlugin) OnPost(post model.Post) {
+	p.posts = append(p.posts, post)
+}
+
+// testHomeserver is a minimal homeserver which serves one encrypted room with the bot and Alice.
+type testHomeserver struct {
+	t      *testing.T
+	server *httptest.Server
+	mutex  sync.Mutex
+
+	alice            *olm.Account
+	aliceDeviceKeys  deviceKeys
+	aliceOneTimeKey  signedKey
+	loginDeviceIDs   []string
+	deviceKeyUploads []deviceKeys
+	oneTimeKeys      map[string]signedKey
+
+	syncResponses []string
+	syncQueries   []url.Values
+
+	// stateKey is configured for the bots created by createBot
+	stateKey string
+
+	toDevice []map[string]map[string]olmEncryptedContent
+	sent     []megolmEncryptedContent
+}
+
+func newTestHomeserver(t *testing.T) *testHomeserver {
+	alice, err := olm.NewAccount()
+	require.NoError(t, err)
+	require.NoError(t, alice.GenerateOneTimeKeys(1))
+
+	hs := &testHomeserver{t: t, alice: alice, oneTimeKeys: make(map[string]signedKey)}
+
+	hs.aliceDeviceKeys = deviceKeys{
+		UserID:     testAliceUserID,
+		DeviceID:   testAliceDevice,
+		Algorithms: []string{algorithmOlm, algorithmMegolm},
+		Keys: map[string]string{
+			"curve25519:" + testAliceDevice: alice.IdentityKey(),
+			"ed25519:" + testAliceDevice:    alice.SigningKey(),
+		},
+	}
+	hs.aliceDeviceKeys.Signatures = hs.aliceSign(hs.aliceDeviceKeys)
+	for _, key := range alice.OneTimeKeys() {
+		hs.aliceOneTimeKey = signedKey{Key: key}
+	}
+	hs.aliceOneTimeKey.Signatures = hs.aliceSign(hs.aliceOneTimeKey)
+
+	hs.server = httptest.NewServer(http.HandlerFunc(hs.handle))
+	return hs
+}
+
+func (hs *testHomeserver) aliceSign(v interface{}) map[string]map[string]string {
+	data, err := canonicalJSON(v)
+	require.NoError(hs.t, err)
+	return map[string]map[string]string{testAliceUserID: {"ed25519:" + testAliceDevice: hs.alice.Sign(data)}}
+}
+
+func (hs *testHomeserver) handle(w http.ResponseWriter, r *http.Request) {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.Path, "/_matrix/client/r0")
+	var response interface{} = map[string]interface{}{}
+
+	switch {
+	case path == "/login":
+		var login loginRequest
+		json.Unmarshal(body, &login)
+		hs.loginDeviceIDs = append(hs.loginDeviceIDs, login.DeviceID)
+		deviceID := login.DeviceID
+		if len(deviceID) == 0 {
+			deviceID = "BOTDEVICE"
+		}
+		response = loginResponse{AccessToken: "TOKEN", UserID: testBotUserID, DeviceID: deviceID}
+	case path == "/user/"+testBotUserID+"/filter":
+		response = filterResponse{FilterID: "1"}
+	case path == "/sync":
+		hs.syncQueries = append(hs.syncQueries, r.URL.Query())
+		if len(hs.syncResponses) == 0 {
+			w.Write([]byte(`{"next_batch":"` + r.URL.Query().Get("since") + `"}`))
+			return
+		}
+		w.Write([]byte(hs.syncResponses[0]))
+		hs.syncResponses = hs.syncResponses[1:]
+		return
+	case path == "/keys/upload":
+		var upload keysUploadRequest
+		json.Unmarshal(body, &upload)
+		if upload.DeviceKeys != nil {
+			hs.deviceKeyUploads = append(hs.deviceKeyUploads, *upload.DeviceKeys)
+		}
+		for id, key := range upload.OneTimeKeys {
+			hs.oneTimeKeys[id] = key
+		}
+		response = keysUploadResponse{OneTimeKeyCounts: map[string]int{keyAlgorithm: len(hs.oneTimeKeys)}}
+	case path == "/keys/query":
+		response = keysQueryResponse{DeviceKeys: map[string]map[string]deviceKeys{
+			testAliceUserID: {testAliceDevice: hs.aliceDeviceKeys},
+		}}
+	case path == "/keys/claim":
+		response = keysClaimResponse{OneTimeKeys: map[string]map[string]map[string]signedKey{
+			testAliceUserID: {testAliceDevice: {keyAlgorithm + ":AAAAAQ": hs.aliceOneTimeKey}},
+		}}
+	case strings.HasPrefix(path, "/sendToDevice/m.room.encrypted/"):
+		var request struct {
+			Messages map[string]map[string]olmEncryptedContent `json:"messages"`
+		}
+		json.Unmarshal(body, &request)
+		hs.toDevice = append(hs.toDevice, request.Messages)
+	case path == "/rooms/"+testRoomID+"/joined_members":
+		response = joinedMembersResponse{Joined: map[string]interface{}{testBotUserID: map[string]interface{}{}, testAliceUserID: map[string]interface{}{}}}
+	case path == "/rooms/"+testRoomID+"/state/m.room.encryption":
+		response = roomEncryptionContent{Algorithm: algorithmMegolm}
+	case path == "/rooms/"+testRoomID+"/send/m.room.encrypted":
+		var content megolmEncryptedContent
+		json.Unmarshal(body, &content)
+		hs.sent = append(hs.sent, content)
+		response = sendResponse{EventID: "$sent"}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		response = errorResponse{ErrCode: "M_NOT_FOUND", Error: "Unknown path " + path}
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+func (hs *testHomeserver) createBot(st *memorystorage.MemoryStorage) *Bot {
+	bot, err := CreateMatrixBot("BOT", botconfig.MatrixConfig{
+		Server:     hs.server.URL,
+		Username:   "bot",
+		Password:   "password",
+		Encryption: true,
+		StateKey:   hs.stateKey,
+	}, st, commanddispatcher.New(""))
+	require.NoError(hs.t, err)
+	return bot
+}
+
+func TestMatrixBotEncryption(t *testing.T) {
+	assert := assert.New(t)
+
+	hs := newTestHomeserver(t)
+	defer hs.server.Close()
+	doHTTPRequest = hs.server.Client().Do
+	st := memorystorage.New()
+
+	bot := hs.createBot(st)
+
+	// The device keys and the one-time keys have to be uploaded signed
+	require.Len(t, hs.deviceKeyUploads, 1)
+	botKeys := hs.deviceKeyUploads[0]
+	assert.Equal("BOTDEVICE", botKeys.DeviceID)
+	assert.NoError(verify(botKeys, botKeys.Signatures, testBotUserID, "BOTDEVICE", botKeys.ed25519()))
+	assert.Len(hs.oneTimeKeys, olm.MaxOneTimeKeys/2)
+	var botOneTimeKey signedKey
+	for _, key := range hs.oneTimeKeys {
+		assert.NoError(verify(key, key.Signatures, testBotUserID, "BOTDEVICE", botKeys.ed25519()))
+		botOneTimeKey = key
+	}
+
+	// Alice shares a Megolm session with the bot via Olm and sends an encrypted message
+	aliceOlm, err := hs.alice.NewOutboundSession(botKeys.curve25519(), botOneTimeKey.Key)
+	require.NoError(t, err)
+	aliceMegolm, err := olm.NewOutboundGroupSession()
+	require.NoError(t, err)
+
+	roomKey, _ := json.Marshal(roomKeyContent{Algorithm: algorithmMegolm, RoomID: testRoomID, SessionID: aliceMegolm.ID(), SessionKey: aliceMegolm.SessionKey()})
+	payload, _ := json.Marshal(olmPayload{
+		Type:          "m.room_key",
+		Content:       roomKey,
+		Sender:        testAliceUserID,
+		Recipient:     testBotUserID,
+		RecipientKeys: map[string]string{"ed25519": botKeys.ed25519()},
+		Keys:          map[string]string{"ed25519": hs.alice.SigningKey()},
+	})
+	messageType, message, err := aliceOlm.Encrypt(payload)
+	require.NoError(t, err)
+	toDevice, _ := json.Marshal(olmEncryptedContent{
+		Algorithm:  algorithmOlm,
+		SenderKey:  hs.alice.IdentityKey(),
+		Ciphertext: map[string]olmCiphertext{botKeys.curve25519(): {Type: messageType, Body: base64.RawStdEncoding.EncodeToString(message)}},
+	})
+
+	roomMessage, _ := json.Marshal(megolmPayload{Type: "m.room.message", Content: json.RawMessage(`{"msgtype":"m.text","body":"secret hello"}`), RoomID: testRoomID})
+	encrypted, _ := json.Marshal(megolmEncryptedContent{
+		Algorithm:  algorithmMegolm,
+		SenderKey:  hs.alice.IdentityKey(),
+		Ciphertext: aliceMegolm.Encrypt(roomMessage),
+		SessionID:  aliceMegolm.ID(),
+		DeviceID:   testAliceDevice,
+	})
+
+	hs.syncResponses = []string{
+		`{"next_batch":"s1","device_one_time_keys_count":{"signed_curve25519":50},"rooms":{"join":{"` + testRoomID + `":{"state":{"events":[
+			{"type":"m.room.encryption","state_key":"","content":{"algorithm":"` + algorithmMegolm + `"}}]}}}}}`,
+		`{"next_batch":"s2","to_device":{"events":[{"type":"m.room.encrypted","sender":"` + testAliceUserID + `","content":` + string(toDevice) + `}]},
+			"rooms":{"join":{"` + testRoomID + `":{"timeline":{"events":[
+			{"type":"m.room.encrypted","sender":"` + testAliceUserID + `","event_id":"$1","content":` + string(encrypted) + `}]}}}}}`,
+	}
+
+	p := &mockPostPlugin{}
+	bot.plugins = append(bot.plugins, p)
+
+	require.NoError(t, bot.handlePolling())
+	require.NoError(t, bot.handlePolling())
+
+	require.Len(t, hs.syncQueries, 2)
+	assert.Equal("1", hs.syncQueries[0].Get("filter"))
+	assert.Equal("", hs.syncQueries[0].Get("since"))
+	assert.Equal("s1", hs.syncQueries[1].Get("since"))
+	assert.Equal("30000", hs.syncQueries[1].Get("timeout"))
+
+	require.Len(t, p.posts, 1)
+	assert.Equal("secret hello", p.posts[0].Content)
+	assert.Equal(testRoomID, p.posts[0].ChannelID)
+	assert.Equal(testAliceUserID, p.posts[0].User.ID)
+
+	// The bot shares its own Megolm session with Alice and sends an encrypted message
+	_, err = bot.CreatePost(model.Post{ChannelID: testRoomID, Content: "secret reply"})
+	require.NoError(t, err)
+	require.Len(t, hs.toDevice, 1)
+	require.Len(t, hs.sent, 1)
+
+	botRoomKey := hs.toDevice[0][testAliceUserID][testAliceDevice]
+	ciphertext := botRoomKey.Ciphertext[hs.alice.IdentityKey()]
+	body, err := base64.RawStdEncoding.DecodeString(ciphertext.Body)
+	require.NoError(t, err)
+	// The bot reuses the Olm session established by Alice
+	assert.Equal(olm.MessageTypeNormal, ciphertext.Type)
+	plaintext, err := aliceOlm.Decrypt(ciphertext.Type, body)
+	require.NoError(t, err)
+
+	var botPayload olmPayload
+	require.NoError(t, json.Unmarshal(plaintext, &botPayload))
+	assert.Equal("m.room_key", botPayload.Type)
+	assert.Equal(testAliceUserID, botPayload.Recipient)
+	var botKey roomKeyContent
+	require.NoError(t, json.Unmarshal(botPayload.Content, &botKey))
+	aliceInboundGroup, err := olm.NewInboundGroupSession(botKey.SessionKey)
+	require.NoError(t, err)
+
+	decryptSent := func(i int) string {
+		plaintext, _, err := aliceInboundGroup.Decrypt(hs.sent[i].Ciphertext)
+		require.NoError(t, err)
+		var payload megolmPayload
+		require.NoError(t, json.Unmarshal(plaintext, &payload))
+		assert.Equal(testRoomID, payload.RoomID)
+		assert.Equal("m.room.message", payload.Type)
+		var content roomEventContent
+		require.NoError(t, json.Unmarshal(payload.Content, &content))
+		return content.Body
+	}
+	assert.Equal("secret reply", decryptSent(0))
+
+	// The sync token, the device and the encryption state are persisted
+	state, err := st.GetMatrixState("BOT")
+	require.NoError(t, err)
+	assert.Equal("s2", state.NextBatch)
+	assert.Equal("BOTDEVICE", state.DeviceID)
+	assert.NotEmpty(state.Encryption)
+
+	// After a restart the bot continues with the same device, sync token and keys
+	bot.Stop()
+	restarted := hs.createBot(st)
+	assert.Equal([]string{"", "BOTDEVICE"}, hs.loginDeviceIDs)
+	assert.Len(hs.deviceKeyUploads, 1)
+	assert.Equal(bot.crypto.account.IdentityKey(), restarted.crypto.account.IdentityKey())
+
+	require.NoError(t, restarted.handlePolling())
+	assert.Equal("s2", hs.syncQueries[2].Get("since"))
+
+	_, err = restarted.CreatePost(model.Post{ChannelID: testRoomID, Content: "after restart"})
+	require.NoError(t, err)
+	assert.Len(hs.toDevice, 1, "Megolm session should not be shared again")
+	require.Len(t, hs.sent, 2)
+	assert.Equal("after restart", decryptSent(1))
+}
+
+func TestMatrixBotStopStoresState(t *testing.T) {
+	assert := assert.New(t)
+
+	hs := newTestHomeserver(t)
+	defer hs.server.Close()
+	doHTTPRequest = hs.server.Client().Do
+	st := memorystorage.New()
+
+	hs.stateKey = "secret"
+	hs.syncResponses = []string{`{"next_batch":"s1"}`}
+	bot := hs.createBot(st)
+	identityKey := bot.crypto.account.IdentityKey()
+
+	bot.Start()
+	assert.Eventually(func() bool {
+		hs.mutex.Lock()
+		defer hs.mutex.Unlock()
+		return len(hs.syncQueries) > 1
+	}, time.Second, time.Millisecond)
+	bot.Stop()
+
+	// Stop waits for the sync, so the latest sync token is stored
+	state, err := st.GetMatrixState("BOT")
+	require.NoError(t, err)
+	assert.Equal("s1", state.NextBatch)
+
+	// The private keys are not stored in plain text
+	assert.True(strings.HasPrefix(state.Encryption, sealedStatePrefix))
+	assert.NotContains(state.Encryption, "signing_key")
+
+	restarted := hs.createBot(st)
+	assert.Equal(identityKey, restarted.crypto.account.IdentityKey())
+
+	hs.stateKey = "wrong"
+	_, err = CreateMatrixBot("BOT", botconfig.MatrixConfig{Server: hs.server.URL, Username: "bot", Password: "password", Encryption: true, StateKey: hs.stateKey}, st, commanddispatcher.New(""))
+	assert.Error(err)
+}
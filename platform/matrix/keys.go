@@ -0,0 +1,258 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/platform/matrix/olm"
+)
+
+const keyAlgorithm = "signed_curve25519"
+
+// deviceKeys are the public keys of a device, signed by the device.
+type deviceKeys struct {
+	UserID     string                       `json:"user_id"`
+	DeviceID   string                       `json:"device_id"`
+	Algorithms []string                     `json:"algorithms"`
+	Keys       map[string]string            `json:"keys"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+}
+
+func (d deviceKeys) curve25519() string { return d.Keys["curve25519:"+d.DeviceID] }
+func (d deviceKeys) ed25519() string    { return d.Keys["ed25519:"+d.DeviceID] }
+
+// signedKey is a one-time key signed by the device.
+type signedKey struct {
+	Key        string                       `json:"key"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+}
+
+type keysUploadRequest struct {
+	DeviceKeys  *deviceKeys          `json:"device_keys,omitempty"`
+	OneTimeKeys map[string]signedKey `json:"one_time_keys,omitempty"`
+}
+
+type keysUploadResponse struct {
+	OneTimeKeyCounts map[string]int `json:"one_time_key_counts"`
+}
+
+type keysQueryRequest struct {
+	DeviceKeys map[string][]string `json:"device_keys"`
+	Timeout    int                 `json:"timeout"`
+}
+
+type keysQueryResponse struct {
+	DeviceKeys map[string]map[string]deviceKeys `json:"device_keys"`
+}
+
+type keysClaimRequest struct {
+	OneTimeKeys map[string]map[string]string `json:"one_time_keys"`
+	Timeout     int                          `json:"timeout"`
+}
+
+type keysClaimResponse struct {
+	OneTimeKeys map[string]map[string]map[string]signedKey `json:"one_time_keys"`
+}
+
+// canonicalJSON returns the canonical JSON of v which is signed, i.e., with sorted keys, without
+// insignificant whitespace and without the signatures and unsigned fields.
+func canonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]interface{}
+	if err := decoder.Decode(&m); err != nil {
+		return nil, err
+	}
+	delete(m, "signatures")
+	delete(m, "unsigned")
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(m); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// sign returns the signatures of v made by the device of the bot.
+func (b *Bot) sign(v interface{}) (map[string]map[string]string, error) {
+	data, err := canonicalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]map[string]string{
+		b.userID: {"ed25519:" + b.deviceID: b.crypto.account.Sign(data)},
+	}, nil
+}
+
+// verify checks the signature of v made by the device with the Ed25519 key.
+func verify(v interface{}, signatures map[string]map[string]string, userID string, deviceID string, key string) error {
+	signature, ok := signatures[userID]["ed25519:"+deviceID]
+	if !ok {
+		return fmt.Errorf("No signature of device %s of %s", deviceID, userID)
+	}
+	data, err := canonicalJSON(v)
+	if err != nil {
+		return err
+	}
+	return olm.VerifySignature(key, data, signature)
+}
+
+// postKeys calls a keys endpoint of the Matrix API.
+func (b *Bot) postKeys(path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "json marshal failed")
+	}
+
+	data, err := b.api.call("/client/r0/keys/"+path, "POST", string(body), true)
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+
+	var apiErr errorResponse
+	if err := json.Unmarshal(data, &apiErr); err == nil && len(apiErr.ErrCode) > 0 {
+		return fmt.Errorf("%s: %s", apiErr.ErrCode, apiErr.Error)
+	}
+
+	return json.Unmarshal(data, response)
+}
+
+// uploadKeys uploads new one-time keys so that the server has half of the maximum number of keys
+// and, if requested, the device keys. The caller has to hold the lock of the encryption state.
+func (b *Bot) uploadKeys(withDeviceKeys bool, serverKeyCount int) error {
+	request := keysUploadRequest{}
+
+	if withDeviceKeys {
+		keys := deviceKeys{
+			UserID:     b.userID,
+			DeviceID:   b.deviceID,
+			Algorithms: []string{algorithmOlm, algorithmMegolm},
+			Keys: map[string]string{
+				"curve25519:" + b.deviceID: b.crypto.account.IdentityKey(),
+				"ed25519:" + b.deviceID:    b.crypto.account.SigningKey(),
+			},
+		}
+		signatures, err := b.sign(keys)
+		if err != nil {
+			return err
+		}
+		keys.Signatures = signatures
+		request.DeviceKeys = &keys
+	}
+
+	if count := olm.MaxOneTimeKeys/2 - serverKeyCount; count > 0 {
+		if err := b.crypto.account.GenerateOneTimeKeys(count); err != nil {
+			return err
+		}
+	}
+	for id, key := range b.crypto.account.OneTimeKeys() {
+		k := signedKey{Key: key}
+		signatures, err := b.sign(k)
+		if err != nil {
+			return err
+		}
+		k.Signatures = signatures
+		if request.OneTimeKeys == nil {
+			request.OneTimeKeys = make(map[string]signedKey)
+		}
+		request.OneTimeKeys[keyAlgorithm+":"+id] = k
+	}
+
+	var response keysUploadResponse
+	if err := b.postKeys("upload", request, &response); err != nil {
+		return fmt.Errorf("Error uploading keys: %s", err)
+	}
+	b.crypto.account.MarkKeysAsPublished()
+
+	log.Debugf("Uploaded keys, the server has now %d one-time keys", response.OneTimeKeyCounts[keyAlgorithm])
+	return nil
+}
+
+// getDevices returns the devices of the users. Devices of users which are not known are queried
+// from the server. The caller has to hold the lock of the encryption state.
+func (b *Bot) getDevices(users []string) (map[string]map[string]deviceKeys, error) {
+	request := keysQueryRequest{DeviceKeys: make(map[string][]string), Timeout: 10000}
+	for _, user := range users {
+		if _, ok := b.crypto.devices[user]; !ok {
+			request.DeviceKeys[user] = []string{}
+		}
+	}
+
+	if len(request.DeviceKeys) > 0 {
+		var response keysQueryResponse
+		if err := b.postKeys("query", request, &response); err != nil {
+			return nil, fmt.Errorf("Error querying device keys: %s", err)
+		}
+
+		for user := range request.DeviceKeys {
+			b.crypto.devices[user] = make(map[string]deviceKeys)
+			for deviceID, device := range response.DeviceKeys[user] {
+				if device.UserID != user || device.DeviceID != deviceID {
+					log.Warnf("Ignoring device %s of %s with wrong user or device ID", deviceID, user)
+					continue
+				}
+				if err := verify(device, device.Signatures, user, deviceID, device.ed25519()); err != nil {
+					log.Warnf("Ignoring device %s of %s with invalid signature: %s", deviceID, user, err)
+					continue
+				}
+				b.crypto.devices[user][deviceID] = device
+			}
+		}
+	}
+
+	devices := make(map[string]map[string]deviceKeys)
+	for _, user := range users {
+		devices[user] = b.crypto.devices[user]
+	}
+	return devices, nil
+}
+
+// createOlmSessions claims one-time keys of the devices without Olm session and creates new sessions
+// with them. The caller has to hold the lock of the encryption state.
+func (b *Bot) createOlmSessions(devices []deviceKeys) error {
+	request := keysClaimRequest{OneTimeKeys: make(map[string]map[string]string), Timeout: 10000}
+	for _, device := range devices {
+		if len(b.crypto.sessions[device.curve25519()]) > 0 {
+			continue
+		}
+		if _, ok := request.OneTimeKeys[device.UserID]; !ok {
+			request.OneTimeKeys[device.UserID] = make(map[string]string)
+		}
+		request.OneTimeKeys[device.UserID][device.DeviceID] = keyAlgorithm
+	}
+	if len(request.OneTimeKeys) == 0 {
+		return nil
+	}
+
+	var response keysClaimResponse
+	if err := b.postKeys("claim", request, &response); err != nil {
+		return fmt.Errorf("Error claiming one-time keys: %s", err)
+	}
+
+	for _, device := range devices {
+		for _, key := range response.OneTimeKeys[device.UserID][device.DeviceID] {
+			if err := verify(key, key.Signatures, device.UserID, device.DeviceID, device.ed25519()); err != nil {
+				log.Warnf("Invalid one-time key of device %s of %s: %s", device.DeviceID, device.UserID, err)
+				continue
+			}
+			session, err := b.crypto.account.NewOutboundSession(device.curve25519(), key.Key)
+			if err != nil {
+				log.Warnf("Could not create Olm session with device %s of %s: %s", device.DeviceID, device.UserID, err)
+				continue
+			}
+			b.crypto.addSession(device.curve25519(), session)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pillPattern matches links to users in the formatted body, e.g., <a href="https://matrix.to/#/@bot:server">Bot</a>,
+// which clients show as pills.
+var pillPattern = regexp.MustCompile(`<a\s+href="https://matrix\.to/#/([^"?]+)[^"]*"\s*>(.*?)</a>`)
+
+// pillTexts returns the texts of the pills in the formatted body which link to the user.
+func pillTexts(formattedBody string, userID string) []string {
+	var texts []string
+	for _, m := range pillPattern.FindAllStringSubmatch(formattedBody, -1) {
+		id, err := url.PathUnescape(m[1])
+		if err != nil || id != userID {
+			continue
+		}
+		texts = append(texts, html.UnescapeString(m[2]))
+	}
+	return texts
+}
+
+// fetchDisplayName gets the display name of the bot, which clients use for mentions of the bot.
+func (b *Bot) fetchDisplayName() {
+	user, err := b.fetchUser(b.userID)
+	if err != nil {
+		log.Warnf("Could not get the display name of the bot, mentions by display name are not recognized: %s", err)
+		return
+	}
+	b.displayName = user.Nickname
+}
+
+// mentionsBot returns true if the message mentions the bot in m.mentions or with a pill in the formatted body.
+func (b *Bot) mentionsBot(content roomEventContent) bool {
+	for _, userID := range content.Mentions.UserIDs {
+		if userID == b.userID {
+			return true
+		}
+	}
+	return len(pillTexts(content.FormattedBody, b.userID)) > 0
+}
+
+// normalizeMentions returns the body of the message with the mention of the bot replaced by its user ID,
+// which is the mention the dispatcher knows. Clients put the text of the pill, usually the display name,
+// into the body. The display name is only replaced if the message mentions the bot, so that the name
+// alone is no mention. If the bot is mentioned in m.mentions only, its user ID is appended.
+func (b *Bot) normalizeMentions(content roomEventContent) string {
+	body := content.Body
+	if !b.mentionsBot(content) || strings.Contains(body, b.userID) {
+		return body
+	}
+
+	names := append(pillTexts(content.FormattedBody, b.userID), b.displayName)
+	for _, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+		for _, n := range []string{"@" + name, name} {
+			if i := strings.Index(body, n); i >= 0 {
+				return body[:i] + b.userID + body[i+len(n):]
+			}
+		}
+	}
+
+	return body + " " + b.userID
+}
@@ -0,0 +1,84 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/storage"
+)
+
+func TestMatrixBotNormalizeMentions(t *testing.T) {
+	b := &Bot{userID: "@bot:server", displayName: "Bot"}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"No mention", `{"body":"Bot roll 20"}`, "Bot roll 20"},
+		{"User ID", `{"body":"@bot:server roll 20"}`, "@bot:server roll 20"},
+		{"Pill", `{"body":"Robo: roll 20","formatted_body":"<a href=\"https://matrix.to/#/@bot:server\">Robo</a>: roll 20"}`, "@bot:server: roll 20"},
+		{"Escaped pill", `{"body":"R&D: roll 20","formatted_body":"<a href=\"https://matrix.to/#/%40bot%3Aserver\">R&amp;D</a>: roll 20"}`, "@bot:server: roll 20"},
+		{"Pill of other user", `{"body":"Bot: roll 20","formatted_body":"<a href=\"https://matrix.to/#/@other:server\">Bot</a>: roll 20"}`, "Bot: roll 20"},
+		{"Display name", `{"body":"@Bot roll 20","m.mentions":{"user_ids":["@bot:server"]}}`, "@bot:server roll 20"},
+		{"Only m.mentions", `{"body":"hello","m.mentions":{"user_ids":["@other:server","@bot:server"]}}`, "hello @bot:server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content roomEventContent
+			if err := json.Unmarshal([]byte(tt.content), &content); err != nil {
+				t.Fatalf("Invalid content: %s", err)
+			}
+			assert.Equal(t, tt.want, b.normalizeMentions(content))
+		})
+	}
+}
+
+type mockCommandReceiver struct {
+	content string
+}
+
+func (r *mockCommandReceiver) OnCommand(cmd string, content string, post model.Post) {
+	r.content = content
+}
+
+func TestMatrixBotMentionCommand(t *testing.T) {
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	dispatcher := commanddispatcher.New("")
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, &storage.MockStorage{})
+	if bot == nil || err != nil {
+		t.Fatalf("Could not create Matrix Bot")
+	}
+	p := &mockPostPlugin{}
+	bot.plugins = append(bot.plugins, p)
+	receiver := &mockCommandReceiver{}
+	dispatcher.Register("roll", receiver, commanddispatcher.CommandHelp{})
+
+	messages := `{"next_batch":"%s","rooms":{"join":{"!room:server":{"timeline":{"events":[
+		{"type":"m.room.message","sender":"@user:server","event_id":"$1","content":{"msgtype":"m.text","body":"Bot: roll 20",
+			"format":"org.matrix.custom.html","formatted_body":"<a href=\"https://matrix.to/#/@TEST_USER:TEST_SERVER\">Bot</a>: roll 20",
+			"m.mentions":{"user_ids":["@TEST_USER:TEST_SERVER"]}}}
+	]}}}}}`
+
+	// Messages of the initial sync are old and not reported
+	api.apiResponse = fmt.Sprintf(messages, "s1")
+	if err := bot.handlePolling(); err != nil {
+		t.Fatalf("Sync failed: %s", err)
+	}
+	api.apiResponse = fmt.Sprintf(messages, "s2")
+	if err := bot.handlePolling(); err != nil {
+		t.Fatalf("Sync failed: %s", err)
+	}
+
+	assert.Equal(t, "20", receiver.content)
+	if assert.Len(t, p.posts, 1) {
+		assert.Equal(t, "Bot: roll 20", p.posts[0].Content)
+	}
+}
@@ -1,38 +1,202 @@
 package matrix
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
 	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
 )
 
+const formatHTML = "org.matrix.custom.html"
+
+type inReplyTo struct {
+	EventID string `json:"event_id"`
+}
+
+type relatesTo struct {
+	InReplyTo *inReplyTo `json:"m.in_reply_to,omitempty"`
+
+	// RelType and EventID are used for relations like edits (m.replace).
+	RelType string `json:"rel_type,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+}
+
+type roomMessage struct {
+	MsgType       string     `json:"msgtype"`
+	Body          string     `json:"body"`
+	Format        string     `json:"format,omitempty"`
+	FormattedBody string     `json:"formatted_body,omitempty"`
+	RelatesTo     *relatesTo `json:"m.relates_to,omitempty"`
+
+	// NewContent is the replacement content of an edit.
+	NewContent *roomMessage `json:"m.new_content,omitempty"`
+}
+
+// convertMessageFromRedseligg converts a post into a Matrix message with a HTML formatted body.
+// Embeds and attachments are added to the body.
+func convertMessageFromRedseligg(post model.Post) roomMessage {
+	msg := roomMessage{
+		MsgType:       "m.text",
+		Body:          post.Render(model.PlainText, true, true),
+		Format:        formatHTML,
+		FormattedBody: post.Render(model.HTML, true, true),
+	}
+	if len(post.ReplyTo) > 0 {
+		msg.RelatesTo = &relatesTo{InReplyTo: &inReplyTo{EventID: post.ReplyTo}}
+	}
+	return msg
+}
+
+// sendResponse is returned by the Matrix API after sending or redacting an event.
+type sendResponse struct {
+	EventID string `json:"event_id"`
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+// parseSendResponse returns the ID of the event created by the request or an error if the
+// Matrix API reported one.
+func parseSendResponse(response []byte) (string, error) {
+	var r sendResponse
+	if err := json.Unmarshal(response, &r); err != nil {
+		log.Debugf("Could not parse send response %s: %s", response, err)
+		return "", nil
+	}
+	if len(r.ErrCode) > 0 {
+		return "", fmt.Errorf("%s: %s", r.ErrCode, r.Error)
+	}
+	return r.EventID, nil
+}
+
 // The sendWhisper function sends a whisper to the user with userID
 //
 // Note: sending a whisper is the same as sending a message
 // just with a room id which belongs to just the two
 // participants
-func (b Bot) sendWhisper(userID string, content string) error {
+func (b *Bot) sendWhisper(userID string, content string) (model.MessageIdentifier, error) {
 	return b.sendRoomMessage(userID, content)
 }
 
 // The sendRoomMessage function sends a message to the room with
 // the ID roomID.
-func (b Bot) sendRoomMessage(roomIdent string, content string) error {
+func (b *Bot) sendRoomMessage(roomIdent string, content string) (model.MessageIdentifier, error) {
+	roomID := b.resolveRoomID(roomIdent)
+
+	response, err := b.sendRoomEvent(roomID, "m.room.message", `{"msgtype":"m.text", "body":"`+content+`"}`)
+	if err != nil {
+		return model.MessageIdentifier{}, errors.Wrap(err, "apiCall failed")
+	}
+
+	log.Traceln("send api response:", string(response))
+	log.Tracef("Sent: MESSAGE to roomID = %s, Content = %s", roomID, content)
+
+	eventID, err := parseSendResponse(response)
+	return model.MessageIdentifier{ID: eventID, Channel: roomID}, err
+}
+
+// The sendFormattedRoomMessage function sends a formatted message to the room with
+// the ID roomID.
+func (b *Bot) sendFormattedRoomMessage(roomIdent string, msg roomMessage) (model.MessageIdentifier, error) {
+	roomID := b.resolveRoomID(roomIdent)
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return model.MessageIdentifier{}, errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.sendRoomEvent(roomID, "m.room.message", string(body))
+	if err != nil {
+		return model.MessageIdentifier{}, errors.Wrap(err, "apiCall failed")
+	}
+
+	log.Traceln("send api response:", string(response))
+	log.Tracef("Sent: FORMATTED MESSAGE to roomID = %s, Content = %s", roomID, msg.Body)
+
+	eventID, err := parseSendResponse(response)
+	return model.MessageIdentifier{ID: eventID, Channel: roomID}, err
+}
+
+// The sendRoomEvent function sends an event with the JSON content to the room.
+// If end-to-end encryption is enabled and the room is encrypted the event is encrypted.
+func (b *Bot) sendRoomEvent(roomID string, eventType string, content string) ([]byte, error) {
+	if b.crypto != nil {
+		encrypted, err := b.isRoomEncrypted(roomID)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted {
+			encryptedContent, err := b.encryptRoomEvent(roomID, eventType, json.RawMessage(content))
+			if err != nil {
+				return nil, errors.Wrap(err, "encryption failed")
+			}
+			body, err := json.Marshal(encryptedContent)
+			if err != nil {
+				return nil, errors.Wrap(err, "json marshal failed")
+			}
+			eventType = "m.room.encrypted"
+			content = string(body)
+		}
+	}
+
+	return b.api.call("/client/r0/rooms/"+roomID+"/send/"+eventType, "POST", content, true)
+}
+
+// The editRoomMessage function replaces the content of the message with a m.replace relation.
+// Clients without support for edits show the new content prefixed with "* ".
+func (b *Bot) editRoomMessage(messageID model.MessageIdentifier, msg roomMessage) error {
+	newContent := msg
+	newContent.RelatesTo = nil
+
+	msg.Body = "* " + msg.Body
+	if len(msg.FormattedBody) > 0 {
+		msg.FormattedBody = "* " + msg.FormattedBody
+	}
+	msg.NewContent = &newContent
+	msg.RelatesTo = &relatesTo{RelType: "m.replace", EventID: messageID.ID}
+
+	_, err := b.sendFormattedRoomMessage(messageID.Channel, msg)
+	return err
+}
+
+// The redactRoomMessage function removes the content of the message.
+func (b *Bot) redactRoomMessage(messageID model.MessageIdentifier) error {
+	roomID := b.resolveRoomID(messageID.Channel)
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	response, err := b.api.call("/client/r0/rooms/"+roomID+"/redact/"+url.PathEscape(messageID.ID)+"/"+txnID, "PUT", `{}`, true)
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+
+	log.Tracef("Sent: REDACTION of event %s in roomID = %s", messageID.ID, roomID)
+
+	_, err = parseSendResponse(response)
+	return err
+}
+
+// resolveRoomID returns the room ID for a known room name, room alias or room ID.
+// Unknown rooms are assumed to be given by their ID.
+func (b *Bot) resolveRoomID(roomIdent string) string {
 	var roomID string
 	if _, ok := b.knownRoomIDs[roomIdent]; ok {
 		roomID = roomIdent
 	} else if val, ok := b.knownRooms[roomIdent]; ok {
 		roomID = val
+	} else if isRoomAlias(roomIdent) {
+		var err error
+		if roomID, err = b.resolveRoomAlias(roomIdent); err != nil {
+			log.Warnf("%s. We will try to use it as a roomID", err)
+			roomID = roomIdent
+		}
 	} else {
 		log.Warnf("Unknown roomIdent %s. We will try to use it as a roomID", roomIdent)
 		roomID = roomIdent
 	}
 
-	response, err := b.api.call("/client/r0/rooms/"+roomID+"/send/m.room.message", "POST", `{"msgtype":"m.text", "body":"`+content+`"}`, true)
-	if err != nil {
-		return errors.Wrap(err, "apiCall failed")
-	}
-
-	log.Traceln("send api response:", string(response))
-	log.Tracef("Sent: MESSAGE to roomID = %s, Content = %s", roomID, content)
-
-	return nil
+	return roomID
 }
@@ -1,9 +1,13 @@
 package matrix
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 )
 
@@ -14,13 +18,13 @@ func TestSendRoomMessage(t *testing.T) {
 	dispatcher := commanddispatcher.New("")
 	storage := &storage.MockStorage{}
 
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create Matrix Bot")
 	}
 
 	// successfully sending message with assumption of roomID
-	err = bot.sendRoomMessage("_ROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendRoomMessage("_ROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_ROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -39,7 +43,7 @@ func TestSendRoomMessage(t *testing.T) {
 
 	// successfully sending message where room is mapped via NAME
 	bot.addKnownRoom("_REALROOMID_", "_REALROOMNAME_")
-	err = bot.sendRoomMessage("_REALROOMNAME_", "_MSGCONTENT_")
+	_, err = bot.sendRoomMessage("_REALROOMNAME_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_REALROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -58,7 +62,7 @@ func TestSendRoomMessage(t *testing.T) {
 
 	// successfully sending message where room is mapped via ID
 	bot.addKnownRoom("_REALROOMID_", "_REALROOMNAME_")
-	err = bot.sendRoomMessage("_REALROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendRoomMessage("_REALROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_REALROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -78,7 +82,7 @@ func TestSendRoomMessage(t *testing.T) {
 	// return error when api call fails
 	api.reset()
 	api.letAPICallFail = true
-	err = bot.sendRoomMessage("_ROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendRoomMessage("_ROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_ROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -106,13 +110,13 @@ func TestSendWhisper(t *testing.T) {
 	dispatcher := commanddispatcher.New("")
 	storage := &storage.MockStorage{}
 
-	bot, err := createMatrixBotWithAPI(api, "TEST_USER", "TEST_PASS", dispatcher, storage)
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, dispatcher, storage)
 	if bot == nil || err != nil {
 		t.Fatalf("Could not create Matrix Bot")
 	}
 
 	// successfully sending message with assumption of roomID
-	err = bot.sendWhisper("_ROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendWhisper("_ROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_ROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -131,7 +135,7 @@ func TestSendWhisper(t *testing.T) {
 
 	// successfully sending message where room is mapped via NAME
 	bot.addKnownRoom("_REALROOMID_", "_REALROOMNAME_")
-	err = bot.sendWhisper("_REALROOMNAME_", "_MSGCONTENT_")
+	_, err = bot.sendWhisper("_REALROOMNAME_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_REALROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -150,7 +154,7 @@ func TestSendWhisper(t *testing.T) {
 
 	// successfully sending message where room is mapped via ID
 	bot.addKnownRoom("_REALROOMID_", "_REALROOMNAME_")
-	err = bot.sendWhisper("_REALROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendWhisper("_REALROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_REALROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -170,7 +174,7 @@ func TestSendWhisper(t *testing.T) {
 	// return error when api call fails
 	api.reset()
 	api.letAPICallFail = true
-	err = bot.sendWhisper("_ROOMID_", "_MSGCONTENT_")
+	_, err = bot.sendWhisper("_ROOMID_", "_MSGCONTENT_")
 	if api.lastAPICallPath != `/client/r0/rooms/_ROOMID_/send/m.room.message` {
 		t.Fatalf("handlePolling api call path wrong: %s", api.lastAPICallPath)
 	}
@@ -187,3 +191,75 @@ func TestSendWhisper(t *testing.T) {
 		t.Fatalf("sending message not failed even though mock api call failed")
 	}
 }
+
+func TestSendFormattedRoomMessage(t *testing.T) {
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), &storage.MockStorage{})
+	if bot == nil || err != nil {
+		t.Fatalf("Could not create Matrix Bot")
+	}
+
+	post := model.Post{
+		Rich:    []model.Node{model.Text("Hello "), model.Bold(model.Text("<World>"))},
+		ReplyTo: "$EVENTID",
+	}
+	_, err = bot.sendFormattedRoomMessage("_ROOMID_", convertMessageFromRedseligg(post))
+	assert.NoError(t, err)
+	assert.Equal(t, `/client/r0/rooms/_ROOMID_/send/m.room.message`, api.lastAPICallPath)
+	var sent roomMessage
+	assert.NoError(t, json.Unmarshal([]byte(api.lastAPICallBody), &sent))
+	assert.Equal(t, roomMessage{
+		MsgType:       "m.text",
+		Body:          "Hello <World>",
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "Hello <strong>&lt;World&gt;</strong>",
+		RelatesTo:     &relatesTo{InReplyTo: &inReplyTo{EventID: "$EVENTID"}},
+	}, sent)
+}
+
+func TestEditAndRedactRoomMessage(t *testing.T) {
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), &storage.MockStorage{})
+	if bot == nil || err != nil {
+		t.Fatalf("Could not create Matrix Bot")
+	}
+
+	api.apiResponse = `{"event_id":"$NEWEVENT"}`
+	response, err := bot.CreatePost(model.Post{ChannelID: "_ROOMID_", Content: "Hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.MessageIdentifier{ID: "$NEWEVENT", Channel: "_ROOMID_"}, response.PostedMessageIdent)
+
+	messageID := model.MessageIdentifier{ID: "$EVENTID", Channel: "_ROOMID_"}
+	response, err = bot.UpdatePost(messageID, model.Post{Content: "Updated"})
+	assert.NoError(t, err)
+	assert.Equal(t, messageID, response.PostedMessageIdent)
+	assert.Equal(t, `/client/r0/rooms/_ROOMID_/send/m.room.message`, api.lastAPICallPath)
+	var sent roomMessage
+	assert.NoError(t, json.Unmarshal([]byte(api.lastAPICallBody), &sent))
+	assert.Equal(t, roomMessage{
+		MsgType:       "m.text",
+		Body:          "* Updated",
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "* Updated",
+		RelatesTo:     &relatesTo{RelType: "m.replace", EventID: "$EVENTID"},
+		NewContent: &roomMessage{
+			MsgType:       "m.text",
+			Body:          "Updated",
+			Format:        "org.matrix.custom.html",
+			FormattedBody: "Updated",
+		},
+	}, sent)
+
+	_, err = bot.DeletePost(messageID)
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", api.lastAPICallMethod)
+	assert.Regexp(t, `^/client/r0/rooms/_ROOMID_/redact/\$EVENTID/\d+$`, api.lastAPICallPath)
+
+	api.apiResponse = `{"errcode":"M_FORBIDDEN","error":"Not allowed"}`
+	_, err = bot.DeletePost(messageID)
+	assert.Error(t, err)
+}
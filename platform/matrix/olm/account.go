@@ -0,0 +1,251 @@
+package olm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxOneTimeKeys is the maximum number of one-time keys an Account keeps.
+// When more keys are generated the oldest ones are discarded.
+const MaxOneTimeKeys = 100
+
+type oneTimeKey struct {
+	ID        uint32            `json:"id"`
+	Key       curve25519KeyPair `json:"key"`
+	Published bool              `json:"published"`
+}
+
+// Account holds the long-term identity keys and the one-time keys of a device.
+type Account struct {
+	identityKey curve25519KeyPair
+	signingKey  ed25519.PrivateKey
+
+	oneTimeKeys      []oneTimeKey
+	nextOneTimeKeyID uint32
+}
+
+// NewAccount creates an Account with new identity keys.
+func NewAccount() (*Account, error) {
+	identityKey, err := generateCurve25519KeyPair()
+	if err != nil {
+		return nil, err
+	}
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate signing key: %s", err)
+	}
+
+	return &Account{
+		identityKey:      identityKey,
+		signingKey:       signingKey,
+		nextOneTimeKeyID: 1,
+	}, nil
+}
+
+// IdentityKey returns the public Curve25519 identity key of the account.
+func (a *Account) IdentityKey() string {
+	return encodeBase64(a.identityKey.Public[:])
+}
+
+// SigningKey returns the public Ed25519 fingerprint key of the account.
+func (a *Account) SigningKey() string {
+	return encodeBase64(a.signingKey.Public().(ed25519.PublicKey))
+}
+
+// Sign signs the message with the Ed25519 key of the account.
+func (a *Account) Sign(message []byte) string {
+	return encodeBase64(ed25519.Sign(a.signingKey, message))
+}
+
+// GenerateOneTimeKeys generates count new one-time keys.
+func (a *Account) GenerateOneTimeKeys(count int) error {
+	for i := 0; i < count; i++ {
+		key, err := generateCurve25519KeyPair()
+		if err != nil {
+			return err
+		}
+		a.oneTimeKeys = append(a.oneTimeKeys, oneTimeKey{ID: a.nextOneTimeKeyID, Key: key})
+		a.nextOneTimeKeyID++
+	}
+	if len(a.oneTimeKeys) > MaxOneTimeKeys {
+		a.oneTimeKeys = a.oneTimeKeys[len(a.oneTimeKeys)-MaxOneTimeKeys:]
+	}
+	return nil
+}
+
+// OneTimeKeys returns the one-time keys which have not been published, yet, as map of key ID to key.
+func (a *Account) OneTimeKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, k := range a.oneTimeKeys {
+		if !k.Published {
+			keys[keyID(k.ID)] = encodeBase64(k.Key.Public[:])
+		}
+	}
+	return keys
+}
+
+// MarkKeysAsPublished marks all current one-time keys as published.
+func (a *Account) MarkKeysAsPublished() {
+	for i := range a.oneTimeKeys {
+		a.oneTimeKeys[i].Published = true
+	}
+}
+
+func keyID(id uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, id)
+	return encodeBase64(b)
+}
+
+// NewOutboundSession creates a new Olm session to the device with the identity key using one of
+// its one-time keys. The first messages on this session are pre-key messages.
+func (a *Account) NewOutboundSession(theirIdentityKey string, theirOneTimeKey string) (*Session, error) {
+	identityKey, err := decodeKey(theirIdentityKey)
+	if err != nil {
+		return nil, err
+	}
+	oneTimeKey, err := decodeKey(theirOneTimeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	baseKey, err := generateCurve25519KeyPair()
+	if err != nil {
+		return nil, err
+	}
+	ratchetKey, err := generateCurve25519KeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	var secret []byte
+	secret = append(secret, sharedSecret(a.identityKey.Private, oneTimeKey)...)
+	secret = append(secret, sharedSecret(baseKey.Private, identityKey)...)
+	secret = append(secret, sharedSecret(baseKey.Private, oneTimeKey)...)
+
+	s := &Session{
+		AliceIdentityKey: a.identityKey.Public,
+		AliceBaseKey:     baseKey.Public,
+		BobOneTimeKey:    oneTimeKey,
+	}
+	s.initialize(secret)
+	s.Sender = &senderChain{RatchetKey: ratchetKey, ChainKey: s.Sender.ChainKey}
+
+	return s, nil
+}
+
+// NewInboundSession creates a new Olm session from a pre-key message sent by another device.
+// If theirIdentityKey is not empty the message has to be sent by the device with that key.
+// The one-time key used by the session should be removed with RemoveOneTimeKeys after the
+// message has been decrypted successfully.
+func (a *Account) NewInboundSession(theirIdentityKey string, message []byte) (*Session, error) {
+	m, err := decodePreKeyMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if len(theirIdentityKey) > 0 {
+		identityKey, err := decodeKey(theirIdentityKey)
+		if err != nil {
+			return nil, err
+		}
+		if identityKey != m.identityKey {
+			return nil, fmt.Errorf("Pre-key message was not sent by the expected device")
+		}
+	}
+	inner, err := decodeOlmMessage(m.message)
+	if err != nil {
+		return nil, err
+	}
+
+	oneTimeKey, ok := a.findOneTimeKey(m.oneTimeKey)
+	if !ok {
+		return nil, fmt.Errorf("Unknown one-time key")
+	}
+
+	var secret []byte
+	secret = append(secret, sharedSecret(oneTimeKey.Private, m.identityKey)...)
+	secret = append(secret, sharedSecret(a.identityKey.Private, m.baseKey)...)
+	secret = append(secret, sharedSecret(oneTimeKey.Private, m.baseKey)...)
+
+	s := &Session{
+		AliceIdentityKey: m.identityKey,
+		AliceBaseKey:     m.baseKey,
+		BobOneTimeKey:    m.oneTimeKey,
+	}
+	s.initialize(secret)
+	s.Receivers = []receiverChain{{RatchetKey: inner.ratchetKey, ChainKey: s.Sender.ChainKey}}
+	s.Sender = nil
+
+	return s, nil
+}
+
+func (a *Account) findOneTimeKey(public [32]byte) (curve25519KeyPair, bool) {
+	for _, k := range a.oneTimeKeys {
+		if k.Key.Public == public {
+			return k.Key, true
+		}
+	}
+	return curve25519KeyPair{}, false
+}
+
+// RemoveOneTimeKeys removes the one-time key used by the session, so that it cannot be used again.
+func (a *Account) RemoveOneTimeKeys(s *Session) {
+	for i, k := range a.oneTimeKeys {
+		if k.Key.Public == s.BobOneTimeKey {
+			a.oneTimeKeys = append(a.oneTimeKeys[:i], a.oneTimeKeys[i+1:]...)
+			return
+		}
+	}
+}
+
+type accountJSON struct {
+	IdentityKey      curve25519KeyPair `json:"identity_key"`
+	SigningKey       []byte            `json:"signing_key"`
+	OneTimeKeys      []oneTimeKey      `json:"one_time_keys"`
+	NextOneTimeKeyID uint32            `json:"next_one_time_key_id"`
+}
+
+// MarshalJSON serializes the account including its private keys.
+func (a *Account) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accountJSON{
+		IdentityKey:      a.identityKey,
+		SigningKey:       a.signingKey.Seed(),
+		OneTimeKeys:      a.oneTimeKeys,
+		NextOneTimeKeyID: a.nextOneTimeKeyID,
+	})
+}
+
+// UnmarshalJSON restores an account serialized with MarshalJSON.
+func (a *Account) UnmarshalJSON(data []byte) error {
+	var aj accountJSON
+	if err := json.Unmarshal(data, &aj); err != nil {
+		return err
+	}
+	if len(aj.SigningKey) != ed25519.SeedSize {
+		return fmt.Errorf("Invalid signing key in account")
+	}
+	a.identityKey = aj.IdentityKey
+	a.signingKey = ed25519.NewKeyFromSeed(aj.SigningKey)
+	a.oneTimeKeys = aj.OneTimeKeys
+	a.nextOneTimeKeyID = aj.NextOneTimeKeyID
+	return nil
+}
+
+// VerifySignature verifies the Ed25519 signature of the message made with the key.
+func VerifySignature(key string, message []byte, signature string) error {
+	publicKey, err := decodeKey(key)
+	if err != nil {
+		return err
+	}
+	sig, err := encoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("Invalid signature encoding: %s", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(publicKey[:]), message, sig) {
+		return fmt.Errorf("Invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package olm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	megolmRatchetParts = 4
+	megolmPartLength   = 32
+
+	sessionKeyVersion = 2
+	signatureLength   = ed25519.SignatureSize
+)
+
+// megolmRatchet is the hash ratchet of Megolm consisting of four parts which are rehashed at
+// different rates, so that it can be advanced cheaply to any later index.
+type megolmRatchet struct {
+	Data    [megolmRatchetParts][megolmPartLength]byte `json:"data"`
+	Counter uint32                                     `json:"counter"`
+}
+
+func rehash(from [megolmPartLength]byte, to int) [megolmPartLength]byte {
+	var result [megolmPartLength]byte
+	copy(result[:], hmacSHA256(from[:], []byte{byte(to)}))
+	return result
+}
+
+// advance advances the ratchet by one step.
+func (r *megolmRatchet) advance() {
+	var mask uint32 = 0x00FFFFFF
+	h := 0
+
+	r.Counter++
+
+	// Find the highest part which has to be updated
+	for h < megolmRatchetParts {
+		if r.Counter&mask == 0 {
+			break
+		}
+		h++
+		mask >>= 8
+	}
+
+	// Update the parts from the highest to the lowest
+	for i := megolmRatchetParts - 1; i >= h; i-- {
+		r.Data[i] = rehash(r.Data[h], i)
+	}
+}
+
+// advanceTo advances the ratchet to the index, which must not be smaller than the counter.
+func (r *megolmRatchet) advanceTo(index uint32) {
+	for j := 0; j < megolmRatchetParts; j++ {
+		shift := uint((megolmRatchetParts - j - 1) * 8)
+		var mask uint32 = ^uint32(0) << shift
+
+		// How many times do we need to rehash this part?
+		steps := ((index >> shift) - (r.Counter >> shift)) & 0xff
+		if steps == 0 {
+			// Update the lower parts only if this part changes
+			if index < r.Counter {
+				steps = 0x100
+			} else {
+				continue
+			}
+		}
+
+		// For all but the last step, we can just bump this part
+		for ; steps > 1; steps-- {
+			r.Data[j] = rehash(r.Data[j], j)
+		}
+
+		// On the last step we also need to bump the lower parts
+		for k := megolmRatchetParts - 1; k >= j; k-- {
+			r.Data[k] = rehash(r.Data[j], k)
+		}
+		r.Counter = index & mask
+	}
+	r.Counter = index
+}
+
+func (r *megolmRatchet) cipherKeys() cipherKeys {
+	var data []byte
+	for _, part := range r.Data {
+		data = append(data, part[:]...)
+	}
+	return deriveCipherKeys(data, "MEGOLM_KEYS")
+}
+
+// OutboundGroupSession is a Megolm session used to encrypt messages to a room.
+type OutboundGroupSession struct {
+	ratchet    megolmRatchet
+	signingKey ed25519.PrivateKey
+}
+
+// NewOutboundGroupSession creates a new Megolm session with random keys.
+func NewOutboundGroupSession() (*OutboundGroupSession, error) {
+	s := &OutboundGroupSession{}
+	for i := range s.ratchet.Data {
+		if _, err := io.ReadFull(rand.Reader, s.ratchet.Data[i][:]); err != nil {
+			return nil, fmt.Errorf("Could not generate Megolm session: %s", err)
+		}
+	}
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate Megolm signing key: %s", err)
+	}
+	s.signingKey = signingKey
+	return s, nil
+}
+
+// ID returns the session ID which is the public signing key of the session.
+func (s *OutboundGroupSession) ID() string {
+	return encodeBase64(s.signingKey.Public().(ed25519.PublicKey))
+}
+
+// MessageIndex returns the index of the next message encrypted with the session.
+func (s *OutboundGroupSession) MessageIndex() uint32 {
+	return s.ratchet.Counter
+}
+
+// SessionKey returns the key which has to be shared with the other devices so that they can
+// decrypt the messages starting with the current message index.
+func (s *OutboundGroupSession) SessionKey() string {
+	key := []byte{sessionKeyVersion}
+	key = append(key, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(key[1:], s.ratchet.Counter)
+	for _, part := range s.ratchet.Data {
+		key = append(key, part[:]...)
+	}
+	key = append(key, s.signingKey.Public().(ed25519.PublicKey)...)
+	key = append(key, ed25519.Sign(s.signingKey, key)...)
+	return encodeBase64(key)
+}
+
+// Encrypt encrypts the plaintext and returns the base64 encoded message.
+func (s *OutboundGroupSession) Encrypt(plaintext []byte) string {
+	keys := s.ratchet.cipherKeys()
+	message := encodeMegolmMessage(s.ratchet.Counter, keys.encrypt(plaintext))
+	message = append(message, keys.mac(message)...)
+	message = append(message, ed25519.Sign(s.signingKey, message)...)
+
+	s.ratchet.advance()
+
+	return encodeBase64(message)
+}
+
+type outboundGroupSessionJSON struct {
+	Ratchet    megolmRatchet `json:"ratchet"`
+	SigningKey []byte        `json:"signing_key"`
+}
+
+// MarshalJSON serializes the session including its private keys.
+func (s *OutboundGroupSession) MarshalJSON() ([]byte, error) {
+	return json.Marshal(outboundGroupSessionJSON{Ratchet: s.ratchet, SigningKey: s.signingKey.Seed()})
+}
+
+// UnmarshalJSON restores a session serialized with MarshalJSON.
+func (s *OutboundGroupSession) UnmarshalJSON(data []byte) error {
+	var sj outboundGroupSessionJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	if len(sj.SigningKey) != ed25519.SeedSize {
+		return fmt.Errorf("Invalid signing key in Megolm session")
+	}
+	s.ratchet = sj.Ratchet
+	s.signingKey = ed25519.NewKeyFromSeed(sj.SigningKey)
+	return nil
+}
+
+// InboundGroupSession is a Megolm session used to decrypt messages of another device.
+type InboundGroupSession struct {
+	initialRatchet megolmRatchet
+	latestRatchet  megolmRatchet
+	signingKey     ed25519.PublicKey
+}
+
+// NewInboundGroupSession creates a session from a session key shared by the sending device.
+func NewInboundGroupSession(sessionKey string) (*InboundGroupSession, error) {
+	key, err := encoding.DecodeString(sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid session key encoding: %s", err)
+	}
+	const keyLength = 1 + 4 + megolmRatchetParts*megolmPartLength + ed25519.PublicKeySize
+	if len(key) != keyLength+signatureLength || key[0] != sessionKeyVersion {
+		return nil, fmt.Errorf("Invalid session key")
+	}
+
+	s := &InboundGroupSession{}
+	s.signingKey = ed25519.PublicKey(append([]byte{}, key[keyLength-ed25519.PublicKeySize:keyLength]...))
+	if !ed25519.Verify(s.signingKey, key[:keyLength], key[keyLength:]) {
+		return nil, fmt.Errorf("Invalid session key signature")
+	}
+
+	s.initialRatchet.Counter = binary.BigEndian.Uint32(key[1:5])
+	for i := range s.initialRatchet.Data {
+		copy(s.initialRatchet.Data[i][:], key[5+i*megolmPartLength:])
+	}
+	s.latestRatchet = s.initialRatchet
+
+	return s, nil
+}
+
+// ID returns the session ID which is the public signing key of the session.
+func (s *InboundGroupSession) ID() string {
+	return encodeBase64(s.signingKey)
+}
+
+// FirstKnownIndex returns the first message index which can be decrypted with the session.
+func (s *InboundGroupSession) FirstKnownIndex() uint32 {
+	return s.initialRatchet.Counter
+}
+
+// Decrypt decrypts the base64 encoded message and returns the plaintext together with the message index.
+func (s *InboundGroupSession) Decrypt(message string) ([]byte, uint32, error) {
+	data, err := encoding.DecodeString(message)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Invalid message encoding: %s", err)
+	}
+	m, err := decodeMegolmMessage(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ed25519.Verify(s.signingKey, data[:len(data)-signatureLength], m.signature) {
+		return nil, 0, fmt.Errorf("Bad message signature")
+	}
+	if m.index < s.initialRatchet.Counter {
+		return nil, 0, fmt.Errorf("Unknown message index %d", m.index)
+	}
+
+	var ratchet megolmRatchet
+	if m.index >= s.latestRatchet.Counter {
+		s.latestRatchet.advanceTo(m.index)
+		ratchet = s.latestRatchet
+	} else {
+		ratchet = s.initialRatchet
+		ratchet.advanceTo(m.index)
+	}
+
+	keys := ratchet.cipherKeys()
+	if !keys.verify(m.body, m.mac) {
+		return nil, 0, fmt.Errorf("Bad message MAC")
+	}
+	plaintext, err := keys.decrypt(m.ciphertext)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return plaintext, m.index, nil
+}
+
+type inboundGroupSessionJSON struct {
+	InitialRatchet megolmRatchet `json:"initial_ratchet"`
+	LatestRatchet  megolmRatchet `json:"latest_ratchet"`
+	SigningKey     []byte        `json:"signing_key"`
+}
+
+// MarshalJSON serializes the session.
+func (s *InboundGroupSession) MarshalJSON() ([]byte, error) {
+	return json.Marshal(inboundGroupSessionJSON{
+		InitialRatchet: s.initialRatchet,
+		LatestRatchet:  s.latestRatchet,
+		SigningKey:     s.signingKey,
+	})
+}
+
+// UnmarshalJSON restores a session serialized with MarshalJSON.
+func (s *InboundGroupSession) UnmarshalJSON(data []byte) error {
+	var sj inboundGroupSessionJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	if len(sj.SigningKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("Invalid signing key in Megolm session")
+	}
+	s.initialRatchet = sj.InitialRatchet
+	s.latestRatchet = sj.LatestRatchet
+	s.signingKey = ed25519.PublicKey(sj.SigningKey)
+	return nil
+}
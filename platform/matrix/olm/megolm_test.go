@@ -0,0 +1,129 @@
+package olm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMegolmRatchetAdvanceTo(t *testing.T) {
+	var initial megolmRatchet
+	for i := range initial.Data {
+		for j := range initial.Data[i] {
+			initial.Data[i][j] = byte(i*megolmPartLength + j)
+		}
+	}
+
+	for _, target := range []uint32{1, 255, 256, 257, 0x10000, 0x1000001} {
+		stepwise := initial
+		if target <= 0x10000 {
+			for stepwise.Counter < target {
+				stepwise.advance()
+			}
+		}
+
+		jumped := initial
+		jumped.advanceTo(target)
+		if jumped.Counter != target {
+			t.Errorf("Expected counter %d, got %d", target, jumped.Counter)
+		}
+		if target <= 0x10000 && jumped.Data != stepwise.Data {
+			t.Errorf("advanceTo(%d) differs from advancing step by step", target)
+		}
+
+		// Advancing in two jumps has to give the same result
+		twice := initial
+		twice.advanceTo(target / 2)
+		twice.advanceTo(target)
+		if twice.Data != jumped.Data {
+			t.Errorf("advanceTo(%d) in two jumps gives a different result", target)
+		}
+	}
+}
+
+func TestMegolmSession(t *testing.T) {
+	outbound, err := NewOutboundGroupSession()
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+
+	first := outbound.Encrypt([]byte("not shared"))
+
+	inbound, err := NewInboundGroupSession(outbound.SessionKey())
+	if err != nil {
+		t.Fatalf("Could not create inbound session: %s", err)
+	}
+	if inbound.ID() != outbound.ID() {
+		t.Errorf("Session IDs do not match")
+	}
+	if inbound.FirstKnownIndex() != 1 {
+		t.Errorf("Expected first known index 1, got %d", inbound.FirstKnownIndex())
+	}
+
+	var messages []string
+	for _, text := range []string{"one", "two", "three"} {
+		messages = append(messages, outbound.Encrypt([]byte(text)))
+	}
+	if outbound.MessageIndex() != 4 {
+		t.Errorf("Expected message index 4, got %d", outbound.MessageIndex())
+	}
+
+	for _, i := range []int{2, 0, 1, 2} {
+		plaintext, index, err := inbound.Decrypt(messages[i])
+		if err != nil {
+			t.Fatalf("Could not decrypt message %d: %s", i, err)
+		}
+		if index != uint32(i+1) {
+			t.Errorf("Expected index %d, got %d", i+1, index)
+		}
+		if expected := []string{"one", "two", "three"}[i]; string(plaintext) != expected {
+			t.Errorf("Decrypted wrong plaintext for message %d: %s", i, plaintext)
+		}
+	}
+
+	if _, _, err := inbound.Decrypt(first); err == nil {
+		t.Errorf("Message before the shared index should not decrypt")
+	}
+
+	data, _ := encoding.DecodeString(messages[0])
+	data[3] ^= 0xff
+	if _, _, err := inbound.Decrypt(encoding.EncodeToString(data)); err == nil {
+		t.Errorf("Tampered message should not decrypt")
+	}
+}
+
+func TestMegolmSerialization(t *testing.T) {
+	outbound, err := NewOutboundGroupSession()
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	inbound, err := NewInboundGroupSession(outbound.SessionKey())
+	if err != nil {
+		t.Fatalf("Could not create inbound session: %s", err)
+	}
+
+	data, err := json.Marshal(outbound)
+	if err != nil {
+		t.Fatalf("Could not serialize session: %s", err)
+	}
+	restoredOutbound := &OutboundGroupSession{}
+	if err := json.Unmarshal(data, restoredOutbound); err != nil {
+		t.Fatalf("Could not restore session: %s", err)
+	}
+
+	data, err = json.Marshal(inbound)
+	if err != nil {
+		t.Fatalf("Could not serialize session: %s", err)
+	}
+	restoredInbound := &InboundGroupSession{}
+	if err := json.Unmarshal(data, restoredInbound); err != nil {
+		t.Fatalf("Could not restore session: %s", err)
+	}
+
+	plaintext, _, err := restoredInbound.Decrypt(restoredOutbound.Encrypt([]byte("restored")))
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+	if string(plaintext) != "restored" {
+		t.Errorf("Decrypted wrong plaintext: %s", plaintext)
+	}
+}
@@ -0,0 +1,195 @@
+package olm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The messages are encoded similar to protocol buffers, every field starts with a tag byte
+// containing the field number and type, followed by a varint or a length prefixed value.
+const (
+	typeVarint = 0
+	typeBytes  = 2
+)
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return appendVarint(append(b, byte(field<<3|typeVarint)), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendVarint(append(b, byte(field<<3|typeBytes)), uint64(len(v)))
+	return append(b, v...)
+}
+
+// decodeFields returns the varint and bytes fields of a message without version byte.
+func decodeFields(data []byte) (map[int]uint64, map[int][]byte, error) {
+	varints := make(map[int]uint64)
+	fields := make(map[int][]byte)
+
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("Invalid message tag")
+		}
+		data = data[n:]
+
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("Invalid message field")
+		}
+		data = data[n:]
+
+		switch tag & 7 {
+		case typeVarint:
+			varints[int(tag>>3)] = value
+		case typeBytes:
+			if value > uint64(len(data)) {
+				return nil, nil, fmt.Errorf("Message field too long")
+			}
+			fields[int(tag>>3)] = data[:value]
+			data = data[value:]
+		default:
+			return nil, nil, fmt.Errorf("Unknown message field type %d", tag&7)
+		}
+	}
+
+	return varints, fields, nil
+}
+
+func keyField(fields map[int][]byte, field int) ([32]byte, error) {
+	var k [32]byte
+	if len(fields[field]) != len(k) {
+		return k, fmt.Errorf("Invalid key in message field %d", field)
+	}
+	copy(k[:], fields[field])
+	return k, nil
+}
+
+// olmMessage is a message on an established Olm session.
+type olmMessage struct {
+	ratchetKey [32]byte
+	index      uint32
+	ciphertext []byte
+
+	// body is the encoded message without MAC, mac is the truncated MAC.
+	body []byte
+	mac  []byte
+}
+
+func encodeOlmMessage(ratchetKey [32]byte, index uint32, ciphertext []byte) []byte {
+	b := []byte{protocolVersion}
+	b = appendBytesField(b, 1, ratchetKey[:])
+	b = appendVarintField(b, 2, uint64(index))
+	b = appendBytesField(b, 4, ciphertext)
+	return b
+}
+
+func decodeOlmMessage(data []byte) (olmMessage, error) {
+	var m olmMessage
+	if len(data) < 1+macLength || data[0] != protocolVersion {
+		return m, fmt.Errorf("Invalid Olm message")
+	}
+	m.body = data[:len(data)-macLength]
+	m.mac = data[len(data)-macLength:]
+
+	varints, fields, err := decodeFields(m.body[1:])
+	if err != nil {
+		return m, err
+	}
+	if m.ratchetKey, err = keyField(fields, 1); err != nil {
+		return m, err
+	}
+	m.index = uint32(varints[2])
+	m.ciphertext = fields[4]
+	if len(m.ciphertext) == 0 {
+		return m, fmt.Errorf("Olm message without ciphertext")
+	}
+
+	return m, nil
+}
+
+// preKeyMessage is the message used to establish a new Olm session.
+type preKeyMessage struct {
+	oneTimeKey  [32]byte
+	baseKey     [32]byte
+	identityKey [32]byte
+	message     []byte
+}
+
+func encodePreKeyMessage(oneTimeKey, baseKey, identityKey [32]byte, message []byte) []byte {
+	b := []byte{protocolVersion}
+	b = appendBytesField(b, 1, oneTimeKey[:])
+	b = appendBytesField(b, 2, baseKey[:])
+	b = appendBytesField(b, 3, identityKey[:])
+	b = appendBytesField(b, 4, message)
+	return b
+}
+
+func decodePreKeyMessage(data []byte) (preKeyMessage, error) {
+	var m preKeyMessage
+	if len(data) < 1 || data[0] != protocolVersion {
+		return m, fmt.Errorf("Invalid Olm pre-key message")
+	}
+
+	_, fields, err := decodeFields(data[1:])
+	if err != nil {
+		return m, err
+	}
+	if m.oneTimeKey, err = keyField(fields, 1); err != nil {
+		return m, err
+	}
+	if m.baseKey, err = keyField(fields, 2); err != nil {
+		return m, err
+	}
+	if m.identityKey, err = keyField(fields, 3); err != nil {
+		return m, err
+	}
+	m.message = fields[4]
+
+	return m, nil
+}
+
+// megolmMessage is a message encrypted with a Megolm session.
+type megolmMessage struct {
+	index      uint32
+	ciphertext []byte
+
+	// body is the encoded message without MAC and signature
+	body      []byte
+	mac       []byte
+	signature []byte
+}
+
+func encodeMegolmMessage(index uint32, ciphertext []byte) []byte {
+	b := []byte{protocolVersion}
+	b = appendVarintField(b, 1, uint64(index))
+	b = appendBytesField(b, 2, ciphertext)
+	return b
+}
+
+func decodeMegolmMessage(data []byte) (megolmMessage, error) {
+	var m megolmMessage
+	if len(data) < 1+macLength+signatureLength || data[0] != protocolVersion {
+		return m, fmt.Errorf("Invalid Megolm message")
+	}
+	m.signature = data[len(data)-signatureLength:]
+	m.mac = data[len(data)-signatureLength-macLength : len(data)-signatureLength]
+	m.body = data[:len(data)-signatureLength-macLength]
+
+	varints, fields, err := decodeFields(m.body[1:])
+	if err != nil {
+		return m, err
+	}
+	m.index = uint32(varints[1])
+	m.ciphertext = fields[2]
+	if len(m.ciphertext) == 0 {
+		return m, fmt.Errorf("Megolm message without ciphertext")
+	}
+
+	return m, nil
+}
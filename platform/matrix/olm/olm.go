@@ -0,0 +1,143 @@
+// Package olm implements the Olm and Megolm cryptographic ratchets used for end-to-end
+// encryption in Matrix, compatible with libolm.
+//
+// Olm is used to establish encrypted one-to-one channels between devices. Megolm is used to
+// encrypt room messages, the keys of Megolm sessions are shared via Olm.
+// All keys are encoded as unpadded base64, as used by the Matrix protocol.
+package olm
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Message types of Olm messages
+const (
+	MessageTypePreKey = 0 // MessageTypePreKey is used until the first reply on a session was received
+	MessageTypeNormal = 1
+)
+
+const (
+	protocolVersion = 3
+	macLength       = 8
+)
+
+var encoding = base64.RawStdEncoding
+
+func encodeBase64(data []byte) string {
+	return encoding.EncodeToString(data)
+}
+
+func decodeKey(key string) ([32]byte, error) {
+	var k [32]byte
+	data, err := encoding.DecodeString(key)
+	if err != nil {
+		return k, fmt.Errorf("Invalid key encoding: %s", err)
+	}
+	if len(data) != len(k) {
+		return k, fmt.Errorf("Invalid key length %d", len(data))
+	}
+	copy(k[:], data)
+	return k, nil
+}
+
+type curve25519KeyPair struct {
+	Private [32]byte `json:"private"`
+	Public  [32]byte `json:"public"`
+}
+
+func generateCurve25519KeyPair() (curve25519KeyPair, error) {
+	var kp curve25519KeyPair
+	if _, err := io.ReadFull(rand.Reader, kp.Private[:]); err != nil {
+		return kp, fmt.Errorf("Could not generate key: %s", err)
+	}
+	curve25519.ScalarBaseMult(&kp.Public, &kp.Private)
+	return kp, nil
+}
+
+func sharedSecret(private [32]byte, public [32]byte) []byte {
+	var secret [32]byte
+	curve25519.ScalarMult(&secret, &private, &public)
+	return secret[:]
+}
+
+func deriveSecrets(salt []byte, secret []byte, info string, length int) []byte {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), out); err != nil {
+		// Can only happen when requesting more than 255 blocks
+		panic(err)
+	}
+	return out
+}
+
+func hmacSHA256(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// cipherKeys are the keys for AES-256-CBC with HMAC-SHA-256 derived from a message key.
+type cipherKeys struct {
+	aesKey  []byte
+	hmacKey []byte
+	iv      []byte
+}
+
+func deriveCipherKeys(messageKey []byte, info string) cipherKeys {
+	secrets := deriveSecrets(nil, messageKey, info, 80)
+	return cipherKeys{aesKey: secrets[:32], hmacKey: secrets[32:64], iv: secrets[64:]}
+}
+
+func (k cipherKeys) encrypt(plaintext []byte) []byte {
+	block, err := aes.NewCipher(k.aesKey)
+	if err != nil {
+		panic(err)
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, k.iv).CryptBlocks(ciphertext, padded)
+	return ciphertext
+}
+
+func (k cipherKeys) decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("Invalid ciphertext length")
+	}
+	block, err := aes.NewCipher(k.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, k.iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("Invalid padding")
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("Invalid padding")
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// mac returns the truncated MAC of the message.
+func (k cipherKeys) mac(message []byte) []byte {
+	return hmacSHA256(k.hmacKey, message)[:macLength]
+}
+
+func (k cipherKeys) verify(message []byte, mac []byte) bool {
+	return hmac.Equal(k.mac(message), mac)
+}
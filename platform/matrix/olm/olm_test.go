@@ -0,0 +1,286 @@
+package olm
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Invalid hex in test vector: %s", err)
+	}
+	return data
+}
+
+func createSessions(t *testing.T) (*Account, *Session, *Account, *Session) {
+	alice, err := NewAccount()
+	if err != nil {
+		t.Fatalf("Could not create account: %s", err)
+	}
+	bob, err := NewAccount()
+	if err != nil {
+		t.Fatalf("Could not create account: %s", err)
+	}
+	if err := bob.GenerateOneTimeKeys(1); err != nil {
+		t.Fatalf("Could not generate one-time keys: %s", err)
+	}
+	var bobOneTimeKey string
+	for _, key := range bob.OneTimeKeys() {
+		bobOneTimeKey = key
+	}
+
+	aliceSession, err := alice.NewOutboundSession(bob.IdentityKey(), bobOneTimeKey)
+	if err != nil {
+		t.Fatalf("Could not create outbound session: %s", err)
+	}
+
+	msgType, message, err := aliceSession.Encrypt([]byte("Hello Bob"))
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+	if msgType != MessageTypePreKey {
+		t.Fatalf("Expected pre-key message, got type %d", msgType)
+	}
+
+	bobSession, err := bob.NewInboundSession(alice.IdentityKey(), message)
+	if err != nil {
+		t.Fatalf("Could not create inbound session: %s", err)
+	}
+	if !bobSession.MatchesInboundSession(message) {
+		t.Fatalf("Inbound session does not match pre-key message")
+	}
+	plaintext, err := bobSession.Decrypt(msgType, message)
+	if err != nil {
+		t.Fatalf("Could not decrypt pre-key message: %s", err)
+	}
+	if string(plaintext) != "Hello Bob" {
+		t.Fatalf("Decrypted wrong plaintext: %s", plaintext)
+	}
+	bob.RemoveOneTimeKeys(bobSession)
+	if len(bob.OneTimeKeys()) != 0 {
+		t.Fatalf("One-time key was not removed")
+	}
+
+	return alice, aliceSession, bob, bobSession
+}
+
+func TestOlmSession(t *testing.T) {
+	_, aliceSession, _, bobSession := createSessions(t)
+
+	if aliceSession.ID() != bobSession.ID() {
+		t.Errorf("Session IDs do not match: %s != %s", aliceSession.ID(), bobSession.ID())
+	}
+
+	// Several rounds of messages in both directions, each direction change is a new ratchet step
+	for i, tt := range []struct {
+		from *Session
+		to   *Session
+		text string
+	}{
+		{bobSession, aliceSession, "Hello Alice"},
+		{bobSession, aliceSession, "How are you?"},
+		{aliceSession, bobSession, "Fine"},
+		{aliceSession, bobSession, "And you?"},
+		{bobSession, aliceSession, "Great"},
+	} {
+		msgType, message, err := tt.from.Encrypt([]byte(tt.text))
+		if err != nil {
+			t.Fatalf("%d: Could not encrypt: %s", i, err)
+		}
+		if msgType != MessageTypeNormal {
+			t.Errorf("%d: Expected normal message, got type %d", i, msgType)
+		}
+		plaintext, err := tt.to.Decrypt(msgType, message)
+		if err != nil {
+			t.Fatalf("%d: Could not decrypt: %s", i, err)
+		}
+		if string(plaintext) != tt.text {
+			t.Errorf("%d: Decrypted wrong plaintext: %s", i, plaintext)
+		}
+	}
+}
+
+func TestOlmSessionOutOfOrder(t *testing.T) {
+	_, aliceSession, _, bobSession := createSessions(t)
+
+	var messages [][]byte
+	for _, text := range []string{"one", "two", "three"} {
+		_, message, err := bobSession.Encrypt([]byte(text))
+		if err != nil {
+			t.Fatalf("Could not encrypt: %s", err)
+		}
+		messages = append(messages, message)
+	}
+
+	for _, i := range []int{2, 0, 1} {
+		plaintext, err := aliceSession.Decrypt(MessageTypeNormal, messages[i])
+		if err != nil {
+			t.Fatalf("Could not decrypt message %d: %s", i, err)
+		}
+		if expected := []string{"one", "two", "three"}[i]; string(plaintext) != expected {
+			t.Errorf("Decrypted wrong plaintext for message %d: %s", i, plaintext)
+		}
+	}
+
+	if _, err := aliceSession.Decrypt(MessageTypeNormal, messages[1]); err == nil {
+		t.Errorf("Replayed message should not decrypt")
+	}
+
+	tampered := append([]byte{}, messages[0]...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := aliceSession.Decrypt(MessageTypeNormal, tampered); err == nil {
+		t.Errorf("Tampered message should not decrypt")
+	}
+}
+
+func TestOlmSerialization(t *testing.T) {
+	alice, aliceSession, bob, bobSession := createSessions(t)
+
+	data, err := json.Marshal(bob)
+	if err != nil {
+		t.Fatalf("Could not serialize account: %s", err)
+	}
+	restoredBob := &Account{}
+	if err := json.Unmarshal(data, restoredBob); err != nil {
+		t.Fatalf("Could not restore account: %s", err)
+	}
+	if restoredBob.IdentityKey() != bob.IdentityKey() || restoredBob.SigningKey() != bob.SigningKey() {
+		t.Errorf("Restored account has different keys")
+	}
+	if restoredBob.Sign([]byte("test")) != bob.Sign([]byte("test")) {
+		t.Errorf("Restored account signs differently")
+	}
+
+	data, err = json.Marshal(bobSession)
+	if err != nil {
+		t.Fatalf("Could not serialize session: %s", err)
+	}
+	restoredSession := &Session{}
+	if err := json.Unmarshal(data, restoredSession); err != nil {
+		t.Fatalf("Could not restore session: %s", err)
+	}
+
+	msgType, message, err := restoredSession.Encrypt([]byte("Restored"))
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+	plaintext, err := aliceSession.Decrypt(msgType, message)
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+	if string(plaintext) != "Restored" {
+		t.Errorf("Decrypted wrong plaintext: %s", plaintext)
+	}
+
+	if alice.IdentityKey() == bob.IdentityKey() {
+		t.Errorf("Accounts should have different identity keys")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	account, err := NewAccount()
+	if err != nil {
+		t.Fatalf("Could not create account: %s", err)
+	}
+
+	signature := account.Sign([]byte("message"))
+	if err := VerifySignature(account.SigningKey(), []byte("message"), signature); err != nil {
+		t.Errorf("Valid signature not verified: %s", err)
+	}
+	if err := VerifySignature(account.SigningKey(), []byte("other message"), signature); err == nil {
+		t.Errorf("Signature of other message verified")
+	}
+	if err := VerifySignature(account.IdentityKey(), []byte("message"), signature); err == nil {
+		t.Errorf("Signature verified with wrong key")
+	}
+}
+
+// The known-answer tests check the primitives Olm and Megolm are built from against published
+// test vectors, so that the implementation is not only checked against itself.
+
+func TestKnownAnswerCurve25519(t *testing.T) {
+	// RFC 7748, section 6.1
+	var alicePrivate, bobPublic [32]byte
+	copy(alicePrivate[:], decodeHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"))
+	copy(bobPublic[:], decodeHex(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"))
+
+	secret := sharedSecret(alicePrivate, bobPublic)
+	if !bytes.Equal(secret, decodeHex(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")) {
+		t.Errorf("Wrong shared secret: %x", secret)
+	}
+}
+
+func TestKnownAnswerHKDF(t *testing.T) {
+	// RFC 5869, test cases 1 and 3
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+
+	okm := deriveSecrets(decodeHex(t, "000102030405060708090a0b0c"), ikm, string(decodeHex(t, "f0f1f2f3f4f5f6f7f8f9")), 42)
+	if !bytes.Equal(okm, decodeHex(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")) {
+		t.Errorf("Wrong output with salt and info: %x", okm)
+	}
+
+	okm = deriveSecrets(nil, ikm, "", 42)
+	if !bytes.Equal(okm, decodeHex(t, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8")) {
+		t.Errorf("Wrong output without salt and info: %x", okm)
+	}
+}
+
+func TestKnownAnswerHMAC(t *testing.T) {
+	// RFC 4231, test case 2
+	mac := hmacSHA256([]byte("Jefe"), []byte("what do ya want for nothing?"))
+	if !bytes.Equal(mac, decodeHex(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843")) {
+		t.Errorf("Wrong MAC: %x", mac)
+	}
+}
+
+func TestKnownAnswerAESCBC(t *testing.T) {
+	// NIST SP 800-38A, F.2.5 CBC-AES256.Encrypt, followed by the PKCS#7 padding block
+	keys := cipherKeys{
+		aesKey: decodeHex(t, "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4"),
+		iv:     decodeHex(t, "000102030405060708090a0b0c0d0e0f"),
+	}
+	plaintext := decodeHex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51")
+
+	ciphertext := keys.encrypt(plaintext)
+	if len(ciphertext) != 48 || !bytes.Equal(ciphertext[:32], decodeHex(t, "f58c4c04d6e5f1ba779eabfb5f7bfbd69cfc4e967edb808d679f777bc6702c7d")) {
+		t.Errorf("Wrong ciphertext: %x", ciphertext)
+	}
+
+	decrypted, err := keys.decrypt(ciphertext)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Wrong plaintext: %x, %v", decrypted, err)
+	}
+}
+
+func TestKnownAnswerSigning(t *testing.T) {
+	// Signing JSON test vectors of the Matrix specification (appendices, signing details)
+	seed, err := encoding.DecodeString("YJDBA9Xnr2sVqXD9Vj7XVUnmFZcZrlw8Md7kMW+3XA1")
+	if err != nil {
+		t.Fatalf("Invalid seed: %s", err)
+	}
+	account := &Account{signingKey: ed25519.NewKeyFromSeed(seed)}
+
+	if account.SigningKey() != "XGX0JRS2Af3be3knz2fBiRbApjm2Dh61gXDJA8kcJNI" {
+		t.Errorf("Wrong public key: %s", account.SigningKey())
+	}
+
+	vectors := []struct {
+		message   string
+		signature string
+	}{
+		{`{}`, "K8280/U9SSy9IVtjBuVeLr+HpOB4BQFWbg+UZaADMtTdGYI7Geitb76LTrr5QV/7Xg4ahLwYGYZzuHGZKM5ZAQ"},
+		{`{"one":1,"two":"Two"}`, "KqmLSbO39/Bzb0QIYE82zqLwsA+PDzYIpIRA2sRQ4sL53+sN6/fpNSoqE7BP7vBZhG6kYdD13EIMJpvhJI+6Bw"},
+	}
+	for _, v := range vectors {
+		if signature := account.Sign([]byte(v.message)); signature != v.signature {
+			t.Errorf("Wrong signature of %s: %s", v.message, signature)
+		}
+		if err := VerifySignature(account.SigningKey(), []byte(v.message), v.signature); err != nil {
+			t.Errorf("Signature of %s not verified: %s", v.message, err)
+		}
+	}
+}
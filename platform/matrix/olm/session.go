@@ -0,0 +1,228 @@
+package olm
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+const (
+	maxReceiverChains = 5
+	maxSkippedKeys    = 40
+	maxMessageGap     = 2000
+)
+
+var (
+	chainKeySeed   = []byte{0x02}
+	messageKeySeed = []byte{0x01}
+)
+
+type senderChain struct {
+	RatchetKey curve25519KeyPair `json:"ratchet_key"`
+	ChainKey   [32]byte          `json:"chain_key"`
+	Index      uint32            `json:"index"`
+}
+
+type receiverChain struct {
+	RatchetKey [32]byte `json:"ratchet_key"`
+	ChainKey   [32]byte `json:"chain_key"`
+	Index      uint32   `json:"index"`
+}
+
+type skippedKey struct {
+	RatchetKey [32]byte `json:"ratchet_key"`
+	Index      uint32   `json:"index"`
+	MessageKey []byte   `json:"message_key"`
+}
+
+func advanceChainKey(chainKey [32]byte) [32]byte {
+	var next [32]byte
+	copy(next[:], hmacSHA256(chainKey[:], chainKeySeed))
+	return next
+}
+
+func messageKey(chainKey [32]byte) []byte {
+	return hmacSHA256(chainKey[:], messageKeySeed)
+}
+
+// Session is an Olm session between two devices, implementing the double ratchet.
+// It can be serialized with encoding/json.
+type Session struct {
+	// ReceivedMessage is set once a message was decrypted. Until then the sent messages are pre-key messages.
+	ReceivedMessage bool `json:"received_message"`
+
+	AliceIdentityKey [32]byte `json:"alice_identity_key"`
+	AliceBaseKey     [32]byte `json:"alice_base_key"`
+	BobOneTimeKey    [32]byte `json:"bob_one_time_key"`
+
+	RootKey     [32]byte        `json:"root_key"`
+	Sender      *senderChain    `json:"sender,omitempty"`
+	Receivers   []receiverChain `json:"receivers,omitempty"` // Receivers are ordered from newest to oldest
+	SkippedKeys []skippedKey    `json:"skipped_keys,omitempty"`
+}
+
+// initialize derives the root key and the first chain key from the shared secret.
+// The chain key is stored in a sender chain without ratchet key.
+func (s *Session) initialize(secret []byte) {
+	derived := deriveSecrets(nil, secret, "OLM_ROOT", 64)
+	copy(s.RootKey[:], derived[:32])
+	s.Sender = &senderChain{}
+	copy(s.Sender.ChainKey[:], derived[32:])
+}
+
+// ratchet performs a step of the root ratchet and returns the new chain key.
+func (s *Session) ratchet(private [32]byte, public [32]byte) ([32]byte, [32]byte) {
+	var rootKey, chainKey [32]byte
+	derived := deriveSecrets(s.RootKey[:], sharedSecret(private, public), "OLM_RATCHET", 64)
+	copy(rootKey[:], derived[:32])
+	copy(chainKey[:], derived[32:])
+	return rootKey, chainKey
+}
+
+// ID returns an identifier of the session which is the same for both devices.
+func (s *Session) ID() string {
+	var data []byte
+	data = append(data, s.AliceIdentityKey[:]...)
+	data = append(data, s.AliceBaseKey[:]...)
+	data = append(data, s.BobOneTimeKey[:]...)
+	hash := sha256.Sum256(data)
+	return encodeBase64(hash[:])
+}
+
+// MatchesInboundSession returns true if the pre-key message was sent on this session.
+func (s *Session) MatchesInboundSession(message []byte) bool {
+	m, err := decodePreKeyMessage(message)
+	if err != nil {
+		return false
+	}
+	return m.identityKey == s.AliceIdentityKey && m.baseKey == s.AliceBaseKey && m.oneTimeKey == s.BobOneTimeKey
+}
+
+// Encrypt encrypts the plaintext and returns the message type together with the message.
+func (s *Session) Encrypt(plaintext []byte) (int, []byte, error) {
+	if s.Sender == nil {
+		if len(s.Receivers) == 0 {
+			return 0, nil, fmt.Errorf("Session has no chain to encrypt with")
+		}
+		ratchetKey, err := generateCurve25519KeyPair()
+		if err != nil {
+			return 0, nil, err
+		}
+		rootKey, chainKey := s.ratchet(ratchetKey.Private, s.Receivers[0].RatchetKey)
+		s.RootKey = rootKey
+		s.Sender = &senderChain{RatchetKey: ratchetKey, ChainKey: chainKey}
+	}
+
+	keys := deriveCipherKeys(messageKey(s.Sender.ChainKey), "OLM_KEYS")
+	message := encodeOlmMessage(s.Sender.RatchetKey.Public, s.Sender.Index, keys.encrypt(plaintext))
+	message = append(message, keys.mac(message)...)
+
+	s.Sender.ChainKey = advanceChainKey(s.Sender.ChainKey)
+	s.Sender.Index++
+
+	if !s.ReceivedMessage {
+		return MessageTypePreKey, encodePreKeyMessage(s.BobOneTimeKey, s.AliceBaseKey, s.AliceIdentityKey, message), nil
+	}
+	return MessageTypeNormal, message, nil
+}
+
+// Decrypt decrypts a message of the given type.
+func (s *Session) Decrypt(messageType int, message []byte) ([]byte, error) {
+	switch messageType {
+	case MessageTypePreKey:
+		m, err := decodePreKeyMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		message = m.message
+	case MessageTypeNormal:
+	default:
+		return nil, fmt.Errorf("Unknown Olm message type %d", messageType)
+	}
+
+	m, err := decodeOlmMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range s.Receivers {
+		if s.Receivers[i].RatchetKey == m.ratchetKey {
+			plaintext, err := s.decryptWithChain(&s.Receivers[i], m)
+			if err != nil {
+				return nil, err
+			}
+			s.ReceivedMessage = true
+			return plaintext, nil
+		}
+	}
+
+	// The other device started a new ratchet
+	if s.Sender == nil {
+		return nil, fmt.Errorf("Message uses an unknown ratchet key")
+	}
+	rootKey, chainKey := s.ratchet(s.Sender.RatchetKey.Private, m.ratchetKey)
+	chain := receiverChain{RatchetKey: m.ratchetKey, ChainKey: chainKey}
+	plaintext, err := s.decryptWithChain(&chain, m)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RootKey = rootKey
+	s.Sender = nil
+	s.Receivers = append([]receiverChain{chain}, s.Receivers...)
+	if len(s.Receivers) > maxReceiverChains {
+		s.Receivers = s.Receivers[:maxReceiverChains]
+	}
+	s.ReceivedMessage = true
+
+	return plaintext, nil
+}
+
+// decryptWithChain decrypts the message and advances the chain. The chain and the skipped keys
+// are only modified if the message could be decrypted.
+func (s *Session) decryptWithChain(chain *receiverChain, m olmMessage) ([]byte, error) {
+	if m.index < chain.Index {
+		for i, k := range s.SkippedKeys {
+			if k.RatchetKey == m.ratchetKey && k.Index == m.index {
+				plaintext, err := decryptMessage(k.MessageKey, m)
+				if err != nil {
+					return nil, err
+				}
+				s.SkippedKeys = append(s.SkippedKeys[:i], s.SkippedKeys[i+1:]...)
+				return plaintext, nil
+			}
+		}
+		return nil, fmt.Errorf("Message key for index %d not available anymore", m.index)
+	}
+	if m.index-chain.Index > maxMessageGap {
+		return nil, fmt.Errorf("Message index %d too far in the future", m.index)
+	}
+
+	chainKey := chain.ChainKey
+	var skipped []skippedKey
+	for i := chain.Index; i < m.index; i++ {
+		skipped = append(skipped, skippedKey{RatchetKey: m.ratchetKey, Index: i, MessageKey: messageKey(chainKey)})
+		chainKey = advanceChainKey(chainKey)
+	}
+
+	plaintext, err := decryptMessage(messageKey(chainKey), m)
+	if err != nil {
+		return nil, err
+	}
+
+	chain.ChainKey = advanceChainKey(chainKey)
+	chain.Index = m.index + 1
+	s.SkippedKeys = append(s.SkippedKeys, skipped...)
+	if len(s.SkippedKeys) > maxSkippedKeys {
+		s.SkippedKeys = s.SkippedKeys[len(s.SkippedKeys)-maxSkippedKeys:]
+	}
+
+	return plaintext, nil
+}
+
+func decryptMessage(messageKey []byte, m olmMessage) ([]byte, error) {
+	keys := deriveCipherKeys(messageKey, "OLM_KEYS")
+	if !keys.verify(m.body, m.mac) {
+		return nil, fmt.Errorf("Bad message MAC")
+	}
+	return keys.decrypt(m.ciphertext)
+}
@@ -2,6 +2,7 @@ package matrix
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/utils"
@@ -9,51 +10,108 @@ import (
 
 var version string
 
-// GetUsers a list of users based on search options.
-func (b *Bot) GetUsers() ([]model.User, error) { return nil, nil }
+// GetUsers returns the members of all rooms the bot has joined.
+func (b *Bot) GetUsers() ([]model.User, error) { return b.directory.Users(), nil }
 
-// GetUser gets a user.
-func (b *Bot) GetUser(userID string) (model.User, error) { return model.User{}, nil }
+// GetUser gets a user by their full user ID, e.g., @user:matrix.org.
+func (b *Bot) GetUser(userID string) (model.User, error) {
+	if user, err := b.directory.User(userID); err == nil {
+		return user, nil
+	}
+	return b.fetchUser(userID)
+}
 
-// GetUserByUsername gets a user by their username.
-func (b *Bot) GetUserByUsername(name string) (model.User, error) { return model.User{}, nil }
+// GetUserByUsername gets a user by their username, which is the full user ID on Matrix.
+func (b *Bot) GetUserByUsername(name string) (model.User, error) {
+	if user, err := b.directory.UserByName(name); err == nil {
+		return user, nil
+	}
+	return b.fetchUser(name)
+}
 
-// GetChannel gets a channel.
-func (b *Bot) GetChannel(channelID string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannel gets a room by its ID.
+func (b *Bot) GetChannel(channelID string) (model.Channel, error) {
+	if channel, err := b.directory.Channel(channelID); err == nil {
+		return channel, nil
+	}
+	return b.fetchRoom(channelID)
+}
 
-// GetChannelByName gets a channel by its name, given a team id.
-func (b *Bot) GetChannelByName(name string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannelByName gets a joined room by its name.
+func (b *Bot) GetChannelByName(name string) (model.Channel, error) {
+	return b.directory.ChannelByName(name)
+}
 
 // CreatePost creates a post.
 func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
-	if post.IsPrivate {
-		err := b.sendWhisper(post.User.ID, post.Content)
+	var messageID model.MessageIdentifier
+	var err error
+
+	if post.IsRich() || len(post.ReplyTo) > 0 {
+		receiver := post.ChannelID
+		if post.IsPrivate {
+			receiver = post.User.ID
+		}
+		messageID, err = b.sendFormattedRoomMessage(receiver, convertMessageFromRedseligg(post))
+		if err != nil {
+			return model.PostResponse{}, fmt.Errorf("Error sending message: %s", err)
+		}
+	} else if post.IsPrivate {
+		messageID, err = b.sendWhisper(post.User.ID, post.Content)
 		if err != nil {
 			return model.PostResponse{}, fmt.Errorf("Error sending whisper: %s", err)
 		}
 	} else {
-		err := b.sendRoomMessage(post.ChannelID, post.Content)
+		messageID, err = b.sendRoomMessage(post.ChannelID, post.Content)
 		if err != nil {
 			return model.PostResponse{}, fmt.Errorf("Error sending message: %s", err)
 		}
 	}
 
-	return model.PostResponse{}, nil
+	return model.PostResponse{PostedMessageIdent: messageID}, nil
 }
 
-// UpdatePost updates a post.
+// UpdatePost updates a post. The identifier of the original post stays valid for further updates.
 func (b *Bot) UpdatePost(messageID model.MessageIdentifier, newPost model.Post) (model.PostResponse, error) {
-	return model.PostResponse{}, fmt.Errorf("Not implemented")
+	err := b.editRoomMessage(messageID, convertMessageFromRedseligg(newPost))
+	if err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error updating message: %s", err)
+	}
+	return model.PostResponse{PostedMessageIdent: messageID}, nil
 }
 
 // DeletePost deletes a post.
 func (b *Bot) DeletePost(messageID model.MessageIdentifier) (model.PostResponse, error) {
-	return model.PostResponse{}, fmt.Errorf("Not implemented")
+	err := b.redactRoomMessage(messageID)
+	if err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error deleting message: %s", err)
+	}
+	return model.PostResponse{PostedMessageIdent: messageID}, nil
+}
+
+// TimeoutUser is not supported on Matrix.
+func (b *Bot) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// BanUser is not supported on Matrix.
+func (b *Bot) BanUser(channelID string, userID string, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// ClearChat is not supported on Matrix.
+func (b *Bot) ClearChat(channelID string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// SetSlowMode is not supported on Matrix.
+func (b *Bot) SetSlowMode(channelID string, interval time.Duration) error {
+	return fmt.Errorf("Not supported")
 }
 
-// GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
+// GetReaction gives back the platform specific string for a reaction, e.g., one -> 1️⃣
 func (b *Bot) GetReaction(reactionName string) (string, error) {
-	return "", fmt.Errorf("Not implemented")
+	return getMatrixEmojiFromRedseliggEmoji(reactionName)
 }
 
 // LogTrace writes a log message to the server log file.
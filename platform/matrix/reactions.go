@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"github.com/torlenor/redseligg/model"
+)
+
+// maxKnownReactions is the number of reactions remembered to be able to report their removal.
+const maxKnownReactions = 1000
+
+func (b *Bot) addKnownReaction(eventID string, reaction model.Reaction) {
+	if len(b.knownReactionOrder) >= maxKnownReactions {
+		delete(b.knownReactions, b.knownReactionOrder[0])
+		b.knownReactionOrder = b.knownReactionOrder[1:]
+	}
+	b.knownReactions[eventID] = reaction
+	b.knownReactionOrder = append(b.knownReactionOrder, eventID)
+}
+
+// handleReactions reports m.reaction annotations as added reactions and their redactions as removed reactions.
+func (b *Bot) handleReactions(rooms []room) {
+	for _, room := range rooms {
+		for _, event := range room.Timeline.Events {
+			switch event.Type {
+			case "m.reaction":
+				if event.Content.RelatesTo.RelType != "m.annotation" {
+					continue
+				}
+
+				emoji, err := getRedseliggEmojiFromMatrixEmoji(event.Content.RelatesTo.Key)
+				if err != nil {
+					log.Debugf("Could not map emoji %s, consider adding it to the mapping: %s", event.Content.RelatesTo.Key, err)
+				}
+
+				reaction := model.Reaction{
+					Message: model.MessageIdentifier{
+						ID:      event.Content.RelatesTo.EventID,
+						Channel: room.RoomID,
+					},
+					Type:     "added",
+					Reaction: emoji,
+					User:     model.User{ID: event.Sender, Name: event.Sender},
+				}
+				b.addKnownReaction(event.EventID, reaction)
+				b.dispatchReaction(reaction)
+
+			case "m.room.redaction":
+				reaction, ok := b.knownReactions[event.Redacts]
+				if !ok {
+					continue
+				}
+				delete(b.knownReactions, event.Redacts)
+
+				reaction.Type = "removed"
+				b.dispatchReaction(reaction)
+			}
+		}
+	}
+}
+
+func (b *Bot) dispatchReaction(reaction model.Reaction) {
+	log.Tracef("Received: REACTION %s %s on message %s", reaction.Type, reaction.Reaction, reaction.Message.ID)
+	for _, plugin := range b.plugins {
+		plugin := plugin
+		if reaction.Type == "added" {
+			b.Enqueue(plugin, func() { plugin.OnReactionAdded(reaction) })
+		} else {
+			b.Enqueue(plugin, func() { plugin.OnReactionRemoved(reaction) })
+		}
+	}
+}
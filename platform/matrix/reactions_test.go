@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/plugin"
+	"github.com/torlenor/redseligg/storage"
+)
+
+type mockReactionPlugin struct {
+	plugin.RedseliggPlugin
+
+	added   []model.Reaction
+	removed []model.Reaction
+}
+
+func (p *mockReactionPlugin) OnReactionAdded(reaction model.Reaction) {
+	p.added = append(p.added, reaction)
+}
+
+func (p *mockReactionPlugin) OnReactionRemoved(reaction model.Reaction) {
+	p.removed = append(p.removed, reaction)
+}
+
+func TestMatrixBotReactions(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &mockAPI{server: "TEST_SERVER", authToken: "TEST_TOKEN"}
+	api.reset()
+
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", testConfig, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+
+	p := &mockReactionPlugin{}
+	bot.plugins = append(bot.plugins, p)
+
+	reactionSync := `{"next_batch":"s2","rooms":{"join":{"!room:server":{"timeline":{"events":[
+		{"type":"m.reaction","sender":"@user:server","event_id":"$reaction","content":{"m.relates_to":{"rel_type":"m.annotation","event_id":"$message","key":"1️⃣"}}}
+	]}}}}}`
+
+	// Reactions of the initial sync are old and not reported
+	api.apiResponse = `{"next_batch":"s1","rooms":{"join":{"!room:server":{"timeline":{"events":[
+		{"type":"m.reaction","sender":"@user:server","event_id":"$old","content":{"m.relates_to":{"rel_type":"m.annotation","event_id":"$message","key":"2️⃣"}}}
+	]}}}}}`
+	assert.NoError(bot.handlePolling())
+	assert.Equal(0, len(p.added))
+
+	api.apiResponse = reactionSync
+	assert.NoError(bot.handlePolling())
+	assert.Equal([]model.Reaction{{
+		Message:  model.MessageIdentifier{ID: "$message", Channel: "!room:server"},
+		Type:     "added",
+		Reaction: "one",
+		User:     model.User{ID: "@user:server", Name: "@user:server"},
+	}}, p.added)
+
+	api.apiResponse = `{"next_batch":"s3","rooms":{"join":{"!room:server":{"timeline":{"events":[
+		{"type":"m.room.redaction","sender":"@user:server","event_id":"$redaction","redacts":"$reaction","content":{}},
+		{"type":"m.room.redaction","sender":"@user:server","event_id":"$redaction2","redacts":"$unknown","content":{}}
+	]}}}}}`
+	assert.NoError(bot.handlePolling())
+	assert.Equal(1, len(p.removed))
+	assert.Equal("removed", p.removed[0].Type)
+	assert.Equal("one", p.removed[0].Reaction)
+	assert.Equal("$message", p.removed[0].Message.ID)
+}
@@ -0,0 +1,168 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type roomAliasResponse struct {
+	RoomID  string   `json:"room_id"`
+	Servers []string `json:"servers"`
+}
+
+type joinResponse struct {
+	RoomID string `json:"room_id"`
+}
+
+// roomAliases caches the room IDs of room aliases.
+type roomAliases struct {
+	mutex sync.Mutex
+	ids   map[string]string
+}
+
+func newRoomAliases() *roomAliases {
+	return &roomAliases{ids: make(map[string]string)}
+}
+
+func (a *roomAliases) get(alias string) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	roomID, ok := a.ids[alias]
+	return roomID, ok
+}
+
+func (a *roomAliases) set(alias string, roomID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.ids[alias] = roomID
+}
+
+// isRoomAlias returns true if the room is given by an alias, e.g., #room:matrix.org.
+func isRoomAlias(roomIdent string) bool {
+	return strings.HasPrefix(roomIdent, "#")
+}
+
+// resolveRoomAlias returns the room ID of the alias. Resolved aliases are cached.
+func (b *Bot) resolveRoomAlias(alias string) (string, error) {
+	if roomID, ok := b.aliases.get(alias); ok {
+		return roomID, nil
+	}
+
+	var r roomAliasResponse
+	if err := b.getJSON("/client/r0/directory/room/"+url.PathEscape(alias), &r); err != nil {
+		return "", fmt.Errorf("Error resolving room alias %s: %s", alias, err)
+	}
+	if len(r.RoomID) == 0 {
+		return "", fmt.Errorf("Error resolving room alias %s: No room ID returned", alias)
+	}
+
+	b.aliases.set(alias, r.RoomID)
+	return r.RoomID, nil
+}
+
+// joinRoom joins the room given by its ID or alias and returns the room ID.
+func (b *Bot) joinRoom(roomIdent string) (string, error) {
+	response, err := b.api.call("/client/r0/join/"+url.PathEscape(roomIdent), "POST", `{}`, true)
+	if err != nil {
+		return "", errors.Wrap(err, "apiCall failed")
+	}
+
+	var apiErr errorResponse
+	if err := json.Unmarshal(response, &apiErr); err == nil && len(apiErr.ErrCode) > 0 {
+		return "", fmt.Errorf("%s: %s", apiErr.ErrCode, apiErr.Error)
+	}
+
+	var r joinResponse
+	if err := json.Unmarshal(response, &r); err != nil {
+		return "", errors.Wrap(err, "json unmarshal failed")
+	}
+
+	if isRoomAlias(roomIdent) {
+		b.aliases.set(roomIdent, r.RoomID)
+	}
+	return r.RoomID, nil
+}
+
+// joinConfiguredRooms joins the rooms from the config of the bot.
+func (b *Bot) joinConfiguredRooms() {
+	for _, roomIdent := range b.rooms {
+		roomID, err := b.joinRoom(roomIdent)
+		if err != nil {
+			log.Errorf("Could not join room %s: %s", roomIdent, err)
+			continue
+		}
+		log.Debugf("Joined room %s (%s)", roomIdent, roomID)
+	}
+}
+
+// leaveRoom leaves the room. The room is forgotten when the leave is reported by the next sync.
+func (b *Bot) leaveRoom(roomID string) {
+	if _, err := b.api.call("/client/r0/rooms/"+url.PathEscape(roomID)+"/leave", "POST", `{}`, true); err != nil {
+		log.Errorf("Leaving room %s failed: %s", roomID, err)
+	}
+}
+
+// inviter returns the user who invited the bot into the room.
+func (b *Bot) inviter(room room) string {
+	for _, event := range room.InviteState.Events {
+		if event.Type == "m.room.member" && event.StateKey == b.userID && event.Content.Membership == "invite" {
+			return event.Sender
+		}
+	}
+	return ""
+}
+
+// isInviteAllowed returns true if invites of the user are accepted. The entries of the
+// allow list are either users, e.g., @user:matrix.org, or servers, e.g., matrix.org.
+func (b *Bot) isInviteAllowed(userID string) bool {
+	if len(b.inviteAllowList) == 0 {
+		return true
+	}
+
+	for _, allowed := range b.inviteAllowList {
+		if strings.HasPrefix(allowed, "@") {
+			if userID == allowed {
+				return true
+			}
+		} else if strings.HasSuffix(userID, ":"+strings.TrimPrefix(allowed, ":")) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleEmptyRooms leaves the joined rooms in which the bot is the only member, if enabled.
+// On the initial sync all rooms are checked, afterwards only rooms which members left.
+func (b *Bot) handleEmptyRooms(rooms []room, initialSync bool) {
+	if !b.leaveEmptyRooms {
+		return
+	}
+
+	for _, room := range rooms {
+		check := initialSync
+		for _, event := range room.Timeline.Events {
+			if event.Type == "m.room.member" && event.StateKey != b.userID &&
+				(event.Content.Membership == "leave" || event.Content.Membership == "ban") {
+				check = true
+			}
+		}
+		if !check {
+			continue
+		}
+
+		var members joinedMembersResponse
+		if err := b.getJSON("/client/r0/rooms/"+url.PathEscape(room.RoomID)+"/joined_members", &members); err != nil {
+			log.Errorf("Error getting members of room %s: %s", room.RoomID, err)
+			continue
+		}
+		if _, joined := members.Joined[b.userID]; joined && len(members.Joined) == 1 {
+			log.Infof("Leaving room %s because no one else is in it", room.RoomID)
+			b.leaveRoom(room.RoomID)
+		}
+	}
+}
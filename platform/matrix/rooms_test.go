@@ -0,0 +1,164 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/storage"
+)
+
+func TestMatrixBotTokenLogin(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &mockAPI{server: "TEST_SERVER"}
+	api.reset()
+	api.apiResponses = map[string]string{
+		"/client/r0/account/whoami": `{"user_id":"@bot:server","device_id":"TOKEN_DEVICE"}`,
+	}
+
+	cfg := botconfig.MatrixConfig{Token: "ACCESS_TOKEN"}
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+	assert.False(api.loginCalled)
+	assert.Equal("ACCESS_TOKEN", api.authToken)
+	assert.Equal("@bot:server", bot.userID)
+	assert.Equal("TOKEN_DEVICE", bot.deviceID)
+
+	// The configured device is used if the server does not return one
+	api.reset()
+	api.apiResponses["/client/r0/account/whoami"] = `{"user_id":"@bot:server"}`
+	cfg.DeviceID = "CONFIGURED_DEVICE"
+	bot, err = createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+	assert.Equal("CONFIGURED_DEVICE", bot.deviceID)
+
+	// Invalid token
+	api.reset()
+	api.apiResponses["/client/r0/account/whoami"] = `{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid macaroon passed."}`
+	bot, err = createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.Error(err)
+	assert.Nil(bot)
+
+	// The configured device is used for password logins
+	api.reset()
+	bot, err = createMatrixBotWithAPI(api, "TEST_BOT", botconfig.MatrixConfig{Username: "TEST_USER", Password: "TEST_PASS", DeviceID: "CONFIGURED_DEVICE"}, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+	assert.True(api.loginCalled)
+	assert.Equal("CONFIGURED_DEVICE", bot.deviceID)
+}
+
+func TestMatrixBotInvitePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &mockAPI{server: "server"}
+	api.reset()
+
+	cfg := testConfig
+	cfg.InviteAllowList = []string{"@friend:other", "server"}
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+
+	invite := func(roomID string, inviter string) string {
+		return `{"next_batch":"s1","rooms":{"invite":{"` + roomID + `":{"invite_state":{"events":[
+			{"type":"m.room.member","sender":"` + inviter + `","state_key":"@TEST_USER:server","content":{"membership":"invite"}}
+		]}}}}}`
+	}
+
+	api.apiResponse = invite("!a:server", "@user:server")
+	assert.NoError(bot.handlePolling())
+	assert.Equal("/client/r0/rooms/!a:server/join", api.lastAPICallPath)
+
+	api.apiResponse = invite("!b:other", "@friend:other")
+	assert.NoError(bot.handlePolling())
+	assert.Equal("/client/r0/rooms/!b:other/join", api.lastAPICallPath)
+
+	api.apiResponse = invite("!c:other", "@stranger:other")
+	assert.NoError(bot.handlePolling())
+	assert.Equal("/client/r0/rooms/%21c:other/leave", api.lastAPICallPath)
+
+	api.apiResponse = invite("!d:evilserver", "@user:evilserver")
+	assert.NoError(bot.handlePolling())
+	assert.Equal("/client/r0/rooms/%21d:evilserver/leave", api.lastAPICallPath)
+
+	// Without allow list every invite is accepted
+	bot.inviteAllowList = nil
+	api.apiResponse = invite("!e:evilserver", "@user:evilserver")
+	assert.NoError(bot.handlePolling())
+	assert.Equal("/client/r0/rooms/!e:evilserver/join", api.lastAPICallPath)
+}
+
+func TestMatrixBotRoomAliases(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &mockAPI{server: "server"}
+	api.reset()
+
+	cfg := testConfig
+	cfg.Rooms = []string{"#room:server", "!other:server"}
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+
+	api.reset()
+	api.apiResponses = map[string]string{
+		"/client/r0/join/%23room:server":  `{"room_id":"!room:server"}`,
+		"/client/r0/join/%21other:server": `{"room_id":"!other:server"}`,
+	}
+	bot.joinConfiguredRooms()
+	assert.Equal([]string{"POST /client/r0/join/%23room:server", "POST /client/r0/join/%21other:server"}, api.apiCallPaths)
+
+	// Joined aliases are already known
+	assert.Equal("!room:server", bot.resolveRoomID("#room:server"))
+
+	// Other aliases are resolved once using the room directory
+	api.reset()
+	api.apiResponses["/client/r0/directory/room/%23alias:server"] = `{"room_id":"!alias:server","servers":["server"]}`
+	assert.Equal("!alias:server", bot.resolveRoomID("#alias:server"))
+	assert.Equal("!alias:server", bot.resolveRoomID("#alias:server"))
+	assert.Equal([]string{"GET /client/r0/directory/room/%23alias:server"}, api.apiCallPaths)
+
+	// Unknown aliases are used as they are
+	api.apiResponses["/client/r0/directory/room/%23unknown:server"] = `{"errcode":"M_NOT_FOUND","error":"Room alias not found"}`
+	assert.Equal("#unknown:server", bot.resolveRoomID("#unknown:server"))
+}
+
+func TestMatrixBotLeaveEmptyRooms(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &mockAPI{server: "server"}
+	api.reset()
+
+	cfg := testConfig
+	cfg.LeaveEmptyRooms = true
+	bot, err := createMatrixBotWithAPI(api, "TEST_BOT", cfg, commanddispatcher.New(""), &storage.MockStorage{})
+	assert.NoError(err)
+
+	api.reset()
+	api.apiResponses = map[string]string{
+		"/client/r0/rooms/%21empty:server/joined_members": `{"joined":{"@TEST_USER:server":{}}}`,
+		"/client/r0/rooms/%21full:server/joined_members":  `{"joined":{"@TEST_USER:server":{},"@user:server":{}}}`,
+	}
+
+	// On the initial sync all rooms are checked
+	api.apiResponse = `{"next_batch":"s1","rooms":{"join":{"!empty:server":{},"!full:server":{}}}}`
+	assert.NoError(bot.handlePolling())
+	assert.Contains(api.apiCallPaths, "POST /client/r0/rooms/%21empty:server/leave")
+	assert.NotContains(api.apiCallPaths, "POST /client/r0/rooms/%21full:server/leave")
+
+	// Afterwards only rooms which members left
+	api.apiCallPaths = nil
+	api.apiResponse = `{"next_batch":"s2","rooms":{"join":{"!full:server":{"timeline":{"events":[
+		{"type":"m.room.message","sender":"@user:server","event_id":"$message","content":{"msgtype":"m.text","body":"bye"}}
+	]}}}}}`
+	assert.NoError(bot.handlePolling())
+	assert.NotContains(api.apiCallPaths, "GET /client/r0/rooms/%21full:server/joined_members")
+
+	api.apiResponses["/client/r0/rooms/%21full:server/joined_members"] = `{"joined":{"@TEST_USER:server":{}}}`
+	api.apiResponse = `{"next_batch":"s3","rooms":{"join":{"!full:server":{"timeline":{"events":[
+		{"type":"m.room.member","sender":"@user:server","state_key":"@user:server","event_id":"$leave","content":{"membership":"leave"}}
+	]}}}}}`
+	assert.NoError(bot.handlePolling())
+	assert.Contains(api.apiCallPaths, "POST /client/r0/rooms/%21full:server/leave")
+}
@@ -10,15 +10,26 @@ func (b *Bot) handlePolling() error {
 }
 
 func (b *Bot) startBot() {
-	// do some message polling or whatever until stopped
-	tickChan := time.Tick(b.pollingInterval)
+	defer b.wg.Done()
 
+	b.fetchDisplayName()
+	b.joinConfiguredRooms()
+
+	// Sync is long-polling, so the next sync starts right after the last one returned.
+	// Only after errors we wait before trying again.
 	for {
 		select {
-		case <-tickChan:
-			b.handlePolling()
 		case <-b.pollingDone:
 			return
+		default:
+		}
+
+		if err := b.handlePolling(); err != nil {
+			select {
+			case <-time.After(b.pollingInterval):
+			case <-b.pollingDone:
+				return
+			}
 		}
 	}
 }
@@ -26,10 +37,11 @@ func (b *Bot) startBot() {
 // Start the Matrix Bot
 func (b *Bot) Start() {
 	log.Println("MatrixBot is STARTING")
+	b.StartEventQueues()
+	b.pollingDone = make(chan bool)
+	b.wg.Add(1)
 	go b.startBot()
-	for _, plugin := range b.plugins {
-		plugin.OnRun()
-	}
+	b.RunPlugins(b.plugins)
 	log.Println("MatrixBot is RUNNING")
 }
 
@@ -48,11 +60,16 @@ func (b *Bot) Run(ctx context.Context) error {
 func (b *Bot) Stop() {
 	log.Println("MatrixBot is SHUTING DOWN")
 
+	b.StopEventQueues()
+
 	for _, plugin := range b.plugins {
 		plugin.OnStop()
 	}
 
-	b.pollingDone <- true
+	// The sync which is running is finished first, so that the stored sync token is the latest one.
+	close(b.pollingDone)
+	b.wg.Wait()
+	b.storeState()
 
 	log.Println("MatrixBot is SHUT DOWN")
 }
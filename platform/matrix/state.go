@@ -0,0 +1,171 @@
+package matrix
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/storagemodels"
+)
+
+// sealedStatePrefix marks an encryption state which is encrypted with the state key.
+const sealedStatePrefix = "sealed:"
+
+// Parameters of scrypt, which derives the key of the stored encryption state from the state key.
+const (
+	scryptN         = 1 << 15
+	scryptR         = 8
+	scryptP         = 1
+	stateSaltLength = 16
+	stateKeyLength  = 32
+)
+
+// stateSealer encrypts the stored encryption state with a key derived from the state key and a
+// random salt. Deriving the key is slow on purpose, so it is done once and kept with its salt.
+type stateSealer struct {
+	salt []byte
+	aead cipher.AEAD
+}
+
+func newStateSealer(stateKey string, salt []byte) (*stateSealer, error) {
+	key, err := scrypt.Key([]byte(stateKey), salt, scryptN, scryptR, scryptP, stateKeyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &stateSealer{salt: salt, aead: aead}, nil
+}
+
+// newRandomStateSealer creates a stateSealer with a new random salt.
+func newRandomStateSealer(stateKey string) (*stateSealer, error) {
+	salt := make([]byte, stateSaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return newStateSealer(stateKey, salt)
+}
+
+// seal encrypts the encryption state. The salt and the nonce are stored in front of the ciphertext.
+func (s *stateSealer) seal(state string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(append(append([]byte{}, s.salt...), nonce...), nonce, []byte(state), nil)
+	return sealedStatePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openState decrypts an encryption state sealed with a stateSealer and returns the stateSealer
+// for the salt of the state, so that it can be used to seal the state again. A state which is
+// not sealed is returned as it is without stateSealer, it is sealed the next time it is stored.
+func openState(state string, stateKey string) (string, *stateSealer, error) {
+	if !strings.HasPrefix(state, sealedStatePrefix) {
+		return state, nil, nil
+	}
+	if len(stateKey) == 0 {
+		return "", nil, fmt.Errorf("The stored encryption state is encrypted, but no state_key is configured")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(state, sealedStatePrefix))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(sealed) < stateSaltLength {
+		return "", nil, fmt.Errorf("The stored encryption state is invalid")
+	}
+	sealer, err := newStateSealer(stateKey, sealed[:stateSaltLength])
+	if err != nil {
+		return "", nil, err
+	}
+	sealed = sealed[stateSaltLength:]
+	nonceSize := sealer.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", nil, fmt.Errorf("The stored encryption state is invalid")
+	}
+	data, err := sealer.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not decrypt the stored encryption state, wrong state_key?")
+	}
+	return string(data), sealer, nil
+}
+
+// stateStorage is implemented by storages which can persist the state of a Matrix bot.
+type stateStorage interface {
+	GetMatrixState(botID string) (storagemodels.MatrixState, error)
+	StoreMatrixState(botID string, data storagemodels.MatrixState) error
+}
+
+func (b *Bot) getStateStorage() stateStorage {
+	if s, ok := b.Storage.(stateStorage); ok {
+		return s
+	}
+	return nil
+}
+
+// loadState returns the state stored for the bot or an empty state if there is none.
+func (b *Bot) loadState() storagemodels.MatrixState {
+	s := b.getStateStorage()
+	if s == nil {
+		log.Warnln("Storage does not support storing the Matrix state, events may be missed or repeated after a restart")
+		return storagemodels.MatrixState{}
+	}
+
+	state, err := s.GetMatrixState(b.botID)
+	if err != nil && err != storage.ErrNotFound {
+		log.Errorf("Could not load Matrix state: %s", err)
+	}
+	return state
+}
+
+// storeState stores the device, the sync token and the encryption state of the bot.
+// The encryption state contains the private keys, it is encrypted if a state key is configured.
+func (b *Bot) storeState() {
+	s := b.getStateStorage()
+	if s == nil {
+		return
+	}
+
+	state := storagemodels.MatrixState{
+		DeviceID:   b.deviceID,
+		NextBatch:  b.nextBatch,
+		Encryption: b.storedEncryption,
+	}
+	if b.crypto != nil {
+		data, err := json.Marshal(b.crypto)
+		if err != nil {
+			log.Errorf("Could not serialize encryption state: %s", err)
+			return
+		}
+		state.Encryption = string(data)
+		if len(b.stateKey) > 0 {
+			if b.stateSealer == nil {
+				if b.stateSealer, err = newRandomStateSealer(b.stateKey); err != nil {
+					log.Errorf("Could not encrypt encryption state: %s", err)
+					return
+				}
+			}
+			if state.Encryption, err = b.stateSealer.seal(state.Encryption); err != nil {
+				log.Errorf("Could not encrypt encryption state: %s", err)
+				return
+			}
+		}
+	}
+
+	if err := s.StoreMatrixState(b.botID, state); err != nil {
+		log.Errorf("Could not store Matrix state: %s", err)
+	}
+}
@@ -19,6 +19,7 @@ type userData struct {
 	EmailVerified bool   `json:"email_verified"`
 	AuthService   string `json:"auth_service"`
 	Roles         string `json:"roles"`
+	IsBot         bool   `json:"is_bot"`
 	Locale        string `json:"locale"`
 	NotifyProps   struct {
 		Email        string `json:"email"`
@@ -40,16 +41,22 @@ type userData struct {
 type usersData []userData
 
 func (b *Bot) getUserByID(userID string) (*userData, error) {
-	if val, ok := b.KnownUsers[userID]; ok {
+	b.knownMutex.RLock()
+	val, ok := b.KnownUsers[userID]
+	b.knownMutex.RUnlock()
+	if ok {
 		return &val, nil
 	}
 
 	response, err := b.apiRunner("/api/v4/users/ids", "POST", `[
 		"`+userID+`"
 		]`)
-	if err != nil && response.statusCode > 200 {
+	if err != nil {
 		return nil, err
 	}
+	if response.statusCode != 200 {
+		return nil, errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
+	}
 	var users usersData
 	err = json.Unmarshal(response.body, &users)
 	if err != nil {
@@ -83,14 +90,20 @@ type channelData struct {
 }
 
 func (b *Bot) getChannelByID(channelID string) (*channelData, error) {
-	if val, ok := b.KnownChannels[channelID]; ok {
+	b.knownMutex.RLock()
+	val, ok := b.KnownChannels[channelID]
+	b.knownMutex.RUnlock()
+	if ok {
 		return &val, nil
 	}
 
 	response, err := b.apiRunner("/api/v4/channels/"+channelID, "GET", "")
-	if err != nil && response.statusCode > 200 {
+	if err != nil {
 		return nil, err
 	}
+	if response.statusCode != 200 {
+		return nil, errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
+	}
 	var channel channelData
 	err = json.Unmarshal(response.body, &channel)
 	if err != nil {
@@ -101,3 +114,20 @@ func (b *Bot) getChannelByID(channelID string) (*channelData, error) {
 
 	return &channel, nil
 }
+
+func (b *Bot) getPostByID(postID string) (*post, error) {
+	response, err := b.apiRunner("/api/v4/posts/"+postID, "GET", "")
+	if err != nil {
+		return nil, err
+	}
+	if response.statusCode != 200 {
+		return nil, errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
+	}
+	var p post
+	err = json.Unmarshal(response.body, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -2,9 +2,8 @@ package mattermost
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"strings"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
@@ -17,6 +16,9 @@ import (
 	"github.com/torlenor/redseligg/plugin"
 )
 
+// maxMessageLength is the maximum length of a Mattermost post.
+const maxMessageLength = 16383
+
 type stats struct {
 	messagesSent int64
 	whispersSent int64
@@ -36,7 +38,14 @@ type Bot struct {
 
 	config botconfig.MattermostConfig
 
-	ws *websocket.Conn
+	ws      *websocket.Conn
+	wsMutex sync.Mutex // wsMutex guards writing to and replacing the websocket connection
+
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	connectionID string // connectionID is the ID of the gateway connection used to resume it
+	nextEventSeq int64  // nextEventSeq is the sequence number of the next expected event
 
 	token string
 
@@ -50,6 +59,8 @@ type Bot struct {
 
 	MeUser UserObject
 
+	knownMutex sync.RWMutex // knownMutex guards the known users and channels
+
 	KnownUsers     map[string]userData // key is UserID
 	knownUserNames map[string]string   // mapping of UserName to UserID
 	knownUserIDs   map[string]string   // mapping of UserID to UserName
@@ -59,38 +70,6 @@ type Bot struct {
 	knownChannelIDs   map[string]string      // mapping of ChannelID to UserChannelNameName
 }
 
-func (b *Bot) startMattermostBot() {
-	for {
-		_, message, err := b.ws.ReadMessage()
-		if err != nil {
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				b.log.Debugln("Connection closed normally: ", err)
-			} else {
-				b.log.Errorln("UNHANDLED ERROR: ", err)
-			}
-			break
-		}
-
-		var data map[string]interface{}
-
-		if err := json.Unmarshal(message, &data); err != nil {
-			b.log.Errorln("UNHANDLED ERROR: ", err)
-			continue
-		}
-
-		if event, ok := data["event"]; ok { // Dispatch to event handlers
-			switch event {
-			case "posted":
-				b.handleEventPosted(message)
-			default:
-				b.log.Warnf("Received unhandled event %s: %s", event, message)
-			}
-		} else {
-			b.log.Warnf("Received unhandled message: %s", message)
-		}
-	}
-}
-
 // CreateMattermostBot creates a new instance of a MattermostBot
 func CreateMattermostBot(cfg botconfig.MattermostConfig, storage storage.Storage, commandDispatcher *commanddispatcher.CommandDispatcher) (*Bot, error) {
 	log := logging.Get("MattermostBot")
@@ -99,7 +78,16 @@ func CreateMattermostBot(cfg botconfig.MattermostConfig, storage storage.Storage
 	b := Bot{
 		BotImpl: platform.BotImpl{
 			ProvidedFeatures: map[string]bool{
-				platform.FeatureMessagePost: true,
+				platform.FeatureMessagePost:   true,
+				platform.FeatureMessageUpdate: true,
+				platform.FeatureMessageDelete: true,
+
+				platform.FeatureMessageFormatting: true,
+				platform.FeatureMessageEmbeds:     true,
+				platform.FeatureMessageReplies:    true,
+				platform.FeatureMessageThreads:    true,
+
+				platform.FeatureReactionNotify: true,
 			},
 			Dispatcher: commandDispatcher,
 			Storage:    storage,
@@ -110,6 +98,8 @@ func CreateMattermostBot(cfg botconfig.MattermostConfig, storage storage.Storage
 
 		lastWsSeqNumber: 0,
 
+		done: make(chan struct{}),
+
 		KnownUsers:     make(map[string]userData),
 		knownUserNames: make(map[string]string),
 		knownUserIDs:   make(map[string]string),
@@ -123,15 +113,14 @@ func CreateMattermostBot(cfg botconfig.MattermostConfig, storage storage.Storage
 	if err != nil {
 		return nil, fmt.Errorf("Error logging in: %s", err)
 	}
+	b.Dispatcher.SetBotMentions("@" + b.MeUser.Username)
 
-	wsServer := strings.Replace(b.config.Server, "https", "wss", 1)
-	ws, err := b.dialGateway(wsServer + "/api/v4/websocket")
-	if err != nil {
+	if err := b.connectGateway(); err != nil {
 		return nil, err
 	}
-	b.ws = ws
 
-	b.authWs()
+	b.Dispatcher.SetPoster(&b)
+	b.Dispatcher.SetMaxMessageLength(maxMessageLength)
 
 	return &b, nil
 }
@@ -139,10 +128,10 @@ func CreateMattermostBot(cfg botconfig.MattermostConfig, storage storage.Storage
 // Start the Mattermost Bot
 func (b *Bot) Start() {
 	b.log.Infoln("MattermostBot is STARTING")
-	go b.startMattermostBot()
-	for _, plugin := range b.plugins {
-		plugin.OnRun()
-	}
+	b.StartEventQueues()
+	b.wg.Add(1)
+	go b.run()
+	b.RunPlugins(b.plugins)
 	b.log.Infoln("MattermostBot is RUNNING")
 }
 
@@ -152,6 +141,8 @@ func (b *Bot) Run(ctx context.Context) error {
 
 	<-ctx.Done()
 
+	b.StopEventQueues()
+
 	for _, plugin := range b.plugins {
 		plugin.OnStop()
 	}
@@ -165,10 +156,18 @@ func (b *Bot) Run(ctx context.Context) error {
 func (b *Bot) Stop() {
 	b.log.Infoln("MattermostBot is SHUTING DOWN")
 	b.log.Infof("MattermostBot Stats:\n%s", b.stats.toString())
+
+	close(b.done)
+
+	b.wsMutex.Lock()
 	err := b.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 	if err != nil {
 		b.log.Errorln("write close:", err)
 	}
+	b.ws.Close()
+	b.wsMutex.Unlock()
+
+	b.wg.Wait()
 
 	b.log.Infoln("MattermostBot is SHUT DOWN")
 }
@@ -186,6 +185,11 @@ func (b *Bot) AddPlugin(plugin platform.BotPlugin) {
 
 func (b *Bot) addKnownUser(user userData) {
 	b.log.Debugf("Added new known User: %s (%s)", user.Username, user.ID)
+	b.knownMutex.Lock()
+	defer b.knownMutex.Unlock()
+	if old, ok := b.KnownUsers[user.ID]; ok {
+		delete(b.knownUserNames, old.Username)
+	}
 	b.KnownUsers[user.ID] = user
 	b.knownUserNames[user.Username] = user.ID
 	b.knownUserIDs[user.ID] = user.Username
@@ -193,17 +197,46 @@ func (b *Bot) addKnownUser(user userData) {
 
 func (b *Bot) addKnownChannel(channel channelData) {
 	b.log.Debugf("Added new known Channel: %s (%s)", channel.ID, channel.Name)
+	b.knownMutex.Lock()
+	defer b.knownMutex.Unlock()
+	if old, ok := b.KnownChannels[channel.ID]; ok {
+		delete(b.knownChannelNames, old.Name)
+	}
 	b.KnownChannels[channel.ID] = channel
 	b.knownChannelNames[channel.Name] = channel.ID
 	b.knownChannelIDs[channel.ID] = channel.Name
 }
 
+func (b *Bot) removeKnownChannel(channelID string) {
+	b.log.Debugf("Removed known Channel: %s", channelID)
+	b.knownMutex.Lock()
+	defer b.knownMutex.Unlock()
+	if old, ok := b.KnownChannels[channelID]; ok {
+		delete(b.knownChannelNames, old.Name)
+		delete(b.knownChannelIDs, channelID)
+		delete(b.KnownChannels, channelID)
+	}
+}
+
+// clearKnown forgets all known users and channels.
+func (b *Bot) clearKnown() {
+	b.log.Debugf("Cleared known Users and Channels")
+	b.knownMutex.Lock()
+	defer b.knownMutex.Unlock()
+	b.KnownUsers = make(map[string]userData)
+	b.knownUserNames = make(map[string]string)
+	b.knownUserIDs = make(map[string]string)
+	b.KnownChannels = make(map[string]channelData)
+	b.knownChannelNames = make(map[string]string)
+	b.knownChannelIDs = make(map[string]string)
+}
+
 // GetInfo returns information about the Bot
 func (b *Bot) GetInfo() platform.BotInfo {
 	return platform.BotInfo{
 		BotID:    "",
 		Platform: "Mattermost",
 		Healthy:  true,
-		Plugins:  []platform.PluginInfo{},
+		Plugins:  b.PluginInfos(b.plugins),
 	}
 }
@@ -0,0 +1,184 @@
+package mattermost
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// usersPerPage is the page size used when listing all users.
+const usersPerPage = 200
+
+type teamData struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type eventUserUpdated struct {
+	Event string `json:"event"`
+	Data  struct {
+		User userData `json:"user"`
+	} `json:"data"`
+	Broadcast broadcast `json:"broadcast"`
+	Seq       int       `json:"seq"`
+}
+
+type eventChannel struct {
+	Event string `json:"event"`
+	Data  struct {
+		ChannelID string `json:"channel_id"`
+		TeamID    string `json:"team_id"`
+		Channel   string `json:"channel"`
+	} `json:"data"`
+	Broadcast broadcast `json:"broadcast"`
+	Seq       int       `json:"seq"`
+}
+
+func convertUser(user userData) model.User {
+	return model.User{
+		ID:        user.ID,
+		Name:      user.Username,
+		Nickname:  user.Nickname,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Locale:    user.Locale,
+		IsAdmin:   strings.Contains(" "+user.Roles+" ", " system_admin "),
+		IsBot:     user.IsBot,
+	}
+}
+
+func convertChannel(channel channelData) model.Channel {
+	return model.Channel{
+		ID:        channel.ID,
+		Name:      channel.Name,
+		Topic:     channel.Header,
+		Purpose:   channel.Purpose,
+		IsPrivate: channel.Type == "P" || channel.Type == "D" || channel.Type == "G",
+	}
+}
+
+func (b *Bot) apiGet(path string, v interface{}) error {
+	response, err := b.apiRunner(path, "GET", "")
+	if err != nil {
+		return err
+	}
+	if response.statusCode != 200 {
+		return errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
+	}
+	return json.Unmarshal(response.body, v)
+}
+
+// getAllUsers requests all users of the server page by page and adds them to the known users.
+func (b *Bot) getAllUsers() ([]userData, error) {
+	var all []userData
+	for page := 0; ; page++ {
+		var users usersData
+		if err := b.apiGet("/api/v4/users?per_page="+strconv.Itoa(usersPerPage)+"&page="+strconv.Itoa(page), &users); err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			b.addKnownUser(user)
+		}
+		all = append(all, users...)
+		if len(users) < usersPerPage {
+			return all, nil
+		}
+	}
+}
+
+func (b *Bot) getUserByName(name string) (*userData, error) {
+	b.knownMutex.RLock()
+	id, ok := b.knownUserNames[name]
+	val := b.KnownUsers[id]
+	b.knownMutex.RUnlock()
+	if ok {
+		return &val, nil
+	}
+
+	var user userData
+	if err := b.apiGet("/api/v4/users/username/"+url.PathEscape(name), &user); err != nil {
+		return nil, errors.Wrap(err, "Could not find user with name "+name)
+	}
+	b.addKnownUser(user)
+
+	return &user, nil
+}
+
+// getChannelByName looks for the channel in all teams of the bot.
+func (b *Bot) getChannelByName(name string) (*channelData, error) {
+	b.knownMutex.RLock()
+	id, ok := b.knownChannelNames[name]
+	val := b.KnownChannels[id]
+	b.knownMutex.RUnlock()
+	if ok {
+		return &val, nil
+	}
+
+	var teams []teamData
+	if err := b.apiGet("/api/v4/users/me/teams", &teams); err != nil {
+		return nil, errors.Wrap(err, "Could not get teams")
+	}
+	for _, team := range teams {
+		var channel channelData
+		if err := b.apiGet("/api/v4/teams/"+team.ID+"/channels/name/"+url.PathEscape(name), &channel); err == nil {
+			b.addKnownChannel(channel)
+			return &channel, nil
+		}
+	}
+
+	return nil, errors.New("Could not find channel with name " + name)
+}
+
+func (b *Bot) handleEventUserUpdated(data []byte) {
+	var updated eventUserUpdated
+	if err := json.Unmarshal(data, &updated); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	b.addKnownUser(updated.Data.User)
+}
+
+func (b *Bot) handleEventChannelCreated(data []byte) {
+	var created eventChannel
+	if err := json.Unmarshal(data, &created); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	if _, err := b.getChannelByID(created.Data.ChannelID); err != nil {
+		b.log.Warnf("Could not get created channel %s: %s", created.Data.ChannelID, err)
+	}
+}
+
+func (b *Bot) handleEventChannelUpdated(data []byte) {
+	var updated eventChannel
+	if err := json.Unmarshal(data, &updated); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	var channel channelData
+	if err := json.Unmarshal([]byte(updated.Data.Channel), &channel); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+	b.addKnownChannel(channel)
+}
+
+func (b *Bot) handleEventChannelDeleted(data []byte) {
+	var deleted eventChannel
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	b.removeKnownChannel(deleted.Data.ChannelID)
+}
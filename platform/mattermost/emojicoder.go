@@ -0,0 +1,32 @@
+package mattermost
+
+import "fmt"
+
+// Mattermost uses emoji names which mostly match the Redseligg names, but some emojis have aliases.
+func getRedseliggEmojiFromMattermostEmoji(mattermostEmoji string) (string, error) {
+	switch mattermostEmoji {
+
+	case "zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "keycap_ten":
+		return mattermostEmoji, nil
+	case "+1", "thumbsup":
+		return "+1", nil
+	case "-1", "thumbsdown":
+		return "-1", nil
+	default:
+		return mattermostEmoji, fmt.Errorf("Emoji not known")
+	}
+}
+
+func getMattermostEmojiFromRedseliggEmoji(redseliggEmoji string) (string, error) {
+	switch redseliggEmoji {
+
+	case "zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "keycap_ten", "+1", "-1":
+		return redseliggEmoji, nil
+	case "thumbsup":
+		return "+1", nil
+	case "thumbsdown":
+		return "-1", nil
+	default:
+		return "", fmt.Errorf("Emoji not known")
+	}
+}
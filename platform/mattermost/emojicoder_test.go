@@ -0,0 +1,41 @@
+package mattermost
+
+import "testing"
+
+func Test_getRedseliggEmojiFromMattermostEmoji(t *testing.T) {
+	tests := []struct {
+		name            string
+		mattermostEmoji string
+		want            string
+		wantErr         bool
+	}{
+		{
+			name:            "Request valid Emoji",
+			mattermostEmoji: "seven",
+			want:            "seven",
+		},
+		{
+			name:            "Request alias",
+			mattermostEmoji: "thumbsup",
+			want:            "+1",
+		},
+		{
+			name:            "Request invalid Emoji",
+			mattermostEmoji: "unknown",
+			want:            "unknown",
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRedseliggEmojiFromMattermostEmoji(tt.mattermostEmoji)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getRedseliggEmojiFromMattermostEmoji() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getRedseliggEmojiFromMattermostEmoji() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
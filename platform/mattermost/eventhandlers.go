@@ -26,6 +26,26 @@ type post struct {
 	PendingPostID string `json:"pending_post_id"`
 }
 
+// convertPost converts a received post. Posts in direct message channels (type "D") are private.
+func (b *Bot) convertPost(p post, channelType string) model.Post {
+	user := model.User{ID: p.UserID}
+	if u, err := b.getUserByID(p.UserID); err == nil {
+		user = convertUser(*u)
+	}
+
+	return model.Post{ServerID: b.config.Server, User: user, ChannelID: p.ChannelID, Content: p.Message, IsPrivate: channelType == "D"}
+}
+
+// channelType returns the type of the channel or an empty string if it is not known.
+func (b *Bot) channelType(channelID string) string {
+	channel, err := b.getChannelByID(channelID)
+	if err != nil {
+		b.log.Warnf("Could not get channel %s: %s", channelID, err)
+		return ""
+	}
+	return channel.Type
+}
+
 func (b *Bot) handleEventPosted(data []byte) {
 	var posted eventPosted
 
@@ -43,27 +63,174 @@ func (b *Bot) handleEventPosted(data []byte) {
 
 	b.log.Printf("%s", data)
 
-	isPrivate := false
-	if posted.Data.ChannelType == "D" {
-		isPrivate = true
+	receiveMessage := b.convertPost(post, posted.Data.ChannelType)
+	if receiveMessage.IsPrivate {
+		b.stats.whispersReceived++
+	} else {
+		b.stats.messagesReceived++
+	}
+
+	for _, plugin := range b.plugins {
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnPost(receiveMessage) })
+	}
+
+	b.Dispatcher.OnPost(receiveMessage)
+}
+
+func (b *Bot) handleEventReaction(data []byte) {
+	var event eventReaction
+	if err := json.Unmarshal(data, &event); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
 	}
 
-	var userName string
-	user, err := b.getUserByID(post.UserID)
+	var r reaction
+	if err := json.Unmarshal([]byte(event.Data.Reaction), &r); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	emoji, err := getRedseliggEmojiFromMattermostEmoji(r.EmojiName)
 	if err != nil {
-		userName = user.Username
+		b.log.Debugf("Could not map emoji %s, consider adding it to the mapping: %s", r.EmojiName, err)
+	}
+
+	channelID := event.Broadcast.ChannelID
+	if len(channelID) == 0 {
+		p, err := b.getPostByID(r.PostID)
+		if err != nil {
+			b.log.Warnf("Could not get channel of post %s: %s", r.PostID, err)
+		} else {
+			channelID = p.ChannelID
+		}
+	}
+
+	user := model.User{ID: r.UserID}
+	if u, err := b.getUserByID(r.UserID); err == nil {
+		user = convertUser(*u)
+	}
+
+	reaction := model.Reaction{
+		Message: model.MessageIdentifier{
+			ID:      r.PostID,
+			Channel: channelID,
+		},
+		Type:     "added",
+		Reaction: emoji,
+		User:     user,
+	}
+	if event.Event == "reaction_removed" {
+		reaction.Type = "removed"
 	}
 
-	receiveMessage := model.Post{ServerID: b.config.Server, User: model.User{Name: userName, ID: post.UserID}, ChannelID: post.ChannelID, Content: post.Message, IsPrivate: isPrivate}
 	for _, plugin := range b.plugins {
-		plugin.OnPost(receiveMessage)
+		plugin := plugin
+		if reaction.Type == "added" {
+			b.Enqueue(plugin, func() { plugin.OnReactionAdded(reaction) })
+		} else {
+			b.Enqueue(plugin, func() { plugin.OnReactionRemoved(reaction) })
+		}
 	}
+}
 
-	if ok, text := b.Dispatcher.IsHelp(receiveMessage); ok {
-		postMessage := receiveMessage
-		postMessage.Content = text
-		b.CreatePost(postMessage)
+func (b *Bot) handleEventPostEdited(data []byte) {
+	var edited eventPost
+	if err := json.Unmarshal(data, &edited); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	var p post
+	if err := json.Unmarshal([]byte(edited.Data.Post), &p); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+	// The bot edits its own posts, e.g., via UpdatePost, the plugins are not notified about them
+	if p.UserID == b.MeUser.ID {
+		return
+	}
+
+	updated := b.convertPost(p, b.channelType(p.ChannelID))
+	ident := model.MessageIdentifier{ID: p.ID, Channel: p.ChannelID}
+	for _, plugin := range b.plugins {
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnPostUpdated(ident, updated) })
+	}
+}
+
+func (b *Bot) handleEventPostDeleted(data []byte) {
+	var deleted eventPost
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	var p post
+	if err := json.Unmarshal([]byte(deleted.Data.Post), &p); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	ident := model.MessageIdentifier{ID: p.ID, Channel: p.ChannelID}
+	for _, plugin := range b.plugins {
+		plugin := plugin
+		b.Enqueue(plugin, func() { plugin.OnPostDeleted(ident) })
+	}
+}
+
+// handleEventMembership handles users added to or removed from channels. Team memberships are ignored.
+func (b *Bot) handleEventMembership(data []byte) {
+	var event eventMembership
+	if err := json.Unmarshal(data, &event); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	userID := event.Data.UserID
+	if len(userID) == 0 {
+		userID = event.Broadcast.UserID
+	}
+	channelID := event.Broadcast.ChannelID
+	if len(channelID) == 0 {
+		channelID = event.Data.ChannelID
+	}
+	if len(channelID) == 0 {
+		b.log.Debugf("Ignoring %s event of UserID = %s for TeamID = %s", event.Event, userID, event.Data.TeamID)
+		return
+	}
+
+	user := model.User{ID: userID}
+	if u, err := b.getUserByID(userID); err == nil {
+		user = convertUser(*u)
+	}
+
+	membership := model.Membership{ChannelID: channelID, User: user}
+	if event.Event == "user_added" {
+		membership.Type = "joined"
 	} else {
-		b.Dispatcher.OnPost(receiveMessage)
+		membership.Type = "left"
+	}
+
+	for _, plugin := range b.plugins {
+		plugin := plugin
+		if membership.Type == "joined" {
+			b.Enqueue(plugin, func() { plugin.OnUserJoined(membership) })
+		} else {
+			b.Enqueue(plugin, func() { plugin.OnUserLeft(membership) })
+		}
+	}
+}
+
+// handleEventDirectAdded adds new direct and group message channels of the bot to the known channels.
+func (b *Bot) handleEventDirectAdded(data []byte) {
+	var added eventChannel
+	if err := json.Unmarshal(data, &added); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	if _, err := b.getChannelByID(added.Broadcast.ChannelID); err != nil {
+		b.log.Warnf("Could not get direct message channel %s: %s", added.Broadcast.ChannelID, err)
 	}
 }
@@ -1,5 +1,13 @@
 package mattermost
 
+// eventHeader contains the fields common to the events and the replies to actions received from the gateway.
+type eventHeader struct {
+	Event    string `json:"event"`
+	Seq      int64  `json:"seq"`
+	SeqReply int64  `json:"seq_reply"`
+	Status   string `json:"status"`
+}
+
 type broadcast struct {
 	OmitUsers interface{} `json:"omit_users"`
 	UserID    string      `json:"user_id"`
@@ -20,6 +28,7 @@ type eventStatusChange struct {
 type eventHello struct {
 	Event string `json:"event"`
 	Data  struct {
+		ConnectionID  string `json:"connection_id"`
 		ServerVersion string `json:"server_version"`
 	} `json:"data"`
 	Broadcast broadcast `json:"broadcast"`
@@ -54,3 +63,43 @@ type eventPosted struct {
 	} `json:"broadcast"`
 	Seq int `json:"seq"`
 }
+
+// eventPost is sent for post_edited and post_deleted.
+type eventPost struct {
+	Event string `json:"event"`
+	Data  struct {
+		Post string `json:"post"`
+	} `json:"data"`
+	Broadcast broadcast `json:"broadcast"`
+	Seq       int       `json:"seq"`
+}
+
+// eventMembership is sent for user_added and user_removed. The user who was removed from a channel
+// receives the channel in the data and is the user of the broadcast.
+type eventMembership struct {
+	Event string `json:"event"`
+	Data  struct {
+		UserID    string `json:"user_id"`
+		ChannelID string `json:"channel_id"`
+		TeamID    string `json:"team_id"`
+		RemoverID string `json:"remover_id"`
+	} `json:"data"`
+	Broadcast broadcast `json:"broadcast"`
+	Seq       int       `json:"seq"`
+}
+
+type eventReaction struct {
+	Event string `json:"event"`
+	Data  struct {
+		Reaction string `json:"reaction"`
+	} `json:"data"`
+	Broadcast broadcast `json:"broadcast"`
+	Seq       int       `json:"seq"`
+}
+
+type reaction struct {
+	UserID    string `json:"user_id"`
+	PostID    string `json:"post_id"`
+	EmojiName string `json:"emoji_name"`
+	CreateAt  int64  `json:"create_at"`
+}
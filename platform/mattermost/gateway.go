@@ -1,12 +1,25 @@
 package mattermost
 
 import (
+	"encoding/json"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 )
 
+var (
+	// The time to wait before reconnecting after a lost connection doubles with every failure.
+	minReconnectWait = 1 * time.Second
+	maxReconnectWait = 2 * time.Minute
+)
+
+// errMissedEvents is returned when the sequence numbers of the received events have a gap.
+var errMissedEvents = errors.New("Missed events")
+
 func (b *Bot) dialGateway(gatewayURL string) (*websocket.Conn, error) {
 	b.log.Debugf("Dialing the Mattermost gateway: %s", gatewayURL)
 	c, _, err := websocket.DefaultDialer.Dial(gatewayURL, nil)
@@ -17,7 +30,42 @@ func (b *Bot) dialGateway(gatewayURL string) (*websocket.Conn, error) {
 	return c, nil
 }
 
-func (b *Bot) authWs() {
+// gatewayURL returns the URL of the gateway. After the first connection the server is asked
+// to resume it and to send the events starting with the next expected sequence number.
+func (b *Bot) gatewayURL() string {
+	gatewayURL := strings.Replace(b.config.Server, "http", "ws", 1) + "/api/v4/websocket"
+	if len(b.connectionID) > 0 {
+		gatewayURL += "?connection_id=" + url.QueryEscape(b.connectionID) + "&sequence_number=" + strconv.FormatInt(b.nextEventSeq, 10)
+	}
+	return gatewayURL
+}
+
+// connectGateway dials the gateway and authenticates the new connection.
+func (b *Bot) connectGateway() error {
+	ws, err := b.dialGateway(b.gatewayURL())
+	if err != nil {
+		return err
+	}
+
+	b.wsMutex.Lock()
+	if b.stopped() {
+		b.wsMutex.Unlock()
+		ws.Close()
+		return errors.New("Bot is stopped")
+	}
+	if b.ws != nil {
+		b.ws.Close()
+	}
+	b.ws = ws
+	b.wsMutex.Unlock()
+
+	return b.authWs()
+}
+
+func (b *Bot) authWs() error {
+	b.wsMutex.Lock()
+	defer b.wsMutex.Unlock()
+
 	b.lastWsSeqNumber++
 	ident := []byte(`{
 		"seq": ` + strconv.Itoa(int(b.lastWsSeqNumber)) + `,
@@ -31,6 +79,180 @@ func (b *Bot) authWs() {
 
 	err := b.ws.WriteMessage(websocket.TextMessage, ident)
 	if err != nil {
-		b.log.Fatal("Error sending AUTH to gateway:", err)
+		return errors.Wrap(err, "Error sending AUTH to gateway")
 	}
+	return nil
+}
+
+func (b *Bot) stopped() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// run receives the events and reconnects until the bot is stopped. The time to wait
+// before reconnecting is reset when the gateway sends something.
+func (b *Bot) run() {
+	defer b.wg.Done()
+
+	wait := minReconnectWait
+	for {
+		err := b.receiveEvents(func() { wait = minReconnectWait })
+		if b.stopped() {
+			return
+		}
+
+		immediate := err == errMissedEvents
+		if immediate {
+			b.log.Infof("Reconnecting to the Mattermost gateway to receive the missed events")
+		} else {
+			b.log.Warnf("Connection to the Mattermost gateway lost: %s", err)
+		}
+
+		for {
+			if !immediate {
+				b.log.Infof("Reconnecting to the Mattermost gateway in %s", wait)
+				select {
+				case <-time.After(wait):
+				case <-b.done:
+					return
+				}
+				wait *= 2
+				if wait > maxReconnectWait {
+					wait = maxReconnectWait
+				}
+			}
+			immediate = false
+
+			if err := b.connectGateway(); err != nil {
+				if b.stopped() {
+					return
+				}
+				b.log.Errorf("Could not reconnect to the Mattermost gateway: %s", err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// receiveEvents handles the received events until the connection is lost, events are missed
+// or the bot is stopped.
+func (b *Bot) receiveEvents(onConnected func()) error {
+	connected := false
+	for {
+		if b.stopped() {
+			return nil
+		}
+
+		_, message, err := b.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				b.log.Debugln("Connection closed normally: ", err)
+			}
+			return err
+		}
+		if !connected {
+			connected = true
+			onConnected()
+		}
+
+		if err := b.handleEvent(message); err != nil {
+			return err
+		}
+	}
+}
+
+// handleHello handles the first event of a connection. When the previous connection could
+// not be resumed the server starts a new one and the events in between are lost.
+func (b *Bot) handleHello(data []byte) {
+	var hello eventHello
+	if err := json.Unmarshal(data, &hello); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return
+	}
+
+	b.log.Infof("Connected to Mattermost server version %s", hello.Data.ServerVersion)
+
+	if len(b.connectionID) > 0 {
+		if hello.Data.ConnectionID == b.connectionID {
+			b.log.Infof("Resumed connection to the Mattermost gateway")
+			return
+		}
+		b.log.Warnf("Could not resume connection to the Mattermost gateway, events may have been missed")
+		// The known users and channels may be outdated, they are requested again when needed
+		b.clearKnown()
+	}
+	b.connectionID = hello.Data.ConnectionID
+	b.nextEventSeq = 0
+}
+
+// checkEventSeq checks the sequence number of a received event. The server numbers
+// the events of a connection consecutively starting at 0, a gap means missed events.
+func (b *Bot) checkEventSeq(event string, seq int64) error {
+	if seq != b.nextEventSeq {
+		b.log.Warnf("Received %s event with seq %d, but expected seq %d", event, seq, b.nextEventSeq)
+		return errMissedEvents
+	}
+	b.nextEventSeq = seq + 1
+	return nil
+}
+
+// handleEvent dispatches the event to the event handlers. Replies to actions are only logged.
+func (b *Bot) handleEvent(message []byte) error {
+	var header eventHeader
+	if err := json.Unmarshal(message, &header); err != nil {
+		b.log.Errorln("UNHANDLED ERROR: ", err)
+		return nil
+	}
+
+	if len(header.Event) == 0 {
+		if header.SeqReply > 0 {
+			b.log.Debugf("Received reply to action %d: %s", header.SeqReply, header.Status)
+		} else {
+			b.log.Warnf("Received unhandled message: %s", message)
+		}
+		return nil
+	}
+
+	if header.Event == "hello" {
+		b.handleHello(message)
+	}
+	if err := b.checkEventSeq(header.Event, header.Seq); err != nil {
+		return err
+	}
+
+	switch header.Event {
+	case "hello":
+		// Handled above
+	case "posted":
+		b.handleEventPosted(message)
+	case "post_edited":
+		b.handleEventPostEdited(message)
+	case "post_deleted":
+		b.handleEventPostDeleted(message)
+	case "reaction_added", "reaction_removed":
+		b.handleEventReaction(message)
+	case "user_added", "user_removed":
+		b.handleEventMembership(message)
+	case "direct_added", "group_added":
+		b.handleEventDirectAdded(message)
+	case "user_updated":
+		b.handleEventUserUpdated(message)
+	case "channel_created":
+		b.handleEventChannelCreated(message)
+	case "channel_updated":
+		b.handleEventChannelUpdated(message)
+	case "channel_deleted":
+		b.handleEventChannelDeleted(message)
+	case "typing", "status_change", "channel_viewed":
+		// Not needed
+	default:
+		b.log.Warnf("Received unhandled event %s: %s", header.Event, message)
+	}
+
+	return nil
 }
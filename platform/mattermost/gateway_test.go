@@ -0,0 +1,167 @@
+package mattermost
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/platform"
+	"github.com/torlenor/redseligg/plugin"
+)
+
+type mockEventPlugin struct {
+	plugin.RedseliggPlugin
+
+	posts   []model.Post
+	updated []model.Post
+	deleted []model.MessageIdentifier
+	joined  []model.Membership
+	left    []model.Membership
+}
+
+func (p *mockEventPlugin) OnPost(post model.Post) { p.posts = append(p.posts, post) }
+func (p *mockEventPlugin) OnPostUpdated(ident model.MessageIdentifier, post model.Post) {
+	p.updated = append(p.updated, post)
+}
+func (p *mockEventPlugin) OnPostDeleted(ident model.MessageIdentifier) {
+	p.deleted = append(p.deleted, ident)
+}
+func (p *mockEventPlugin) OnUserJoined(m model.Membership) { p.joined = append(p.joined, m) }
+func (p *mockEventPlugin) OnUserLeft(m model.Membership)   { p.left = append(p.left, m) }
+
+func createEventTestBot(server string) (*Bot, *mockEventPlugin) {
+	p := &mockEventPlugin{}
+	b := &Bot{
+		BotImpl: platform.BotImpl{Dispatcher: commanddispatcher.New("!")},
+		config:  botconfig.MattermostConfig{Server: server},
+		log:     logging.Get("MattermostBot"),
+		done:    make(chan struct{}),
+		plugins: []plugin.Hooks{p},
+
+		KnownUsers:     make(map[string]userData),
+		knownUserNames: make(map[string]string),
+		knownUserIDs:   make(map[string]string),
+
+		KnownChannels:     make(map[string]channelData),
+		knownChannelNames: make(map[string]string),
+		knownChannelIDs:   make(map[string]string),
+	}
+	b.MeUser.ID = "BOTID"
+	b.addKnownUser(userData{ID: "USERID", Username: "ronni", Nickname: "Ronni", Roles: "system_user"})
+	b.addKnownChannel(channelData{ID: "CHANNELID", Name: "town-square", Type: "O"})
+	b.addKnownChannel(channelData{ID: "DMID", Name: "USERID__BOTID", Type: "D"})
+	return b, p
+}
+
+func TestBot_handleEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	b, p := createEventTestBot("")
+
+	events := []string{
+		`{"event":"hello","data":{"connection_id":"CONNID","server_version":"5.37.0"},"broadcast":{},"seq":0}`,
+		`{"status":"OK","seq_reply":1}`,
+		`{"event":"posted","data":{"channel_type":"D","post":"{\"id\":\"POSTID\",\"user_id\":\"USERID\",\"channel_id\":\"DMID\",\"message\":\"Hello\"}"},"broadcast":{"channel_id":"DMID"},"seq":1}`,
+		`{"event":"post_edited","data":{"post":"{\"id\":\"POSTID\",\"user_id\":\"USERID\",\"channel_id\":\"DMID\",\"message\":\"Hello again\"}"},"broadcast":{"channel_id":"DMID"},"seq":2}`,
+		`{"event":"post_edited","data":{"post":"{\"id\":\"BOTPOSTID\",\"user_id\":\"BOTID\",\"channel_id\":\"DMID\",\"message\":\"Edited by the bot\"}"},"broadcast":{"channel_id":"DMID"},"seq":3}`,
+		`{"event":"post_deleted","data":{"post":"{\"id\":\"POSTID\",\"user_id\":\"USERID\",\"channel_id\":\"DMID\",\"message\":\"Hello again\"}"},"broadcast":{"channel_id":"DMID"},"seq":4}`,
+		`{"event":"user_added","data":{"team_id":"TEAMID","user_id":"USERID"},"broadcast":{"channel_id":"CHANNELID"},"seq":5}`,
+		`{"event":"user_added","data":{"user_id":"USERID"},"broadcast":{"team_id":"TEAMID"},"seq":6}`,
+		`{"event":"user_removed","data":{"channel_id":"CHANNELID","remover_id":"OTHERID"},"broadcast":{"user_id":"USERID"},"seq":7}`,
+		`{"event":"typing","data":{"user_id":"USERID"},"broadcast":{"channel_id":"CHANNELID"},"seq":8}`,
+	}
+	for _, event := range events {
+		assert.NoError(b.handleEvent([]byte(event)))
+	}
+
+	assert.Equal("CONNID", b.connectionID)
+	assert.Equal(int64(9), b.nextEventSeq)
+
+	user := model.User{ID: "USERID", Name: "ronni", Nickname: "Ronni"}
+	assert.Equal([]model.Post{{ChannelID: "DMID", User: user, Content: "Hello", IsPrivate: true}}, p.posts)
+	assert.Equal([]model.Post{{ChannelID: "DMID", User: user, Content: "Hello again", IsPrivate: true}}, p.updated)
+	assert.Equal([]model.MessageIdentifier{{ID: "POSTID", Channel: "DMID"}}, p.deleted)
+	assert.Equal([]model.Membership{{ChannelID: "CHANNELID", Type: "joined", User: user}}, p.joined)
+	assert.Equal([]model.Membership{{ChannelID: "CHANNELID", Type: "left", User: user}}, p.left)
+	assert.Equal(int64(1), b.stats.whispersReceived)
+
+	assert.Equal(errMissedEvents, b.handleEvent([]byte(`{"event":"typing","data":{},"broadcast":{},"seq":10}`)))
+	assert.Equal(int64(9), b.nextEventSeq)
+}
+
+func TestBot_handleHello(t *testing.T) {
+	assert := assert.New(t)
+
+	b, _ := createEventTestBot("")
+	b.connectionID = "CONNID"
+	b.nextEventSeq = 5
+
+	b.handleHello([]byte(`{"event":"hello","data":{"connection_id":"CONNID"},"seq":5}`))
+	assert.Equal(int64(5), b.nextEventSeq)
+	assert.Equal(2, len(b.KnownChannels))
+
+	b.handleHello([]byte(`{"event":"hello","data":{"connection_id":"NEWID"},"seq":0}`))
+	assert.Equal("NEWID", b.connectionID)
+	assert.Equal(int64(0), b.nextEventSeq)
+	assert.Equal(0, len(b.KnownChannels))
+}
+
+func TestBot_Reconnect(t *testing.T) {
+	oldMin := minReconnectWait
+	minReconnectWait = time.Millisecond
+	defer func() { minReconnectWait = oldMin }()
+
+	queries := make(chan string, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		queries <- r.URL.RawQuery
+
+		if len(r.URL.RawQuery) == 0 {
+			c.WriteMessage(websocket.TextMessage, []byte(`{"event":"hello","data":{"connection_id":"CONNID"},"seq":0}`))
+			c.WriteMessage(websocket.TextMessage, []byte(`{"event":"typing","data":{},"seq":1}`))
+			// The connection drops
+			return
+		}
+
+		c.WriteMessage(websocket.TextMessage, []byte(`{"event":"hello","data":{"connection_id":"CONNID"},"seq":2}`))
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	b, _ := createEventTestBot(server.URL)
+	if err := b.connectGateway(); err != nil {
+		t.Fatalf("Connecting should not have failed: %s", err)
+	}
+	b.Start()
+
+	expected := []string{"", "connection_id=CONNID&sequence_number=2"}
+	for _, e := range expected {
+		select {
+		case query := <-queries:
+			if query != e {
+				t.Errorf("Expected gateway query %q, got %q", e, query)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Bot did not connect with query %q", e)
+		}
+	}
+
+	b.Stop()
+}
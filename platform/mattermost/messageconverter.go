@@ -0,0 +1,76 @@
+package mattermost
+
+import (
+	"fmt"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+type attachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+// attachment is a Mattermost message attachment which is used to show embeds.
+type attachment struct {
+	Fallback  string            `json:"fallback,omitempty"`
+	Color     string            `json:"color,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Fields    []attachmentField `json:"fields,omitempty"`
+	ImageURL  string            `json:"image_url,omitempty"`
+	ThumbURL  string            `json:"thumb_url,omitempty"`
+	Footer    string            `json:"footer,omitempty"`
+}
+
+type postProps struct {
+	Attachments []attachment `json:"attachments"`
+}
+
+type postRequest struct {
+	ID        string     `json:"id,omitempty"` // ID is only set when updating a post
+	ChannelID string     `json:"channel_id"`
+	Message   string     `json:"message"`
+	RootID    string     `json:"root_id,omitempty"`
+	Props     *postProps `json:"props,omitempty"`
+}
+
+func convertEmbedFromRedseligg(e model.Embed) attachment {
+	converted := attachment{
+		Fallback:  model.PlainText.Render(e.Nodes()),
+		Title:     e.Title,
+		TitleLink: e.URL,
+		Text:      e.Description,
+		ImageURL:  e.ImageURL,
+		ThumbURL:  e.ThumbnailURL,
+		Footer:    e.Footer,
+	}
+	if e.Color != 0 {
+		converted.Color = fmt.Sprintf("#%06x", e.Color)
+	}
+	for _, f := range e.Fields {
+		converted.Fields = append(converted.Fields, attachmentField{Title: f.Name, Value: f.Value, Short: f.Inline})
+	}
+	return converted
+}
+
+// convertMessageFromRedseligg converts a post into a Mattermost post. Embeds are sent as
+// message attachments and replies are sent into the thread of the message.
+func convertMessageFromRedseligg(post model.Post) postRequest {
+	msg := postRequest{
+		Message: post.Render(model.Markdown, false, true),
+		RootID:  post.ThreadID,
+	}
+	if len(msg.RootID) == 0 {
+		msg.RootID = post.ReplyTo
+	}
+	if len(post.Embeds) > 0 {
+		msg.Props = &postProps{}
+		for _, e := range post.Embeds {
+			msg.Props.Attachments = append(msg.Props.Attachments, convertEmbedFromRedseligg(e))
+		}
+	}
+	return msg
+}
@@ -4,26 +4,73 @@ import (
 	"encoding/json"
 
 	"github.com/pkg/errors"
+
+	"github.com/torlenor/redseligg/model"
 )
 
-func (b *Bot) sendMessage(channelID string, content string) error {
+// postResponseFromAPI parses the post returned by the Mattermost API when creating or updating a post.
+func postResponseFromAPI(response *apiResponse) (post, error) {
+	var p post
+	if response.statusCode != 200 && response.statusCode != 201 {
+		return p, errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
+	}
+	err := json.Unmarshal(response.body, &p)
+	if err != nil {
+		return p, errors.Wrap(err, "json unmarshal failed")
+	}
+	return p, nil
+}
+
+func (b *Bot) sendMessage(channelID string, msg postRequest) (post, error) {
+	msg.ChannelID = channelID
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return post{}, errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.apiRunner("/api/v4/posts", "POST", string(body))
+	if err != nil {
+		return post{}, errors.New("Sending Message failed: " + err.Error())
+	}
 
-	_, err := b.apiRunner("/api/v4/posts", "POST", `{
-		"channel_id": "`+channelID+`",
-		"message": "`+content+`"
-		}`)
+	return postResponseFromAPI(response)
+}
 
+func (b *Bot) updateMessage(messageID model.MessageIdentifier, msg postRequest) (post, error) {
+	msg.ID = messageID.ID
+	msg.ChannelID = messageID.Channel
+	body, err := json.Marshal(msg)
 	if err != nil {
-		return errors.New("Sending Message failed: " + err.Error())
+		return post{}, errors.Wrap(err, "json marshal failed")
+	}
+
+	response, err := b.apiRunner("/api/v4/posts/"+messageID.ID, "PUT", string(body))
+	if err != nil {
+		return post{}, errors.New("Updating Message failed: " + err.Error())
+	}
+
+	return postResponseFromAPI(response)
+}
+
+func (b *Bot) deleteMessage(messageID model.MessageIdentifier) error {
+	response, err := b.apiRunner("/api/v4/posts/"+messageID.ID, "DELETE", "")
+	if err != nil {
+		return errors.New("Deleting Message failed: " + err.Error())
+	}
+	if response.statusCode != 200 {
+		return errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
 	}
 
 	return nil
 }
 
-func (b *Bot) sendWhisper(userID string, content string) error {
+func (b *Bot) sendWhisper(userID string, msg postRequest) (post, error) {
 	// It is a known channel
-	if _, ok := b.knownChannelIDs[userID]; ok {
-		return b.sendMessage(userID, content)
+	b.knownMutex.RLock()
+	_, ok := b.knownChannelIDs[userID]
+	b.knownMutex.RUnlock()
+	if ok {
+		return b.sendMessage(userID, msg)
 	}
 
 	// It is not a known Channel so maybe it is a userID
@@ -31,14 +78,17 @@ func (b *Bot) sendWhisper(userID string, content string) error {
 			"`+b.MeUser.ID+`",
 			"`+userID+`"
 			]`)
-	if err != nil && response.statusCode > 200 {
-		return err
+	if err != nil {
+		return post{}, err
+	}
+	if response.statusCode != 200 && response.statusCode != 201 {
+		return post{}, errors.Errorf("API call returned status %d: %s", response.statusCode, response.body)
 	}
 	var channel channelData
 	err = json.Unmarshal(response.body, &channel)
 	if err != nil {
-		return err
+		return post{}, err
 	}
 	b.addKnownChannel(channel)
-	return b.sendMessage(channel.ID, content)
+	return b.sendMessage(channel.ID, msg)
 }
@@ -0,0 +1,56 @@
+package mattermost
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
+)
+
+func TestBot_CreateUpdateDeletePost(t *testing.T) {
+	assert := assert.New(t)
+
+	var lastMethod, lastPath, lastBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lastMethod, lastPath, lastBody = r.Method, r.URL.Path, string(body)
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(201)
+			w.Write([]byte(`{"id":"POSTID","channel_id":"CHANNELID","message":"Hello"}`))
+		case "PUT":
+			w.Write([]byte(`{"id":"POSTID","channel_id":"CHANNELID","message":"Updated"}`))
+		case "DELETE":
+			w.Write([]byte(`{"status":"OK"}`))
+		}
+	}))
+	defer server.Close()
+
+	b := &Bot{
+		config: botconfig.MattermostConfig{Server: server.URL},
+		log:    logging.Get("MattermostBot"),
+	}
+
+	response, err := b.CreatePost(model.Post{ChannelID: "CHANNELID", Content: "Hello"})
+	assert.NoError(err)
+	assert.Equal(model.MessageIdentifier{ID: "POSTID", Channel: "CHANNELID"}, response.PostedMessageIdent)
+	assert.Equal("/api/v4/posts", lastPath)
+
+	response, err = b.UpdatePost(response.PostedMessageIdent, model.Post{Content: "Updated"})
+	assert.NoError(err)
+	assert.Equal(model.MessageIdentifier{ID: "POSTID", Channel: "CHANNELID"}, response.PostedMessageIdent)
+	assert.Equal("PUT", lastMethod)
+	assert.Equal("/api/v4/posts/POSTID", lastPath)
+	assert.JSONEq(`{"id":"POSTID","channel_id":"CHANNELID","message":"Updated"}`, lastBody)
+
+	_, err = b.DeletePost(response.PostedMessageIdent)
+	assert.NoError(err)
+	assert.Equal("DELETE", lastMethod)
+	assert.Equal("/api/v4/posts/POSTID", lastPath)
+}
@@ -2,6 +2,7 @@ package mattermost
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/utils"
@@ -9,52 +10,118 @@ import (
 
 var version string
 
-// GetUsers a list of users based on search options.
-func (b *Bot) GetUsers() ([]model.User, error) { return nil, nil }
+// GetUsers returns all users of the Mattermost server.
+func (b *Bot) GetUsers() ([]model.User, error) {
+	users, err := b.getAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting users: %s", err)
+	}
+
+	var result []model.User
+	for _, user := range users {
+		result = append(result, convertUser(user))
+	}
+	return result, nil
+}
 
 // GetUser gets a user.
-func (b *Bot) GetUser(userID string) (model.User, error) { return model.User{}, nil }
+func (b *Bot) GetUser(userID string) (model.User, error) {
+	user, err := b.getUserByID(userID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("Error getting user: %s", err)
+	}
+	return convertUser(*user), nil
+}
 
 // GetUserByUsername gets a user by their username.
-func (b *Bot) GetUserByUsername(name string) (model.User, error) { return model.User{}, nil }
+func (b *Bot) GetUserByUsername(name string) (model.User, error) {
+	user, err := b.getUserByName(name)
+	if err != nil {
+		return model.User{}, fmt.Errorf("Error getting user: %s", err)
+	}
+	return convertUser(*user), nil
+}
 
 // GetChannel gets a channel.
-func (b *Bot) GetChannel(channelID string) (model.Channel, error) { return model.Channel{}, nil }
+func (b *Bot) GetChannel(channelID string) (model.Channel, error) {
+	channel, err := b.getChannelByID(channelID)
+	if err != nil {
+		return model.Channel{}, fmt.Errorf("Error getting channel: %s", err)
+	}
+	return convertChannel(*channel), nil
+}
 
-// GetChannelByName gets a channel by its name, given a team id.
-func (b *Bot) GetChannelByName(name string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannelByName gets a channel by its name from the teams of the bot.
+func (b *Bot) GetChannelByName(name string) (model.Channel, error) {
+	channel, err := b.getChannelByName(name)
+	if err != nil {
+		return model.Channel{}, fmt.Errorf("Error getting channel: %s", err)
+	}
+	return convertChannel(*channel), nil
+}
 
 // CreatePost creates a post.
 func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
-
 	if post.IsPrivate {
-		err := b.sendWhisper(post.User.ID, post.Content)
+		p, err := b.sendWhisper(post.User.ID, convertMessageFromRedseligg(post))
 		if err != nil {
 			return model.PostResponse{}, fmt.Errorf("Error sending whisper: %s", err)
 		}
-	} else {
-		err := b.sendMessage(post.ChannelID, post.Content)
-		if err != nil {
-			return model.PostResponse{}, fmt.Errorf("Error sending message: %s", err)
-		}
+		return model.PostResponse{PostedMessageIdent: model.MessageIdentifier{ID: p.ID, Channel: p.ChannelID}}, nil
 	}
 
-	return model.PostResponse{}, nil
+	p, err := b.sendMessage(post.ChannelID, convertMessageFromRedseligg(post))
+	if err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error sending message: %s", err)
+	}
+	return model.PostResponse{PostedMessageIdent: model.MessageIdentifier{ID: p.ID, Channel: p.ChannelID}}, nil
 }
 
 // UpdatePost updates a post.
 func (b *Bot) UpdatePost(messageID model.MessageIdentifier, newPost model.Post) (model.PostResponse, error) {
-	return model.PostResponse{}, fmt.Errorf("Not implemented")
+	p, err := b.updateMessage(messageID, convertMessageFromRedseligg(newPost))
+	if err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error updating message: %s", err)
+	}
+	return model.PostResponse{PostedMessageIdent: model.MessageIdentifier{ID: p.ID, Channel: p.ChannelID}}, nil
 }
 
 // DeletePost deletes a post.
 func (b *Bot) DeletePost(messageID model.MessageIdentifier) (model.PostResponse, error) {
-	return model.PostResponse{}, fmt.Errorf("Not implemented")
+	err := b.deleteMessage(messageID)
+	if err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error deleting message: %s", err)
+	}
+	return model.PostResponse{PostedMessageIdent: messageID}, nil
+}
+
+// TimeoutUser is not supported on Mattermost.
+func (b *Bot) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// BanUser is not supported on Mattermost.
+func (b *Bot) BanUser(channelID string, userID string, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// ClearChat is not supported on Mattermost.
+func (b *Bot) ClearChat(channelID string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// SetSlowMode is not supported on Mattermost.
+func (b *Bot) SetSlowMode(channelID string, interval time.Duration) error {
+	return fmt.Errorf("Not supported")
 }
 
 // GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
 func (b *Bot) GetReaction(reactionName string) (string, error) {
-	return "", fmt.Errorf("Not implemented")
+	emoji, err := getMattermostEmojiFromRedseliggEmoji(reactionName)
+	if err != nil {
+		return "", err
+	}
+	return ":" + emoji + ":", nil
 }
 
 // LogTrace writes a log message to the server log file.
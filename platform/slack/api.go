@@ -2,6 +2,7 @@ package slack
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -59,6 +60,53 @@ func (b *Bot) RtmConnect() (RtmConnectResponse, error) {
 	return rtmConnectResponse, err
 }
 
+type authTestResponse struct {
+	Ok     bool   `json:"ok"`
+	Error  string `json:"error"`
+	UserID string `json:"user_id"`
+	TeamID string `json:"team_id"`
+}
+
+// authTest returns the identity of the bot
+func (b *Bot) authTest() (authTestResponse, error) {
+	response, err := b.apiCallJON("/api/auth.test", "POST", "")
+	if err != nil {
+		return authTestResponse{}, errors.Wrap(err, "apiCall failed")
+	}
+
+	authTest := authTestResponse{}
+	if err := json.Unmarshal(response.body, &authTest); err != nil {
+		return authTestResponse{}, err
+	}
+	if !authTest.Ok {
+		return authTestResponse{}, fmt.Errorf("Error in auth.test: %s", authTest.Error)
+	}
+	return authTest, nil
+}
+
+type connectionsOpenResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+	URL   string `json:"url"`
+}
+
+// openSocketModeConnection returns a WebSocket URL for Socket Mode. It needs the app-level token.
+func (b *Bot) openSocketModeConnection() (string, error) {
+	response, err := b.apiCallWithToken("/api/apps.connections.open", "POST", "", b.config.AppToken)
+	if err != nil {
+		return "", errors.Wrap(err, "apiCall failed")
+	}
+
+	connectionsOpen := connectionsOpenResponse{}
+	if err := json.Unmarshal(response.body, &connectionsOpen); err != nil {
+		return "", err
+	}
+	if !connectionsOpen.Ok {
+		return "", fmt.Errorf("Error in apps.connections.open: %s", connectionsOpen.Error)
+	}
+	return connectionsOpen.URL, nil
+}
+
 type apiResponse struct {
 	header     http.Header
 	body       []byte
@@ -93,6 +141,11 @@ func (b *Bot) apiCall(path string, method string, args string, body string) (*ap
 }
 
 func (b *Bot) apiCallJON(path string, method string, body string) (*apiResponse, error) {
+	return b.apiCallWithToken(path, method, body, b.config.Token)
+}
+
+// apiCallWithToken calls the API with a JSON body and the token as bearer token.
+func (b *Bot) apiCallWithToken(path string, method string, body string, token string) (*apiResponse, error) {
 	client := &http.Client{}
 
 	req, err := http.NewRequest(method, "https://slack.com"+path, strings.NewReader(body))
@@ -100,7 +153,7 @@ func (b *Bot) apiCallJON(path string, method string, body string) (*apiResponse,
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+b.config.Token)
+	req.Header.Add("Authorization", "Bearer "+token)
 	req.Header.Add("Content-Type", "application/json; charset=utf-8")
 
 	response, err := client.Do(req)
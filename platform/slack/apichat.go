@@ -8,8 +8,12 @@ import (
 )
 
 type messagePost struct {
-	Channel string `json:"channel"`
-	Text    string `json:"text"`
+	Channel     string       `json:"channel"`
+	User        string       `json:"user,omitempty"` // User is the receiver of ephemeral messages
+	Text        string       `json:"text"`
+	Blocks      []block      `json:"blocks,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
+	ThreadTS    string       `json:"thread_ts,omitempty"`
 }
 
 type genericChatResponse struct {
@@ -18,11 +22,14 @@ type genericChatResponse struct {
 	TS      string `json:"ts"`
 }
 
-func (b *Bot) chatPostMessage(channel, content string) (genericChatResponse, error) {
+func (b *Bot) chatPostMessage(channel string, msg message) (genericChatResponse, error) {
 	body, err := json.Marshal(
 		messagePost{
-			Channel: channel,
-			Text:    content,
+			Channel:     channel,
+			Text:        msg.Text,
+			Blocks:      msg.Blocks,
+			Attachments: msg.Attachments,
+			ThreadTS:    msg.ThreadTS,
 		},
 	)
 	if err != nil {
@@ -46,6 +53,44 @@ func (b *Bot) chatPostMessage(channel, content string) (genericChatResponse, err
 	return response, nil
 }
 
+type ephemeralResponse struct {
+	Ok        bool   `json:"ok"`
+	MessageTS string `json:"message_ts"`
+}
+
+// chatPostEphemeral sends a message into the channel which only the given user can see.
+func (b *Bot) chatPostEphemeral(channel string, user string, msg message) (genericChatResponse, error) {
+	body, err := json.Marshal(
+		messagePost{
+			Channel:     channel,
+			User:        user,
+			Text:        msg.Text,
+			Blocks:      msg.Blocks,
+			Attachments: msg.Attachments,
+			ThreadTS:    msg.ThreadTS,
+		},
+	)
+	if err != nil {
+		return genericChatResponse{}, errors.Wrap(err, "json marshal failed")
+	}
+
+	rawResponse, err := b.apiCallJON("/api/chat.postEphemeral", "POST", string(body))
+	if err != nil {
+		return genericChatResponse{}, errors.Wrap(err, "apiCall failed")
+	}
+
+	response := ephemeralResponse{}
+	err = json.Unmarshal(rawResponse.body, &response)
+
+	if err == nil && !response.Ok {
+		return genericChatResponse{}, fmt.Errorf("Error in sending ephemeral message: Received not OK. Response was: %s", rawResponse.body)
+	} else if err != nil {
+		return genericChatResponse{}, err
+	}
+
+	return genericChatResponse{Ok: true, Channel: channel, TS: response.MessageTS}, nil
+}
+
 type deleteBody struct {
 	Channel string `json:"channel"`
 	TS      string `json:"ts"`
@@ -84,17 +129,21 @@ type updateBody struct {
 	TS      string `json:"ts"`
 	AsUser  bool   `json:"as_user"`
 
-	Text string `json:"text"`
+	Text        string       `json:"text"`
+	Blocks      []block      `json:"blocks,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
 }
 
-func (b *Bot) chatUpdate(channel, ts, content string) (genericChatResponse, error) {
+func (b *Bot) chatUpdate(channel, ts string, msg message) (genericChatResponse, error) {
 	body, err := json.Marshal(
 		updateBody{
 			Channel: channel,
 			TS:      ts,
 			AsUser:  true,
 
-			Text: content,
+			Text:        msg.Text,
+			Blocks:      msg.Blocks,
+			Attachments: msg.Attachments,
 		},
 	)
 	if err != nil {
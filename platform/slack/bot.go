@@ -18,6 +18,16 @@ import (
 	"github.com/torlenor/redseligg/utils"
 )
 
+// maxMessageLength is the length after which Slack recommends to split messages.
+const maxMessageLength = 4000
+
+// Transports to receive the events from Slack
+const (
+	transportRTM        = "rtm"
+	transportSocketMode = "socket"
+	transportEvents     = "events"
+)
+
 type webSocketClient interface {
 	Dial(wsURL string) error
 	Close() error
@@ -38,6 +48,11 @@ type Bot struct {
 	rtmURL string
 	ws     webSocketClient
 
+	socketMode *socketMode
+
+	// eventMutex serializes the handling of the events, which may be received concurrently via the Events API
+	eventMutex sync.Mutex
+
 	channels channelManager
 	users    userManager
 
@@ -66,6 +81,12 @@ func CreateSlackBot(cfg botconfig.SlackConfig, storage storage.Storage, commandD
 				platform.FeatureMessageUpdate:  true,
 				platform.FeatureMessageDelete:  true,
 				platform.FeatureReactionNotify: true,
+
+				platform.FeatureMessageFormatting: true,
+				platform.FeatureMessageEmbeds:     true,
+				platform.FeatureMessageReplies:    true,
+				platform.FeatureMessageThreads:    true,
+				platform.FeatureMessageButtons:    true,
 			},
 			Dispatcher: commandDispatcher,
 			Storage:    storage,
@@ -86,12 +107,33 @@ func CreateSlackBot(cfg botconfig.SlackConfig, storage storage.Storage, commandD
 		return nil, fmt.Errorf("No Slack token defined in config file")
 	}
 
-	rtmConnectResponse, err := b.RtmConnect()
-	if err != nil {
-		return nil, fmt.Errorf("Error connecting to Slack servers: %s", err)
+	switch b.config.Transport {
+	case "", transportRTM:
+		b.config.Transport = transportRTM
+
+		rtmConnectResponse, err := b.RtmConnect()
+		if err != nil {
+			return nil, fmt.Errorf("Error connecting to Slack servers: %s", err)
+		}
+
+		b.rtmURL = rtmConnectResponse.URL
+		b.Dispatcher.SetBotMentions("<@" + rtmConnectResponse.Self.ID + ">")
+	case transportSocketMode, transportEvents:
+		if b.config.Transport == transportSocketMode {
+			b.socketMode = &socketMode{bot: &b, ws: ws}
+		}
+
+		authTest, err := b.authTest()
+		if err != nil {
+			return nil, fmt.Errorf("Error connecting to Slack servers: %s", err)
+		}
+		b.Dispatcher.SetBotMentions("<@" + authTest.UserID + ">")
+	default:
+		return nil, fmt.Errorf("Unknown Slack transport %s", b.config.Transport)
 	}
 
-	b.rtmURL = rtmConnectResponse.URL
+	b.Dispatcher.SetPoster(&b)
+	b.Dispatcher.SetMaxMessageLength(maxMessageLength)
 
 	return &b, nil
 }
@@ -106,17 +148,37 @@ func (b *Bot) startPingWatchdog() {
 	b.watchdog.SetFailCallback(b.onFail).Start(10 * time.Second)
 }
 
+// dispatchEvent dispatches an event received via any transport to the event handlers.
+func (b *Bot) dispatchEvent(event interface{}, message []byte) {
+	b.eventMutex.Lock()
+	defer b.eventMutex.Unlock()
+
+	b.eventDispatcher(event, message)
+}
+
 // Start the Bot
 func (b *Bot) Start() {
-	b.log.Infof("SlackBot is STARTING (have %d plugin(s))", len(b.plugins))
-
-	err := b.ws.Dial(b.rtmURL)
-	if err != nil {
-		b.log.Errorln("Could not dial Slack RTM WebSocket, Slack Bot not operational:", err)
-		return
+	b.log.Infof("SlackBot is STARTING (have %d plugin(s), transport %s)", len(b.plugins), b.config.Transport)
+	b.StartEventQueues()
+
+	switch b.config.Transport {
+	case transportSocketMode:
+		if err := b.socketMode.start(); err != nil {
+			b.log.Errorln("Could not start Socket Mode, Slack Bot not operational:", err)
+			return
+		}
+	case transportEvents:
+		b.log.Infoln("Receiving events via the Events API at /bots/{botId}/events of the control API")
+		b.healthy = true
+	default:
+		err := b.ws.Dial(b.rtmURL)
+		if err != nil {
+			b.log.Errorln("Could not dial Slack RTM WebSocket, Slack Bot not operational:", err)
+			return
+		}
 	}
 
-	err = b.populateChannelList()
+	err := b.populateChannelList()
 	if err != nil {
 		b.log.Warnln("Populating Channel List failed, no Channel information will be available:", err)
 	}
@@ -126,18 +188,18 @@ func (b *Bot) Start() {
 		b.log.Warnln("Populating User List failed, no User information will be available:", err)
 	}
 
-	b.startPingWatchdog()
+	if b.config.Transport == transportRTM {
+		b.startPingWatchdog()
 
-	go func() {
-		b.wg.Add(1)
-		b.run()
-		defer b.wg.Done()
-	}()
-
-	for _, plugin := range b.plugins {
-		plugin.OnRun()
+		go func() {
+			b.wg.Add(1)
+			b.run()
+			defer b.wg.Done()
+		}()
 	}
 
+	b.RunPlugins(b.plugins)
+
 	b.log.Infoln("SlackBot is RUNNING")
 }
 
@@ -152,6 +214,8 @@ func (b *Bot) Run(ctx context.Context) error {
 
 	<-ctx.Done()
 
+	b.StopEventQueues()
+
 	for _, plugin := range b.plugins {
 		plugin.OnStop()
 	}
@@ -165,16 +229,23 @@ func (b *Bot) Run(ctx context.Context) error {
 func (b *Bot) Stop() {
 	b.log.Infoln("SlackBot is SHUTING DOWN")
 
-	b.stopPingWatchdog()
+	switch b.config.Transport {
+	case transportSocketMode:
+		b.socketMode.stop()
+	case transportEvents:
+		b.healthy = false
+	default:
+		b.stopPingWatchdog()
 
-	err := b.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-	if err != nil {
-		b.log.Warnln("Error when writing close message to ws:", err)
-	}
+		err := b.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		if err != nil {
+			b.log.Warnln("Error when writing close message to ws:", err)
+		}
 
-	b.wg.Wait()
+		b.wg.Wait()
 
-	b.ws.Close()
+		b.ws.Close()
+	}
 
 	b.log.Infoln("SlackBot is SHUT DOWN")
 }
@@ -196,6 +267,6 @@ func (b *Bot) GetInfo() platform.BotInfo {
 		BotID:    "",
 		Platform: "Slack",
 		Healthy:  true,
-		Plugins:  []platform.PluginInfo{},
+		Plugins:  b.PluginInfos(b.plugins),
 	}
 }
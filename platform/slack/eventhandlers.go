@@ -65,18 +65,13 @@ func (b *Bot) handleEventMessage(data []byte) {
 		if err != nil {
 			b.log.Warnf("Was not able to determine User from message. User ID %s, error: %s", message.User, err)
 		}
-		receiveMessage := model.Post{ServerID: message.Team, User: model.User{ID: message.User, Name: user.Name}, ChannelID: message.Channel, Content: cleanupMessage(message.Text)}
+		receiveMessage := model.Post{ServerID: message.Team, User: model.User{ID: message.User, Name: user.Name}, ChannelID: message.Channel, Content: cleanupMessage(message.Text), ThreadID: message.ThreadTs}
 		for _, plugin := range b.plugins {
-			plugin.OnPost(receiveMessage)
+			plugin := plugin
+			b.Enqueue(plugin, func() { plugin.OnPost(receiveMessage) })
 		}
 
-		if ok, text := b.Dispatcher.IsHelp(receiveMessage); ok {
-			postMessage := receiveMessage
-			postMessage.Content = text
-			b.CreatePost(postMessage)
-		} else {
-			b.Dispatcher.OnPost(receiveMessage)
-		}
+		b.Dispatcher.OnPost(receiveMessage)
 	} else {
 		b.log.Debugf("Received message::message_deleted event on Channel ID %s", message.Channel)
 	}
@@ -240,13 +235,15 @@ func (b *Bot) handleEventReactionAddedOrRemoved(data []byte) {
 		// example: {"type":"reaction_added","user":"UNL92ERS4","item":{"type":"message","channel":"G011C8YPGET","ts":"1586690851.001000"},"reaction":"wink","item_user":"UNL92ERS4","event_ts":"1586690859.001100","ts":"1586690859.001100"}
 		forPlugin.Type = "added"
 		for _, plugin := range b.plugins {
-			plugin.OnReactionAdded(forPlugin)
+			plugin := plugin
+			b.Enqueue(plugin, func() { plugin.OnReactionAdded(forPlugin) })
 		}
 	case "reaction_removed":
 		// example: {"type":"reaction_removed","user":"UNL92ERS4","item":{"type":"message","channel":"G011C8YPGET","ts":"1586690851.001000"},"reaction":"wink","item_user":"UNL92ERS4","event_ts":"1586691109.001200","ts":"1586691109.001200"}
 		forPlugin.Type = "removed"
 		for _, plugin := range b.plugins {
-			plugin.OnReactionRemoved(forPlugin)
+			plugin := plugin
+			b.Enqueue(plugin, func() { plugin.OnReactionRemoved(forPlugin) })
 		}
 	default:
 		b.log.Warnf("Received unknown Event Reaction of type %s on Channel ID %s", reaction.Type, reaction.Item.Channel)
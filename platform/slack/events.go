@@ -13,6 +13,7 @@ type eventMessage struct {
 	Channel              string `json:"channel"`
 	EventTs              string `json:"event_ts"`
 	Ts                   string `json:"ts"`
+	ThreadTs             string `json:"thread_ts"`
 }
 
 type eventUserTyping struct {
@@ -0,0 +1,138 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/torlenor/redseligg/utils"
+)
+
+// maxRequestAge is the maximum age of a request to the Events API, older requests may be replayed.
+const maxRequestAge = 5 * time.Minute
+
+// maxRequestSize is the maximum size of the body of a request to the Events API.
+const maxRequestSize = 1 << 20
+
+// eventCallback is the outer event of the Events API, the actual event is given in Event.
+type eventCallback struct {
+	Type      string          `json:"type"`
+	TeamID    string          `json:"team_id"`
+	EventID   string          `json:"event_id"`
+	Event     json.RawMessage `json:"event"`
+	Challenge string          `json:"challenge"` // Challenge is given for url_verification requests
+}
+
+// verifySignature checks the signature of a request of Slack, see https://api.slack.com/authentication/verifying-requests-from-slack
+func verifySignature(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid request timestamp")
+	}
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("Request timestamp too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(timestamp, 10) + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("Invalid request signature")
+	}
+	return nil
+}
+
+// HandleEventRequest handles a request of the Slack Events API, slash commands and interactions
+// are accepted at the same URL. Only requests signed with the signing secret are accepted.
+func (b *Bot) HandleEventRequest(w http.ResponseWriter, r *http.Request) {
+	if b.config.Transport != transportEvents {
+		http.Error(w, utils.GenerateErrorResponse("Events API not enabled for this bot"), http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, utils.GenerateErrorResponse(err.Error()), http.StatusInternalServerError)
+		return
+	}
+	r.Body.Close()
+
+	if err := verifySignature(b.config.SigningSecret, r.Header, body, time.Now()); err != nil {
+		b.log.Warnf("Rejected Events API request: %s", err)
+		http.Error(w, utils.GenerateErrorResponse(err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		b.handleFormRequest(w, body)
+		return
+	}
+
+	var callback eventCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		http.Error(w, utils.GenerateErrorResponse("Invalid body received: JSON invalid"), http.StatusBadRequest)
+		return
+	}
+
+	switch callback.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, callback.Challenge)
+	case "event_callback":
+		// Slack expects an answer within 3 seconds, the event is handled afterwards
+		w.WriteHeader(http.StatusOK)
+		go b.handleEventCallback(callback)
+	default:
+		b.log.Warnf("Received unhandled Events API request of type %s", callback.Type)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleFormRequest handles the slash commands and interactions, which Slack sends form encoded.
+func (b *Bot) handleFormRequest(w http.ResponseWriter, body []byte) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, utils.GenerateErrorResponse("Invalid body received: Form invalid"), http.StatusBadRequest)
+		return
+	}
+
+	if payload := values.Get("payload"); len(payload) > 0 {
+		w.WriteHeader(http.StatusOK)
+		go b.handleInteraction([]byte(payload))
+	} else if len(values.Get("command")) > 0 {
+		w.WriteHeader(http.StatusOK)
+		go b.handleSlashCommand(slashCommandFromForm(values))
+	} else {
+		http.Error(w, utils.GenerateErrorResponse("Invalid body received: Neither slash command nor interaction"), http.StatusBadRequest)
+	}
+}
+
+// handleEventCallback dispatches the event of an Events API callback to the event handlers.
+func (b *Bot) handleEventCallback(callback eventCallback) {
+	if callback.Type != "event_callback" {
+		b.log.Warnf("Received unhandled Events API payload of type %s", callback.Type)
+		return
+	}
+
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(callback.Event, &event); err != nil {
+		b.log.Errorln("Unable to handle Events API event, error unmarshalling JSON:", err)
+		return
+	}
+
+	b.dispatchEvent(event.Type, callback.Event)
+}
@@ -0,0 +1,155 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/logging"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/platform"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signedRequest(body string, timestamp time.Time, secret string) *http.Request {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	r := httptest.NewRequest("POST", "/bots/slack/events", strings.NewReader(body))
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+type mockCommandReceiver struct {
+	posts chan model.Post
+}
+
+func (m *mockCommandReceiver) OnCommand(cmd string, content string, post model.Post) {
+	m.posts <- post
+}
+
+func createEventsAPITestBot() *Bot {
+	return &Bot{
+		BotImpl: platform.BotImpl{Dispatcher: commanddispatcher.New("!")},
+		config:  botconfig.SlackConfig{Transport: transportEvents, SigningSecret: testSigningSecret},
+		log:     logging.Get("SlackBot"),
+		users:   newUserManager(),
+	}
+}
+
+func Test_verifySignature(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	body := `{"type":"event_callback"}`
+
+	r := signedRequest(body, now, testSigningSecret)
+	assert.NoError(verifySignature(testSigningSecret, r.Header, []byte(body), now))
+
+	assert.Error(verifySignature(testSigningSecret, r.Header, []byte(body+" "), now))
+	assert.Error(verifySignature("other secret", r.Header, []byte(body), now))
+	assert.Error(verifySignature(testSigningSecret, r.Header, []byte(body), now.Add(10*time.Minute)))
+
+	r.Header.Del("X-Slack-Request-Timestamp")
+	assert.Error(verifySignature(testSigningSecret, r.Header, []byte(body), now))
+}
+
+func TestBot_HandleEventRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	bot := createEventsAPITestBot()
+	receiver := &mockCommandReceiver{posts: make(chan model.Post, 1)}
+	bot.Dispatcher.Register("ping", receiver, commanddispatcher.CommandHelp{})
+
+	w := httptest.NewRecorder()
+	bot.HandleEventRequest(w, signedRequest(`{"type":"url_verification","challenge":"CHALLENGE"}`, time.Now(), testSigningSecret))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("CHALLENGE", w.Body.String())
+
+	w = httptest.NewRecorder()
+	bot.HandleEventRequest(w, signedRequest(`{"type":"url_verification","challenge":"CHALLENGE"}`, time.Now(), "wrong"))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	bot.HandleEventRequest(w, signedRequest(`{"type":"event_callback","team_id":"T1","event":{"type":"message","channel":"C1","user":"U1","text":"!ping","ts":"1.2","thread_ts":"1.1"}}`, time.Now(), testSigningSecret))
+	assert.Equal(http.StatusOK, w.Code)
+
+	select {
+	case post := <-receiver.posts:
+		assert.Equal("C1", post.ChannelID)
+		assert.Equal("U1", post.User.ID)
+		assert.Equal("1.1", post.ThreadID)
+	case <-time.After(time.Second):
+		t.Fatalf("Event was not dispatched")
+	}
+
+	bot.config.Transport = transportRTM
+	w = httptest.NewRecorder()
+	bot.HandleEventRequest(w, signedRequest(`{"type":"url_verification","challenge":"CHALLENGE"}`, time.Now(), testSigningSecret))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
+
+func TestBot_HandleEventRequest_FormEncoded(t *testing.T) {
+	assert := assert.New(t)
+
+	bot := createEventsAPITestBot()
+	receiver := &mockCommandReceiver{posts: make(chan model.Post, 1)}
+	bot.Dispatcher.Register("roll", receiver, commanddispatcher.CommandHelp{})
+
+	receivedPost := func() model.Post {
+		select {
+		case post := <-receiver.posts:
+			return post
+		case <-time.After(time.Second):
+			t.Fatalf("Command was not dispatched")
+		}
+		return model.Post{}
+	}
+
+	form := url.Values{}
+	form.Set("command", "/roll")
+	form.Set("text", "20 ")
+	form.Set("team_id", "T1")
+	form.Set("channel_id", "C1")
+	form.Set("user_id", "U1")
+	form.Set("user_name", "someone")
+	r := signedRequest(form.Encode(), time.Now(), testSigningSecret)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	bot.HandleEventRequest(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+
+	post := receivedPost()
+	assert.Equal(model.Post{ServerID: "T1", ChannelID: "C1", User: model.User{ID: "U1", Name: "someone"}, Content: "!roll 20"}, post)
+
+	form = url.Values{}
+	form.Set("payload", `{"type":"block_actions","team":{"id":"T1"},"user":{"id":"U2","username":"other"},"channel":{"id":"D1"},"container":{"message_ts":"1.2","thread_ts":"1.1"},"actions":[{"type":"button","action_id":"url-0"},{"type":"button","action_id":"command-1","value":"roll 6"}]}`)
+	r = signedRequest(form.Encode(), time.Now(), testSigningSecret)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	bot.HandleEventRequest(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+
+	post = receivedPost()
+	assert.Equal(model.Post{ServerID: "T1", ChannelID: "D1", User: model.User{ID: "U2", Name: "other"}, Content: "!roll 6", ThreadID: "1.1", IsPrivate: true}, post)
+
+	r = signedRequest("something=else", time.Now(), testSigningSecret)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	bot.HandleEventRequest(w, r)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,120 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// slashCommand is sent by Slack when a user calls a slash command of the app, e.g., /roll 20.
+type slashCommand struct {
+	Command     string `json:"command"`
+	Text        string `json:"text"`
+	TeamID      string `json:"team_id"`
+	ChannelID   string `json:"channel_id"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	ResponseURL string `json:"response_url"`
+}
+
+// slashCommandFromForm returns the slash command of a form encoded request of the Events API.
+func slashCommandFromForm(values url.Values) slashCommand {
+	return slashCommand{
+		Command:     values.Get("command"),
+		Text:        values.Get("text"),
+		TeamID:      values.Get("team_id"),
+		ChannelID:   values.Get("channel_id"),
+		UserID:      values.Get("user_id"),
+		UserName:    values.Get("user_name"),
+		ResponseURL: values.Get("response_url"),
+	}
+}
+
+type blockAction struct {
+	Type     string `json:"type"`
+	ActionID string `json:"action_id"`
+	BlockID  string `json:"block_id"`
+	Value    string `json:"value"`
+}
+
+// interactionPayload is sent by Slack when a user interacts with a message, e.g., clicks a button.
+type interactionPayload struct {
+	Type string `json:"type"`
+	Team struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Container struct {
+		MessageTS string `json:"message_ts"`
+		ThreadTS  string `json:"thread_ts"`
+	} `json:"container"`
+	Actions []blockAction `json:"actions"`
+}
+
+// isDirectMessageChannel returns true for direct message channels and for user IDs, which are
+// used as channel to send direct messages.
+func isDirectMessageChannel(id string) bool {
+	return strings.HasPrefix(id, "D") || strings.HasPrefix(id, "U") || strings.HasPrefix(id, "W")
+}
+
+// handleSlashCommand dispatches the slash command like a typed command, i.e., /roll 20 is handled as !roll 20.
+func (b *Bot) handleSlashCommand(cmd slashCommand) {
+	content := b.Dispatcher.GetCallPrefix() + strings.TrimPrefix(cmd.Command, "/")
+	if text := strings.TrimSpace(cmd.Text); len(text) > 0 {
+		content += " " + text
+	}
+
+	post := model.Post{
+		ServerID:  cmd.TeamID,
+		ChannelID: cmd.ChannelID,
+		User:      model.User{ID: cmd.UserID, Name: cmd.UserName},
+		Content:   content,
+		IsPrivate: isDirectMessageChannel(cmd.ChannelID),
+	}
+
+	b.log.Tracef("Received slash command from User = %s, Command = %s, ChannelID = %s", post.User.Name, content, post.ChannelID)
+
+	b.Dispatcher.OnPost(post)
+}
+
+// handleInteraction dispatches the commands of the buttons clicked by a user. Replies are sent
+// into the thread of the message containing the buttons.
+func (b *Bot) handleInteraction(data []byte) {
+	var payload interactionPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		b.log.Errorln("Unable to handle interaction payload, error unmarshalling JSON:", err)
+		return
+	}
+
+	if payload.Type != "block_actions" {
+		b.log.Debugf("Ignoring interaction of type %s", payload.Type)
+		return
+	}
+
+	for _, action := range payload.Actions {
+		if !strings.HasPrefix(action.ActionID, commandActionPrefix) || len(action.Value) == 0 {
+			continue
+		}
+
+		post := model.Post{
+			ServerID:  payload.Team.ID,
+			ChannelID: payload.Channel.ID,
+			User:      model.User{ID: payload.User.ID, Name: payload.User.Username},
+			Content:   b.Dispatcher.GetCallPrefix() + action.Value,
+			ThreadID:  payload.Container.ThreadTS,
+			IsPrivate: isDirectMessageChannel(payload.Channel.ID),
+		}
+
+		b.log.Tracef("Received block action from User = %s, Command = %s, ChannelID = %s", post.User.Name, action.Value, post.ChannelID)
+
+		b.Dispatcher.OnPost(post)
+	}
+}
@@ -0,0 +1,207 @@
+package slack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// mrkdwn renders rich content in the Slack mrkdwn format.
+var mrkdwn = model.Markup{
+	Escape:    mrkdwnEscaper.Replace,
+	Verbatim:  func(text string) string { return text },
+	Bold:      [2]string{"*", "*"},
+	Italic:    [2]string{"_", "_"},
+	Code:      func(code string) string { return "`" + mrkdwnEscaper.Replace(code) + "`" },
+	CodeBlock: func(code string) string { return "```" + mrkdwnEscaper.Replace(code) + "```" },
+	Link: func(url string, text string) string {
+		if len(text) == 0 {
+			return "<" + url + ">"
+		}
+		return "<" + url + "|" + text + ">"
+	},
+	ListItem: func(index int, ordered bool, item string) string {
+		if ordered {
+			return strconv.Itoa(index) + ". " + item
+		}
+		return "• " + item
+	},
+	ItemSeparator:  "\n",
+	LineBreak:      "\n",
+	BlockSeparator: "\n",
+}
+
+type attachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+// attachment is a Slack message attachment which is used to show embeds.
+type attachment struct {
+	Fallback  string            `json:"fallback,omitempty"`
+	Color     string            `json:"color,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Fields    []attachmentField `json:"fields,omitempty"`
+	ImageURL  string            `json:"image_url,omitempty"`
+	ThumbURL  string            `json:"thumb_url,omitempty"`
+	Footer    string            `json:"footer,omitempty"`
+}
+
+// Limits of Block Kit messages
+const (
+	maxBlocks            = 50
+	maxSectionTextLength = 3000
+	maxActionElements    = 25
+	maxButtonTextLength  = 75
+	maxButtonValueLength = 2000
+)
+
+// commandActionPrefix is the prefix of the action IDs of buttons which execute a command.
+const commandActionPrefix = "command-"
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type blockElement struct {
+	Type     string     `json:"type"`
+	Text     textObject `json:"text"`
+	ActionID string     `json:"action_id"`
+	Value    string     `json:"value,omitempty"`
+	URL      string     `json:"url,omitempty"`
+}
+
+// block is a Block Kit layout block, only sections and actions are used.
+type block struct {
+	Type     string         `json:"type"`
+	Text     *textObject    `json:"text,omitempty"`
+	Elements []blockElement `json:"elements,omitempty"`
+}
+
+type message struct {
+	Text        string
+	Blocks      []block
+	Attachments []attachment
+	ThreadTS    string
+}
+
+func truncate(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength])
+}
+
+// splitText splits the text into parts of at most maxLength runes, preferably at line breaks.
+func splitText(text string, maxLength int) []string {
+	var parts []string
+	var current []rune
+	for _, line := range strings.SplitAfter(text, "\n") {
+		runes := []rune(line)
+		if len(current)+len(runes) > maxLength && len(current) > 0 {
+			parts = append(parts, string(current))
+			current = nil
+		}
+		for len(runes) > maxLength {
+			parts = append(parts, string(runes[:maxLength]))
+			runes = runes[maxLength:]
+		}
+		current = append(current, runes...)
+	}
+	if len(current) > 0 {
+		parts = append(parts, string(current))
+	}
+	return parts
+}
+
+// convertButtonsFromRedseligg converts the buttons into action blocks. Buttons with a command
+// carry the command in their value, the others open their URL.
+func convertButtonsFromRedseligg(buttons []model.Button) []block {
+	var blocks []block
+	for i, b := range buttons {
+		if i%maxActionElements == 0 {
+			blocks = append(blocks, block{Type: "actions"})
+		}
+		element := blockElement{
+			Type:     "button",
+			Text:     textObject{Type: "plain_text", Text: truncate(b.Label, maxButtonTextLength)},
+			ActionID: commandActionPrefix + strconv.Itoa(i),
+			Value:    truncate(b.Command, maxButtonValueLength),
+		}
+		if len(b.URL) > 0 {
+			element.ActionID = "url-" + strconv.Itoa(i)
+			element.Value = ""
+			element.URL = b.URL
+		}
+		blocks[len(blocks)-1].Elements = append(blocks[len(blocks)-1].Elements, element)
+	}
+	return blocks
+}
+
+// convertBlocksFromRedseligg renders rich posts and posts with buttons as Block Kit blocks.
+// Plain text posts do not need blocks.
+func convertBlocksFromRedseligg(post model.Post, text string) []block {
+	if !post.IsRich() && len(post.Buttons) == 0 {
+		return nil
+	}
+
+	var blocks []block
+	for _, section := range splitText(text, maxSectionTextLength) {
+		if len(strings.TrimSpace(section)) == 0 {
+			continue
+		}
+		blocks = append(blocks, block{Type: "section", Text: &textObject{Type: "mrkdwn", Text: section}})
+	}
+	blocks = append(blocks, convertButtonsFromRedseligg(post.Buttons)...)
+
+	if len(blocks) > maxBlocks {
+		blocks = blocks[:maxBlocks]
+	}
+	return blocks
+}
+
+func convertEmbedFromRedseligg(e model.Embed) attachment {
+	converted := attachment{
+		Fallback:  model.PlainText.Render(e.Nodes()),
+		Title:     e.Title,
+		TitleLink: e.URL,
+		Text:      e.Description,
+		ImageURL:  e.ImageURL,
+		ThumbURL:  e.ThumbnailURL,
+		Footer:    e.Footer,
+	}
+	if e.Color != 0 {
+		converted.Color = fmt.Sprintf("#%06x", e.Color)
+	}
+	for _, f := range e.Fields {
+		converted.Fields = append(converted.Fields, attachmentField{Title: f.Name, Value: f.Value, Short: f.Inline})
+	}
+	return converted
+}
+
+// convertMessageFromRedseligg converts a post into a Slack message. Rich content and buttons
+// are sent as Block Kit blocks with the text as fallback, embeds are sent as message attachments
+// and replies are sent into the thread of the message.
+func convertMessageFromRedseligg(post model.Post) message {
+	msg := message{
+		Text:     post.Render(mrkdwn, false, true),
+		ThreadTS: post.ThreadID,
+	}
+	msg.Blocks = convertBlocksFromRedseligg(post, msg.Text)
+	if len(msg.ThreadTS) == 0 {
+		msg.ThreadTS = post.ReplyTo
+	}
+	for _, e := range post.Embeds {
+		msg.Attachments = append(msg.Attachments, convertEmbedFromRedseligg(e))
+	}
+	return msg
+}
@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+func Test_splitText(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"short"}, splitText("short", 10))
+	assert.Equal([]string{"line 1\n", "line 2"}, splitText("line 1\nline 2", 10))
+	assert.Equal([]string{"0123456789", "0123\nab"}, splitText("01234567890123\nab", 10))
+	assert.Equal(0, len(splitText("", 10)))
+}
+
+func Test_convertMessageFromRedseligg(t *testing.T) {
+	assert := assert.New(t)
+
+	plain := convertMessageFromRedseligg(model.Post{Content: "some text", ThreadID: "1234.5678"})
+	assert.Equal(message{Text: "some text", ThreadTS: "1234.5678"}, plain)
+
+	rich := convertMessageFromRedseligg(model.Post{
+		Rich: []model.Node{model.Paragraph(model.Bold(model.Text("bold")), model.Text(" text"))},
+		Buttons: []model.Button{
+			{Label: "Roll", Command: "roll 6"},
+			{Label: "Docs", URL: "https://example.com"},
+		},
+		ReplyTo: "1111.2222",
+	})
+	assert.Equal("*bold* text", rich.Text)
+	assert.Equal("1111.2222", rich.ThreadTS)
+	assert.Equal([]block{
+		{Type: "section", Text: &textObject{Type: "mrkdwn", Text: "*bold* text"}},
+		{Type: "actions", Elements: []blockElement{
+			{Type: "button", Text: textObject{Type: "plain_text", Text: "Roll"}, ActionID: "command-0", Value: "roll 6"},
+			{Type: "button", Text: textObject{Type: "plain_text", Text: "Docs"}, ActionID: "url-1", URL: "https://example.com"},
+		}},
+	}, rich.Blocks)
+
+	var buttons []model.Button
+	for i := 0; i < maxActionElements+1; i++ {
+		buttons = append(buttons, model.Button{Label: "b", Command: "cmd"})
+	}
+	long := convertMessageFromRedseligg(model.Post{Content: strings.Repeat("a", maxSectionTextLength+1), Buttons: buttons})
+	assert.Equal(4, len(long.Blocks))
+	assert.Equal(maxActionElements, len(long.Blocks[2].Elements))
+	assert.Equal(1, len(long.Blocks[3].Elements))
+}
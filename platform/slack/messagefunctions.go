@@ -9,13 +9,17 @@ type sendMessage struct {
 	Text    string `json:"text"`
 }
 
-func (b *Bot) sendMessage(channelID string, content string) (genericChatResponse, error) {
-	return b.chatPostMessage(channelID, content)
+func (b *Bot) sendMessage(channelID string, msg message) (genericChatResponse, error) {
+	return b.chatPostMessage(channelID, msg)
 	// return messagePostResponse{}, b.sendMessageViaRTM(channelID, content)
 }
 
-func (b *Bot) sendWhisper(userID string, content string) (genericChatResponse, error) {
-	return b.sendMessage(userID, content)
+func (b *Bot) sendWhisper(userID string, msg message) (genericChatResponse, error) {
+	return b.sendMessage(userID, msg)
+}
+
+func (b *Bot) sendEphemeral(channelID string, userID string, msg message) (genericChatResponse, error) {
+	return b.chatPostEphemeral(channelID, userID, msg)
 }
 
 func (b *Bot) sendMessageViaRTM(channelID string, content string) error {
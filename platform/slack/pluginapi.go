@@ -2,6 +2,7 @@ package slack
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/utils"
@@ -42,13 +43,18 @@ func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
 			return model.PostResponse{}, fmt.Errorf("Plugin did not provide User or UserID, not sending Whisper")
 		}
 		var err error
-		response, err = b.sendWhisper(userID, post.Content)
+		if len(post.ChannelID) > 0 && !isDirectMessageChannel(post.ChannelID) {
+			// Private feedback in a channel is shown only to the user
+			response, err = b.sendEphemeral(post.ChannelID, userID, convertMessageFromRedseligg(post))
+		} else {
+			response, err = b.sendWhisper(userID, convertMessageFromRedseligg(post))
+		}
 		if err != nil {
 			return model.PostResponse{}, fmt.Errorf("Error sending whisper: %s", err)
 		}
 	} else {
 		var err error
-		response, err = b.sendMessage(post.ChannelID, post.Content)
+		response, err = b.sendMessage(post.ChannelID, convertMessageFromRedseligg(post))
 		if err != nil {
 			return model.PostResponse{}, fmt.Errorf("Error sending message: %s", err)
 		}
@@ -61,7 +67,7 @@ func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
 
 // UpdatePost updates a post.
 func (b *Bot) UpdatePost(messageID model.MessageIdentifier, newPost model.Post) (model.PostResponse, error) {
-	response, err := b.chatUpdate(messageID.Channel, messageID.ID, newPost.Content)
+	response, err := b.chatUpdate(messageID.Channel, messageID.ID, convertMessageFromRedseligg(newPost))
 	if err != nil {
 		return model.PostResponse{}, fmt.Errorf("Error updating post: %s", err)
 	}
@@ -79,6 +85,26 @@ func (b *Bot) DeletePost(messageID model.MessageIdentifier) (model.PostResponse,
 	return model.PostResponse{PostedMessageIdent: model.MessageIdentifier{ID: response.TS, Channel: response.Channel}}, nil
 }
 
+// TimeoutUser is not supported on Slack.
+func (b *Bot) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// BanUser is not supported on Slack.
+func (b *Bot) BanUser(channelID string, userID string, reason string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// ClearChat is not supported on Slack.
+func (b *Bot) ClearChat(channelID string) error {
+	return fmt.Errorf("Not supported")
+}
+
+// SetSlowMode is not supported on Slack.
+func (b *Bot) SetSlowMode(channelID string, interval time.Duration) error {
+	return fmt.Errorf("Not supported")
+}
+
 // GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
 func (b *Bot) GetReaction(reactionName string) (string, error) {
 	return "", fmt.Errorf("Not implemented")
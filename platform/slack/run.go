@@ -33,7 +33,7 @@ func (b *Bot) run() {
 		}
 
 		if event, ok := data["type"]; ok { // Dispatch to event handlers
-			b.eventDispatcher(event, message)
+			b.dispatchEvent(event, message)
 		} else if _, ok := data["ok"]; ok {
 			ackMessage := eventAck{}
 			if err := json.Unmarshal(message, &ackMessage); err != nil {
@@ -0,0 +1,172 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// The time to wait before reconnecting after a failed connection doubles with every failure.
+	minReconnectWait = 1 * time.Second
+	maxReconnectWait = 2 * time.Minute
+)
+
+// errDisconnectRequested is returned when Slack asks for a new connection, e.g., before it restarts the server.
+var errDisconnectRequested = errors.New("Disconnect requested by Slack")
+
+// socketModeEnvelope wraps all messages received via Socket Mode.
+type socketModeEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Reason     string          `json:"reason"` // Reason is given for disconnect messages
+}
+
+// socketModeAck acknowledges an envelope, otherwise Slack sends it again.
+type socketModeAck struct {
+	EnvelopeID string `json:"envelope_id"`
+}
+
+// socketMode receives the events via a WebSocket connection opened with the app-level token.
+// Lost connections are reopened.
+type socketMode struct {
+	bot *Bot
+	ws  webSocketClient
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (s *socketMode) start() error {
+	s.done = make(chan struct{})
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return nil
+}
+
+func (s *socketMode) stop() {
+	close(s.done)
+
+	if err := s.ws.SendMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+		s.bot.log.Debugln("Error when writing close message to ws:", err)
+	}
+	s.ws.Close()
+
+	s.wg.Wait()
+}
+
+func (s *socketMode) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *socketMode) connect() error {
+	url, err := s.bot.openSocketModeConnection()
+	if err != nil {
+		return fmt.Errorf("Could not open Socket Mode connection: %s", err)
+	}
+	if err := s.ws.Dial(url); err != nil {
+		return fmt.Errorf("Could not dial Socket Mode WebSocket: %s", err)
+	}
+	return nil
+}
+
+// run handles the received messages and reconnects until Socket Mode is stopped.
+func (s *socketMode) run() {
+	defer s.wg.Done()
+
+	s.bot.healthy = true
+	defer func() { s.bot.healthy = false }()
+
+	wait := minReconnectWait
+	for {
+		err := s.handleMessages()
+		s.ws.Close()
+		if s.stopped() {
+			return
+		}
+
+		if err == errDisconnectRequested {
+			s.bot.log.Debugln("Reconnecting Socket Mode:", err)
+		} else {
+			s.bot.log.Warnf("Socket Mode connection lost: %s, reconnecting in %s", err, wait)
+			select {
+			case <-time.After(wait):
+			case <-s.done:
+				return
+			}
+			wait *= 2
+			if wait > maxReconnectWait {
+				wait = maxReconnectWait
+			}
+		}
+
+		if err := s.connect(); err != nil {
+			s.bot.log.Errorln(err)
+			continue
+		}
+		wait = minReconnectWait
+	}
+}
+
+// handleMessages acknowledges and dispatches the received envelopes until the connection ends.
+func (s *socketMode) handleMessages() error {
+	for {
+		_, message, err := s.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var envelope socketModeEnvelope
+		if err := json.Unmarshal(message, &envelope); err != nil {
+			s.bot.log.Errorf("Error unmarshalling received message from Socket Mode: %s, message was: %s", err, message)
+			continue
+		}
+
+		if len(envelope.EnvelopeID) > 0 {
+			if err := s.ws.SendJSONMessage(socketModeAck{EnvelopeID: envelope.EnvelopeID}); err != nil {
+				s.bot.log.Warnf("Could not acknowledge envelope %s: %s", envelope.EnvelopeID, err)
+			}
+		}
+
+		switch envelope.Type {
+		case "hello":
+			s.bot.log.Debugln("Socket Mode connection established")
+		case "disconnect":
+			s.bot.log.Debugf("Received disconnect with reason %s", envelope.Reason)
+			return errDisconnectRequested
+		case "events_api":
+			var callback eventCallback
+			if err := json.Unmarshal(envelope.Payload, &callback); err != nil {
+				s.bot.log.Errorln("Unable to handle Events API payload, error unmarshalling JSON:", err)
+				continue
+			}
+			s.bot.handleEventCallback(callback)
+		case "slash_commands":
+			var cmd slashCommand
+			if err := json.Unmarshal(envelope.Payload, &cmd); err != nil {
+				s.bot.log.Errorln("Unable to handle slash command payload, error unmarshalling JSON:", err)
+				continue
+			}
+			s.bot.handleSlashCommand(cmd)
+		case "interactive":
+			s.bot.handleInteraction(envelope.Payload)
+		default:
+			s.bot.log.Warnf("Received unhandled Socket Mode message %s: %s", envelope.Type, message)
+		}
+	}
+}
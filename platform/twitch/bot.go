@@ -2,8 +2,10 @@ package twitch
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/irc.v3"
 
@@ -22,6 +24,22 @@ var (
 	log = logging.Get("TwitchBot")
 )
 
+// maxMessageLength is the maximum length of a Twitch chat message.
+const maxMessageLength = 500
+
+var (
+	// The time to wait before reconnecting after a lost connection doubles with every failure.
+	minReconnectWait = 1 * time.Second
+	maxReconnectWait = 2 * time.Minute
+
+	// Twitch allows to join 20 channels per 10 seconds.
+	joinBatchSize     = 20
+	joinBatchInterval = 10 * time.Second
+)
+
+// errReconnectRequested is returned when Twitch asks for a new connection, e.g., before it restarts the server.
+var errReconnectRequested = errors.New("Reconnect requested by Twitch")
+
 type webSocketClient interface {
 	Dial(wsURL string) error
 	Close() error
@@ -40,9 +58,19 @@ type Bot struct {
 
 	cfg botconfig.TwitchConfig
 
-	ws webSocketClient
+	ws        webSocketClient
+	sendQueue *sendQueue
+
+	helixMutex   sync.Mutex // helixMutex guards the Twitch API authentication and user IDs
+	helixAuth    *helixAuth
+	helixUserIDs map[string]string // [login]
+
+	done chan struct{}
+	wg   sync.WaitGroup
 
-	wg sync.WaitGroup
+	directory *platform.Directory
+
+	channelStates map[string]model.ChannelState // [ChannelID], only accessed from the message loop
 }
 
 // CreateTwitchBot creates a new instance of a TwitchBot
@@ -52,7 +80,13 @@ func CreateTwitchBot(cfg botconfig.TwitchConfig, storage storage.Storage, comman
 	b := Bot{
 		BotImpl: platform.BotImpl{
 			ProvidedFeatures: map[string]bool{
-				platform.FeatureMessagePost: true,
+				platform.FeatureMessagePost:   true,
+				platform.FeatureMessageDelete: true,
+
+				platform.FeatureModerationTimeout:   true,
+				platform.FeatureModerationBan:       true,
+				platform.FeatureModerationClearChat: true,
+				platform.FeatureModerationSlowMode:  true,
 			},
 			Dispatcher: commandDispatcher,
 			Storage:    storage,
@@ -61,8 +95,25 @@ func CreateTwitchBot(cfg botconfig.TwitchConfig, storage storage.Storage, comman
 		cfg: cfg,
 
 		ws: ws,
+
+		directory: platform.NewDirectory(),
+
+		channelStates: make(map[string]model.ChannelState),
+		helixUserIDs:  make(map[string]string),
 	}
 
+	for _, channel := range cfg.Channels {
+		b.directory.AddChannel(model.Channel{ID: "#" + channel, Name: channel})
+	}
+
+	b.sendQueue = newSendQueue(func(data []byte) error { return b.ws.SendMessage(websocket.TextMessage, data) })
+	// The bot is the broadcaster of its own channel, which is also used for whispers
+	b.sendQueue.setModerator(cfg.Username, true)
+
+	b.Dispatcher.SetPoster(&b)
+	b.Dispatcher.SetMaxMessageLength(maxMessageLength)
+	b.Dispatcher.SetBotMentions("@" + cfg.Username)
+
 	return &b, nil
 }
 
@@ -77,43 +128,78 @@ func (b *Bot) openWebSocketConnection() error {
 	b.ws.SendMessage(websocket.TextMessage, []byte("CAP REQ :twitch.tv/tags"))
 	b.ws.SendMessage(websocket.TextMessage, []byte("PASS "+b.cfg.Token))
 	b.ws.SendMessage(websocket.TextMessage, []byte("NICK #"+b.cfg.Username))
-	for _, channel := range b.cfg.Channels {
+	b.ws.SendMessage(websocket.TextMessage, []byte("USER #"+b.cfg.Username))
+	for i, channel := range b.cfg.Channels {
+		if i > 0 && i%joinBatchSize == 0 {
+			select {
+			case <-time.After(joinBatchInterval):
+			case <-b.done:
+				return nil
+			}
+		}
 		log.Infof("Joining channel " + channel)
 		b.ws.SendMessage(websocket.TextMessage, []byte("JOIN #"+channel))
 	}
-	b.ws.SendMessage(websocket.TextMessage, []byte("USER #"+b.cfg.Username))
 
 	return nil
 }
 
-func (b *Bot) messageLoop() {
-	var e error
-	defer func() {
-		if e != nil {
-			log.Error(e)
-			go b.onFail()
+func (b *Bot) stopped() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// run handles the received messages and reconnects until the bot is stopped. The time to wait
+// before reconnecting is reset when Twitch welcomes the bot.
+func (b *Bot) run() {
+	defer b.wg.Done()
+
+	wait := minReconnectWait
+	for {
+		err := b.messageLoop(func() { wait = minReconnectWait })
+		if b.stopped() {
+			return
+		}
+		b.ws.Close()
+
+		if err == errReconnectRequested {
+			log.Infof("Reconnecting to Twitch Chat: %s", err)
+		} else {
+			log.Warnf("Connection to Twitch Chat lost: %s, reconnecting in %s", err, wait)
+			select {
+			case <-time.After(wait):
+			case <-b.done:
+				return
+			}
+			wait *= 2
+			if wait > maxReconnectWait {
+				wait = maxReconnectWait
+			}
+		}
+
+		if err := b.openWebSocketConnection(); err != nil {
+			log.Errorln("Could not reconnect to Twitch Chat WebSocket:", err)
 		}
-	}()
+	}
+}
 
+// messageLoop handles the received messages until the connection is lost or the bot is stopped.
+func (b *Bot) messageLoop(onWelcome func()) error {
 	for {
+		if b.stopped() {
+			return nil
+		}
+
 		_, message, err := b.ws.ReadMessage()
 		if err != nil {
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				log.Debugln("Connection to Twitch Chat closed normally: ", err)
-				break
-			} else if websocket.IsCloseError(err, websocket.CloseGoingAway) {
-				log.Infof("Received GoingAway from Twitch Chat, attempting a reconnect")
-				b.ws.Close()
-				err := b.openWebSocketConnection()
-				if err != nil {
-					e = fmt.Errorf("Could not reconnect to Twitch Chat WebSocket: %s", err.Error())
-					break
-				}
-				continue
-			} else {
-				e = fmt.Errorf("Unhandled error in Twitch Chat communication logic: %s", err)
-				break
+			if websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				return errReconnectRequested
 			}
+			return err
 		}
 
 		ircMessage, err := irc.ParseMessage(string(message))
@@ -128,73 +214,121 @@ func (b *Bot) messageLoop() {
 			b.ws.SendMessage(websocket.TextMessage, []byte(ircMessage.String()))
 		case "PRIVMSG":
 			if len(ircMessage.Params) > 1 {
+				user := convertUser(ircMessage)
+				b.addUser(user)
 				post := model.Post{
 					ChannelID: ircMessage.Params[0],
-					User:      model.User{Name: ircMessage.User, ID: ircMessage.User},
+					User:      user,
 					Content:   ircMessage.Params[1],
 				}
 				for _, plugin := range b.plugins {
-					plugin.OnPost(post)
+					plugin := plugin
+					b.Enqueue(plugin, func() { plugin.OnPost(post) })
 				}
+				b.handleCheer(ircMessage, post)
 
-				if ok, text := b.Dispatcher.IsHelp(post); ok {
-					postMessage := post
-					postMessage.Content = text
-					b.CreatePost(postMessage)
-				} else {
-					b.Dispatcher.OnPost(post)
-				}
+				b.Dispatcher.OnPost(post)
 			} else {
 				log.Warnf("Params not long enough")
 			}
 		case "USERSTATE":
-			// Not needed
-		case "JOIN":
-			// Viewer joins the channel
-		case "PART":
-			// View leaves the channel
+			b.handleUserState(ircMessage)
+		case "RECONNECT":
+			return errReconnectRequested
+		case "NOTICE":
+			if len(ircMessage.Params) > 1 {
+				log.Infof("Notice from Twitch Chat in %s: %s", ircMessage.Params[0], ircMessage.Params[1])
+			}
+		case "JOIN", "PART":
+			// Viewer joins or leaves the channel
+			b.handleMembership(ircMessage)
+		case "USERNOTICE":
+			b.handleUserNotice(ircMessage)
+		case "ROOMSTATE":
+			b.handleRoomState(ircMessage)
 		case "001":
 			// Welcome message
+			onWelcome()
 		case "CAP":
 			// Capabilities ack
 		case "353":
 			// List of current viewers "/NAMES"
+			if len(ircMessage.Params) > 3 {
+				for _, name := range strings.Fields(ircMessage.Params[3]) {
+					b.addKnownUser(name)
+				}
+			}
 		default:
 			log.Warnf("Unhandled IRC command from server: %s, full message: %s", ircMessage.Command, ircMessage)
 		}
 	}
 }
 
+// handleUserState updates if the bot is moderator of the channel. Twitch sends USERSTATE
+// when the bot joins a channel and after each message of the bot.
+func (b *Bot) handleUserState(message *irc.Message) {
+	if len(message.Params) > 0 {
+		b.sendQueue.setModerator(message.Params[0], convertUser(message).IsMod)
+	}
+}
+
+// addKnownUser adds a chatter of whom only the login name is known to the directory, e.g., from JOIN.
+// The login name is used as ID until the chatter sends a message.
+func (b *Bot) addKnownUser(name string) {
+	if len(name) == 0 {
+		return
+	}
+	if _, err := b.directory.UserByName(name); err == nil {
+		return
+	}
+	b.directory.AddUser(model.User{ID: name, Name: name})
+}
+
+// addUser adds a chatter with the user ID given in the tags to the directory.
+func (b *Bot) addUser(user model.User) {
+	if user.ID != user.Name {
+		// The chatter may be known by the login name only
+		b.directory.RemoveUser(user.Name)
+	}
+	b.directory.AddUser(user)
+}
+
 // Run the Bot (blocking)
 func (b *Bot) Run(ctx context.Context) error {
-	b.openWebSocketConnection()
+	b.done = make(chan struct{})
+	b.StartEventQueues()
 
-	go func() {
-		b.wg.Add(1)
-		b.messageLoop()
-		defer b.wg.Done()
-	}()
-
-	for _, plugin := range b.plugins {
-		plugin.OnRun()
+	if err := b.openWebSocketConnection(); err != nil {
+		log.Errorln("Could not connect to Twitch Chat WebSocket, retrying:", err)
 	}
+	b.sendQueue.start()
+
+	b.wg.Add(1)
+	go b.run()
+
+	b.RunPlugins(b.plugins)
 
 	<-ctx.Done()
 	log.Infoln("TwitchBot is SHUTING DOWN")
 
+	b.StopEventQueues()
+
 	for _, plugin := range b.plugins {
 		plugin.OnStop()
 	}
 
+	close(b.done)
+	b.sendQueue.stop()
+
 	err := b.sendCloseToWebsocket()
 	if err != nil {
 		log.Errorln("Error when writing close message to ws:", err)
 	}
 
-	b.wg.Wait()
-
 	b.ws.Close()
 
+	b.wg.Wait()
+
 	log.Infoln("TwitchBot is SHUT DOWN")
 
 	return nil
@@ -224,33 +358,6 @@ func (b *Bot) GetInfo() platform.BotInfo {
 		BotID:    "",
 		Platform: "Twitch",
 		Healthy:  true,
-		Plugins:  []platform.PluginInfo{},
-	}
-}
-
-func (b *Bot) onFail() {
-	log.Warn("Encountered an error, trying to restart the bot")
-
-	err := b.sendCloseToWebsocket()
-	if err != nil {
-		log.Errorf("Error when writing close message to ws: %s, still trying to recover", err)
+		Plugins:  b.PluginInfos(b.plugins),
 	}
-
-	b.wg.Wait()
-
-	b.ws.Close()
-
-	err = b.openWebSocketConnection()
-	if err != nil {
-		log.Errorln("Could not open Twitch Chat WebSocket, Twitch Bot not operational:", err)
-		return
-	}
-
-	go func() {
-		b.wg.Add(1)
-		b.messageLoop()
-		defer b.wg.Done()
-	}()
-
-	log.Info("Recovery attempt finished")
 }
@@ -41,3 +41,29 @@ func Test_TwitchBot_Run(t *testing.T) {
 	cancel()
 	time.Sleep(100 * time.Millisecond)
 }
+
+func Test_TwitchBot_Directory(t *testing.T) {
+	ws := &ws.MockClient{}
+	dispatcher := commanddispatcher.CommandDispatcher{}
+	storage := storage.MockStorage{}
+	cfg := botconfig.TwitchConfig{Channels: []string{"somechannel"}}
+
+	bot, err := CreateTwitchBot(cfg, &storage, &dispatcher, ws)
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+
+	channel, err := bot.GetChannelByName("somechannel")
+	if err != nil || channel.ID != "#somechannel" {
+		t.Fatalf("Configured channel not known: %v, %s", channel, err)
+	}
+
+	if _, err := bot.GetUser("someuser"); err == nil {
+		t.Fatalf("Unknown user should return an error")
+	}
+	bot.addKnownUser("someuser")
+	user, err := bot.GetUserByUsername("SomeUser")
+	if err != nil || user.ID != "someuser" {
+		t.Fatalf("Chatter not known: %v, %s", user, err)
+	}
+}
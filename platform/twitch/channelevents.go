@@ -0,0 +1,171 @@
+package twitch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/irc.v3"
+
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/plugin"
+)
+
+// anonymousGifter is the login name Twitch uses for anonymous gifts.
+const anonymousGifter = "ananonymousgifter"
+
+func (b *Bot) notifyPlugins(hook func(plugin.Hooks)) {
+	for _, p := range b.plugins {
+		p := p
+		b.Enqueue(p, func() { hook(p) })
+	}
+}
+
+// knownUser returns the chatter with the login name. Chatters who did not send a message
+// yet are known by their login name only.
+func (b *Bot) knownUser(login string) model.User {
+	if user, err := b.directory.UserByName(login); err == nil {
+		return user
+	}
+	return model.User{ID: login, Name: login}
+}
+
+func tagInt(message *irc.Message, key string) int {
+	value, _ := message.GetTag(key)
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+func tagBool(message *irc.Message, key string) bool {
+	value, _ := message.GetTag(key)
+	return value == "1"
+}
+
+// handleMembership handles JOIN and PART of other chatters, which Twitch sends with the twitch.tv/membership capability.
+func (b *Bot) handleMembership(message *irc.Message) {
+	if len(message.Params) == 0 || len(message.User) == 0 || strings.EqualFold(message.User, b.cfg.Username) {
+		return
+	}
+
+	if message.Command == "JOIN" {
+		b.addKnownUser(message.User)
+	}
+	membership := model.Membership{ChannelID: message.Params[0], User: b.knownUser(message.User)}
+
+	switch message.Command {
+	case "JOIN":
+		membership.Type = "joined"
+		b.notifyPlugins(func(p plugin.Hooks) { p.OnUserJoined(membership) })
+	case "PART":
+		membership.Type = "left"
+		b.notifyPlugins(func(p plugin.Hooks) { p.OnUserLeft(membership) })
+	}
+}
+
+// handleUserNotice handles subscriptions and raids.
+func (b *Bot) handleUserNotice(message *irc.Message) {
+	if len(message.Params) == 0 {
+		log.Warnf("Params not long enough")
+		return
+	}
+
+	msgID, _ := message.GetTag("msg-id")
+	user := convertUser(message)
+	channelID := message.Params[0]
+
+	subscription := model.Subscription{
+		ChannelID: channelID,
+		User:      user,
+		Anonymous: user.Name == anonymousGifter,
+	}
+	subscription.Plan, _ = message.GetTag("msg-param-sub-plan")
+	if len(message.Params) > 1 {
+		subscription.Message = message.Params[1]
+	}
+
+	switch msgID {
+	case "sub", "resub":
+		subscription.Type = model.SubscriptionNew
+		if msgID == "resub" {
+			subscription.Type = model.SubscriptionRenewed
+		}
+		subscription.Months = tagInt(message, "msg-param-cumulative-months")
+		if tagBool(message, "msg-param-should-share-streak") {
+			subscription.Streak = tagInt(message, "msg-param-streak-months")
+		}
+	case "subgift", "anonsubgift":
+		subscription.Type = model.SubscriptionGift
+		subscription.Anonymous = subscription.Anonymous || msgID == "anonsubgift"
+		subscription.Months = tagInt(message, "msg-param-months")
+		subscription.Count = 1
+		subscription.Recipient.ID, _ = message.GetTag("msg-param-recipient-id")
+		subscription.Recipient.Name, _ = message.GetTag("msg-param-recipient-user-name")
+		subscription.Recipient.Nickname, _ = message.GetTag("msg-param-recipient-display-name")
+	case "submysterygift", "anonsubmysterygift":
+		subscription.Type = model.SubscriptionMysteryGift
+		subscription.Anonymous = subscription.Anonymous || msgID == "anonsubmysterygift"
+		subscription.Count = tagInt(message, "msg-param-mass-gift-count")
+	case "raid":
+		raid := model.Raid{ChannelID: channelID, User: user, Viewers: tagInt(message, "msg-param-viewerCount")}
+		log.Debugf("Channel %s raided by %s with %d viewers", channelID, user.Name, raid.Viewers)
+		b.notifyPlugins(func(p plugin.Hooks) { p.OnRaid(raid) })
+		return
+	default:
+		log.Debugf("Unhandled USERNOTICE %s in channel %s", msgID, channelID)
+		return
+	}
+
+	log.Debugf("Received subscription of type %s from %s in channel %s", subscription.Type, user.Name, channelID)
+	b.notifyPlugins(func(p plugin.Hooks) { p.OnSubscription(subscription) })
+}
+
+// handleCheer notifies the plugins about the bits sent with a message.
+func (b *Bot) handleCheer(message *irc.Message, post model.Post) {
+	bits := tagInt(message, "bits")
+	if bits <= 0 {
+		return
+	}
+
+	cheer := model.Cheer{ChannelID: post.ChannelID, User: post.User, Bits: bits, Message: post.Content}
+	b.notifyPlugins(func(p plugin.Hooks) { p.OnCheer(cheer) })
+}
+
+// handleRoomState handles the chat settings of a channel. Twitch sends all settings when
+// the channel is joined and only the changed settings afterwards.
+func (b *Bot) handleRoomState(message *irc.Message) {
+	if len(message.Params) == 0 {
+		log.Warnf("Params not long enough")
+		return
+	}
+
+	channelID := message.Params[0]
+	state, ok := b.channelStates[channelID]
+	if !ok {
+		state = model.ChannelState{ChannelID: channelID}
+	}
+
+	if _, ok := message.GetTag("emote-only"); ok {
+		state.EmoteOnly = tagBool(message, "emote-only")
+	}
+	if _, ok := message.GetTag("subs-only"); ok {
+		state.SubsOnly = tagBool(message, "subs-only")
+	}
+	if _, ok := message.GetTag("r9k"); ok {
+		state.UniqueChat = tagBool(message, "r9k")
+	}
+	if _, ok := message.GetTag("slow"); ok {
+		state.SlowMode = time.Duration(tagInt(message, "slow")) * time.Second
+	}
+	if value, ok := message.GetTag("followers-only"); ok {
+		// -1 is disabled, otherwise the minutes users have to follow
+		minutes, _ := strconv.Atoi(value)
+		state.FollowersOnly = minutes >= 0
+		state.FollowersOnlyDuration = 0
+		if minutes > 0 {
+			state.FollowersOnlyDuration = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	b.channelStates[channelID] = state
+	b.notifyPlugins(func(p plugin.Hooks) { p.OnChannelStateChanged(state) })
+}
@@ -0,0 +1,134 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/irc.v3"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/plugin"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/ws"
+)
+
+type mockChannelEventPlugin struct {
+	plugin.RedseliggPlugin
+
+	joined        []model.Membership
+	left          []model.Membership
+	subscriptions []model.Subscription
+	raids         []model.Raid
+	cheers        []model.Cheer
+	states        []model.ChannelState
+}
+
+func (p *mockChannelEventPlugin) OnUserJoined(m model.Membership) { p.joined = append(p.joined, m) }
+func (p *mockChannelEventPlugin) OnUserLeft(m model.Membership)   { p.left = append(p.left, m) }
+func (p *mockChannelEventPlugin) OnSubscription(s model.Subscription) {
+	p.subscriptions = append(p.subscriptions, s)
+}
+func (p *mockChannelEventPlugin) OnRaid(r model.Raid)   { p.raids = append(p.raids, r) }
+func (p *mockChannelEventPlugin) OnCheer(c model.Cheer) { p.cheers = append(p.cheers, c) }
+func (p *mockChannelEventPlugin) OnChannelStateChanged(s model.ChannelState) {
+	p.states = append(p.states, s)
+}
+
+func createChannelEventTestBot(t *testing.T) (*Bot, *mockChannelEventPlugin) {
+	bot, err := CreateTwitchBot(botconfig.TwitchConfig{Username: "bot"}, &storage.MockStorage{}, commanddispatcher.New("!"), &ws.MockClient{})
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+	p := &mockChannelEventPlugin{}
+	bot.plugins = append(bot.plugins, p)
+	return bot, p
+}
+
+func TestBot_handleMembership(t *testing.T) {
+	assert := assert.New(t)
+
+	bot, p := createChannelEventTestBot(t)
+	bot.addUser(model.User{ID: "1234567", Name: "ronni"})
+
+	bot.handleMembership(irc.MustParseMessage(":ronni!ronni@ronni.tmi.twitch.tv JOIN #dallas"))
+	bot.handleMembership(irc.MustParseMessage(":newbie!newbie@newbie.tmi.twitch.tv PART #dallas"))
+	bot.handleMembership(irc.MustParseMessage(":bot!bot@bot.tmi.twitch.tv JOIN #dallas"))
+
+	assert.Equal([]model.Membership{{ChannelID: "#dallas", Type: "joined", User: model.User{ID: "1234567", Name: "ronni"}}}, p.joined)
+	assert.Equal([]model.Membership{{ChannelID: "#dallas", Type: "left", User: model.User{ID: "newbie", Name: "newbie"}}}, p.left)
+}
+
+func TestBot_handleUserNotice(t *testing.T) {
+	assert := assert.New(t)
+
+	bot, p := createChannelEventTestBot(t)
+
+	bot.handleUserNotice(irc.MustParseMessage(`@badges=subscriber/0;display-name=Ronni;login=ronni;msg-id=resub;msg-param-cumulative-months=6;msg-param-should-share-streak=1;msg-param-streak-months=2;msg-param-sub-plan=Prime;user-id=1337 :tmi.twitch.tv USERNOTICE #dallas :Great stream!`))
+	bot.handleUserNotice(irc.MustParseMessage(`@display-name=TWW2;login=tww2;msg-id=subgift;msg-param-months=1;msg-param-recipient-display-name=Mr_Woodchuck;msg-param-recipient-id=55554444;msg-param-recipient-user-name=mr_woodchuck;msg-param-sub-plan=1000;user-id=13405587 :tmi.twitch.tv USERNOTICE #forstycup`))
+	bot.handleUserNotice(irc.MustParseMessage(`@display-name=AnAnonymousGifter;login=ananonymousgifter;msg-id=submysterygift;msg-param-mass-gift-count=5;msg-param-sub-plan=1000;user-id=274598607 :tmi.twitch.tv USERNOTICE #dallas`))
+	bot.handleUserNotice(irc.MustParseMessage(`@display-name=TestChannel;login=testchannel;msg-id=raid;msg-param-displayName=TestChannel;msg-param-login=testchannel;msg-param-viewerCount=15;user-id=1337 :tmi.twitch.tv USERNOTICE #othertestchannel`))
+	bot.handleUserNotice(irc.MustParseMessage(`@login=ronni;msg-id=ritual;user-id=1337 :tmi.twitch.tv USERNOTICE #dallas`))
+
+	assert.Equal(3, len(p.subscriptions))
+
+	resub := p.subscriptions[0]
+	assert.Equal(model.SubscriptionRenewed, resub.Type)
+	assert.Equal("1337", resub.User.ID)
+	assert.Equal("ronni", resub.User.Name)
+	assert.Equal("Prime", resub.Plan)
+	assert.Equal(6, resub.Months)
+	assert.Equal(2, resub.Streak)
+	assert.Equal("Great stream!", resub.Message)
+
+	gift := p.subscriptions[1]
+	assert.Equal(model.SubscriptionGift, gift.Type)
+	assert.Equal("tww2", gift.User.Name)
+	assert.Equal(model.User{ID: "55554444", Name: "mr_woodchuck", Nickname: "Mr_Woodchuck"}, gift.Recipient)
+	assert.Equal(1, gift.Count)
+	assert.False(gift.Anonymous)
+
+	mystery := p.subscriptions[2]
+	assert.Equal(model.SubscriptionMysteryGift, mystery.Type)
+	assert.Equal(5, mystery.Count)
+	assert.True(mystery.Anonymous)
+
+	assert.Equal(1, len(p.raids))
+	assert.Equal("#othertestchannel", p.raids[0].ChannelID)
+	assert.Equal("testchannel", p.raids[0].User.Name)
+	assert.Equal(15, p.raids[0].Viewers)
+}
+
+func TestBot_handleCheer(t *testing.T) {
+	assert := assert.New(t)
+
+	bot, p := createChannelEventTestBot(t)
+
+	message := irc.MustParseMessage(`@bits=100;display-name=Ronni;user-id=1337 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #dallas :cheer100 Nice!`)
+	bot.handleCheer(message, model.Post{ChannelID: "#dallas", User: convertUser(message), Content: message.Params[1]})
+	message = irc.MustParseMessage(`@display-name=Ronni;user-id=1337 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #dallas :no bits`)
+	bot.handleCheer(message, model.Post{ChannelID: "#dallas", User: convertUser(message), Content: message.Params[1]})
+
+	assert.Equal(1, len(p.cheers))
+	assert.Equal(100, p.cheers[0].Bits)
+	assert.Equal("1337", p.cheers[0].User.ID)
+	assert.Equal("cheer100 Nice!", p.cheers[0].Message)
+}
+
+func TestBot_handleRoomState(t *testing.T) {
+	assert := assert.New(t)
+
+	bot, p := createChannelEventTestBot(t)
+
+	bot.handleRoomState(irc.MustParseMessage(`@emote-only=0;followers-only=-1;r9k=0;rituals=0;room-id=12345678;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #dallas`))
+	bot.handleRoomState(irc.MustParseMessage(`@room-id=12345678;slow=10 :tmi.twitch.tv ROOMSTATE #dallas`))
+	bot.handleRoomState(irc.MustParseMessage(`@followers-only=30;room-id=12345678 :tmi.twitch.tv ROOMSTATE #dallas`))
+
+	assert.Equal([]model.ChannelState{
+		{ChannelID: "#dallas"},
+		{ChannelID: "#dallas", SlowMode: 10 * time.Second},
+		{ChannelID: "#dallas", SlowMode: 10 * time.Second, FollowersOnly: true, FollowersOnlyDuration: 30 * time.Minute},
+	}, p.states)
+}
@@ -31,3 +31,33 @@ func getRedseliggEmojiFromTwitchEmoji(discordEmoji string) (string, error) {
 		return discordEmoji, fmt.Errorf("Emoji not known")
 	}
 }
+
+func getTwitchEmojiFromRedseliggEmoji(redseliggEmoji string) (string, error) {
+	switch redseliggEmoji {
+
+	case "zero":
+		return "0️⃣", nil
+	case "one":
+		return "1️⃣", nil
+	case "two":
+		return "2️⃣", nil
+	case "three":
+		return "3️⃣", nil
+	case "four":
+		return "4️⃣", nil
+	case "five":
+		return "5️⃣", nil
+	case "six":
+		return "6️⃣", nil
+	case "seven":
+		return "7️⃣", nil
+	case "eight":
+		return "8️⃣", nil
+	case "nine":
+		return "9️⃣", nil
+	case "keycap_ten":
+		return "🔟", nil
+	default:
+		return "", fmt.Errorf("Emoji not known")
+	}
+}
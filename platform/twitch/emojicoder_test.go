@@ -37,3 +37,14 @@ func Test_getRedseliggEmojiFromTwitchEmoji(t *testing.T) {
 		})
 	}
 }
+
+func Test_getTwitchEmojiFromRedseliggEmoji(t *testing.T) {
+	got, err := getTwitchEmojiFromRedseliggEmoji("seven")
+	if err != nil || got != "7️⃣" {
+		t.Errorf("getTwitchEmojiFromRedseliggEmoji() = %v, %v, want 7️⃣", got, err)
+	}
+
+	if _, err := getTwitchEmojiFromRedseliggEmoji("unknown"); err == nil {
+		t.Errorf("getTwitchEmojiFromRedseliggEmoji() should return an error for unknown emojis")
+	}
+}
@@ -0,0 +1,202 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// URLs of the Twitch API, variables for testing.
+var (
+	helixURL    = "https://api.twitch.tv/helix"
+	validateURL = "https://id.twitch.tv/oauth2/validate"
+)
+
+var helixClient = &http.Client{Timeout: 10 * time.Second}
+
+// helixAuth is the response of the token validation, which gives the client ID and the user of the token.
+type helixAuth struct {
+	ClientID string   `json:"client_id"`
+	Login    string   `json:"login"`
+	UserID   string   `json:"user_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+type helixUsers struct {
+	Data []struct {
+		ID    string `json:"id"`
+		Login string `json:"login"`
+	} `json:"data"`
+}
+
+type helixError struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// helixToken returns the token of the config without the "oauth:" prefix used for IRC.
+func (b *Bot) helixToken() string {
+	return strings.TrimPrefix(b.cfg.Token, "oauth:")
+}
+
+// auth validates the token once and returns the client ID and the user of the token.
+func (b *Bot) auth() (helixAuth, error) {
+	b.helixMutex.Lock()
+	defer b.helixMutex.Unlock()
+
+	if b.helixAuth != nil {
+		return *b.helixAuth, nil
+	}
+
+	req, err := http.NewRequest("GET", validateURL, nil)
+	if err != nil {
+		return helixAuth{}, err
+	}
+	req.Header.Set("Authorization", "OAuth "+b.helixToken())
+
+	response, err := helixClient.Do(req)
+	if err != nil {
+		return helixAuth{}, errors.Wrap(err, "Token validation failed")
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return helixAuth{}, err
+	}
+	if response.StatusCode != 200 {
+		return helixAuth{}, fmt.Errorf("Token validation failed: %s", body)
+	}
+
+	var auth helixAuth
+	if err := json.Unmarshal(body, &auth); err != nil {
+		return helixAuth{}, errors.Wrap(err, "json unmarshal failed")
+	}
+	log.Debugf("Twitch API token of user %s has the scopes %s", auth.Login, strings.Join(auth.Scopes, ", "))
+	b.helixAuth = &auth
+
+	return auth, nil
+}
+
+// helixCall calls the Helix API and decodes the response into v, if given.
+func (b *Bot) helixCall(method string, path string, query url.Values, body interface{}, v interface{}) error {
+	auth, err := b.auth()
+	if err != nil {
+		return err
+	}
+
+	var reqBody *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "json marshal failed")
+		}
+		reqBody = bytes.NewReader(data)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, helixURL+path+"?"+query.Encode(), reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.helixToken())
+	req.Header.Set("Client-Id", auth.ClientID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := helixClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "apiCall failed")
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= 300 {
+		var e helixError
+		if err := json.Unmarshal(data, &e); err == nil && len(e.Message) > 0 {
+			return fmt.Errorf("Twitch API returned status %d: %s", response.StatusCode, e.Message)
+		}
+		return fmt.Errorf("Twitch API returned status %d: %s", response.StatusCode, data)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			return errors.Wrap(err, "json unmarshal failed")
+		}
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// helixUserID returns the Twitch user ID for a user ID or login name. Chatters who did not send
+// a message yet are known by their login name only, their ID is requested from the API.
+func (b *Bot) helixUserID(user string) (string, error) {
+	if isNumeric(user) {
+		return user, nil
+	}
+	return b.helixLoginID(user)
+}
+
+// helixLoginID returns the Twitch user ID of the login name.
+func (b *Bot) helixLoginID(login string) (string, error) {
+	login = strings.ToLower(login)
+
+	b.helixMutex.Lock()
+	id, ok := b.helixUserIDs[login]
+	b.helixMutex.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var users helixUsers
+	if err := b.helixCall("GET", "/users", url.Values{"login": {login}}, nil, &users); err != nil {
+		return "", err
+	}
+	if len(users.Data) == 0 {
+		return "", fmt.Errorf("Unknown Twitch user %s", login)
+	}
+
+	b.helixMutex.Lock()
+	b.helixUserIDs[login] = users.Data[0].ID
+	b.helixMutex.Unlock()
+
+	return users.Data[0].ID, nil
+}
+
+// moderationQuery returns the query parameters naming the broadcaster of the channel, e.g., #channel,
+// and the bot as moderator, which are needed by all moderation endpoints.
+func (b *Bot) moderationQuery(channelID string) (url.Values, error) {
+	auth, err := b.auth()
+	if err != nil {
+		return nil, err
+	}
+	broadcasterID, err := b.helixLoginID(strings.TrimPrefix(channelID, "#"))
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"broadcaster_id": {broadcasterID}, "moderator_id": {auth.UserID}}, nil
+}
@@ -3,8 +3,23 @@ package twitch
 import (
 	"regexp"
 	"strings"
+
+	"github.com/torlenor/redseligg/model"
 )
 
+var userIDRegexp = regexp.MustCompile(`<@([0-9]+)>`)
+
+// replaceKnownUserIDs replaces the mentions of known user IDs, e.g., <@12345>, with the login name of the user.
+func (b *Bot) replaceKnownUserIDs(msg string) string {
+	return userIDRegexp.ReplaceAllStringFunc(msg, func(mention string) string {
+		user, err := b.directory.User(mention[2 : len(mention)-1])
+		if err != nil {
+			return mention
+		}
+		return user.Name
+	})
+}
+
 func replaceRedseliggUserID(msg string) string {
 	re := regexp.MustCompile(`(<@[a-zA-Z ]+>)`)
 
@@ -28,3 +43,17 @@ func replaceRedseliggUserID(msg string) string {
 func convertMessageFromRedseligg(text string) string {
 	return replaceRedseliggUserID(text)
 }
+
+// stripLineBreaks replaces CR and LF, which would end the IRC line and could be used to send arbitrary commands.
+func stripLineBreaks(text string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(text)
+}
+
+// renderPost returns the post as plain text. Twitch does not support formatting and messages
+// consist of only one line, therefore line breaks of rich posts are replaced.
+func renderPost(post model.Post) string {
+	if !post.IsRich() {
+		return post.Content
+	}
+	return strings.Join(strings.Split(post.Render(model.PlainText, true, true), "\n"), " ")
+}
@@ -0,0 +1,146 @@
+package twitch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/irc.v3"
+)
+
+// Limits of the Twitch moderation API
+const (
+	maxTimeoutDuration  = 14 * 24 * time.Hour
+	minSlowModeInterval = 3 * time.Second
+	maxSlowModeInterval = 120 * time.Second
+)
+
+// sendPrivMsg sends the text to the channel, e.g., #channel, as soon as the rate limits allow it.
+func (b *Bot) sendPrivMsg(channelID string, text string) error {
+	if !strings.HasPrefix(channelID, "#") {
+		channelID = "#" + channelID
+	}
+	ircMessage := irc.Message{
+		Command: "PRIVMSG",
+		Params:  []string{channelID, stripLineBreaks(text)},
+	}
+	return b.sendQueue.enqueue(channelID, []byte(ircMessage.String()))
+}
+
+type helixBanRequest struct {
+	Data helixBan `json:"data"`
+}
+
+type helixBan struct {
+	UserID   string `json:"user_id"`
+	Duration int    `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type helixChatSettings struct {
+	SlowMode         bool `json:"slow_mode"`
+	SlowModeWaitTime *int `json:"slow_mode_wait_time,omitempty"`
+}
+
+type helixWhisper struct {
+	Message string `json:"message"`
+}
+
+// ban bans the user from the channel or times them out if a duration in seconds is given.
+// The token needs the moderator:manage:banned_users scope.
+func (b *Bot) ban(channelID string, userID string, seconds int, reason string) error {
+	query, err := b.moderationQuery(channelID)
+	if err != nil {
+		return err
+	}
+	targetID, err := b.helixUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	ban := helixBan{UserID: targetID, Duration: seconds, Reason: stripLineBreaks(reason)}
+	return b.helixCall("POST", "/moderation/bans", query, helixBanRequest{Data: ban}, nil)
+}
+
+// deleteChatMessages removes the message with the ID from the channel or all messages if no ID is given.
+// The token needs the moderator:manage:chat_messages scope.
+func (b *Bot) deleteChatMessages(channelID string, messageID string) error {
+	query, err := b.moderationQuery(channelID)
+	if err != nil {
+		return err
+	}
+	if len(messageID) > 0 {
+		query.Set("message_id", messageID)
+	}
+	return b.helixCall("DELETE", "/moderation/chat", query, nil, nil)
+}
+
+// sendWhisper sends the text to the user, which is given by ID or login name.
+// The token needs the user:manage:whispers scope and a verified phone number.
+func (b *Bot) sendWhisper(user string, text string) error {
+	auth, err := b.auth()
+	if err != nil {
+		return err
+	}
+	toUserID, err := b.helixUserID(user)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"from_user_id": {auth.UserID}, "to_user_id": {toUserID}}
+	return b.helixCall("POST", "/whispers", query, helixWhisper{Message: stripLineBreaks(text)}, nil)
+}
+
+// TimeoutUser prevents the user from writing in the channel for the given duration (up to two weeks).
+func (b *Bot) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	if duration <= 0 || duration > maxTimeoutDuration {
+		return fmt.Errorf("Timeout duration must be between 1s and %s", maxTimeoutDuration)
+	}
+	seconds := int((duration + time.Second - 1) / time.Second)
+
+	if err := b.ban(channelID, userID, seconds, reason); err != nil {
+		return fmt.Errorf("Could not timeout user: %s", err)
+	}
+	return nil
+}
+
+// BanUser bans the user from the channel.
+func (b *Bot) BanUser(channelID string, userID string, reason string) error {
+	if err := b.ban(channelID, userID, 0, reason); err != nil {
+		return fmt.Errorf("Could not ban user: %s", err)
+	}
+	return nil
+}
+
+// ClearChat removes all messages from the channel.
+func (b *Bot) ClearChat(channelID string) error {
+	if err := b.deleteChatMessages(channelID, ""); err != nil {
+		return fmt.Errorf("Could not clear chat: %s", err)
+	}
+	return nil
+}
+
+// SetSlowMode sets the time users have to wait between two messages in the channel (3 seconds
+// up to two minutes). An interval of 0 turns slow mode off. The token needs the
+// moderator:manage:chat_settings scope.
+func (b *Bot) SetSlowMode(channelID string, interval time.Duration) error {
+	if interval != 0 && (interval < minSlowModeInterval || interval > maxSlowModeInterval) {
+		return fmt.Errorf("Slow mode interval must be 0 or between %s and %s", minSlowModeInterval, maxSlowModeInterval)
+	}
+
+	query, err := b.moderationQuery(channelID)
+	if err != nil {
+		return fmt.Errorf("Could not set slow mode: %s", err)
+	}
+
+	settings := helixChatSettings{SlowMode: interval > 0}
+	if interval > 0 {
+		seconds := int((interval + time.Second - 1) / time.Second)
+		settings.SlowModeWaitTime = &seconds
+	}
+	if err := b.helixCall("PATCH", "/chat/settings", query, settings, nil); err != nil {
+		return fmt.Errorf("Could not set slow mode: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package twitch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/ws"
+)
+
+type helixRequest struct {
+	method string
+	path   string
+	query  string
+	body   string
+}
+
+// helixTestServer simulates the token validation and the Helix endpoints. The user lookup
+// knows the channel dallas and the user someone, all other requests are recorded.
+func helixTestServer(t *testing.T) (*httptest.Server, *[]helixRequest) {
+	var requests []helixRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer TOKEN" && r.Header.Get("Authorization") != "OAuth TOKEN" {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error":"Unauthorized","status":401,"message":"Invalid OAuth token"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/validate":
+			w.Write([]byte(`{"client_id":"CLIENTID","login":"bot","user_id":"999","scopes":["moderator:manage:banned_users"]}`))
+		case "/helix/users":
+			assert.Equal(t, "CLIENTID", r.Header.Get("Client-Id"))
+			switch r.URL.Query().Get("login") {
+			case "dallas":
+				w.Write([]byte(`{"data":[{"id":"111","login":"dallas"}]}`))
+			case "someone":
+				w.Write([]byte(`{"data":[{"id":"222","login":"someone"}]}`))
+			default:
+				w.Write([]byte(`{"data":[]}`))
+			}
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, helixRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, body: string(body)})
+			w.WriteHeader(204)
+		}
+	}))
+
+	oldHelixURL, oldValidateURL := helixURL, validateURL
+	helixURL, validateURL = server.URL+"/helix", server.URL+"/validate"
+	t.Cleanup(func() {
+		helixURL, validateURL = oldHelixURL, oldValidateURL
+		server.Close()
+	})
+
+	return server, &requests
+}
+
+func TestBot_Moderation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, requests := helixTestServer(t)
+
+	client := &ws.MockClient{}
+	bot, err := CreateTwitchBot(botconfig.TwitchConfig{Username: "bot", Token: "oauth:TOKEN"}, &storage.MockStorage{}, &commanddispatcher.CommandDispatcher{}, client)
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+	bot.addUser(model.User{ID: "1234567", Name: "ronni"})
+
+	last := func() helixRequest {
+		if len(*requests) == 0 {
+			return helixRequest{}
+		}
+		r := (*requests)[len(*requests)-1]
+		*requests = nil
+		return r
+	}
+	moderation := "broadcaster_id=111&moderator_id=999"
+
+	assert.NoError(bot.TimeoutUser("#dallas", "1234567", 90*time.Second, "spam\r\nPRIVMSG #dallas :injected"))
+	assert.Equal(helixRequest{"POST", "/helix/moderation/bans", moderation, `{"data":{"user_id":"1234567","duration":90,"reason":"spam PRIVMSG #dallas :injected"}}`}, last())
+	assert.NoError(bot.TimeoutUser("dallas", "someone", 1500*time.Millisecond, ""))
+	assert.Equal(helixRequest{"POST", "/helix/moderation/bans", moderation, `{"data":{"user_id":"222","duration":2}}`}, last())
+	assert.Error(bot.TimeoutUser("#dallas", "1234567", 15*24*time.Hour, ""))
+	assert.Error(bot.TimeoutUser("#dallas", "1234567", 0, ""))
+	assert.Error(bot.TimeoutUser("#dallas", "unknown", time.Minute, ""))
+	assert.Error(bot.TimeoutUser("#unknown", "1234567", time.Minute, ""))
+
+	assert.NoError(bot.BanUser("#dallas", "1234567", ""))
+	assert.Equal(helixRequest{"POST", "/helix/moderation/bans", moderation, `{"data":{"user_id":"1234567"}}`}, last())
+
+	assert.NoError(bot.ClearChat("#dallas"))
+	assert.Equal(helixRequest{"DELETE", "/helix/moderation/chat", moderation, ""}, last())
+
+	assert.NoError(bot.SetSlowMode("#dallas", 30*time.Second))
+	assert.Equal(helixRequest{"PATCH", "/helix/chat/settings", moderation, `{"slow_mode":true,"slow_mode_wait_time":30}`}, last())
+	assert.NoError(bot.SetSlowMode("#dallas", 0))
+	assert.Equal(helixRequest{"PATCH", "/helix/chat/settings", moderation, `{"slow_mode":false}`}, last())
+	assert.Error(bot.SetSlowMode("#dallas", time.Hour))
+	assert.Error(bot.SetSlowMode("#dallas", time.Second))
+
+	_, err = bot.DeletePost(model.MessageIdentifier{ID: "b34ccfc7-4977-403a-8a94-33c6bac34fb8", Channel: "#dallas"})
+	assert.NoError(err)
+	assert.Equal(helixRequest{"DELETE", "/helix/moderation/chat", "broadcaster_id=111&message_id=b34ccfc7-4977-403a-8a94-33c6bac34fb8&moderator_id=999", ""}, last())
+	_, err = bot.DeletePost(model.MessageIdentifier{Channel: "#dallas"})
+	assert.Error(err)
+
+	_, err = bot.CreatePost(model.Post{ChannelID: "#dallas", User: model.User{ID: "1234567"}, Content: "psst\nsecret", IsPrivate: true})
+	assert.NoError(err)
+	assert.Equal(helixRequest{"POST", "/helix/whispers", "from_user_id=999&to_user_id=1234567", `{"message":"psst secret"}`}, last())
+	_, err = bot.CreatePost(model.Post{User: model.User{Name: "someone"}, Content: "psst", IsPrivate: true})
+	assert.NoError(err)
+	assert.Equal(helixRequest{"POST", "/helix/whispers", "from_user_id=999&to_user_id=222", `{"message":"psst"}`}, last())
+	_, err = bot.CreatePost(model.Post{Content: "psst", IsPrivate: true})
+	assert.Error(err)
+
+	assert.Equal("", string(client.LastSendMessageData))
+}
+
+func TestBot_ModerationInvalidToken(t *testing.T) {
+	helixTestServer(t)
+
+	bot, err := CreateTwitchBot(botconfig.TwitchConfig{Username: "bot", Token: "oauth:WRONG"}, &storage.MockStorage{}, &commanddispatcher.CommandDispatcher{}, &ws.MockClient{})
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+
+	if err := bot.BanUser("#dallas", "1234567", ""); err == nil {
+		t.Fatalf("Ban with invalid token should have failed")
+	}
+}
+
+func TestBot_SendPrivMsgStripsLineBreaks(t *testing.T) {
+	client := &ws.MockClient{}
+	bot, err := CreateTwitchBot(botconfig.TwitchConfig{Username: "bot"}, &storage.MockStorage{}, &commanddispatcher.CommandDispatcher{}, client)
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+	bot.sendQueue.setModerator("#dallas", true)
+
+	if _, err := bot.CreatePost(model.Post{ChannelID: "#dallas", Content: "hello\r\nPART #dallas"}); err != nil {
+		t.Fatalf("Sending should not have failed: %s", err)
+	}
+	assert.Equal(t, "PRIVMSG #dallas :hello PART #dallas", string(client.LastSendMessageData))
+}
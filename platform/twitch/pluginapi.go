@@ -3,38 +3,51 @@ package twitch
 import (
 	"fmt"
 
-	"github.com/gorilla/websocket"
-	"gopkg.in/irc.v3"
-
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/utils"
 )
 
 var version string
 
-// GetUsers a list of users based on search options.
-func (b *Bot) GetUsers() ([]model.User, error) { return nil, nil }
+// GetUsers returns the chatters seen in the joined channels.
+func (b *Bot) GetUsers() ([]model.User, error) { return b.directory.Users(), nil }
 
-// GetUser gets a user.
-func (b *Bot) GetUser(userID string) (model.User, error) { return model.User{}, nil }
+// GetUser gets a chatter seen in the joined channels by their user ID. Chatters who did not send
+// a message yet are known by their login name.
+func (b *Bot) GetUser(userID string) (model.User, error) { return b.directory.User(userID) }
 
-// GetUserByUsername gets a user by their username.
-func (b *Bot) GetUserByUsername(name string) (model.User, error) { return model.User{}, nil }
+// GetUserByUsername gets a chatter seen in the joined channels by their login name.
+func (b *Bot) GetUserByUsername(name string) (model.User, error) { return b.directory.UserByName(name) }
 
-// GetChannel gets a channel.
-func (b *Bot) GetChannel(channelID string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannel gets a joined channel by its ID, e.g., #channel.
+func (b *Bot) GetChannel(channelID string) (model.Channel, error) {
+	return b.directory.Channel(channelID)
+}
 
-// GetChannelByName gets a channel by its name, given a team id.
-func (b *Bot) GetChannelByName(name string) (model.Channel, error) { return model.Channel{}, nil }
+// GetChannelByName gets a joined channel by its name, e.g., channel.
+func (b *Bot) GetChannelByName(name string) (model.Channel, error) {
+	return b.directory.ChannelByName(name)
+}
 
-// CreatePost creates a post.
+// CreatePost creates a post. Private posts are sent as whisper to the user via the Twitch API.
 func (b *Bot) CreatePost(post model.Post) (model.PostResponse, error) {
-	ircMessage := irc.Message{
-		Command: "PRIVMSG",
-		Params:  []string{post.ChannelID, convertMessageFromRedseligg(post.Content)},
+	text := convertMessageFromRedseligg(b.replaceKnownUserIDs(renderPost(post)))
+
+	if post.IsPrivate {
+		user := post.User.ID
+		if len(user) == 0 {
+			user = post.User.Name
+		}
+		if len(user) == 0 {
+			return model.PostResponse{}, fmt.Errorf("Plugin did not provide User or UserID, not sending Whisper")
+		}
+		if err := b.sendWhisper(user, text); err != nil {
+			return model.PostResponse{}, fmt.Errorf("Could not send whisper: %s", err)
+		}
+		return model.PostResponse{}, nil
 	}
-	err := b.ws.SendMessage(websocket.TextMessage, []byte(ircMessage.String()))
-	if err != nil {
+
+	if err := b.sendPrivMsg(post.ChannelID, text); err != nil {
 		return model.PostResponse{}, fmt.Errorf("Could not send message: %s", err)
 	}
 
@@ -46,14 +59,23 @@ func (b *Bot) UpdatePost(messageID model.MessageIdentifier, newPost model.Post)
 	return model.PostResponse{}, fmt.Errorf("Not supported")
 }
 
-// DeletePost deletes a post.
+// DeletePost deletes a message of a chatter. The ID of a message is given in the "id" tag,
+// which is available in the Extras of the user of a received post.
 func (b *Bot) DeletePost(messageID model.MessageIdentifier) (model.PostResponse, error) {
-	return model.PostResponse{}, fmt.Errorf("Not supported")
+	if len(messageID.ID) == 0 || len(messageID.Channel) == 0 {
+		return model.PostResponse{}, fmt.Errorf("Message ID and channel must be given")
+	}
+	if err := b.deleteChatMessages(messageID.Channel, messageID.ID); err != nil {
+		return model.PostResponse{}, fmt.Errorf("Error deleting post: %s", err)
+	}
+
+	return model.PostResponse{PostedMessageIdent: messageID}, nil
 }
 
-// GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
+// GetReaction gives back the platform specific string for a reaction, e.g., one -> 1️⃣
+// Twitch chat has no reactions, but the emoji can be used in messages.
 func (b *Bot) GetReaction(reactionName string) (string, error) {
-	return "", fmt.Errorf("Not implemented")
+	return getTwitchEmojiFromRedseliggEmoji(reactionName)
 }
 
 // LogTrace writes a log message to the server log file.
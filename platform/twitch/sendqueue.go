@@ -0,0 +1,209 @@
+package twitch
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits for sending messages to Twitch chat. Bots exceeding them are ignored or temporarily banned.
+var (
+	rateLimitWindow        = 30 * time.Second
+	rateLimitMessages      = 20              // Messages per window, if the bot is not moderator of the channel
+	rateLimitModMessages   = 100             // Messages per window, if the bot is moderator or broadcaster of the channel
+	channelMessageInterval = 1 * time.Second // Time between two messages in a channel, if the bot is not moderator
+)
+
+// maxQueuedMessages is the maximum number of messages waiting to be sent per channel.
+const maxQueuedMessages = 100
+
+// sendQueue sends the messages to the channels within the rate limits of Twitch. Messages
+// which cannot be sent immediately are queued per channel and the channels take turns.
+type sendQueue struct {
+	send func(data []byte) error
+
+	mutex     sync.Mutex
+	queues    map[string][][]byte  // [channel]
+	channels  []string             // channels with queued messages in the order they are served
+	sent      []time.Time          // times of the messages sent within the window, oldest first
+	lastSent  map[string]time.Time // [channel]
+	moderator map[string]bool      // [channel]
+
+	notify chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newSendQueue(send func(data []byte) error) *sendQueue {
+	return &sendQueue{
+		send:      send,
+		queues:    make(map[string][][]byte),
+		lastSent:  make(map[string]time.Time),
+		moderator: make(map[string]bool),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+func normalizeChannel(channel string) string {
+	channel = strings.ToLower(channel)
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	return channel
+}
+
+// setModerator sets if the bot is moderator or broadcaster of the channel, which raises the limits.
+func (q *sendQueue) setModerator(channel string, moderator bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.moderator[normalizeChannel(channel)] = moderator
+}
+
+func (q *sendQueue) start() {
+	q.done = make(chan struct{})
+	q.wg.Add(1)
+	go q.run()
+}
+
+// stop stops sending, messages which are still queued are dropped.
+func (q *sendQueue) stop() {
+	close(q.done)
+	q.wg.Wait()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.channels) > 0 {
+		log.Warnf("Dropping queued messages for %d channel(s)", len(q.channels))
+	}
+	q.queues = make(map[string][][]byte)
+	q.channels = nil
+}
+
+// waitTime returns how long a message to the channel has to wait. The caller must hold the mutex.
+func (q *sendQueue) waitTime(channel string, now time.Time) time.Duration {
+	for len(q.sent) > 0 && now.Sub(q.sent[0]) >= rateLimitWindow {
+		q.sent = q.sent[1:]
+	}
+
+	limit := rateLimitMessages
+	interval := channelMessageInterval
+	if q.moderator[channel] {
+		limit = rateLimitModMessages
+		interval = 0
+	}
+
+	var wait time.Duration
+	if len(q.sent) >= limit {
+		wait = q.sent[len(q.sent)-limit].Add(rateLimitWindow).Sub(now)
+	}
+	if last, ok := q.lastSent[channel]; ok && interval > 0 {
+		if w := last.Add(interval).Sub(now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// record records a message sent to the channel. The caller must hold the mutex.
+func (q *sendQueue) record(channel string, now time.Time) {
+	q.sent = append(q.sent, now)
+	q.lastSent[channel] = now
+}
+
+// enqueue sends the message immediately if the limits allow it, otherwise it is queued.
+// Errors of queued messages are only logged.
+func (q *sendQueue) enqueue(channel string, data []byte) error {
+	channel = normalizeChannel(channel)
+
+	q.mutex.Lock()
+	if len(q.queues[channel]) == 0 && q.waitTime(channel, time.Now()) <= 0 {
+		q.record(channel, time.Now())
+		q.mutex.Unlock()
+		return q.send(data)
+	}
+
+	if len(q.queues[channel]) >= maxQueuedMessages {
+		q.mutex.Unlock()
+		return fmt.Errorf("Too many messages waiting for channel %s", channel)
+	}
+	if len(q.queues[channel]) == 0 {
+		q.channels = append(q.channels, channel)
+	}
+	q.queues[channel] = append(q.queues[channel], data)
+	q.mutex.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// next returns the next message which can be sent or the time until one can be sent.
+// Without queued messages the returned wait time is negative.
+func (q *sendQueue) next() ([]byte, time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(-1)
+	for i, channel := range q.channels {
+		w := q.waitTime(channel, now)
+		if w > 0 {
+			if wait < 0 || w < wait {
+				wait = w
+			}
+			continue
+		}
+
+		data := q.queues[channel][0]
+		q.queues[channel] = q.queues[channel][1:]
+		q.channels = append(q.channels[:i:i], q.channels[i+1:]...)
+		if len(q.queues[channel]) > 0 {
+			// The other channels go first
+			q.channels = append(q.channels, channel)
+		} else {
+			delete(q.queues, channel)
+		}
+		q.record(channel, now)
+		return data, 0
+	}
+	return nil, wait
+}
+
+func (q *sendQueue) run() {
+	defer q.wg.Done()
+
+	for {
+		data, wait := q.next()
+		if data != nil {
+			if err := q.send(data); err != nil {
+				log.Errorf("Could not send queued message: %s", err)
+			}
+			continue
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-q.done:
+		case <-q.notify:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-q.done:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package twitch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/storage"
+)
+
+type sendRecorder struct {
+	mutex sync.Mutex
+	sent  []string
+}
+
+func (r *sendRecorder) send(data []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sent = append(r.sent, string(data))
+	return nil
+}
+
+func (r *sendRecorder) messages() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.sent...)
+}
+
+func setRateLimits(t *testing.T, window time.Duration, messages int, modMessages int, interval time.Duration) {
+	oldWindow, oldMessages, oldModMessages, oldInterval := rateLimitWindow, rateLimitMessages, rateLimitModMessages, channelMessageInterval
+	rateLimitWindow, rateLimitMessages, rateLimitModMessages, channelMessageInterval = window, messages, modMessages, interval
+	t.Cleanup(func() {
+		rateLimitWindow, rateLimitMessages, rateLimitModMessages, channelMessageInterval = oldWindow, oldMessages, oldModMessages, oldInterval
+	})
+}
+
+func TestSendQueue_ChannelInterval(t *testing.T) {
+	assert := assert.New(t)
+	setRateLimits(t, time.Second, 20, 100, 50*time.Millisecond)
+
+	recorder := &sendRecorder{}
+	q := newSendQueue(recorder.send)
+	q.start()
+	defer q.stop()
+
+	assert.NoError(q.enqueue("dallas", []byte("1")))
+	assert.NoError(q.enqueue("#dallas", []byte("2")))
+	assert.NoError(q.enqueue("#other", []byte("3")))
+	assert.Equal([]string{"1", "3"}, recorder.messages())
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal([]string{"1", "3", "2"}, recorder.messages())
+
+	q.setModerator("#dallas", true)
+	assert.NoError(q.enqueue("#dallas", []byte("4")))
+	assert.NoError(q.enqueue("#dallas", []byte("5")))
+	assert.Equal([]string{"1", "3", "2", "4", "5"}, recorder.messages())
+}
+
+func TestSendQueue_GlobalLimit(t *testing.T) {
+	assert := assert.New(t)
+	setRateLimits(t, 100*time.Millisecond, 1, 2, 0)
+
+	recorder := &sendRecorder{}
+	q := newSendQueue(recorder.send)
+	q.setModerator("#dallas", true)
+	q.start()
+	defer q.stop()
+
+	assert.NoError(q.enqueue("#dallas", []byte("1")))
+	assert.NoError(q.enqueue("#other", []byte("2")))
+	assert.NoError(q.enqueue("#dallas", []byte("3")))
+	assert.NoError(q.enqueue("#dallas", []byte("4")))
+	assert.Equal([]string{"1", "3"}, recorder.messages())
+
+	time.Sleep(250 * time.Millisecond)
+	assert.ElementsMatch([]string{"1", "3", "2", "4"}, recorder.messages())
+}
+
+func TestSendQueue_Full(t *testing.T) {
+	assert := assert.New(t)
+	setRateLimits(t, time.Hour, 1, 1, 0)
+
+	recorder := &sendRecorder{}
+	q := newSendQueue(recorder.send)
+	q.start()
+
+	assert.NoError(q.enqueue("#dallas", []byte("sent")))
+	for i := 0; i < maxQueuedMessages; i++ {
+		assert.NoError(q.enqueue("#dallas", []byte("queued")))
+	}
+	assert.Error(q.enqueue("#dallas", []byte("dropped")))
+
+	q.stop()
+	assert.Equal([]string{"sent"}, recorder.messages())
+	assert.Equal(0, len(q.channels))
+}
+
+// reconnectingClient fails every read to simulate a lost connection.
+type reconnectingClient struct {
+	recorder *sendRecorder
+
+	mutex sync.Mutex
+	dials int
+}
+
+func (c *reconnectingClient) Dial(wsURL string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.dials++
+	return nil
+}
+
+func (c *reconnectingClient) dialCount() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.dials
+}
+
+func (c *reconnectingClient) Close() error { return nil }
+
+func (c *reconnectingClient) ReadMessage() (int, []byte, error) {
+	time.Sleep(time.Millisecond)
+	return 0, nil, errors.New("connection lost")
+}
+
+func (c *reconnectingClient) SendMessage(messageType int, data []byte) error {
+	return c.recorder.send(data)
+}
+
+func (c *reconnectingClient) SendJSONMessage(v interface{}) error { return nil }
+
+func TestBot_Reconnect(t *testing.T) {
+	oldMin, oldMax, oldBatchSize, oldBatchInterval := minReconnectWait, maxReconnectWait, joinBatchSize, joinBatchInterval
+	minReconnectWait, maxReconnectWait, joinBatchSize, joinBatchInterval = time.Millisecond, 4*time.Millisecond, 1, time.Millisecond
+	defer func() {
+		minReconnectWait, maxReconnectWait, joinBatchSize, joinBatchInterval = oldMin, oldMax, oldBatchSize, oldBatchInterval
+	}()
+
+	client := &reconnectingClient{recorder: &sendRecorder{}}
+	cfg := botconfig.TwitchConfig{Username: "bot", Channels: []string{"dallas", "other"}}
+	bot, err := CreateTwitchBot(cfg, &storage.MockStorage{}, &commanddispatcher.CommandDispatcher{}, client)
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+
+	bot.done = make(chan struct{})
+	if err := bot.openWebSocketConnection(); err != nil {
+		t.Fatalf("Connecting should not have failed: %s", err)
+	}
+	bot.wg.Add(1)
+	go bot.run()
+
+	time.Sleep(100 * time.Millisecond)
+	close(bot.done)
+	bot.wg.Wait()
+
+	if client.dialCount() < 3 {
+		t.Fatalf("Expected the bot to reconnect several times, dialed %d times", client.dialCount())
+	}
+	joins := 0
+	for _, message := range client.recorder.messages() {
+		if message == "JOIN #dallas" || message == "JOIN #other" {
+			joins++
+		}
+	}
+	if joins < 2*client.dialCount()-2 {
+		t.Fatalf("Expected the channels to be joined again after reconnecting, got %d JOINs for %d connections", joins, client.dialCount())
+	}
+}
@@ -0,0 +1,60 @@
+package twitch
+
+import (
+	"strings"
+
+	"gopkg.in/irc.v3"
+
+	"github.com/torlenor/redseligg/model"
+)
+
+// parseBadges parses the badges tag, e.g., "broadcaster/1,subscriber/12", into a map of badge to version.
+func parseBadges(value string) map[string]string {
+	badges := make(map[string]string)
+	for _, badge := range strings.Split(value, ",") {
+		if len(badge) == 0 {
+			continue
+		}
+		parts := strings.SplitN(badge, "/", 2)
+		if len(parts) == 2 {
+			badges[parts[0]] = parts[1]
+		} else {
+			badges[parts[0]] = ""
+		}
+	}
+	return badges
+}
+
+// convertUser returns the sender of the message. With the twitch.tv/tags capability the user ID,
+// display name and badges are taken from the IRCv3 tags, all tags are available in the Extras of the user.
+// Without tags the login name is used as ID.
+func convertUser(message *irc.Message) model.User {
+	user := model.User{ID: message.User, Name: message.User}
+	if login, ok := message.GetTag("login"); ok && len(login) > 0 {
+		user.Name = login
+	}
+	if len(message.Tags) == 0 {
+		return user
+	}
+
+	user.Extras = make(map[string]string, len(message.Tags))
+	for key := range message.Tags {
+		user.Extras[key], _ = message.GetTag(key)
+	}
+
+	if id := user.Extras["user-id"]; len(id) > 0 {
+		user.ID = id
+	}
+	user.Nickname = user.Extras["display-name"]
+
+	badges := parseBadges(user.Extras["badges"])
+	// The broadcaster badge is only valid in the channel of the message, so the broadcaster is a mod
+	// there and not mapped to IsOwner, which would make every broadcaster an admin of the whole bot.
+	_, broadcaster := badges["broadcaster"]
+	_, moderator := badges["moderator"]
+	// Twitch staff and admins (user-type) are not mapped to IsAdmin, it is a Twitch-wide badge and
+	// no role in the channel. It is still available in the Extras.
+	user.IsMod = moderator || broadcaster || user.Extras["mod"] == "1"
+
+	return user
+}
@@ -0,0 +1,133 @@
+package twitch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/irc.v3"
+
+	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+	"github.com/torlenor/redseligg/permissions"
+	"github.com/torlenor/redseligg/storage"
+	"github.com/torlenor/redseligg/ws"
+)
+
+func Test_parseBadges(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(map[string]string{}, parseBadges(""))
+	assert.Equal(map[string]string{"broadcaster": "1", "subscriber": "12", "glhf-pledge": ""}, parseBadges("broadcaster/1,subscriber/12,glhf-pledge"))
+}
+
+func Test_convertUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    model.User
+	}{
+		{
+			name:    "Message without tags",
+			message: ":ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #dallas :Kappa",
+			want:    model.User{ID: "ronni", Name: "ronni"},
+		},
+		{
+			name:    "Message of a viewer",
+			message: "@badge-info=;badges=premium/1;color=#0D4200;display-name=Ronni;emotes=;id=b34ccfc7-4977-403a-8a94-33c6bac34fb8;mod=0;room-id=1337;subscriber=0;tmi-sent-ts=1507246572675;turbo=0;user-id=1234567;user-type= :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #dallas :Kappa",
+			want: model.User{ID: "1234567", Name: "ronni", Nickname: "Ronni", Extras: map[string]string{
+				"badge-info": "", "badges": "premium/1", "color": "#0D4200", "display-name": "Ronni", "emotes": "",
+				"id": "b34ccfc7-4977-403a-8a94-33c6bac34fb8", "mod": "0", "room-id": "1337", "subscriber": "0",
+				"tmi-sent-ts": "1507246572675", "turbo": "0", "user-id": "1234567", "user-type": "",
+			}},
+		},
+		{
+			name:    "Message of a moderator",
+			message: "@badges=moderator/1,subscriber/12;display-name=Mod\\sName;mod=1;user-id=42;user-type=mod :mod!mod@mod.tmi.twitch.tv PRIVMSG #dallas :hi",
+			want: model.User{ID: "42", Name: "mod", Nickname: "Mod Name", IsMod: true, Extras: map[string]string{
+				"badges": "moderator/1,subscriber/12", "display-name": "Mod Name", "mod": "1", "user-id": "42", "user-type": "mod",
+			}},
+		},
+		{
+			name:    "Message of the broadcaster",
+			message: "@badges=broadcaster/1;display-name=Dallas;mod=0;user-id=1337;user-type=staff :dallas!dallas@dallas.tmi.twitch.tv PRIVMSG #dallas :hi",
+			want: model.User{ID: "1337", Name: "dallas", Nickname: "Dallas", IsMod: true, Extras: map[string]string{
+				"badges": "broadcaster/1", "display-name": "Dallas", "mod": "0", "user-id": "1337", "user-type": "staff",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertUser(irc.MustParseMessage(tt.message)))
+		})
+	}
+}
+
+type mockPoster struct {
+	lastPost model.Post
+}
+
+func (m *mockPoster) CreatePost(post model.Post) (model.PostResponse, error) {
+	m.lastPost = post
+	return model.PostResponse{}, nil
+}
+
+type mockReceiver struct {
+	calls int
+}
+
+func (m *mockReceiver) OnCommand(cmd string, content string, post model.Post) {
+	m.calls++
+}
+
+func TestBroadcasterPermissions(t *testing.T) {
+	assert := assert.New(t)
+
+	dispatcher := commanddispatcher.New("!")
+	poster := &mockPoster{}
+	dispatcher.SetPoster(poster)
+
+	m := permissions.New("BOT", botconfig.PermissionsConfig{Commands: map[string]string{"quote": permissions.RoleMod}}, nil)
+	m.RegisterCommands(dispatcher)
+
+	receiver := &mockReceiver{}
+	dispatcher.Register("quote", receiver, commanddispatcher.CommandHelp{})
+
+	post := func(channel string, badges string, content string) model.Post {
+		message := irc.MustParseMessage("@badges=" + badges + ";display-name=Dallas;user-id=1337 :dallas!dallas@dallas.tmi.twitch.tv PRIVMSG #" + channel + " :" + content)
+		return model.Post{ChannelID: channel, Channel: channel, User: convertUser(message), Content: content}
+	}
+
+	// The broadcaster of channel a is a mod in channel a ...
+	dispatcher.OnPost(post("a", "broadcaster/1", "!quote"))
+	assert.Equal(1, receiver.calls)
+
+	// ... but not an admin, neither in channel a nor in channel b.
+	dispatcher.OnPost(post("a", "broadcaster/1", "!role grant @dallas admin"))
+	dispatcher.OnPost(post("b", "", "!role grant @dallas admin"))
+	dispatcher.OnPost(post("b", "", "!role grant @dallas mod --here"))
+	assert.Equal(model.Post{}, poster.lastPost)
+	assert.Empty(m.Grants())
+
+	// In channel b the broadcaster of channel a has no badge and is no mod.
+	dispatcher.OnPost(post("b", "", "!quote"))
+	assert.Equal(1, receiver.calls)
+}
+
+func TestBot_addUser(t *testing.T) {
+	assert := assert.New(t)
+
+	bot, err := CreateTwitchBot(botconfig.TwitchConfig{}, &storage.MockStorage{}, &commanddispatcher.CommandDispatcher{}, &ws.MockClient{})
+	if err != nil {
+		t.Fatalf("Creating the bot should not have failed")
+	}
+
+	bot.addKnownUser("ronni")
+	bot.addUser(model.User{ID: "1234567", Name: "ronni"})
+	bot.addKnownUser("ronni")
+
+	users, _ := bot.GetUsers()
+	assert.Equal([]model.User{{ID: "1234567", Name: "ronni"}}, users)
+
+	assert.Equal("hi ronni and <@7654321>", bot.replaceKnownUserIDs("hi <@1234567> and <@7654321>"))
+}
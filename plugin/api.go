@@ -1,6 +1,9 @@
 package plugin
 
 import (
+	"time"
+
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 )
@@ -18,10 +21,24 @@ type API interface {
 	GetStorage() storage.Storage
 
 	// RegisterCommand registers a custom slash "/" or "!" command, depending on what the bot supports.
-	RegisterCommand(p Hooks, command string) error
+	// The help is shown to the users via the auto-generated help command.
+	// If another plugin already registered the command, both receive it and an error describing the conflict is returned.
+	RegisterCommand(p Hooks, command string, help commanddispatcher.CommandHelp) error
+
+	// RegisterCommandSpec registers a command with a declarative description of its arguments.
+	// The plugin receives the parsed arguments via OnParsedCommand and invalid input is answered
+	// with a usage error automatically.
+	RegisterCommandSpec(p Hooks, spec commanddispatcher.CommandSpec) error
+
+	// UnRegisterCommand unregisters a command previously registered by the plugin via RegisterCommand.
+	UnRegisterCommand(p Hooks, command string) error
+
+	// RegisterTrigger registers a trigger on mentions of the bot, regular expressions or keywords.
+	// The plugin receives matching posts which are not commands via OnTrigger.
+	RegisterTrigger(p Hooks, trigger commanddispatcher.Trigger) error
 
-	// UnRegisterCommand unregisters a command previously registered via RegisterCommand.
-	UnRegisterCommand(command string) error
+	// UnRegisterTrigger unregisters a trigger previously registered by the plugin via RegisterTrigger.
+	UnRegisterTrigger(p Hooks, name string) error
 
 	// GetCallPrefix returns the current command call prefix.
 	GetCallPrefix() string
@@ -54,6 +71,22 @@ type API interface {
 	// Currently such a messageID is supplied in CreatePost calls when the platform supports it.
 	DeletePost(messageID model.MessageIdentifier) (model.PostResponse, error)
 
+	// TimeoutUser prevents the user from writing in the channel for the given duration.
+	// Platforms which support it provide the feature FEATURE_MODERATION_TIMEOUT.
+	TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error
+
+	// BanUser bans the user from the channel, on some platforms from the whole server the channel belongs to.
+	// Platforms which support it provide the feature FEATURE_MODERATION_BAN.
+	BanUser(channelID string, userID string, reason string) error
+
+	// ClearChat removes all messages from the channel.
+	// Platforms which support it provide the feature FEATURE_MODERATION_CLEAR_CHAT.
+	ClearChat(channelID string) error
+
+	// SetSlowMode sets the time users have to wait between two messages in the channel, zero disables it.
+	// Platforms which support it provide the feature FEATURE_MODERATION_SLOW_MODE.
+	SetSlowMode(channelID string, interval time.Duration) error
+
 	// GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
 	// If the reaction is unknown returns an error.
 	GetReaction(reactionName string) (string, error)
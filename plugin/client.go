@@ -3,6 +3,7 @@ package plugin
 import (
 	"fmt"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
@@ -17,6 +18,8 @@ type RedseliggPlugin struct {
 
 	BotID    string
 	PluginID string
+
+	commandNames commanddispatcher.CommandNames
 }
 
 // SetAPI gives the API interface to the plugin.
@@ -38,6 +41,16 @@ func (p *RedseliggPlugin) SetBotPluginID(botID string, pluginID string) {
 	p.PluginID = pluginID
 }
 
+// SetCommandNames sets the names under which the commands of the plugin are available.
+func (p *RedseliggPlugin) SetCommandNames(names commanddispatcher.CommandNames) {
+	p.commandNames = names
+}
+
+// CommandNames returns the names under which the commands of the plugin are available.
+func (p *RedseliggPlugin) CommandNames() commanddispatcher.CommandNames {
+	return p.commandNames
+}
+
 // Default hook implementations (see hooks.go)
 
 // PluginType returns the plugin type
@@ -55,8 +68,40 @@ func (p *RedseliggPlugin) OnPost(post model.Post) {}
 // OnCommand in its default implementation.
 func (p *RedseliggPlugin) OnCommand(cmd string, content string, post model.Post) {}
 
+// OnParsedCommand in its default implementation.
+func (p *RedseliggPlugin) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
+}
+
+// OnTrigger in its default implementation.
+func (p *RedseliggPlugin) OnTrigger(name string, match commanddispatcher.TriggerMatch, post model.Post) {
+}
+
+// OnPostUpdated in its default implementation.
+func (p *RedseliggPlugin) OnPostUpdated(model.MessageIdentifier, model.Post) {}
+
+// OnPostDeleted in its default implementation.
+func (p *RedseliggPlugin) OnPostDeleted(model.MessageIdentifier) {}
+
 // OnReactionAdded in its default implementation.
 func (p *RedseliggPlugin) OnReactionAdded(model.Reaction) {}
 
 // OnReactionRemoved in its default implementation.
 func (p *RedseliggPlugin) OnReactionRemoved(model.Reaction) {}
+
+// OnUserJoined in its default implementation.
+func (p *RedseliggPlugin) OnUserJoined(model.Membership) {}
+
+// OnUserLeft in its default implementation.
+func (p *RedseliggPlugin) OnUserLeft(model.Membership) {}
+
+// OnSubscription in its default implementation.
+func (p *RedseliggPlugin) OnSubscription(model.Subscription) {}
+
+// OnRaid in its default implementation.
+func (p *RedseliggPlugin) OnRaid(model.Raid) {}
+
+// OnCheer in its default implementation.
+func (p *RedseliggPlugin) OnCheer(model.Cheer) {}
+
+// OnChannelStateChanged in its default implementation.
+func (p *RedseliggPlugin) OnChannelStateChanged(model.ChannelState) {}
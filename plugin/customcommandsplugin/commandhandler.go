@@ -4,9 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
-	"strings"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 	"github.com/torlenor/redseligg/storagemodels"
@@ -16,6 +16,8 @@ var now = time.Now
 
 var errNotExist = errors.New("Timed message does not exist")
 
+var customCommandRegexp = regexp.MustCompile(`^[a-zA-Z]+$`)
+
 const (
 	identField = "customCommands"
 )
@@ -45,6 +47,13 @@ func (p *CustomCommandsPlugin) storeCommands(data storagemodels.CustomCommandsPl
 	return nil
 }
 
+func customCommandHelp(customCommand string) commanddispatcher.CommandHelp {
+	return commanddispatcher.CommandHelp{
+		Description: "Custom command",
+		Usage:       []string{customCommand},
+	}
+}
+
 func (p *CustomCommandsPlugin) addCommand(channelID, customCommand string, message string) error {
 	commands, err := p.getCommands()
 	if err != nil && err != storage.ErrNotFound {
@@ -70,7 +79,7 @@ func (p *CustomCommandsPlugin) addCommand(channelID, customCommand string, messa
 		p.API.LogTrace(fmt.Sprintf("Updated custom command '%s' with message '%s' for channel %s", customCommand, message, channelID))
 	}
 
-	p.API.RegisterCommand(p, customCommand)
+	p.API.RegisterCommand(p, customCommand, customCommandHelp(customCommand))
 
 	return p.storeCommands(commands)
 }
@@ -98,65 +107,23 @@ func (p *CustomCommandsPlugin) removeCommand(channelID, customCommand string) er
 		return errNotExist
 	}
 
-	p.API.UnRegisterCommand(customCommand)
+	p.API.UnRegisterCommand(p, customCommand)
 
 	return p.storeCommands(commands)
 }
 
-func splitCommand(text string) (c string, customCommand string, msg string, err error) {
-	var re = regexp.MustCompile(`(?m)^+(add|remove) +([a-zA-Z]+) *(.*)$`)
-
-	const cgCommand = 1
-	const cgCustomCommand = 2
-	const cgMessage = 3
-
-	matches := re.FindAllStringSubmatch(strings.Trim(text, " "), -1)
-
-	if matches == nil || len(matches) < 1 {
-		err = errors.New("Not a valid command")
-		return
-	}
-	if len(matches[0]) > cgCustomCommand {
-		switch matches[0][cgCommand] {
-		case "add":
-			if len(matches[0]) > cgMessage && len(matches[0][cgMessage]) > 0 {
-				msg = matches[0][cgMessage]
-			} else {
-				err = errors.New("Not a valid command: No message provided")
-				return
-			}
-		case "remove":
-			// do nothing
-		default:
-			err = errors.New("Not a valid command. Wrong command: Only add and remove are supported")
-			return
-		}
-		c = matches[0][cgCommand]
-		customCommand = matches[0][cgCustomCommand]
-	} else {
-		err = errors.New("Not a valid command")
-	}
-
-	return
-}
-
 // onCommand handles a customcommand command.
-func (p *CustomCommandsPlugin) onCommand(content string, post model.Post) {
-	if content == "add" {
-		p.returnHelpAdd(post.ChannelID)
-		return
-	} else if content == "remove" {
-		p.returnHelpRemove(post.ChannelID)
-		return
-	}
+func (p *CustomCommandsPlugin) onCommand(args commanddispatcher.Arguments, post model.Post) {
+	c := args.Subcommand
+	customCommand := args.String("customCommand")
+	message := args.String("message")
 
-	c, customCommand, message, err := splitCommand(content)
-	if err != nil {
-		p.API.LogError(fmt.Sprintf("Error parsing %scustomcommand command '%s': %s", p.API.GetCallPrefix(), content, err))
-		p.returnHelp(post.ChannelID)
+	if !customCommandRegexp.MatchString(customCommand) {
+		p.returnMessage(post.ChannelID, fmt.Sprintf("Not a valid custom command '%s'. Only letters are allowed.", customCommand))
 		return
 	}
 
+	var err error
 	switch c {
 	case "add":
 		err = p.addCommand(post.ChannelID, customCommand, message)
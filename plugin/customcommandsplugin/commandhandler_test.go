@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-func Test_splitCommand(t *testing.T) {
+func Test_commandSpec(t *testing.T) {
 
 	customCommand := "SomeCommand"
 
@@ -53,19 +53,19 @@ func Test_splitCommand(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotC, gotCustomCommand, gotMsg, err := splitCommand(tt.args.text)
+			args, err := commandSpec.Parse(tt.args.text)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("splitCommand() test = %s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				t.Errorf("commandSpec.Parse() test = %s error = %v, wantErr %v", tt.name, err, tt.wantErr)
 				return
 			}
-			if gotC != tt.wantC {
-				t.Errorf("splitCommand() test = %s gotC = %v, want %v", tt.name, gotC, tt.wantC)
+			if gotC := args.Subcommand; gotC != tt.wantC {
+				t.Errorf("commandSpec.Parse() test = %s gotC = %v, want %v", tt.name, gotC, tt.wantC)
 			}
-			if gotCustomCommand != tt.wantCustomCommand {
-				t.Errorf("splitCommand() test = %s gotCustomCommand = %v, want %v", tt.name, gotCustomCommand, tt.wantCustomCommand)
+			if gotCustomCommand := args.String("customCommand"); gotCustomCommand != tt.wantCustomCommand {
+				t.Errorf("commandSpec.Parse() test = %s gotCustomCommand = %v, want %v", tt.name, gotCustomCommand, tt.wantCustomCommand)
 			}
-			if gotMsg != tt.wantMsg {
-				t.Errorf("splitCommand() test = %s gotMsg = %v, want %v", tt.name, gotMsg, tt.wantMsg)
+			if gotMsg := args.String("message"); gotMsg != tt.wantMsg {
+				t.Errorf("commandSpec.Parse() test = %s gotMsg = %v, want %v", tt.name, gotMsg, tt.wantMsg)
 			}
 		})
 	}
@@ -1,8 +1,8 @@
 package customcommandsplugin
 
 import (
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
-	"github.com/torlenor/redseligg/utils"
 )
 
 // OnRun is called when the platform is ready
@@ -19,29 +19,30 @@ func (p *CustomCommandsPlugin) OnRun() {
 		return
 	}
 	for _, command := range commands.Commands {
-		p.API.RegisterCommand(p, command.Command)
+		p.API.RegisterCommand(p, command.Command, customCommandHelp(command.Command))
 	}
 
-	p.API.RegisterCommand(p, "customcommand")
+	p.API.RegisterCommandSpec(p, commandSpec)
 }
 
-// OnCommand implements the hook from the Bot
-func (p *CustomCommandsPlugin) OnCommand(cmd string, content string, post model.Post) {
+// OnParsedCommand implements the hook from the Bot
+func (p *CustomCommandsPlugin) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
 	if post.IsPrivate {
 		return
 	}
 
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
-		if cmd == "customcommand" && len(content) > 0 {
-			p.onCommand(content, post)
-			return
-		} else if cmd == "customcommand" {
-			p.returnHelp(post.ChannelID)
-			return
-		}
+	if !p.cfg.OnlyMods || post.User.IsMod {
+		p.onCommand(args, post)
 	} else {
 		p.API.LogDebug("Not parsing as command, because User " + post.User.Name + " is not part of mods")
 	}
+}
+
+// OnCommand implements the hook from the Bot
+func (p *CustomCommandsPlugin) OnCommand(cmd string, content string, post model.Post) {
+	if post.IsPrivate {
+		return
+	}
 
 	p.onCustomCommand(cmd, post)
 }
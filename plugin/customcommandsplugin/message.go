@@ -0,0 +1,13 @@
+package customcommandsplugin
+
+import (
+	"github.com/torlenor/redseligg/model"
+)
+
+func (p *CustomCommandsPlugin) returnMessage(channelID, msg string) {
+	post := model.Post{
+		ChannelID: channelID,
+		Content:   msg,
+	}
+	p.API.CreatePost(post)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin"
@@ -12,9 +13,32 @@ import (
 )
 
 const (
-	PLUGIN_TYPE = "customcommands"
+	PLUGIN_TYPE    = "customcommands"
+	PLUGIN_COMMAND = "customcommand"
 )
 
+var commandSpec = commanddispatcher.CommandSpec{
+	Command:     PLUGIN_COMMAND,
+	Description: "Adds or removes custom commands which answer with a fixed message",
+	Examples: []string{
+		"customcommand add hello Hello World!",
+		"customcommand remove hello",
+	},
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command: "add",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "customCommand", Type: commanddispatcher.ArgString},
+				{Name: "message", Type: commanddispatcher.ArgText},
+			},
+		},
+		{
+			Command: "remove",
+			Args:    []commanddispatcher.ArgSpec{{Name: "customCommand", Type: commanddispatcher.ArgString}},
+		},
+	},
+}
+
 // ErrNoValidStorage is set when the provided storage does not implement the correct functions
 var ErrNoValidStorage = errors.New("No valid storage set")
 
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/platform"
@@ -21,6 +22,16 @@ var providedFeatures = map[string]bool{
 
 const command = "customcommand"
 
+// sendCommand feeds the command through a CommandDispatcher, the same way a bot would.
+func sendCommand(p *CustomCommandsPlugin, content string, post model.Post) {
+	dispatcher := commanddispatcher.New("!")
+	dispatcher.SetPoster(p.API)
+	dispatcher.RegisterSpec(commandSpec, p)
+
+	post.Content = "!" + command + " " + content
+	dispatcher.OnPost(post)
+}
+
 func TestCreateCustomCommandsPlugin(t *testing.T) {
 	assert := assert.New(t)
 
@@ -101,27 +112,36 @@ func TestCustomCommandsPlugin_HelpTextAndInvalidCommands(t *testing.T) {
 	}
 
 	api.Reset()
-	postToPlugin.Content = "!" + command
-	expectedPostFromPlugin := model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   fmt.Sprintf(helpText, api.GetCallPrefix(), p.API.GetCallPrefix()),
-		IsPrivate: false,
-	}
-	p.OnCommand(command, "", postToPlugin)
+	expectedPostFromPlugin := postToPlugin
+	expectedPostFromPlugin.Content = "Missing subcommand. Usage: `!customcommand <add|remove>`"
+	sendCommand(p, "", postToPlugin)
+	assert.Equal(true, api.WasCreatePostCalled)
+	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
+
+	api.Reset()
+	expectedPostFromPlugin.Content = "Missing argument <customCommand>. Usage: `!customcommand add <customCommand> <message...>`"
+	sendCommand(p, "add", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	postToPlugin.Content = "!customcommand add"
-	expectedPostFromPlugin.Content = fmt.Sprintf(helpTextAdd, api.GetCallPrefix(), p.API.GetCallPrefix())
-	p.OnCommand(command, "add", postToPlugin)
+	expectedPostFromPlugin.Content = "Missing argument <message>. Usage: `!customcommand add <customCommand> <message...>`"
+	sendCommand(p, "add test", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	postToPlugin.Content = "!customcommand remove"
-	expectedPostFromPlugin.Content = fmt.Sprintf(helpTextRemove, api.GetCallPrefix(), p.API.GetCallPrefix())
-	p.OnCommand(command, "remove", postToPlugin)
+	expectedPostFromPlugin.Content = "Missing argument <customCommand>. Usage: `!customcommand remove <customCommand>`"
+	sendCommand(p, "remove", postToPlugin)
+	assert.Equal(true, api.WasCreatePostCalled)
+	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
+
+	api.Reset()
+	expectedPostFromPlugin = model.Post{
+		ChannelID: "CHANNEL ID",
+		Content:   "Not a valid custom command 'te5t'. Only letters are allowed.",
+	}
+	sendCommand(p, "add te5t some text", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -156,7 +176,7 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand(t *testing.T) {
 		Content:   fmt.Sprintf("Custom command '%s' with message '%s' added.", customCommand, message),
 		IsPrivate: false,
 	}
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -173,7 +193,7 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand(t *testing.T) {
 	postToPlugin.Content = "!customcommand remove " + otherCustomCommand
 	content = "remove " + otherCustomCommand
 	expectedPostFromPlugin.Content = "Custom command to remove does not exist."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -192,7 +212,7 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand(t *testing.T) {
 	postToPlugin.Content = "!customcommand remove " + customCommand
 	content = "remove " + customCommand
 	expectedPostFromPlugin.Content = fmt.Sprintf("Custom command '%s' removed.", customCommand)
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -207,14 +227,14 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand(t *testing.T) {
 	postToPlugin.Content = "!customcommand add " + customCommand + " " + message
 	content = "add " + customCommand + " " + message
 	expectedPostFromPlugin.Content = "Could not add custom command. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	postToPlugin.Content = "!customcommand remove " + customCommand
 	content = "remove " + customCommand
 	expectedPostFromPlugin.Content = "Could not remove custom command. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -265,7 +285,7 @@ func TestCustomCommandsPlugin_UpdateCustomCommand(t *testing.T) {
 		IsPrivate: false,
 	}
 	content := "add " + customCommand + " " + updatedMessage
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -293,7 +313,6 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand_OnlyMods(t *testing.T) {
 	p.BotID = botID
 
 	p.cfg.OnlyMods = true
-	p.cfg.Mods = append(p.cfg.Mods, userName)
 
 	storage := &MockStorage{}
 	api := plugin.MockAPI{Storage: storage}
@@ -318,12 +337,13 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand_OnlyMods(t *testing.T) {
 		IsPrivate: false,
 	}
 	content := "add " + customCommand + " " + message
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -349,16 +369,18 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand_OnlyMods(t *testing.T) {
 
 	api.Reset()
 	postToPlugin.User.Name = " some other user"
+	postToPlugin.User.IsMod = false
 	postToPlugin.Content = "!customcommand remove " + customCommand
 	content = "remove " + customCommand
 	expectedPostFromPlugin.Content = fmt.Sprintf("Custom command '%s' removed.", customCommand)
 
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -373,14 +395,14 @@ func TestCustomCommandsPlugin_AddAndRemoveCustomCommand_OnlyMods(t *testing.T) {
 	postToPlugin.Content = "!customcommand add " + customCommand + " " + message
 	content = "add " + customCommand + " " + message
 	expectedPostFromPlugin.Content = "Could not add custom command. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	postToPlugin.Content = "!customcommand remove " + customCommand
 	content = "remove " + customCommand
 	expectedPostFromPlugin.Content = "Could not remove custom command. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -504,7 +526,7 @@ func TestCustomCommandsPlugin_NoStorage(t *testing.T) {
 		IsPrivate: false,
 	}
 	content := "add " + customCommand + " " + message
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -3,12 +3,17 @@ package echoplugin
 import (
 	"strings"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
 // OnRun implements the hook from the Boot
 func (p *EchoPlugin) OnRun() {
-	p.API.RegisterCommand(p, "echo")
+	p.API.RegisterCommand(p, "echo", commanddispatcher.CommandHelp{
+		Description: "Echoes the given text",
+		Usage:       []string{"echo <text>"},
+		Examples:    []string{"echo Hello World!"},
+	})
 }
 
 // OnCommand implements the hook from the Bot
@@ -2,26 +2,12 @@ package giveawayplugin
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
-func parseTimeStringToDuration(timeStr string) (time.Duration, error) {
-	duration, err := time.ParseDuration(timeStr)
-	if err != nil {
-		return time.Duration(0), fmt.Errorf("Giveaway duration invalid")
-	}
-
-	return duration, nil
-}
-
-func (p *GiveawayPlugin) returnHelp(channelID string) {
-	p.returnMessage(channelID, fmt.Sprintf("Type `%s%s start <time> <secretword> [winners] [prize]` to start a new giveaway.", p.API.GetCallPrefix(), command))
-}
-
 func (p *GiveawayPlugin) returnMessage(channelID, msg string) {
 	post := model.Post{
 		ChannelID: channelID,
@@ -30,40 +16,16 @@ func (p *GiveawayPlugin) returnMessage(channelID, msg string) {
 	p.API.CreatePost(post)
 }
 
-func (p *GiveawayPlugin) onCommandGStart(content string, post model.Post) {
-	args := strings.Split(content, " ")
-
-	if len(args) < 2 {
-		p.returnHelp(post.ChannelID)
-		return
-	}
-
-	timeStr := args[0]
-	word := args[1]
-
-	duration, err := parseTimeStringToDuration(timeStr)
-	if err != nil {
-		p.returnHelp(post.ChannelID)
-		return
-	}
+func (p *GiveawayPlugin) onCommandGStart(args commanddispatcher.Arguments, post model.Post) {
+	duration := args.Duration("time")
+	word := args.String("secretword")
 
 	winners := 1
-	if len(args) > 2 {
-		if val, err := strconv.Atoi(args[2]); err == nil {
-			winners = val
-		} else {
-			p.returnHelp(post.ChannelID)
-			return
-		}
+	if args.Has("winners") {
+		winners = args.Int("winners")
 	}
 
-	prize := []string{}
-	if len(args) > 3 {
-		for _, arg := range args[3:] {
-			prize = append(prize, arg)
-		}
-	}
-	prizeStr := strings.Join(prize, " ")
+	prizeStr := args.String("prize")
 
 	p.giveawaysMutex.Lock()
 	defer p.giveawaysMutex.Unlock()
@@ -1,102 +1,33 @@
 package giveawayplugin
 
 import (
-	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/torlenor/redseligg/botconfig"
 )
 
 func Test_parseConfig(t *testing.T) {
-	mods := []string{"user1", "user2"}
-
-	type args struct {
-		c botconfig.PluginConfig
-	}
 	tests := []struct {
 		name    string
-		args    args
+		config  map[string]interface{}
 		want    config
-		wantErr bool
+		wantErr string
 	}{
-		{
-			name: "Valid config, empty",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "giveaway",
-				},
-			},
-			want: config{},
-		},
-		{
-			name: "Valid config, with mods",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "giveaway",
-					Config: map[string]interface{}{
-						"mods": mods,
-					},
-				},
-			},
-			want: config{Mods: mods},
-		},
-		{
-			name: "Valid config, with only one mod in list",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "giveaway",
-					Config: map[string]interface{}{
-						"mods": []string{"user1"},
-					},
-				},
-			},
-			want: config{Mods: []string{"user1"}},
-		},
-		{
-			name: "Valid config, with mods and onlymods = true",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "giveaway",
-					Config: map[string]interface{}{
-						"mods":     mods,
-						"onlymods": true,
-					},
-				},
-			},
-			want: config{Mods: mods, OnlyMods: true},
-		},
-		{
-			name: "Invalid config, wrong type",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "something",
-				},
-			},
-			wantErr: true,
-		},
-		{
-			name: "Valid config, with mods and onlymods = true",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "giveaway",
-					Config: map[string]interface{}{
-						"onlymods": true,
-					},
-				},
-			},
-			wantErr: true,
-		},
+		{name: "Everyone holds giveaways", want: config{}},
+		{name: "Only mods hold giveaways", config: map[string]interface{}{"onlymods": true}, want: config{OnlyMods: true}},
+		// The old list of mods must not fail the config, the users get the mod role of the bot instead
+		{name: "Old mods list", config: map[string]interface{}{"onlymods": true, "mods": []interface{}{"user1"}}, want: config{OnlyMods: true}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseConfig(tt.args.c)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseConfig() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseConfig() = %v, want %v", got, tt.want)
-			}
+			got, err := parseConfig(botconfig.PluginConfig{Type: "giveaway", Config: tt.config})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
 		})
 	}
+
+	_, err := parseConfig(botconfig.PluginConfig{Type: "vote"})
+	assert.EqualError(t, err, "Not a Giveaway Plugin config")
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/torlenor/redseligg/model"
@@ -32,6 +33,16 @@ func (r *mockRandomizer) Shuffle(arg int, swap func(i, j int)) {
 	r.Argument = arg
 }
 
+// sendCommand feeds the command through a CommandDispatcher, the same way a bot would.
+func sendCommand(p *GiveawayPlugin, content string, post model.Post) {
+	dispatcher := commanddispatcher.New("!")
+	dispatcher.SetPoster(p.API)
+	dispatcher.RegisterSpec(commandSpec, p)
+
+	post.Content = "!" + commandGiveaway + " " + content
+	dispatcher.OnPost(post)
+}
+
 func TestCreateGiveawayPlugin(t *testing.T) {
 	assert := assert.New(t)
 
@@ -65,64 +76,47 @@ func TestGiveawayPluginHelpTextAndInvalidCommands(t *testing.T) {
 		IsPrivate: false,
 	}
 
-	expectedHelpMessage := "Type `!giveaway start <time> <secretword> [winners] [prize]` to start a new giveaway."
-
 	api.Reset()
 	postToPlugin.Content = contentCommandHelp
 	content := "help"
-	expectedPostFromPlugin := model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   expectedHelpMessage,
-		IsPrivate: false,
-	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	expectedPostFromPlugin := postToPlugin
+	expectedPostFromPlugin.Content = commandSpec.Help().Text("!", command)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
 	postToPlugin.Content = contentCommandStart + "    "
-	expectedPostFromPlugin = model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   expectedHelpMessage,
-		IsPrivate: false,
-	}
-	p.OnCommand(commandGiveaway, "start", postToPlugin)
+	expectedPostFromPlugin = postToPlugin
+	expectedPostFromPlugin.Content = "Missing argument <time>. Usage: `!giveaway start <time> <secretword> [winners] [prize...]`"
+	sendCommand(p, "start", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
 	postToPlugin.Content = contentCommandStart + " 1m"
 	content = "start 1m"
-	expectedPostFromPlugin = model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   expectedHelpMessage,
-		IsPrivate: false,
-	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	expectedPostFromPlugin = postToPlugin
+	expectedPostFromPlugin.Content = "Missing argument <secretword>. Usage: `!giveaway start <time> <secretword> [winners] [prize...]`"
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
 	postToPlugin.Content = contentCommandStart + " 1kk hello"
 	content = "start 1kk hello"
-	expectedPostFromPlugin = model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   expectedHelpMessage,
-		IsPrivate: false,
-	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	expectedPostFromPlugin = postToPlugin
+	expectedPostFromPlugin.Content = "Argument <time> is not a valid duration. Usage: `!giveaway start <time> <secretword> [winners] [prize...]`"
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
 	postToPlugin.Content = contentCommandStart + " 1m hello k"
 	content = "start 1m hello k"
-	expectedPostFromPlugin = model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   expectedHelpMessage,
-		IsPrivate: false,
-	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	expectedPostFromPlugin = postToPlugin
+	expectedPostFromPlugin.Content = "Argument <winners> is not a valid number. Usage: `!giveaway start <time> <secretword> [winners] [prize...]`"
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -130,7 +124,7 @@ func TestGiveawayPluginHelpTextAndInvalidCommands(t *testing.T) {
 	postToPlugin.Content = contentCommandStart + " 1m hello"
 	content = "start 1m hello"
 	postToPlugin.IsPrivate = true
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 }
 
@@ -162,7 +156,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "No giveaway running. Use `!giveaway start` command to start a new one.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -175,7 +169,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -186,7 +180,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "Cannot pick a winner. There were no participants to the giveaway.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -199,7 +193,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -224,7 +218,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "Giveaway already running.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -235,7 +229,7 @@ func TestGiveawayPluginCreateAndEndGiveaway(t *testing.T) {
 		Content:   "The winner(s) is/are <@" + userPostToPlugin.User.ID + ">. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -272,7 +266,7 @@ func TestGiveawayPluginCreateAndAutomaticEndGiveaway(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -315,7 +309,6 @@ func TestGiveawayPluginCreateAndEndGiveawayOnlyMods(t *testing.T) {
 	notAllowedUser := "User 1"
 
 	p.cfg.OnlyMods = true
-	p.cfg.Mods = []string{allowedUser}
 
 	randomizer := &mockRandomizer{}
 	p.randomizer = randomizer
@@ -335,17 +328,17 @@ func TestGiveawayPluginCreateAndEndGiveawayOnlyMods(t *testing.T) {
 	secretword := "hello"
 	postToPlugin.Content = contentCommandStart + " 10m " + secretword
 	content := "start 10m " + secretword
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
-	postToPlugin.User = model.User{ID: "SOME USER ID", Name: allowedUser}
+	postToPlugin.User = model.User{ID: "SOME USER ID", Name: allowedUser, IsMod: true}
 	expectedPostFromPlugin := model.Post{
 		ChannelID: "CHANNEL ID",
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -364,17 +357,17 @@ func TestGiveawayPluginCreateAndEndGiveawayOnlyMods(t *testing.T) {
 	api.Reset()
 	postToPlugin.Content = contentCommandEnd
 	postToPlugin.User = model.User{ID: "SOME USER ID", Name: notAllowedUser}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
-	postToPlugin.User = model.User{ID: "SOME USER ID", Name: allowedUser}
+	postToPlugin.User = model.User{ID: "SOME USER ID", Name: allowedUser, IsMod: true}
 	expectedPostFromPlugin = model.Post{
 		ChannelID: "CHANNEL ID",
 		Content:   "The winner(s) is/are <@" + userPostToPlugin.User.ID + ">. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -410,7 +403,7 @@ func TestGiveawayPluginCreateAndEndGiveawayWithMultipleWinners(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -457,7 +450,7 @@ func TestGiveawayPluginCreateAndEndGiveawayWithMultipleWinners(t *testing.T) {
 		Content:   "The winner(s) is/are <@" + "PARTICIPANT_1_ID" + ">, <@" + "PARTICIPANT_2_ID" + ">. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 3)
@@ -494,7 +487,7 @@ func TestGiveawayPluginCreateAndEndGiveawayWithPrize(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -517,7 +510,7 @@ func TestGiveawayPluginCreateAndEndGiveawayWithPrize(t *testing.T) {
 		Content:   "The winner(s) is/are <@" + userPostToPlugin.User.ID + ">. You won 'That awesome PRIZE'. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -551,7 +544,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "No previous giveaway in that channel. Use `!giveaway start` command to start a new one.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "reroll", postToPlugin)
+	sendCommand(p, "reroll", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -564,7 +557,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -575,7 +568,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Cannot pick a new winner. There is currently a giveaway running in this channel.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "reroll", postToPlugin)
+	sendCommand(p, "reroll", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -598,7 +591,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "The winner(s) is/are <@" + userPostToPlugin.User.ID + ">. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -610,7 +603,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "The new winner is <@" + userPostToPlugin.User.ID + ">. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "reroll", postToPlugin)
+	sendCommand(p, "reroll", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -624,7 +617,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -647,7 +640,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "The winner(s) is/are <@" + userPostToPlugin.User.ID + ">. You won '" + prize + "'. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -659,7 +652,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "The new winner is <@" + userPostToPlugin.User.ID + ">. You won '" + prize + "'. Congratulations!",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "reroll", postToPlugin)
+	sendCommand(p, "reroll", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 	assert.Equal(randomizer.Argument, 1)
@@ -673,7 +666,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Giveaway started! Type " + secretword + " to participate.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -684,7 +677,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Cannot pick a winner. There were no participants to the giveaway.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "end", postToPlugin)
+	sendCommand(p, "end", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -695,7 +688,7 @@ func TestGiveawayPluginCreateAndEndGiveawayAndReroll(t *testing.T) {
 		Content:   "Cannot pick a new winner. There were no participants to the previous giveaway.",
 		IsPrivate: false,
 	}
-	p.OnCommand(commandGiveaway, "reroll", postToPlugin)
+	sendCommand(p, "reroll", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
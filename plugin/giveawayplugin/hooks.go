@@ -4,15 +4,39 @@ import (
 	"strings"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
-	"github.com/torlenor/redseligg/utils"
 )
 
 var command = "giveaway"
 
+var commandSpec = commanddispatcher.CommandSpec{
+	Command:     command,
+	Description: "Holds a giveaway and picks the winners among the users who typed the secret word",
+	Examples: []string{
+		"giveaway start 10m banana",
+		"giveaway start 1h banana 2 A shiny new keyboard",
+		"giveaway end",
+		"giveaway reroll",
+	},
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command: "start",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "time", Type: commanddispatcher.ArgDuration},
+				{Name: "secretword", Type: commanddispatcher.ArgString},
+				{Name: "winners", Type: commanddispatcher.ArgInt, Optional: true},
+				{Name: "prize", Type: commanddispatcher.ArgText, Optional: true},
+			},
+		},
+		{Command: "end"},
+		{Command: "reroll"},
+	},
+}
+
 // OnRun implements the hook from the bot
 func (p *GiveawayPlugin) OnRun() {
-	p.API.RegisterCommand(p, command)
+	p.API.RegisterCommandSpec(p, commandSpec)
 
 	p.ticker = time.NewTicker(1000 * time.Millisecond)
 	p.tickerDoneChan = make(chan bool)
@@ -40,7 +64,7 @@ func (p *GiveawayPlugin) OnStop() {
 		p.tickerDoneChan <- true
 	}
 
-	p.API.UnRegisterCommand(command)
+	p.API.UnRegisterCommand(p, command)
 }
 
 // OnPost implements the hook from the Bot
@@ -57,27 +81,20 @@ func (p *GiveawayPlugin) OnPost(post model.Post) {
 	}
 }
 
-// OnCommand implements the hook from the Bot
-func (p *GiveawayPlugin) OnCommand(cmd string, content string, post model.Post) {
+// OnParsedCommand implements the hook from the Bot
+func (p *GiveawayPlugin) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
 	if post.IsPrivate {
 		return
 	}
 
-	subcommand, arguments := utils.ExtractSubCommandAndArgsString(content)
-
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
-		if subcommand == "start" && len(arguments) > 0 {
-			p.onCommandGStart(arguments, post)
-			return
-		} else if subcommand == "end" {
+	if !p.cfg.OnlyMods || post.User.IsMod {
+		switch args.Subcommand {
+		case "start":
+			p.onCommandGStart(args, post)
+		case "end":
 			p.onCommandGEnd(post)
-			return
-		} else if subcommand == "reroll" {
+		case "reroll":
 			p.onCommandGReroll(post)
-			return
-		} else if (subcommand == "start" && len(arguments) == 0) || subcommand == "help" {
-			p.returnHelp(post.ChannelID)
-			return
 		}
 	} else {
 		p.API.LogDebug("Not parsing as command, because User " + post.User.Name + " is not part of mods")
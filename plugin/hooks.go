@@ -1,6 +1,9 @@
 package plugin
 
-import "github.com/torlenor/redseligg/model"
+import (
+	"github.com/torlenor/redseligg/commanddispatcher"
+	"github.com/torlenor/redseligg/model"
+)
 
 // Hooks are all the function the plugin has to implement to work with the bot.
 // If the plugin is not interested in it, just implement it empty (which is also the default implementation).
@@ -15,8 +18,28 @@ type Hooks interface {
 	OnPost(model.Post)
 	// OnCommand delivers the command, the content where the command is already stripped off and the raw Post.
 	OnCommand(cmd string, content string, post model.Post)
+	// OnParsedCommand delivers the command, the arguments parsed according to the CommandSpec registered via RegisterCommandSpec and the raw Post.
+	OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post)
+	// OnTrigger delivers the name of a trigger registered via RegisterTrigger, the match and the raw Post.
+	OnTrigger(name string, match commanddispatcher.TriggerMatch, post model.Post)
+	// OnPostUpdated is called when a post is edited. The identifier is the one of the edited post.
+	OnPostUpdated(model.MessageIdentifier, model.Post)
+	// OnPostDeleted is called when a post is deleted.
+	OnPostDeleted(model.MessageIdentifier)
 	// OnReactionAdded is called when a reaction to posted message is received. This can be, e.g., an emoji.
 	OnReactionAdded(model.Reaction)
 	// OnReactionRemoved is called when a reaction is removed from a posted message. This can be, e.g., an emoji.
 	OnReactionRemoved(model.Reaction)
+	// OnUserJoined is called when a user joins a channel.
+	OnUserJoined(model.Membership)
+	// OnUserLeft is called when a user leaves a channel.
+	OnUserLeft(model.Membership)
+	// OnSubscription is called when a user subscribes to a channel, renews a subscription or gifts subscriptions.
+	OnSubscription(model.Subscription)
+	// OnRaid is called when a channel is raided.
+	OnRaid(model.Raid)
+	// OnCheer is called when a user cheers with bits in a channel. The message is delivered as post, too.
+	OnCheer(model.Cheer)
+	// OnChannelStateChanged is called when the chat settings of a channel are received or changed, e.g., slow mode.
+	OnChannelStateChanged(model.ChannelState)
 }
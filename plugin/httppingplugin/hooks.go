@@ -3,12 +3,17 @@ package httppingplugin
 import (
 	"fmt"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
 // OnRun implements the hook from the Boot
 func (p *HTTPPingPlugin) OnRun() {
-	p.API.RegisterCommand(p, "httpping")
+	p.API.RegisterCommand(p, "httpping", commanddispatcher.CommandHelp{
+		Description: "Measures how long a HTTP GET request to the given URL takes",
+		Usage:       []string{"httpping <url>"},
+		Examples:    []string{"httpping https://example.com"},
+	})
 }
 
 // OnCommand implements the hook from the Bot
@@ -2,7 +2,9 @@ package plugin
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 )
@@ -50,10 +52,23 @@ func (b *MockAPI) HasFeature(feature string) bool {
 func (b *MockAPI) GetStorage() storage.Storage { return b.Storage }
 
 // RegisterCommand registers a custom slash "/" or "!" command, depending on what the bot supports.
-func (b *MockAPI) RegisterCommand(p Hooks, command string) error { return nil }
+func (b *MockAPI) RegisterCommand(p Hooks, command string, help commanddispatcher.CommandHelp) error {
+	return nil
+}
+
+// RegisterCommandSpec registers a command with a declarative description of its arguments.
+func (b *MockAPI) RegisterCommandSpec(p Hooks, spec commanddispatcher.CommandSpec) error {
+	return nil
+}
 
 // UnRegisterCommand unregisters a command previously registered via RegisterCommand.
-func (b *MockAPI) UnRegisterCommand(command string) error { return nil }
+func (b *MockAPI) UnRegisterCommand(p Hooks, command string) error { return nil }
+
+// RegisterTrigger registers a trigger on mentions of the bot, regular expressions or keywords.
+func (b *MockAPI) RegisterTrigger(p Hooks, trigger commanddispatcher.Trigger) error { return nil }
+
+// UnRegisterTrigger unregisters a trigger previously registered via RegisterTrigger.
+func (b *MockAPI) UnRegisterTrigger(p Hooks, name string) error { return nil }
 
 // GetCallPrefix returns the current command call prefix.
 func (b *MockAPI) GetCallPrefix() string { return "!" }
@@ -93,6 +108,26 @@ func (b *MockAPI) DeletePost(messageID model.MessageIdentifier) (model.PostRespo
 	return model.PostResponse{}, fmt.Errorf("Not implemented")
 }
 
+// TimeoutUser prevents the user from writing in the channel for the given duration.
+func (b *MockAPI) TimeoutUser(channelID string, userID string, duration time.Duration, reason string) error {
+	return fmt.Errorf("Not implemented")
+}
+
+// BanUser bans the user from the channel.
+func (b *MockAPI) BanUser(channelID string, userID string, reason string) error {
+	return fmt.Errorf("Not implemented")
+}
+
+// ClearChat removes all messages from the channel.
+func (b *MockAPI) ClearChat(channelID string) error {
+	return fmt.Errorf("Not implemented")
+}
+
+// SetSlowMode sets the time users have to wait between two messages in the channel.
+func (b *MockAPI) SetSlowMode(channelID string, interval time.Duration) error {
+	return fmt.Errorf("Not implemented")
+}
+
 // GetReaction gives back the platform specific string for a reaction, e.g., one -> :one:
 func (b *MockAPI) GetReaction(reactionName string) (string, error) {
 	return "", fmt.Errorf("Not implemented")
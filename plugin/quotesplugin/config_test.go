@@ -8,8 +8,6 @@ import (
 )
 
 func Test_parseConfig(t *testing.T) {
-	mods := []string{"user1", "user2"}
-
 	type args struct {
 		c botconfig.PluginConfig
 	}
@@ -20,7 +18,7 @@ func Test_parseConfig(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "Valid config, empty",
+			name: "Everyone can remove quotes",
 			args: args{
 				c: botconfig.PluginConfig{
 					Type: PLUGIN_TYPE,
@@ -29,44 +27,31 @@ func Test_parseConfig(t *testing.T) {
 			want: config{},
 		},
 		{
-			name: "Valid config, with mods",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"mods": mods,
-					},
-				},
-			},
-			want: config{Mods: mods},
-		},
-		{
-			name: "Valid config, with only one mod in list",
+			name: "Only mods can remove quotes",
 			args: args{
 				c: botconfig.PluginConfig{
 					Type: PLUGIN_TYPE,
 					Config: map[string]interface{}{
-						"mods": []string{"user1"},
+						"onlymods": true,
 					},
 				},
 			},
-			want: config{Mods: []string{"user1"}},
+			want: config{OnlyMods: true},
 		},
 		{
-			name: "Valid config, with mods and onlymods = true",
+			name: "Old mods list does not restrict removing quotes",
 			args: args{
 				c: botconfig.PluginConfig{
 					Type: PLUGIN_TYPE,
 					Config: map[string]interface{}{
-						"mods":     mods,
-						"onlymods": true,
+						"mods": []string{"user1", "user2"},
 					},
 				},
 			},
-			want: config{Mods: mods, OnlyMods: true},
+			want: config{},
 		},
 		{
-			name: "Invalid config, wrong type",
+			name: "Config of another plugin",
 			args: args{
 				c: botconfig.PluginConfig{
 					Type: "something",
@@ -74,18 +59,6 @@ func Test_parseConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
-		{
-			name: "Valid config, with mods and onlymods = true",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"onlymods": true,
-					},
-				},
-			},
-			wantErr: true,
-		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
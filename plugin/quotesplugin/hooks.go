@@ -7,7 +7,7 @@ import (
 
 // OnRun is called when the platform is ready
 func (p *QuotesPlugin) OnRun() {
-	p.API.RegisterCommand(p, command)
+	p.API.RegisterCommand(p, command, commandHelp)
 
 	p.storage = p.getStorage()
 	if p.storage == nil {
@@ -31,12 +31,15 @@ func (p *QuotesPlugin) OnCommand(cmd string, content string, post model.Post) {
 	} else if subcommand == "add" && len(argument) > 0 {
 		p.onCommandQuoteAdd(argument, post)
 		return
-	} else if (subcommand == "add" && len(argument) == 0) || subcommand == "help" {
+	} else if subcommand == "add" && len(argument) == 0 {
 		p.returnHelp(post.ChannelID)
 		return
+	} else if subcommand == "help" {
+		p.returnMessage(post.ChannelID, commandHelp.Text(p.API.GetCallPrefix(), command))
+		return
 	}
 
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
+	if !p.cfg.OnlyMods || post.User.IsMod {
 		if subcommand == "remove" && len(argument) > 0 {
 			p.onCommandQuoteRemove(argument, post)
 			return
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/plugin"
 	"github.com/torlenor/redseligg/storagemodels"
@@ -16,6 +17,21 @@ const (
 	command     = "quote"
 )
 
+var commandHelp = commanddispatcher.CommandHelp{
+	Description: "Shows a random or a specific quote and lets you add and remove quotes",
+	Usage: []string{
+		command + " [number]",
+		command + " add <your quote>",
+		command + " remove <number>",
+	},
+	Examples: []string{
+		command,
+		command + " 3",
+		command + " add Talk is cheap. Show me the code.",
+		command + " remove 3",
+	},
+}
+
 // ErrNoValidStorage is set when the provided storage does not implement the correct functions
 var ErrNoValidStorage = errors.New("No valid storage set")
 
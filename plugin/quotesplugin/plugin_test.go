@@ -15,7 +15,7 @@ import (
 var commandQuote = command
 var commandAdd = command + " add"
 var commandRemove = command + " remove"
-var commandHelp = command + "help"
+var commandQuoteHelp = command + " help"
 
 func TestCreateQuotesPlugin(t *testing.T) {
 	assert := assert.New(t)
@@ -85,7 +85,8 @@ func TestQuotesPlugin_HelpTextAndInvalidCommands(t *testing.T) {
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	postToPlugin.Content = "!" + commandHelp
+	postToPlugin.Content = "!" + commandQuoteHelp
+	expectedPostFromPlugin.Content = commandHelp.Text(api.GetCallPrefix(), command)
 	p.OnCommand(commandQuote, "help", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
@@ -378,7 +379,6 @@ func TestQuotesPlugin_RemoveQuote_OnlyMods(t *testing.T) {
 	p.BotID = botID
 
 	p.cfg.OnlyMods = true
-	p.cfg.Mods = append(p.cfg.Mods, userName)
 
 	storage := &MockStorage{}
 	api := plugin.MockAPI{Storage: storage}
@@ -405,6 +405,7 @@ func TestQuotesPlugin_RemoveQuote_OnlyMods(t *testing.T) {
 		IsPrivate: false,
 	}
 	postToPlugin.User.Name = userName
+	postToPlugin.User.IsMod = true
 	p.OnCommand(commandQuote, "remove 2", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
 // OnRun implements the hook from the Boot
 func (p *RollPlugin) OnRun() {
-	p.API.RegisterCommand(p, "roll")
+	p.API.RegisterCommand(p, "roll", commanddispatcher.CommandHelp{
+		Description: "Rolls a random number between 0 and the given number (default 100)",
+		Usage:       []string{"roll [number]"},
+		Examples:    []string{"roll", "roll 6"},
+	})
 }
 
 // OnCommand implements the hook from the Bot
@@ -3,10 +3,9 @@ package rssplugin
 import (
 	"errors"
 	"fmt"
-	"net/url"
-	"regexp"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 	"github.com/torlenor/redseligg/storagemodels"
@@ -100,34 +99,6 @@ func (p *RssPlugin) removeRssSubscription(channelID, link string) error {
 	return nil
 }
 
-func splitRssCommand(text string) (c string, link string, err error) {
-	var re = regexp.MustCompile(`(?m)^+(add|remove) +(.*)$`)
-
-	const cgCommand = 1
-	const cgLink = 2
-
-	matches := re.FindAllStringSubmatch(text, -1)
-
-	if matches == nil || len(matches) < 1 {
-		err = errors.New("Not a valid command")
-		return
-	}
-
-	if len(matches[0]) > cgLink {
-		c = matches[0][cgCommand]
-		link = matches[0][cgLink]
-	} else {
-		err = errors.New("Not a valid command")
-	}
-
-	_, err = url.ParseRequestURI(link)
-	if err != nil {
-		err = fmt.Errorf("Not a valid url: %s", err)
-	}
-
-	return
-}
-
 func (p *RssPlugin) returnSubscriptionsList(channelID string) {
 	subscriptions, err := p.getRssSubscriptions()
 	if err != nil {
@@ -157,27 +128,16 @@ func (p *RssPlugin) returnSubscriptionsList(channelID string) {
 }
 
 // onCommand handles a !rss command.
-func (p *RssPlugin) onCommand(content string, post model.Post) {
-	if content == "add" {
-		p.returnHelpAdd(post.ChannelID)
-		return
-	} else if content == "remove" {
-		p.returnHelpRemove(post.ChannelID)
-		return
-	}
-
-	if content == "list" {
+func (p *RssPlugin) onCommand(args commanddispatcher.Arguments, post model.Post) {
+	c := args.Subcommand
+	if c == "list" {
 		p.returnSubscriptionsList(post.ChannelID)
 		return
 	}
 
-	c, link, err := splitRssCommand(content)
-	if err != nil {
-		p.API.LogError(fmt.Sprintf("Error parsing command '%s': %s", content, err))
-		p.returnHelp(post.ChannelID)
-		return
-	}
+	link := args.String("link")
 
+	var err error
 	switch c {
 	case "add":
 		err = p.addRssSubscription(post.ChannelID, link)
@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-func Test_splitRssCommand(t *testing.T) {
+func Test_commandSpec(t *testing.T) {
 	validURLStr := "https://a.valid.url/something.xml"
 	invalidURLStr := "dfdsfsdf.valid.url/something.xml"
 
@@ -34,6 +34,13 @@ func Test_splitRssCommand(t *testing.T) {
 			wantC:    "remove",
 			wantLink: validURLStr,
 		},
+		{
+			name: "Valid list command",
+			args: args{
+				text: "list",
+			},
+			wantC: "list",
+		},
 		{
 			name: "Invalid command",
 			args: args{
@@ -46,18 +53,14 @@ func Test_splitRssCommand(t *testing.T) {
 			args: args{
 				text: "add ssdsd " + validURLStr,
 			},
-			wantC:    "add",
-			wantLink: "ssdsd https://a.valid.url/something.xml",
-			wantErr:  true,
+			wantErr: true,
 		},
 		{
 			name: "Invalid remove command",
 			args: args{
 				text: "remove ssdsd " + validURLStr,
 			},
-			wantC:    "remove",
-			wantLink: "ssdsd https://a.valid.url/something.xml",
-			wantErr:  true,
+			wantErr: true,
 		},
 		{
 			name: "Invalid add command - empty url",
@@ -78,32 +81,28 @@ func Test_splitRssCommand(t *testing.T) {
 			args: args{
 				text: "add " + invalidURLStr,
 			},
-			wantC:    "add",
-			wantLink: "dfdsfsdf.valid.url/something.xml",
-			wantErr:  true,
+			wantErr: true,
 		},
 		{
 			name: "Invalid remove command - invalid url",
 			args: args{
 				text: "remove " + invalidURLStr,
 			},
-			wantC:    "remove",
-			wantLink: "dfdsfsdf.valid.url/something.xml",
-			wantErr:  true,
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotC, gotLink, err := splitRssCommand(tt.args.text)
+			args, err := commandSpec.Parse(tt.args.text)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("splitRssCommand() test = %s, error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				t.Errorf("commandSpec.Parse() test = %s, error = %v, wantErr %v", tt.name, err, tt.wantErr)
 				return
 			}
-			if gotC != tt.wantC {
-				t.Errorf("splitRssCommand() test = %s, gotC = %v, want %v", tt.name, gotC, tt.wantC)
+			if gotC := args.Subcommand; gotC != tt.wantC {
+				t.Errorf("commandSpec.Parse() test = %s, gotC = %v, want %v", tt.name, gotC, tt.wantC)
 			}
-			if gotLink != tt.wantLink {
-				t.Errorf("splitRssCommand() test = %s, gotMsg = %v, want %v", tt.name, gotLink, tt.wantLink)
+			if gotLink := args.String("link"); gotLink != tt.wantLink {
+				t.Errorf("commandSpec.Parse() test = %s, gotLink = %v, want %v", tt.name, gotLink, tt.wantLink)
 			}
 		})
 	}
@@ -6,9 +6,9 @@ import (
 
 	"github.com/mmcdole/gofeed"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
-	"github.com/torlenor/redseligg/utils"
 )
 
 func parseRssFeedFromURL(url string) (*gofeed.Feed, error) {
@@ -53,7 +53,7 @@ func (p *RssPlugin) checkRssSubscriptions(t time.Time) {
 
 // OnRun is called when the platform is ready
 func (p *RssPlugin) OnRun() {
-	p.API.RegisterCommand(p, PLUGIN_COMMAND)
+	p.API.RegisterCommandSpec(p, commandSpec)
 
 	p.storage = p.getStorage()
 	if p.storage == nil {
@@ -84,18 +84,14 @@ func (p *RssPlugin) OnStop() {
 	}
 }
 
-// OnCommand implements the hook from the Bot
-func (p *RssPlugin) OnCommand(cmd string, content string, post model.Post) {
+// OnParsedCommand implements the hook from the Bot
+func (p *RssPlugin) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
 	if post.IsPrivate {
 		return
 	}
 
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
-		if len(content) > 0 {
-			p.onCommand(content, post)
-		} else {
-			p.returnHelp(post.ChannelID)
-		}
+	if !p.cfg.OnlyMods || post.User.IsMod {
+		p.onCommand(args, post)
 	} else {
 		p.API.LogDebug("Not parsing as command, because User " + post.User.Name + " is not part of mods")
 	}
@@ -0,0 +1,13 @@
+package rssplugin
+
+import (
+	"github.com/torlenor/redseligg/model"
+)
+
+func (p *RssPlugin) returnMessage(channelID, msg string) {
+	post := model.Post{
+		ChannelID: channelID,
+		Content:   msg,
+	}
+	p.API.CreatePost(post)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin"
 	"github.com/torlenor/redseligg/storagemodels"
@@ -17,6 +18,29 @@ const (
 	PLUGIN_COMMAND = "rss"
 )
 
+var commandSpec = commanddispatcher.CommandSpec{
+	Command:     PLUGIN_COMMAND,
+	Description: "Manages the RSS subscriptions of this channel",
+	Examples: []string{
+		"rss add https://example.com/feed.xml",
+		"rss remove https://example.com/feed.xml",
+		"rss list",
+	},
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command: "add",
+			Args:    []commanddispatcher.ArgSpec{{Name: "link", Type: commanddispatcher.ArgURL}},
+		},
+		{
+			Command: "remove",
+			Args:    []commanddispatcher.ArgSpec{{Name: "link", Type: commanddispatcher.ArgURL}},
+		},
+		{
+			Command: "list",
+		},
+	},
+}
+
 // ErrNoValidStorage is set when the provided storage does not implement the correct functions
 var ErrNoValidStorage = errors.New("No valid storage set")
 
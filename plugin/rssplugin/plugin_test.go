@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/platform"
@@ -23,6 +24,16 @@ var providedFeatures = map[string]bool{
 
 var command = "rss"
 
+// sendCommand feeds the command through a CommandDispatcher, the same way a bot would.
+func sendCommand(p *RssPlugin, content string, post model.Post) {
+	dispatcher := commanddispatcher.New("!")
+	dispatcher.SetPoster(p.API)
+	dispatcher.RegisterSpec(commandSpec, p)
+
+	post.Content = "!" + command + " " + content
+	dispatcher.OnPost(post)
+}
+
 func TestCreateRssPlugin(t *testing.T) {
 	assert := assert.New(t)
 
@@ -103,24 +114,27 @@ func TestRssPlugin_HelpTextAndInvalidCommands(t *testing.T) {
 	}
 
 	api.Reset()
-	expectedPostFromPlugin := model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   p.helpText(),
-		IsPrivate: false,
-	}
-	p.OnCommand(command, "", postToPlugin)
+	expectedPostFromPlugin := postToPlugin
+	expectedPostFromPlugin.Content = "Missing subcommand. Usage: `!rss <add|remove|list>`"
+	sendCommand(p, "", postToPlugin)
+	assert.Equal(true, api.WasCreatePostCalled)
+	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
+
+	api.Reset()
+	expectedPostFromPlugin.Content = "Missing argument <link>. Usage: `!rss add <link>`"
+	sendCommand(p, "add", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	expectedPostFromPlugin.Content = p.helpTextAdd()
-	p.OnCommand(command, "add", postToPlugin)
+	expectedPostFromPlugin.Content = "Missing argument <link>. Usage: `!rss remove <link>`"
+	sendCommand(p, "remove", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	expectedPostFromPlugin.Content = p.helpTextRemove()
-	p.OnCommand(command, "remove", postToPlugin)
+	expectedPostFromPlugin.Content = "Argument <link> is not a valid url. Usage: `!rss add <link>`"
+	sendCommand(p, "add not.a.url", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -153,7 +167,7 @@ func TestRssPlugin_AddAndRemoveRssSubscription(t *testing.T) {
 		Content:   fmt.Sprintf("RSS subscription for link '%s' added.", link),
 		IsPrivate: false,
 	}
-	p.OnCommand(command, "add "+link, postToPlugin)
+	sendCommand(p, "add "+link, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -167,12 +181,12 @@ func TestRssPlugin_AddAndRemoveRssSubscription(t *testing.T) {
 	otherLink := "http://some.other.thing/test.xml"
 
 	expectedPostFromPlugin.Content = "RSS subscription to remove does not exist."
-	p.OnCommand(command, "remove "+otherLink, postToPlugin)
+	sendCommand(p, "remove "+otherLink, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	expectedPostFromPlugin.Content = "RSS subscription to remove does not exist."
-	p.OnCommand(command, "remove "+link, postToPlugin)
+	sendCommand(p, "remove "+link, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -187,12 +201,12 @@ func TestRssPlugin_AddAndRemoveRssSubscription(t *testing.T) {
 	})
 
 	expectedPostFromPlugin.Content = fmt.Sprintf("RSS subscription for link '%s' added.", "http://some.other.thing.which.should.not.be.removed/test.xml")
-	p.OnCommand(command, "add "+"http://some.other.thing.which.should.not.be.removed/test.xml", postToPlugin)
+	sendCommand(p, "add "+"http://some.other.thing.which.should.not.be.removed/test.xml", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	expectedPostFromPlugin.Content = fmt.Sprintf("RSS subscription for link '%s' removed.", link)
-	p.OnCommand(command, "remove "+link, postToPlugin)
+	sendCommand(p, "remove "+link, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -204,12 +218,12 @@ func TestRssPlugin_AddAndRemoveRssSubscription(t *testing.T) {
 
 	storage.ErrorToReturn = errors.New("Some error")
 	expectedPostFromPlugin.Content = "Could not add RSS subscription. Please try again later."
-	p.OnCommand(command, "add "+link, postToPlugin)
+	sendCommand(p, "add "+link, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	expectedPostFromPlugin.Content = "Could not remove RSS subscription. Please try again later."
-	p.OnCommand(command, "remove "+link, postToPlugin)
+	sendCommand(p, "remove "+link, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -229,7 +243,6 @@ func TestRssPlugin_AddAndRemoveRssSubscription_OnlyMods(t *testing.T) {
 	p.BotID = botID
 
 	p.cfg.OnlyMods = true
-	p.cfg.Mods = append(p.cfg.Mods, userName)
 
 	storage := &MockStorage{}
 	api := plugin.MockAPI{Storage: storage}
@@ -253,12 +266,13 @@ func TestRssPlugin_AddAndRemoveRssSubscription_OnlyMods(t *testing.T) {
 		IsPrivate: false,
 	}
 	content := "add " + link
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -277,21 +291,23 @@ func TestRssPlugin_AddAndRemoveRssSubscription_OnlyMods(t *testing.T) {
 	})
 
 	expectedPostFromPlugin.Content = fmt.Sprintf("RSS subscription for link '%s' added.", "http://some.other.thing.which.should.not.be.removed/test.xml")
-	p.OnCommand(command, "add "+"http://some.other.thing.which.should.not.be.removed/test.xml", postToPlugin)
+	sendCommand(p, "add "+"http://some.other.thing.which.should.not.be.removed/test.xml", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
 	postToPlugin.User.Name = " some other user"
+	postToPlugin.User.IsMod = false
 	content = "remove " + link
 	expectedPostFromPlugin.Content = fmt.Sprintf("RSS subscription for link '%s' removed.", link)
 
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -304,13 +320,13 @@ func TestRssPlugin_AddAndRemoveRssSubscription_OnlyMods(t *testing.T) {
 	storage.ErrorToReturn = errors.New("Some error")
 	content = "add " + link
 	expectedPostFromPlugin.Content = "Could not add RSS subscription. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	content = "remove " + link
 	expectedPostFromPlugin.Content = "Could not remove RSS subscription. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -348,7 +364,7 @@ func TestRssPlugin_NoStorage(t *testing.T) {
 		Content:   "Could not add RSS subscription. Please try again later.",
 		IsPrivate: false,
 	}
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
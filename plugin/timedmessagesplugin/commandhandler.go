@@ -3,10 +3,9 @@ package timedmessagesplugin
 import (
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
 	"github.com/torlenor/redseligg/storagemodels"
@@ -113,63 +112,12 @@ func (p *TimedMessagesPlugin) removeAllTimedMessage(channelID, message string) e
 	return p.storeTimedMessages(timedMessages)
 }
 
-func parseTimeStringToDuration(timeStr string) (time.Duration, error) {
-	duration, err := time.ParseDuration(timeStr)
-	if err != nil {
-		return time.Duration(0), fmt.Errorf("Not a valid duration")
-	}
-
-	return duration, nil
-}
-
-func splitTmCommand(text string) (c string, interval time.Duration, msg string, err error) {
-	var re = regexp.MustCompile(`(?m)^+(add|remove) +([0-9]+[a-zA-Z]*) +(.+)$`)
-
-	const cgCommand = 1
-	const cgInterval = 2
-	const cgMessage = 3
-
-	matches := re.FindAllStringSubmatch(text, -1)
-
-	if matches == nil || len(matches) < 1 {
-		err = errors.New("Not a valid command")
-		return
-	}
-
-	if len(matches[0]) > cgMessage {
-		c = matches[0][cgCommand]
-		interval, err = parseTimeStringToDuration(matches[0][cgInterval])
-		if err != nil {
-			return
-		}
-		msg = matches[0][cgMessage]
-	} else {
-		err = errors.New("Not a valid command")
-	}
-
-	return
-}
-
 // onCommand handles a !tm command.
-func (p *TimedMessagesPlugin) onCommand(content string, post model.Post) {
-	if content == "add" {
-		p.returnHelpAdd(post.ChannelID)
-		return
-	} else if content == "remove" {
-		p.returnHelpRemove(post.ChannelID)
-		return
-	}
+func (p *TimedMessagesPlugin) onCommand(args commanddispatcher.Arguments, post model.Post) {
+	message := args.String("message")
 
-	if strings.HasPrefix(content, "remove all ") {
-		cont := strings.Split(content, " ")
-		if len(cont) < 3 {
-			p.returnHelpRemove(post.ChannelID)
-			return
-		}
-
-		msg := strings.Join(cont[2:], " ")
-
-		err := p.removeAllTimedMessage(post.ChannelID, msg)
+	if args.Subcommand == "remove all" {
+		err := p.removeAllTimedMessage(post.ChannelID, message)
 		if err == errNotExist {
 			p.returnMessage(post.ChannelID, "Timed message to remove does not exist.")
 			return
@@ -178,17 +126,14 @@ func (p *TimedMessagesPlugin) onCommand(content string, post model.Post) {
 			p.returnMessage(post.ChannelID, fmt.Sprintf("Could not remove all timed message. Please try again later."))
 			return
 		}
-		p.returnMessage(post.ChannelID, fmt.Sprintf("All timed messages with text '%s' removed.", msg))
+		p.returnMessage(post.ChannelID, fmt.Sprintf("All timed messages with text '%s' removed.", message))
 		return
 	}
 
-	c, interval, message, err := splitTmCommand(content)
-	if err != nil {
-		p.API.LogError(fmt.Sprintf("Error parsing command '%s': %s", content, err))
-		p.returnHelp(post.ChannelID)
-		return
-	}
+	c := args.Subcommand
+	interval := args.Duration("interval")
 
+	var err error
 	switch c {
 	case "add":
 		err = p.addTimedMessage(post.ChannelID, message, interval)
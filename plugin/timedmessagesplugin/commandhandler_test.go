@@ -5,7 +5,7 @@ import (
 	"time"
 )
 
-func Test_splitTmCommand(t *testing.T) {
+func Test_commandSpec(t *testing.T) {
 	validIntervalStr := "1m"
 	validInterval, _ := time.ParseDuration(validIntervalStr)
 
@@ -41,6 +41,23 @@ func Test_splitTmCommand(t *testing.T) {
 			wantInterval: validOtherInterval,
 			wantMsg:      "some other text",
 		},
+		{
+			name: "Valid remove all command",
+			args: args{
+				text: "remove all some text",
+			},
+			wantC:   "remove all",
+			wantMsg: "some text",
+		},
+		{
+			name: "Valid add command with quotes in the message",
+			args: args{
+				text: "add " + validIntervalStr + ` It's "some text"`,
+			},
+			wantC:        "add",
+			wantInterval: validInterval,
+			wantMsg:      `It's "some text"`,
+		},
 		{
 			name: "Invalid command",
 			args: args{
@@ -81,19 +98,23 @@ func Test_splitTmCommand(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotC, gotInterval, gotMsg, err := splitTmCommand(tt.args.text)
+			args, err := commandSpec.Parse(tt.args.text)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("splitTmCommand() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("commandSpec.Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
 				return
 			}
+			gotC, gotInterval, gotMsg := args.Subcommand, args.Duration("interval"), args.String("message")
 			if gotC != tt.wantC {
-				t.Errorf("splitTmCommand() gotC = %v, want %v", gotC, tt.wantC)
+				t.Errorf("commandSpec.Parse() gotC = %v, want %v", gotC, tt.wantC)
 			}
 			if gotInterval != tt.wantInterval {
-				t.Errorf("splitTmCommand() gotInterval = %v, want %v", gotInterval, tt.wantInterval)
+				t.Errorf("commandSpec.Parse() gotInterval = %v, want %v", gotInterval, tt.wantInterval)
 			}
 			if gotMsg != tt.wantMsg {
-				t.Errorf("splitTmCommand() gotMsg = %v, want %v", gotMsg, tt.wantMsg)
+				t.Errorf("commandSpec.Parse() gotMsg = %v, want %v", gotMsg, tt.wantMsg)
 			}
 		})
 	}
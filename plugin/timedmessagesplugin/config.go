@@ -7,7 +7,7 @@ import (
 )
 
 type config struct {
-	Mods     []string
+	// OnlyMods lets only mods add and remove timed messages.
 	OnlyMods bool
 }
 
@@ -16,30 +16,9 @@ func parseConfig(c botconfig.PluginConfig) (config, error) {
 		return config{}, fmt.Errorf("Not a " + PLUGIN_TYPE + " plugin config")
 	}
 
-	var mods []string
-	var onlyMods bool
-
-	var ok bool
-
-	if modEntries, ok := c.Config["mods"].([]interface{}); ok {
-		for _, modEntry := range modEntries {
-			if mod, ok := modEntry.(string); ok {
-				mods = append(mods, mod)
-			}
-		}
-	} else if modEntries, ok := c.Config["mods"].([]string); ok {
-		mods = modEntries
-	}
-
-	if onlyMods, ok = c.Config["onlymods"].(bool); !ok {
-	}
-
-	if onlyMods && len(mods) == 0 {
-		return config{}, fmt.Errorf("Cannot have a " + PLUGIN_TYPE + "  plugin configuration with OnlyMods = true but no Mods defined")
-	}
+	onlyMods, _ := c.Config["onlymods"].(bool)
 
 	cfg := config{
-		Mods:     mods,
 		OnlyMods: onlyMods,
 	}
 
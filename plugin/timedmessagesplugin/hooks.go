@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/storage"
-	"github.com/torlenor/redseligg/utils"
 )
 
 func (p *TimedMessagesPlugin) checkTimedMessages(t time.Time) {
@@ -35,7 +35,7 @@ func (p *TimedMessagesPlugin) checkTimedMessages(t time.Time) {
 
 // OnRun is called when the platform is ready
 func (p *TimedMessagesPlugin) OnRun() {
-	p.API.RegisterCommand(p, "tm")
+	p.API.RegisterCommandSpec(p, commandSpec)
 
 	p.storage = p.getStorage()
 	if p.storage == nil {
@@ -66,18 +66,14 @@ func (p *TimedMessagesPlugin) OnStop() {
 	}
 }
 
-// OnCommand implements the hook from the Bot
-func (p *TimedMessagesPlugin) OnCommand(cmd string, content string, post model.Post) {
+// OnParsedCommand implements the hook from the Bot
+func (p *TimedMessagesPlugin) OnParsedCommand(cmd string, args commanddispatcher.Arguments, post model.Post) {
 	if post.IsPrivate {
 		return
 	}
 
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
-		if len(content) > 0 {
-			p.onCommand(content, post)
-		} else {
-			p.returnHelp(post.ChannelID)
-		}
+	if !p.cfg.OnlyMods || post.User.IsMod {
+		p.onCommand(args, post)
 	} else {
 		p.API.LogDebug("Not parsing as command, because User " + post.User.Name + " is not part of mods")
 	}
@@ -0,0 +1,13 @@
+package timedmessagesplugin
+
+import (
+	"github.com/torlenor/redseligg/model"
+)
+
+func (p *TimedMessagesPlugin) returnMessage(channelID, msg string) {
+	post := model.Post{
+		ChannelID: channelID,
+		Content:   msg,
+	}
+	p.API.CreatePost(post)
+}
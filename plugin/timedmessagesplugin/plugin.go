@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/plugin"
@@ -15,6 +16,40 @@ const (
 	PLUGIN_TYPE = "timedmessages"
 )
 
+var commandSpec = commanddispatcher.CommandSpec{
+	Command:     "tm",
+	Description: "Posts messages automatically in the given interval",
+	Examples: []string{
+		"tm add 1h Remember to drink some water!",
+		"tm remove 1h Remember to drink some water!",
+		"tm remove all Remember to drink some water!",
+	},
+	Subcommands: []commanddispatcher.CommandSpec{
+		{
+			Command: "add",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "interval", Type: commanddispatcher.ArgDuration},
+				{Name: "message", Type: commanddispatcher.ArgText},
+			},
+		},
+		{
+			Command: "remove",
+			Args: []commanddispatcher.ArgSpec{
+				{Name: "interval", Type: commanddispatcher.ArgDuration},
+				{Name: "message", Type: commanddispatcher.ArgText},
+			},
+			Subcommands: []commanddispatcher.CommandSpec{
+				{
+					Command: "all",
+					Args: []commanddispatcher.ArgSpec{
+						{Name: "message", Type: commanddispatcher.ArgText},
+					},
+				},
+			},
+		},
+	},
+}
+
 // ErrNoValidStorage is set when the provided storage does not implement the correct functions
 var ErrNoValidStorage = errors.New("No valid storage set")
 
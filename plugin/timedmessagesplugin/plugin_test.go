@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/torlenor/redseligg/botconfig"
+	"github.com/torlenor/redseligg/commanddispatcher"
 
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/platform"
@@ -22,6 +23,16 @@ var providedFeatures = map[string]bool{
 
 var command = "tm"
 
+// sendCommand feeds the command through a CommandDispatcher, the same way a bot would.
+func sendCommand(p *TimedMessagesPlugin, content string, post model.Post) {
+	dispatcher := commanddispatcher.New("!")
+	dispatcher.SetPoster(p.API)
+	dispatcher.RegisterSpec(commandSpec, p)
+
+	post.Content = "!" + command + " " + content
+	dispatcher.OnPost(post)
+}
+
 func TestCreateTimedMessagesPlugin(t *testing.T) {
 	assert := assert.New(t)
 
@@ -102,24 +113,27 @@ func TestTimedMessagesPlugin_HelpTextAndInvalidCommands(t *testing.T) {
 	}
 
 	api.Reset()
-	expectedPostFromPlugin := model.Post{
-		ChannelID: "CHANNEL ID",
-		Content:   p.helpText(),
-		IsPrivate: false,
-	}
-	p.OnCommand(command, "", postToPlugin)
+	expectedPostFromPlugin := postToPlugin
+	expectedPostFromPlugin.Content = "Missing subcommand. Usage: `!tm <add|remove>`"
+	sendCommand(p, "", postToPlugin)
+	assert.Equal(true, api.WasCreatePostCalled)
+	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
+
+	api.Reset()
+	expectedPostFromPlugin.Content = "Missing argument <interval>. Usage: `!tm add <interval> <message...>`"
+	sendCommand(p, "add", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	expectedPostFromPlugin.Content = p.helpTextAdd()
-	p.OnCommand(command, "add", postToPlugin)
+	expectedPostFromPlugin.Content = "Missing argument <interval>. Usage: `!tm remove <interval> <message...>`"
+	sendCommand(p, "remove", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	api.Reset()
-	expectedPostFromPlugin.Content = p.helpTextRemove()
-	p.OnCommand(command, "remove", postToPlugin)
+	expectedPostFromPlugin.Content = "Argument <interval> is not a valid duration. Usage: `!tm add <interval> <message...>`"
+	sendCommand(p, "add 4dfdfd some text", postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -155,7 +169,7 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage(t *testing.T) {
 		Content:   fmt.Sprintf("Timed message '%s' with interval %s added.", message, timeInterval),
 		IsPrivate: false,
 	}
-	p.OnCommand(command, "add "+timeIntervalStr+" "+message, postToPlugin)
+	sendCommand(p, "add "+timeIntervalStr+" "+message, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -171,12 +185,12 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage(t *testing.T) {
 	otherMessage := "some other message"
 
 	expectedPostFromPlugin.Content = "Timed message to remove does not exist."
-	p.OnCommand(command, "remove "+otherTimeIntervalStr+" "+otherMessage, postToPlugin)
+	sendCommand(p, "remove "+otherTimeIntervalStr+" "+otherMessage, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	expectedPostFromPlugin.Content = "Timed message to remove does not exist."
-	p.OnCommand(command, "remove "+otherTimeIntervalStr+" "+message, postToPlugin)
+	sendCommand(p, "remove "+otherTimeIntervalStr+" "+message, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -193,7 +207,7 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage(t *testing.T) {
 	})
 
 	expectedPostFromPlugin.Content = fmt.Sprintf("Timed message '%s' with interval %s removed.", message, timeInterval)
-	p.OnCommand(command, "remove "+timeIntervalStr+" "+message, postToPlugin)
+	sendCommand(p, "remove "+timeIntervalStr+" "+message, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -206,12 +220,12 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage(t *testing.T) {
 
 	storage.ErrorToReturn = errors.New("Some error")
 	expectedPostFromPlugin.Content = "Could not add timed message. Please try again later."
-	p.OnCommand(command, "add "+timeIntervalStr+" "+message, postToPlugin)
+	sendCommand(p, "add "+timeIntervalStr+" "+message, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	expectedPostFromPlugin.Content = "Could not remove timed message. Please try again later."
-	p.OnCommand(command, "remove "+timeIntervalStr+" "+message, postToPlugin)
+	sendCommand(p, "remove "+timeIntervalStr+" "+message, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -231,7 +245,6 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage_OnlyMods(t *testing.T) {
 	p.BotID = botID
 
 	p.cfg.OnlyMods = true
-	p.cfg.Mods = append(p.cfg.Mods, userName)
 
 	storage := &MockStorage{}
 	api := plugin.MockAPI{Storage: storage}
@@ -258,12 +271,13 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage_OnlyMods(t *testing.T) {
 		IsPrivate: false,
 	}
 	content := "add " + timeIntervalStr + " " + message
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -289,15 +303,17 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage_OnlyMods(t *testing.T) {
 
 	api.Reset()
 	postToPlugin.User.Name = " some other user"
+	postToPlugin.User.IsMod = false
 	content = "remove " + timeIntervalStr + " " + message
 	expectedPostFromPlugin.Content = fmt.Sprintf("Timed message '%s' with interval %s removed.", message, timeInterval)
 
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(false, api.WasCreatePostCalled)
 
 	api.Reset()
 	postToPlugin.User.Name = userName
-	p.OnCommand(command, content, postToPlugin)
+	postToPlugin.User.IsMod = true
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -311,13 +327,13 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage_OnlyMods(t *testing.T) {
 	storage.ErrorToReturn = errors.New("Some error")
 	content = "add " + timeIntervalStr + " " + message
 	expectedPostFromPlugin.Content = "Could not add timed message. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
 	content = "remove " + timeIntervalStr + " " + message
 	expectedPostFromPlugin.Content = "Could not remove timed message. Please try again later."
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -370,7 +386,7 @@ func TestTimedMessagesPlugin_AddAndRemoveTimedMessage_AllMessages(t *testing.T)
 		Content:   fmt.Sprintf("All timed messages with text '%s' removed.", message),
 		IsPrivate: false,
 	}
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 
@@ -417,7 +433,7 @@ func TestTimedMessagesPlugin_NoStorage(t *testing.T) {
 		Content:   "Could not add timed message. Please try again later.",
 		IsPrivate: false,
 	}
-	p.OnCommand(command, content, postToPlugin)
+	sendCommand(p, content, postToPlugin)
 	assert.Equal(true, api.WasCreatePostCalled)
 	assert.Equal(expectedPostFromPlugin, api.LastCreatePostPost)
 }
@@ -1,12 +1,16 @@
 package versionplugin
 
 import (
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
 // OnRun implements the hook from the Boot
 func (p *VersionPlugin) OnRun() {
-	p.API.RegisterCommand(p, "version")
+	p.API.RegisterCommand(p, "version", commanddispatcher.CommandHelp{
+		Description: "Shows the version of the bot",
+		Usage:       []string{"version"},
+	})
 }
 
 // OnCommand implements the hook from the Bot
@@ -5,16 +5,25 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 )
 
 const (
-	helpText        = "Type '%s" + command + " What is the best color? [Red, Green, Blue]' to start a new vote.\nYou can omit the custom options in the [...] to initiate a simple Yes/No vote."
 	helpTextVoteEnd = "To end a vote type `%s" + command + " end description text of the vote`."
 )
 
-func (p *VotePlugin) helpText() string {
-	return fmt.Sprintf(helpText, p.API.GetCallPrefix())
+var commandHelp = commanddispatcher.CommandHelp{
+	Description: "Starts a new vote with custom options or a simple Yes/No vote if the options in [...] are omitted",
+	Usage: []string{
+		command + " <description> [option1, option2, ...]",
+		command + " end <description>",
+	},
+	Examples: []string{
+		command + " What is the best color? [Red, Green, Blue]",
+		command + " Pizza tonight?",
+		command + " end What is the best color?",
+	},
 }
 
 func (p *VotePlugin) helpTextVoteEnd() string {
@@ -22,7 +31,7 @@ func (p *VotePlugin) helpTextVoteEnd() string {
 }
 
 func (p *VotePlugin) returnHelp(channelID string) {
-	p.returnMessage(channelID, p.helpText())
+	p.returnMessage(channelID, commandHelp.Text(p.API.GetCallPrefix(), command))
 }
 
 func (p *VotePlugin) returnVoteEndHelp(channelID string) {
@@ -1,102 +1,20 @@
 package voteplugin
 
 import (
-	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/torlenor/redseligg/botconfig"
 )
 
 func Test_parseConfig(t *testing.T) {
-	mods := []string{"user1", "user2"}
-
-	type args struct {
-		c botconfig.PluginConfig
-	}
-	tests := []struct {
-		name    string
-		args    args
-		want    config
-		wantErr bool
-	}{
-		{
-			name: "Valid config, empty",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-				},
-			},
-			want: config{},
-		},
-		{
-			name: "Valid config, with mods",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"mods": mods,
-					},
-				},
-			},
-			want: config{Mods: mods},
-		},
-		{
-			name: "Valid config, with only one mod in list",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"mods": []string{"user1"},
-					},
-				},
-			},
-			want: config{Mods: []string{"user1"}},
-		},
-		{
-			name: "Valid config, with mods and onlymods = true",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"mods":     mods,
-						"onlymods": true,
-					},
-				},
-			},
-			want: config{Mods: mods, OnlyMods: true},
-		},
-		{
-			name: "Invalid config, wrong type",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: "something",
-				},
-			},
-			wantErr: true,
-		},
-		{
-			name: "Valid config, with mods and onlymods = true",
-			args: args{
-				c: botconfig.PluginConfig{
-					Type: PLUGIN_TYPE,
-					Config: map[string]interface{}{
-						"onlymods": true,
-					},
-				},
-			},
-			wantErr: true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseConfig(tt.args.c)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseConfig() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseConfig() = %v, want %v", got, tt.want)
-			}
-		})
+	for _, onlyMods := range []bool{false, true} {
+		cfg, err := parseConfig(botconfig.PluginConfig{Type: PLUGIN_TYPE, Config: map[string]interface{}{"onlymods": onlyMods}})
+		assert.NoError(t, err)
+		assert.Equal(t, onlyMods, cfg.OnlyMods)
 	}
+
+	_, err := parseConfig(botconfig.PluginConfig{Type: "giveaway"})
+	assert.EqualError(t, err, "Not a vote plugin config")
 }
@@ -9,12 +9,12 @@ import (
 
 // OnRun implements the hook from the Boot
 func (p *VotePlugin) OnRun() {
-	p.API.RegisterCommand(p, command)
+	p.API.RegisterCommand(p, command, commandHelp)
 }
 
 // OnStop implements the hook from the Boot
 func (p *VotePlugin) OnStop() {
-	p.API.RegisterCommand(p, command)
+	p.API.UnRegisterCommand(p, command)
 }
 
 // OnCommand implements the hook from the Bot
@@ -25,7 +25,7 @@ func (p *VotePlugin) OnCommand(cmd string, content string, post model.Post) {
 
 	subcommand, arguments := utils.ExtractSubCommandAndArgsString(content)
 
-	if !p.cfg.OnlyMods || utils.StringSliceContains(p.cfg.Mods, post.User.Name) {
+	if !p.cfg.OnlyMods || post.User.IsMod {
 		if subcommand != "end" && subcommand != "help" && len(arguments) > 0 {
 			p.onCommandVoteStart(subcommand+" "+arguments, post)
 			return
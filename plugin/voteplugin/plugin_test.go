@@ -85,7 +85,7 @@ func TestVotePlugin_HelpTextAndInvalidCommands(t *testing.T) {
 	content := ""
 	expectedPostFromPlugin := model.Post{
 		ChannelID: "CHANNEL ID",
-		Content:   p.helpText(),
+		Content:   commandHelp.Text(api.GetCallPrefix(), command),
 		IsPrivate: false,
 	}
 	p.OnCommand(commandVote, content, postToPlugin)
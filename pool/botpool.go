@@ -59,6 +59,8 @@ func NewBotPool(controlAPI *api.API, botProvider *providers.BotProvider) (*BotPo
 
 		controlAPI.AttachModuleGet("/bots/{botId}", b.getBotEndPoint)
 		controlAPI.AttachModuleDelete("/bots/{botId}", b.deleteBotEndpoint)
+
+		controlAPI.AttachModulePost("/bots/{botId}/events", b.postBotEventsEndpoint)
 	}
 
 	return b, nil
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/torlenor/redseligg/platform"
 	"github.com/torlenor/redseligg/utils"
 )
 
@@ -93,3 +94,21 @@ func (b *BotPool) getBotEndPoint(w http.ResponseWriter, r *http.Request) {
 
 	io.WriteString(w, string(out))
 }
+
+// postBotEventsEndpoint forwards events sent by the platform, e.g., the Slack Events API, to the bot.
+func (b *BotPool) postBotEventsEndpoint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	botID := vars["botId"]
+
+	b.mutex.Lock()
+	bot, ok := b.bots[botID]
+	b.mutex.Unlock()
+
+	receiver, isReceiver := bot.(platform.EventReceiver)
+	if !ok || !isReceiver {
+		http.Error(w, utils.GenerateErrorResponse(fmt.Sprintf("Bot ID %s does not receive events", botID)), http.StatusNotFound)
+		return
+	}
+
+	receiver.HandleEventRequest(w, r)
+}
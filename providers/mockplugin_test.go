@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"github.com/torlenor/redseligg/commanddispatcher"
 	"github.com/torlenor/redseligg/model"
 	"github.com/torlenor/redseligg/plugin"
 )
@@ -17,14 +18,26 @@ func (m *MockPlugin) SetBotPluginID(botID, pluginID string) {
 	m.PluginID = pluginID
 }
 
+func (m *MockPlugin) SetCommandNames(names commanddispatcher.CommandNames) {}
+
 func (m *MockPlugin) SetAPI(api plugin.API) error { return nil }
 
 // PluginType returns the plugin type
 func (m *MockPlugin) PluginType() string { return MockPluginType }
 
-func (m *MockPlugin) OnPost(model.Post)                                     {}
-func (m *MockPlugin) OnCommand(cmd string, content string, post model.Post) {}
-func (m *MockPlugin) OnRun()                                                {}
-func (m *MockPlugin) OnStop()                                               {}
-func (m *MockPlugin) OnReactionAdded(model.Reaction)                        {}
-func (m *MockPlugin) OnReactionRemoved(model.Reaction)                      {}
+func (m *MockPlugin) OnPost(model.Post)                                               {}
+func (m *MockPlugin) OnCommand(cmd string, content string, post model.Post)           {}
+func (m *MockPlugin) OnParsedCommand(string, commanddispatcher.Arguments, model.Post) {}
+func (m *MockPlugin) OnTrigger(string, commanddispatcher.TriggerMatch, model.Post)    {}
+func (m *MockPlugin) OnRun()                                                          {}
+func (m *MockPlugin) OnStop()                                                         {}
+func (m *MockPlugin) OnPostUpdated(model.MessageIdentifier, model.Post)               {}
+func (m *MockPlugin) OnPostDeleted(model.MessageIdentifier)                           {}
+func (m *MockPlugin) OnReactionAdded(model.Reaction)                                  {}
+func (m *MockPlugin) OnReactionRemoved(model.Reaction)                                {}
+func (m *MockPlugin) OnUserJoined(model.Membership)                                   {}
+func (m *MockPlugin) OnUserLeft(model.Membership)                                     {}
+func (m *MockPlugin) OnSubscription(model.Subscription)                               {}
+func (m *MockPlugin) OnRaid(model.Raid)                                               {}
+func (m *MockPlugin) OnCheer(model.Cheer)                                             {}
+func (m *MockPlugin) OnChannelStateChanged(model.ChannelState)                        {}
@@ -28,3 +28,25 @@ func (b *MemoryStorage) GetQuotesPluginQuotesList(botID, pluginID, identifier st
 	}
 	return storagemodels.QuotesPluginQuotesList{}, storage.ErrNotFound
 }
+
+// GetPermissions returns the permissions of a bot.
+func (b *MemoryStorage) GetPermissions(botID string) (storagemodels.Permissions, error) {
+	if q, ok := b.storage[botID][botPluginID][identPermissions]; ok {
+		if val, ok := q.(storagemodels.Permissions); ok {
+			return val, nil
+		}
+		return storagemodels.Permissions{}, fmt.Errorf("Stored data is not a valid Permissions")
+	}
+	return storagemodels.Permissions{}, storage.ErrNotFound
+}
+
+// GetMatrixState returns the state of a Matrix bot.
+func (b *MemoryStorage) GetMatrixState(botID string) (storagemodels.MatrixState, error) {
+	if q, ok := b.storage[botID][botPluginID][identMatrixState]; ok {
+		if val, ok := q.(storagemodels.MatrixState); ok {
+			return val, nil
+		}
+		return storagemodels.MatrixState{}, fmt.Errorf("Stored data is not a valid MatrixState")
+	}
+	return storagemodels.MatrixState{}, storage.ErrNotFound
+}
@@ -1,5 +1,11 @@
 package memorystorage
 
+// botPluginID is used as plugin ID for data which belongs to the bot itself.
+const botPluginID = ""
+
+const identPermissions = "permissions"
+const identMatrixState = "matrixstate"
+
 type memoryStorage map[string]interface{}
 type pluginStorage map[string]memoryStorage
 type botStorage map[string]pluginStorage
@@ -29,3 +29,29 @@ func (b *MemoryStorage) StoreQuotesPluginQuotesList(botID, pluginID, identifier
 
 	return nil
 }
+
+// StorePermissions stores the permissions of a bot.
+func (b *MemoryStorage) StorePermissions(botID string, data storagemodels.Permissions) error {
+	if _, ok := b.storage[botID]; !ok {
+		b.storage[botID] = make(pluginStorage)
+	}
+	if _, ok := b.storage[botID][botPluginID]; !ok {
+		b.storage[botID][botPluginID] = make(memoryStorage)
+	}
+	b.storage[botID][botPluginID][identPermissions] = data
+
+	return nil
+}
+
+// StoreMatrixState stores the state of a Matrix bot.
+func (b *MemoryStorage) StoreMatrixState(botID string, data storagemodels.MatrixState) error {
+	if _, ok := b.storage[botID]; !ok {
+		b.storage[botID] = make(pluginStorage)
+	}
+	if _, ok := b.storage[botID][botPluginID]; !ok {
+		b.storage[botID][botPluginID] = make(memoryStorage)
+	}
+	b.storage[botID][botPluginID][identMatrixState] = data
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/torlenor/redseligg/storage"
 	"github.com/torlenor/redseligg/storagemodels"
 )
 
@@ -113,3 +114,58 @@ func TestMemoryStorage_StoreQuotesPluginQuotesList(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStorage_StorePermissions(t *testing.T) {
+	botID := "SOME_ID"
+	data := storagemodels.Permissions{
+		Grants: []storagemodels.PermissionsGrant{
+			{UserID: "some id", UserName: "some user", Role: "mod"},
+		},
+		Commands: []storagemodels.PermissionsCommandRule{
+			{Command: "quote", Role: "mod", ChannelID: "some channel"},
+		},
+	}
+
+	b := New()
+	if err := b.StorePermissions(botID, data); err != nil {
+		t.Fatalf("MemoryStorage.StorePermissions() error = %v", err)
+	}
+
+	got, err := b.GetPermissions(botID)
+	if err != nil {
+		t.Fatalf("MemoryStorage.GetPermissions() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Errorf("MemoryStorage.GetPermissions() = %v, want %v", got, data)
+	}
+
+	if _, err := b.GetPermissions("OTHER_ID"); err != storage.ErrNotFound {
+		t.Errorf("MemoryStorage.GetPermissions() error = %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestMemoryStorage_StoreMatrixState(t *testing.T) {
+	botID := "SOME_ID"
+	data := storagemodels.MatrixState{
+		DeviceID:   "SOMEDEVICE",
+		NextBatch:  "s72595_4483_1934",
+		Encryption: `{"account":{}}`,
+	}
+
+	b := New()
+	if err := b.StoreMatrixState(botID, data); err != nil {
+		t.Fatalf("MemoryStorage.StoreMatrixState() error = %v", err)
+	}
+
+	got, err := b.GetMatrixState(botID)
+	if err != nil {
+		t.Fatalf("MemoryStorage.GetMatrixState() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Errorf("MemoryStorage.GetMatrixState() = %v, want %v", got, data)
+	}
+
+	if _, err := b.GetMatrixState("OTHER_ID"); err != storage.ErrNotFound {
+		t.Errorf("MemoryStorage.GetMatrixState() error = %v, want %v", err, storage.ErrNotFound)
+	}
+}
@@ -34,6 +34,22 @@ type timedMessagesPluginMessagesData struct {
 	Data storagemodels.TimedMessagesPluginMessages `bson:"data"`
 }
 
+type permissionsData struct {
+	BotID      string `bson:"bot_id"`
+	PluginID   string `bson:"plugin_id"`
+	Identifier string `bson:"identifier"`
+
+	Data storagemodels.Permissions `bson:"data"`
+}
+
+type matrixStateData struct {
+	BotID      string `bson:"bot_id"`
+	PluginID   string `bson:"plugin_id"`
+	Identifier string `bson:"identifier"`
+
+	Data storagemodels.MatrixState `bson:"data"`
+}
+
 type customCommandsPluginCommandsData struct {
 	BotID      string `bson:"bot_id"`
 	PluginID   string `bson:"plugin_id"`
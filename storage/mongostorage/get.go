@@ -89,3 +89,43 @@ func (b *MongoStorage) GetCustomCommandsPluginCommands(botID, pluginID, identifi
 
 	return data.Data, nil
 }
+
+// GetPermissions returns the permissions of a bot.
+func (b *MongoStorage) GetPermissions(botID string) (storagemodels.Permissions, error) {
+	if !b.IsConnected() {
+		return storagemodels.Permissions{}, fmt.Errorf("Not connected to MongoDB")
+	}
+
+	c := b.db.Collection(collectionPluginStorage)
+
+	filter := bson.M{fieldBotID: botID, fieldPluginID: botPluginID, fieldIdentifier: identPermissions}
+	var data permissionsData
+	err := c.FindOne(context.Background(), filter).Decode(&data)
+	if err == mongo.ErrNoDocuments {
+		return storagemodels.Permissions{}, storage.ErrNotFound
+	} else if err != nil {
+		return storagemodels.Permissions{}, fmt.Errorf("Error in finding the permissions for bot id %s: %s", botID, err)
+	}
+
+	return data.Data, nil
+}
+
+// GetMatrixState returns the state of a Matrix bot.
+func (b *MongoStorage) GetMatrixState(botID string) (storagemodels.MatrixState, error) {
+	if !b.IsConnected() {
+		return storagemodels.MatrixState{}, fmt.Errorf("Not connected to MongoDB")
+	}
+
+	c := b.db.Collection(collectionPluginStorage)
+
+	filter := bson.M{fieldBotID: botID, fieldPluginID: botPluginID, fieldIdentifier: identMatrixState}
+	var data matrixStateData
+	err := c.FindOne(context.Background(), filter).Decode(&data)
+	if err == mongo.ErrNoDocuments {
+		return storagemodels.MatrixState{}, storage.ErrNotFound
+	} else if err != nil {
+		return storagemodels.MatrixState{}, fmt.Errorf("Error in finding the Matrix state for bot id %s: %s", botID, err)
+	}
+
+	return data.Data, nil
+}
@@ -23,6 +23,11 @@ var fieldBotID = "bot_id"
 var fieldPluginID = "plugin_id"
 var fieldIdentifier = "identifier"
 
+// botPluginID is used as plugin ID for data which belongs to the bot itself.
+var botPluginID = ""
+var identPermissions = "permissions"
+var identMatrixState = "matrixstate"
+
 // MongoStorage is a MongoDB implementation of a storage.
 type MongoStorage struct {
 	log    *logrus.Entry
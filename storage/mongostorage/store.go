@@ -69,3 +69,31 @@ func (b *MongoStorage) StoreArchivePluginMessage(botID, pluginID, identifier str
 
 	return nil
 }
+
+// StorePermissions stores the permissions of a bot.
+func (b *MongoStorage) StorePermissions(botID string, data storagemodels.Permissions) error {
+	c := b.db.Collection(collectionPluginStorage)
+	filter := bson.M{fieldBotID: botID, fieldPluginID: botPluginID, fieldIdentifier: identPermissions}
+	_, err := c.ReplaceOne(context.Background(), filter,
+		permissionsData{BotID: botID, PluginID: botPluginID, Identifier: identPermissions, Data: data},
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StoreMatrixState stores the state of a Matrix bot.
+func (b *MongoStorage) StoreMatrixState(botID string, data storagemodels.MatrixState) error {
+	c := b.db.Collection(collectionPluginStorage)
+	filter := bson.M{fieldBotID: botID, fieldPluginID: botPluginID, fieldIdentifier: identMatrixState}
+	_, err := c.ReplaceOne(context.Background(), filter,
+		matrixStateData{BotID: botID, PluginID: botPluginID, Identifier: identMatrixState, Data: data},
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -159,6 +159,91 @@ func (s *SQLiteStorage) createTableRssPluginSubscription() error {
 	return nil
 }
 
+func (s *SQLiteStorage) createTablePermissionsGrant() error {
+	_, tableExists := s.db.Query("select * from " + tablePermissionsGrant + ";")
+	if tableExists == nil {
+		s.log.Debugf("Table %s already exists. Skipping creation", tablePermissionsGrant)
+		return nil
+	}
+
+	creatTableSQL := fmt.Sprintf(`CREATE TABLE %s (
+		"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		"bot_id" TEXT,
+		"user_id" TEXT,
+		"user_name" TEXT,
+		"role" TEXT,
+		"channel_id" TEXT
+	  );`, tablePermissionsGrant)
+
+	s.log.Printf("Creating %s table...", tablePermissionsGrant)
+	statement, err := s.db.Prepare(creatTableSQL)
+	if err != nil {
+		return err
+	}
+	_, err = statement.Exec()
+	if err != nil {
+		return err
+	}
+	s.log.Printf("%s table created", tablePermissionsGrant)
+	return nil
+}
+
+func (s *SQLiteStorage) createTablePermissionsCommandRule() error {
+	_, tableExists := s.db.Query("select * from " + tablePermissionsCommandRule + ";")
+	if tableExists == nil {
+		s.log.Debugf("Table %s already exists. Skipping creation", tablePermissionsCommandRule)
+		return nil
+	}
+
+	creatTableSQL := fmt.Sprintf(`CREATE TABLE %s (
+		"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		"bot_id" TEXT,
+		"command" TEXT,
+		"role" TEXT,
+		"channel_id" TEXT
+	  );`, tablePermissionsCommandRule)
+
+	s.log.Printf("Creating %s table...", tablePermissionsCommandRule)
+	statement, err := s.db.Prepare(creatTableSQL)
+	if err != nil {
+		return err
+	}
+	_, err = statement.Exec()
+	if err != nil {
+		return err
+	}
+	s.log.Printf("%s table created", tablePermissionsCommandRule)
+	return nil
+}
+
+func (s *SQLiteStorage) createTableMatrixState() error {
+	_, tableExists := s.db.Query("select * from " + tableMatrixState + ";")
+	if tableExists == nil {
+		s.log.Debugf("Table %s already exists. Skipping creation", tableMatrixState)
+		return nil
+	}
+
+	creatTableSQL := fmt.Sprintf(`CREATE TABLE %s (
+		"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		"bot_id" TEXT,
+		"device_id" TEXT,
+		"next_batch" TEXT,
+		"encryption" TEXT
+	  );`, tableMatrixState)
+
+	s.log.Printf("Creating %s table...", tableMatrixState)
+	statement, err := s.db.Prepare(creatTableSQL)
+	if err != nil {
+		return err
+	}
+	_, err = statement.Exec()
+	if err != nil {
+		return err
+	}
+	s.log.Printf("%s table created", tableMatrixState)
+	return nil
+}
+
 func (s *SQLiteStorage) createTables() error {
 	err := s.createTableArchivePluginMessage()
 	if err != nil {
@@ -180,5 +265,17 @@ func (s *SQLiteStorage) createTables() error {
 	if err != nil {
 		return err
 	}
+	err = s.createTablePermissionsGrant()
+	if err != nil {
+		return err
+	}
+	err = s.createTablePermissionsCommandRule()
+	if err != nil {
+		return err
+	}
+	err = s.createTableMatrixState()
+	if err != nil {
+		return err
+	}
 	return nil
 }
@@ -1,9 +1,11 @@
 package sqlitestorage
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/torlenor/redseligg/storage"
 	"github.com/torlenor/redseligg/storagemodels"
 )
 
@@ -147,3 +149,65 @@ func (b *SQLiteStorage) GetRssPluginSubscriptions(botID, pluginID string) (stora
 
 	return subscriptions, nil
 }
+
+// GetPermissions returns the permissions of a bot.
+func (b *SQLiteStorage) GetPermissions(botID string) (storagemodels.Permissions, error) {
+	permissions := storagemodels.Permissions{}
+
+	row, err := b.db.Query(
+		fmt.Sprintf(`SELECT user_id, user_name, role, channel_id FROM %s WHERE bot_id="%s"`,
+			tablePermissionsGrant,
+			botID))
+	if err != nil {
+		return storagemodels.Permissions{}, err
+	}
+	defer row.Close()
+
+	for row.Next() {
+		grant := storagemodels.PermissionsGrant{}
+		err := row.Scan(&grant.UserID, &grant.UserName, &grant.Role, &grant.ChannelID)
+		if err != nil {
+			b.log.Errorf("Error parsing SQLite select: %s", err)
+			continue
+		}
+		permissions.Grants = append(permissions.Grants, grant)
+	}
+
+	rowRules, err := b.db.Query(
+		fmt.Sprintf(`SELECT command, role, channel_id FROM %s WHERE bot_id="%s"`,
+			tablePermissionsCommandRule,
+			botID))
+	if err != nil {
+		return storagemodels.Permissions{}, err
+	}
+	defer rowRules.Close()
+
+	for rowRules.Next() {
+		rule := storagemodels.PermissionsCommandRule{}
+		err := rowRules.Scan(&rule.Command, &rule.Role, &rule.ChannelID)
+		if err != nil {
+			b.log.Errorf("Error parsing SQLite select: %s", err)
+			continue
+		}
+		permissions.Commands = append(permissions.Commands, rule)
+	}
+
+	return permissions, nil
+}
+
+// GetMatrixState returns the state of a Matrix bot.
+func (b *SQLiteStorage) GetMatrixState(botID string) (storagemodels.MatrixState, error) {
+	row := b.db.QueryRow(
+		fmt.Sprintf(`SELECT device_id, next_batch, encryption FROM %s WHERE bot_id=?`, tableMatrixState),
+		botID)
+
+	state := storagemodels.MatrixState{}
+	err := row.Scan(&state.DeviceID, &state.NextBatch, &state.Encryption)
+	if err == sql.ErrNoRows {
+		return storagemodels.MatrixState{}, storage.ErrNotFound
+	} else if err != nil {
+		return storagemodels.MatrixState{}, err
+	}
+
+	return state, nil
+}
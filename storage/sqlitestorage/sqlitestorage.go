@@ -19,6 +19,9 @@ var tableCustomCommandsPluginCommands = "custom_commands_plugin_commands"
 var tableQuotesPluginQuote = "quotes_plugin_quote"
 var tableTimedMessagesPluginMessage = "timed_messages_plugin_message"
 var tableRssPluginSubscription = "rss_plugin_subscription"
+var tablePermissionsGrant = "permissions_grant"
+var tablePermissionsCommandRule = "permissions_command_rule"
+var tableMatrixState = "matrix_state"
 
 // SQLiteStorage is a SQLite implementation of a storage.
 type SQLiteStorage struct {
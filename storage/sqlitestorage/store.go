@@ -122,3 +122,69 @@ func (b *SQLiteStorage) StoreRssPluginSubscription(botID, pluginID, identifier s
 
 	return nil
 }
+
+// StorePermissions stores the permissions of a bot.
+func (b *SQLiteStorage) StorePermissions(botID string, data storagemodels.Permissions) error {
+	for _, table := range []string{tablePermissionsGrant, tablePermissionsCommandRule} {
+		deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE bot_id=?`, table)
+		statement, err := b.db.Prepare(deleteSQL)
+		if err != nil {
+			return fmt.Errorf("Could not prepare sql statement: %s", err)
+		}
+		_, err = statement.Exec(botID)
+		if err != nil {
+			return fmt.Errorf("Could not clean up table: %s", err)
+		}
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO %s(bot_id, user_id, user_name, role, channel_id) VALUES (?, ?, ?, ?, ?)`, tablePermissionsGrant)
+	statement, err := b.db.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("Could not prepare sql statement: %s", err)
+	}
+	for _, grant := range data.Grants {
+		_, err = statement.Exec(botID, grant.UserID, grant.UserName, grant.Role, grant.ChannelID)
+		if err != nil {
+			return fmt.Errorf("Could not insert data: %s", err)
+		}
+	}
+
+	insertSQL = fmt.Sprintf(`INSERT INTO %s(bot_id, command, role, channel_id) VALUES (?, ?, ?, ?)`, tablePermissionsCommandRule)
+	statement, err = b.db.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("Could not prepare sql statement: %s", err)
+	}
+	for _, rule := range data.Commands {
+		_, err = statement.Exec(botID, rule.Command, rule.Role, rule.ChannelID)
+		if err != nil {
+			return fmt.Errorf("Could not insert data: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// StoreMatrixState stores the state of a Matrix bot.
+func (b *SQLiteStorage) StoreMatrixState(botID string, data storagemodels.MatrixState) error {
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE bot_id=?`, tableMatrixState)
+	statement, err := b.db.Prepare(deleteSQL)
+	if err != nil {
+		return fmt.Errorf("Could not prepare sql statement: %s", err)
+	}
+	_, err = statement.Exec(botID)
+	if err != nil {
+		return fmt.Errorf("Could not clean up table: %s", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO %s(bot_id, device_id, next_batch, encryption) VALUES (?, ?, ?, ?)`, tableMatrixState)
+	statement, err = b.db.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("Could not prepare sql statement: %s", err)
+	}
+	_, err = statement.Exec(botID, data.DeviceID, data.NextBatch, data.Encryption)
+	if err != nil {
+		return fmt.Errorf("Could not insert data: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,12 @@
+package storagemodels
+
+// MatrixState is a storage model used by the Matrix platform.
+// It is used to continue where the bot stopped after a restart.
+type MatrixState struct {
+	// DeviceID is the device the bot is logged in with.
+	DeviceID string
+	// NextBatch is the token of the last sync, i.e., the point in time up to which events have been handled.
+	NextBatch string
+	// Encryption is the serialized end-to-end encryption state of the device, including its private keys.
+	Encryption string
+}
@@ -0,0 +1,31 @@
+// Package storagemodels contains custom storage models used by plugins
+package storagemodels
+
+// PermissionsGrant is a Permissions storage model.
+// It grants a role to a user, either in all channels or only in one channel.
+type PermissionsGrant struct {
+	UserID   string
+	UserName string
+
+	Role string
+
+	// ChannelID is empty if the grant is valid in all channels.
+	ChannelID string
+}
+
+// PermissionsCommandRule is a Permissions storage model.
+// It restricts a command to users with a role, either in all channels or only in one channel.
+type PermissionsCommandRule struct {
+	Command string
+	Role    string
+
+	// ChannelID is empty if the rule is valid in all channels.
+	ChannelID string
+}
+
+// Permissions is a storage model used by the bot itself.
+// It is used to store all roles granted and all command rules set at runtime.
+type Permissions struct {
+	Grants   []PermissionsGrant
+	Commands []PermissionsCommandRule
+}
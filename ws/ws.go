@@ -95,7 +95,15 @@ func (c *Client) Close() error {
 // ReadMessage can be used to read the next message from WebSocket.
 // it blocks until somehing is received or the ws is closed.
 func (c *Client) ReadMessage() (int, []byte, error) {
-	return c.ws.ReadMessage()
+	c.startStopMutex.Lock()
+	ws := c.ws
+	c.startStopMutex.Unlock()
+
+	if ws == nil {
+		return 0, nil, fmt.Errorf("WebSocket client not connected. Use Dial first")
+	}
+
+	return ws.ReadMessage()
 }
 
 // SendMessage is used to send a message via the connected WebSocket to the server